@@ -0,0 +1,121 @@
+package audit_test
+
+import (
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func prepareChain(t *testing.T, n int) (*gorm.DB, *audit.EventRepositoryImpl) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&audit.Event{}, &audit.Head{}))
+
+	repo := &audit.EventRepositoryImpl{Db: db, Chain: audit.NewChain("mock_audit_key")}
+	for i := 0; i < n; i++ {
+		assert.NoError(t, repo.Record(&audit.Event{
+			ActorId:    "mock_user_id",
+			Action:     audit.ActionLogin,
+			TargetType: audit.TargetUser,
+			TargetId:   "mock_user_id",
+			Outcome:    audit.OutcomeSuccess,
+		}))
+	}
+	return db, repo
+}
+
+func TestVerify_ShouldAcceptUntamperedChain(t *testing.T) {
+	_, repo := prepareChain(t, 3)
+
+	var events []audit.Event
+	var head audit.Head
+	assert.NoError(t, repo.FetchAll(&events, &head))
+
+	ok, brokenAt := repo.Chain.Verify(events, head)
+
+	assert.Len(t, events, 3)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), brokenAt)
+	assert.Equal(t, events[0].Hash, events[1].PrevHash)
+}
+
+func TestVerify_ShouldDetectModifiedEvent(t *testing.T) {
+	db, repo := prepareChain(t, 3)
+	assert.NoError(t, db.Exec("UPDATE audit_events SET outcome = ? WHERE seq = 2", audit.OutcomeFailure).Error)
+
+	var events []audit.Event
+	var head audit.Head
+	assert.NoError(t, repo.FetchAll(&events, &head))
+
+	ok, brokenAt := repo.Chain.Verify(events, head)
+
+	assert.False(t, ok)
+	assert.Equal(t, uint64(2), brokenAt)
+}
+
+func TestVerify_ShouldDetectDeletedEvent(t *testing.T) {
+	db, repo := prepareChain(t, 3)
+	assert.NoError(t, db.Exec("DELETE FROM audit_events WHERE seq = 2").Error)
+
+	var events []audit.Event
+	var head audit.Head
+	assert.NoError(t, repo.FetchAll(&events, &head))
+
+	ok, brokenAt := repo.Chain.Verify(events, head)
+
+	assert.False(t, ok)
+	assert.Equal(t, uint64(3), brokenAt)
+}
+
+func TestVerify_ShouldDetectEventsDeletedFromTheEnd(t *testing.T) {
+	db, repo := prepareChain(t, 3)
+	assert.NoError(t, db.Exec("DELETE FROM audit_events WHERE seq = 3").Error)
+
+	var events []audit.Event
+	var head audit.Head
+	assert.NoError(t, repo.FetchAll(&events, &head))
+
+	ok, brokenAt := repo.Chain.Verify(events, head)
+
+	assert.False(t, ok)
+	assert.Equal(t, uint64(3), brokenAt)
+}
+
+func TestVerify_ShouldDetectEventsDeletedFromTheEndBeforeAnAppend(t *testing.T) {
+	db, repo := prepareChain(t, 3)
+	assert.NoError(t, db.Exec("DELETE FROM audit_events WHERE seq = 3").Error)
+	assert.NoError(t, repo.Record(&audit.Event{ActorId: "mock_user_id", Action: audit.ActionLogin, Outcome: audit.OutcomeSuccess}))
+
+	var events []audit.Event
+	var head audit.Head
+	assert.NoError(t, repo.FetchAll(&events, &head))
+
+	ok, brokenAt := repo.Chain.Verify(events, head)
+
+	assert.False(t, ok)
+	assert.Equal(t, uint64(4), brokenAt)
+}
+
+func TestVerify_ShouldDetectEventRehashedWithoutTheKey(t *testing.T) {
+	db, repo := prepareChain(t, 1)
+
+	var events []audit.Event
+	var head audit.Head
+	assert.NoError(t, repo.FetchAll(&events, &head))
+
+	forged := events[0]
+	forged.Outcome = audit.OutcomeFailure
+	forged.Hash = audit.NewChain("guessed_key").Hash(&forged)
+	assert.NoError(t, db.Save(&forged).Error)
+	assert.NoError(t, db.Model(&audit.Head{}).Where("id = ?", 1).Update("hash", forged.Hash).Error)
+
+	assert.NoError(t, repo.FetchAll(&events, &head))
+
+	ok, brokenAt := repo.Chain.Verify(events, head)
+
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), brokenAt)
+}
@@ -0,0 +1,89 @@
+package audit
+
+import "time"
+
+type ListEventsRequest struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type EventResponse struct {
+	Id         string     `json:"id"`
+	Seq        uint64     `json:"seq"`
+	ActorId    string     `json:"actor_id,omitempty"`
+	Action     Action     `json:"action"`
+	TargetType TargetType `json:"target_type,omitempty"`
+	TargetId   string     `json:"target_id,omitempty"`
+	Detail     string     `json:"detail,omitempty"`
+	Ip         string     `json:"ip,omitempty"`
+	Outcome    Outcome    `json:"outcome"`
+	CreatedAt  int64      `json:"created_at"`
+}
+
+func (er *EventResponse) load(e Event) {
+	er.Id = e.Id
+	er.Seq = e.Seq
+	er.ActorId = e.ActorId
+	er.Action = e.Action
+	er.TargetType = e.TargetType
+	er.TargetId = e.TargetId
+	er.Detail = e.Detail
+	er.Ip = e.Ip
+	er.Outcome = e.Outcome
+	er.CreatedAt = e.CreatedAt.Unix()
+}
+
+type EventListResponse struct {
+	Events   []EventResponse `json:"events"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+	Total    int64           `json:"total"`
+}
+
+func (elr *EventListResponse) load(events []Event) {
+	var eventResponses = make([]EventResponse, 0)
+	for _, e := range events {
+		er := EventResponse{}
+		er.load(e)
+		eventResponses = append(eventResponses, er)
+	}
+	elr.Events = eventResponses
+}
+
+// ExportedEvent carries every hashed field, with the full precision
+// timestamp, so that an export can be verified independently of the server.
+type ExportedEvent struct {
+	Id         string     `json:"id"`
+	Seq        uint64     `json:"seq"`
+	ActorId    string     `json:"actor_id"`
+	Action     Action     `json:"action"`
+	TargetType TargetType `json:"target_type"`
+	TargetId   string     `json:"target_id"`
+	Detail     string     `json:"detail"`
+	Ip         string     `json:"ip"`
+	Outcome    Outcome    `json:"outcome"`
+	CreatedAt  string     `json:"created_at"`
+	PrevHash   string     `json:"prev_hash"`
+	Hash       string     `json:"hash"`
+}
+
+func (ee *ExportedEvent) load(e Event) {
+	ee.Id = e.Id
+	ee.Seq = e.Seq
+	ee.ActorId = e.ActorId
+	ee.Action = e.Action
+	ee.TargetType = e.TargetType
+	ee.TargetId = e.TargetId
+	ee.Detail = e.Detail
+	ee.Ip = e.Ip
+	ee.Outcome = e.Outcome
+	ee.CreatedAt = e.CreatedAt.UTC().Format(time.RFC3339Nano)
+	ee.PrevHash = e.PrevHash
+	ee.Hash = e.Hash
+}
+
+type ExportResponse struct {
+	Events   []ExportedEvent `json:"events"`
+	Verified bool            `json:"verified"`
+	BrokenAt uint64          `json:"broken_at,omitempty"`
+}
@@ -0,0 +1,59 @@
+package audit
+
+type Action string
+
+const (
+	ActionLogin              Action = "LOGIN"
+	ActionLoginFailed        Action = "LOGIN_FAILED"
+	ActionTokenRefresh       Action = "TOKEN_REFRESH"
+	ActionPasswordChange     Action = "PASSWORD_CHANGE"
+	ActionVaultCreate        Action = "VAULT_CREATE"
+	ActionVaultUpdate        Action = "VAULT_UPDATE"
+	ActionVaultDelete        Action = "VAULT_DELETE"
+	ActionVaultRestore       Action = "VAULT_RESTORE"
+	ActionVaultPurge         Action = "VAULT_PURGE"
+	ActionSecretRead         Action = "SECRET_READ"
+	ActionSecretWrite        Action = "SECRET_WRITE"
+	ActionSecretDelete       Action = "SECRET_DELETE"
+	ActionSecretRestore      Action = "SECRET_RESTORE"
+	ActionSecretPurge        Action = "SECRET_PURGE"
+	ActionSecretMove         Action = "SECRET_MOVE"
+	ActionSecretCopy         Action = "SECRET_COPY"
+	ActionSecretImport       Action = "SECRET_IMPORT"
+	ActionSecretExport       Action = "SECRET_EXPORT"
+	ActionUserDisable        Action = "USER_DISABLE"
+	ActionUserEnable         Action = "USER_ENABLE"
+	ActionPasswordResetForce Action = "PASSWORD_RESET_FORCE"
+	ActionSessionsRevoke     Action = "SESSIONS_REVOKE"
+	ActionRoleUpdate         Action = "ROLE_UPDATE"
+	ActionSshRoleCreate      Action = "SSH_ROLE_CREATE"
+	ActionSshRoleDelete      Action = "SSH_ROLE_DELETE"
+	ActionSshCertIssue       Action = "SSH_CERT_ISSUE"
+	ActionCertIssue          Action = "CERT_ISSUE"
+	ActionCertRevoke         Action = "CERT_REVOKE"
+	ActionAttachmentUpload   Action = "ATTACHMENT_UPLOAD"
+	ActionAttachmentDownload Action = "ATTACHMENT_DOWNLOAD"
+	ActionAttachmentDelete   Action = "ATTACHMENT_DELETE"
+	ActionFolderCreate       Action = "FOLDER_CREATE"
+	ActionFolderUpdate       Action = "FOLDER_UPDATE"
+	ActionFolderDelete       Action = "FOLDER_DELETE"
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "SUCCESS"
+	OutcomeFailure Outcome = "FAILURE"
+)
+
+type TargetType string
+
+const (
+	TargetUser        TargetType = "USER"
+	TargetVault       TargetType = "VAULT"
+	TargetSecret      TargetType = "SECRET"
+	TargetSshRole     TargetType = "SSH_ROLE"
+	TargetCertificate TargetType = "CERTIFICATE"
+	TargetAttachment  TargetType = "ATTACHMENT"
+	TargetFolder      TargetType = "FOLDER"
+)
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+)
+
+type AuditHandler struct {
+	Repo  EventRepository
+	Chain *Chain
+}
+
+func (ah *AuditHandler) FetchMyEvents(ctx *gin.Context) {
+	var ler ListEventsRequest
+	if err := ctx.ShouldBindQuery(&ler); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid query parameters"})
+		return
+	}
+	if ler.Page == 0 {
+		ler.Page = 1
+	}
+	if ler.PageSize == 0 {
+		ler.PageSize = defaultPageSize
+	}
+
+	var events []Event
+	total, err := ah.Repo.FetchByUserId(ctx.GetString("user_id"), (ler.Page-1)*ler.PageSize, ler.PageSize, &events)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	response := EventListResponse{Page: ler.Page, PageSize: ler.PageSize, Total: total}
+	response.load(events)
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ah *AuditHandler) ExportEvents(ctx *gin.Context) {
+	var events []Event
+	var head Head
+	if err := ah.Repo.FetchAll(&events, &head); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	verified, brokenAt := ah.Chain.Verify(events, head)
+
+	exported := make([]ExportedEvent, 0, len(events))
+	for _, e := range events {
+		ee := ExportedEvent{}
+		ee.load(e)
+		exported = append(exported, ee)
+	}
+
+	ctx.Header("Content-Disposition", "attachment; filename=audit-log.json")
+	ctx.JSON(http.StatusOK, ExportResponse{
+		Events:   exported,
+		Verified: verified,
+		BrokenAt: brokenAt,
+	})
+}
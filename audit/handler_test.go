@@ -0,0 +1,91 @@
+package audit_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	audit_mocks "github.com/adarsh-a-tw/passwordly/audit/mocks"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuditHandler_FetchMyEvents_ShouldFetchEventsOfCurrentUser(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/audit/me?page=2&page_size=5", "GET", nil)
+	ctx.Set("user_id", "mock_user_id")
+
+	repo := &audit_mocks.EventRepository{}
+	repo.On("FetchByUserId", "mock_user_id", 5, 5, mock.AnythingOfType("*[]audit.Event")).Return(int64(6), nil).Run(func(args mock.Arguments) {
+		arg := args.Get(3).(*[]audit.Event)
+		*arg = []audit.Event{{
+			Seq:       1,
+			Id:        "mock_event_id",
+			ActorId:   "mock_user_id",
+			Action:    audit.ActionLogin,
+			Outcome:   audit.OutcomeSuccess,
+			CreatedAt: time.Now(),
+		}}
+	})
+
+	ah := audit.AuditHandler{
+		Repo: repo,
+	}
+
+	ah.FetchMyEvents(ctx)
+
+	var actualResponse audit.EventListResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	repo.AssertCalled(t, "FetchByUserId", "mock_user_id", 5, 5, mock.AnythingOfType("*[]audit.Event"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int64(6), actualResponse.Total)
+	assert.Len(t, actualResponse.Events, 1)
+	assert.Equal(t, audit.ActionLogin, actualResponse.Events[0].Action)
+}
+
+func TestAuditHandler_FetchMyEvents_ShouldThrowInternalServerErrorIfFetchFails(t *testing.T) {
+	expectedResponse := common.ErrorResponse{Message: "Something went wrong. Try again."}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/audit/me", "GET", nil)
+	ctx.Set("user_id", "mock_user_id")
+
+	repo := &audit_mocks.EventRepository{}
+	repo.On("FetchByUserId", "mock_user_id", 0, 20, mock.AnythingOfType("*[]audit.Event")).Return(int64(0), errors.New("MOCK_ERROR"))
+
+	ah := audit.AuditHandler{
+		Repo: repo,
+	}
+
+	ah.FetchMyEvents(ctx)
+
+	var actualResponse common.ErrorResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, expectedResponse, actualResponse)
+}
+
+func TestAuditHandler_ExportEvents_ShouldExportVerifiedChain(t *testing.T) {
+	_, repo := prepareChain(t, 2)
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/audit/export", "GET", nil)
+
+	ah := audit.AuditHandler{
+		Repo:  repo,
+		Chain: repo.Chain,
+	}
+
+	ah.ExportEvents(ctx)
+
+	var actualResponse audit.ExportResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, actualResponse.Verified)
+	assert.Len(t, actualResponse.Events, 2)
+	assert.Equal(t, actualResponse.Events[0].Hash, actualResponse.Events[1].PrevHash)
+}
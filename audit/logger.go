@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Log records an event for the current request. The caller's IP is taken from
+// ctx and the actor defaults to the authenticated user. A nil recorder is
+// ignored and failures are only logged, so auditing never changes the
+// outcome of the request it describes.
+func Log(r Recorder, ctx *gin.Context, e Event) {
+	if r == nil {
+		return
+	}
+	if e.ActorId == "" {
+		e.ActorId = ctx.GetString("user_id")
+	}
+	if ctx.Request != nil {
+		e.Ip = ctx.ClientIP()
+	}
+	if e.Outcome == "" {
+		e.Outcome = OutcomeSuccess
+	}
+	if err := r.Record(&e); err != nil {
+		log.Println("Could not record audit event:", err)
+	}
+}
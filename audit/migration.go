@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// RekeyLegacyChain rehashes events chained before hashes were keyed and
+// records the head of the chain. The old chain is verified first, so that
+// rekeying cannot launder rows edited before the upgrade. It does nothing
+// once the chain has a head.
+func RekeyLegacyChain(db *gorm.DB, chain *Chain) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var heads int64
+		if err := tx.Model(&Head{}).Count(&heads).Error; err != nil {
+			return err
+		}
+		var events []Event
+		if err := tx.Order("seq ASC").Find(&events).Error; err != nil {
+			return err
+		}
+		if heads > 0 || len(events) == 0 {
+			return nil
+		}
+
+		prevHash := genesisHash
+		for i := range events {
+			e := &events[i]
+			if e.Seq != uint64(i)+1 || e.PrevHash != prevHash || e.legacyHash() != e.Hash {
+				return errors.New("the unkeyed audit chain is broken, leaving it as it is")
+			}
+			prevHash = e.Hash
+		}
+
+		prevHash = genesisHash
+		for i := range events {
+			e := &events[i]
+			e.PrevHash = prevHash
+			e.Hash = chain.Hash(e)
+			if err := tx.Model(e).UpdateColumns(map[string]any{"prev_hash": e.PrevHash, "hash": e.Hash}).Error; err != nil {
+				return err
+			}
+			prevHash = e.Hash
+		}
+
+		last := events[len(events)-1]
+		return tx.Create(&Head{Id: headId, Seq: last.Seq, Hash: last.Hash}).Error
+	})
+}
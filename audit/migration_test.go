@@ -0,0 +1,77 @@
+package audit_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// legacyChain records events the way they were chained before hashes were
+// keyed.
+func legacyChain(t *testing.T, n int) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&audit.Event{}, &audit.Head{}))
+
+	prevHash := strings.Repeat("0", 64)
+	for i := 1; i <= n; i++ {
+		e := audit.Event{
+			Seq:       uint64(i),
+			Id:        "event_" + strconv.Itoa(i),
+			ActorId:   "mock_user_id",
+			Action:    audit.ActionLogin,
+			Outcome:   audit.OutcomeSuccess,
+			CreatedAt: time.Date(2026, 1, 1, 0, 0, i, 0, time.UTC),
+			PrevHash:  prevHash,
+		}
+		fields := []string{
+			e.PrevHash, strconv.FormatUint(e.Seq, 10), e.Id, e.ActorId, string(e.Action),
+			string(e.TargetType), e.TargetId, e.Detail, e.Ip, string(e.Outcome),
+			e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		}
+		sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+		e.Hash = hex.EncodeToString(sum[:])
+		assert.NoError(t, db.Create(&e).Error)
+		prevHash = e.Hash
+	}
+	return db
+}
+
+func TestRekeyLegacyChain_ShouldRekeyAnUntamperedChain(t *testing.T) {
+	db := legacyChain(t, 3)
+	repo := &audit.EventRepositoryImpl{Db: db, Chain: audit.NewChain("mock_audit_key")}
+
+	assert.NoError(t, audit.RekeyLegacyChain(db, repo.Chain))
+	assert.NoError(t, audit.RekeyLegacyChain(db, repo.Chain))
+	assert.NoError(t, repo.Record(&audit.Event{ActorId: "mock_user_id", Action: audit.ActionLogin, Outcome: audit.OutcomeSuccess}))
+
+	var events []audit.Event
+	var head audit.Head
+	assert.NoError(t, repo.FetchAll(&events, &head))
+
+	ok, brokenAt := repo.Chain.Verify(events, head)
+
+	assert.Len(t, events, 4)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), brokenAt)
+}
+
+func TestRekeyLegacyChain_ShouldLeaveABrokenChainAsItIs(t *testing.T) {
+	db := legacyChain(t, 3)
+	assert.NoError(t, db.Exec("UPDATE audit_events SET outcome = ? WHERE seq = 2", audit.OutcomeFailure).Error)
+	chain := audit.NewChain("mock_audit_key")
+
+	assert.Error(t, audit.RekeyLegacyChain(db, chain))
+
+	var heads int64
+	assert.NoError(t, db.Model(&audit.Head{}).Count(&heads).Error)
+	assert.Equal(t, int64(0), heads)
+}
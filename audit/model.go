@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a single entry of the append-only audit log. Every event stores
+// the keyed hash of its predecessor, so editing, inserting or deleting a row
+// breaks the chain from that point on.
+type Event struct {
+	Seq        uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Id         string `gorm:"unique;notNull"`
+	ActorId    string `gorm:"index"`
+	Action     Action `gorm:"notNull"`
+	TargetType TargetType
+	TargetId   string `gorm:"index"`
+	Detail     string
+	Ip         string
+	Outcome    Outcome   `gorm:"notNull"`
+	CreatedAt  time.Time `gorm:"notNull"`
+	PrevHash   string    `gorm:"notNull"`
+	Hash       string    `gorm:"notNull"`
+}
+
+func (Event) TableName() string {
+	return "audit_events"
+}
+
+// Head records the sequence number and hash of the newest event. It is
+// written in the same transaction as every event but kept out of
+// audit_events, so that deleting events from the end of the chain is
+// noticed.
+type Head struct {
+	Id   uint   `gorm:"primaryKey;autoIncrement:false"`
+	Seq  uint64 `gorm:"notNull"`
+	Hash string `gorm:"notNull"`
+}
+
+func (Head) TableName() string {
+	return "audit_head"
+}
+
+// headId is the primary key of the single Head row.
+const headId = 1
+
+// Chain hashes events under a key held by the server rather than the
+// database, so that whoever can write audit_events cannot recompute the
+// hashes of the rows they change.
+type Chain struct {
+	key []byte
+}
+
+// NewChain derives a dedicated key from secret, which may be shared with
+// other uses such as the encryption key.
+func NewChain(secret string) *Chain {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("passwordly audit chain"))
+	return &Chain{key: mac.Sum(nil)}
+}
+
+// Hash digests every field of the event together with PrevHash.
+// CreatedAt is truncated to microseconds when recorded, which every
+// supported database stores losslessly.
+func (c *Chain) Hash(e *Event) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(e.digestInput()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify walks events in sequence order and returns the sequence number of
+// the first event whose link or hash does not match, or that is missing
+// from the end of the chain recorded by head. ok is false when the chain
+// has been tampered with.
+func (c *Chain) Verify(events []Event, head Head) (ok bool, brokenAt uint64) {
+	prevSeq, prevHash := uint64(0), genesisHash
+	for _, e := range events {
+		if e.Seq != prevSeq+1 || e.PrevHash != prevHash || !hmac.Equal([]byte(c.Hash(&e)), []byte(e.Hash)) {
+			return false, e.Seq
+		}
+		prevSeq, prevHash = e.Seq, e.Hash
+	}
+
+	switch {
+	case prevSeq < head.Seq:
+		return false, prevSeq + 1
+	case prevSeq > head.Seq:
+		return false, head.Seq + 1
+	case head.Seq > 0 && prevHash != head.Hash:
+		return false, head.Seq
+	}
+	return true, 0
+}
+
+func (e *Event) digestInput() string {
+	fields := []string{
+		e.PrevHash,
+		strconv.FormatUint(e.Seq, 10),
+		e.Id,
+		e.ActorId,
+		string(e.Action),
+		string(e.TargetType),
+		e.TargetId,
+		e.Detail,
+		e.Ip,
+		string(e.Outcome),
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+	return strings.Join(fields, "\x1f")
+}
+
+// legacyHash is the unkeyed digest events were chained with before Chain.
+// It is only used to check old chains before they are rekeyed.
+func (e *Event) legacyHash() string {
+	sum := sha256.Sum256([]byte(e.digestInput()))
+	return hex.EncodeToString(sum[:])
+}
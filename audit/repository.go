@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	genesisHash   = "0000000000000000000000000000000000000000000000000000000000000000"
+	appendRetries = 3
+)
+
+// appendMu serialises appends within this process. Appends racing from other
+// processes collide on the Seq primary key and are retried.
+var appendMu sync.Mutex
+
+type Recorder interface {
+	Record(e *Event) error
+}
+
+type EventRepository interface {
+	Recorder
+	FetchByUserId(userId string, offset int, limit int, events *[]Event) (int64, error)
+	FetchAll(events *[]Event, head *Head) error
+}
+
+type EventRepositoryImpl struct {
+	Db    *gorm.DB
+	Chain *Chain
+}
+
+// Record appends e to the chain, filling in its sequence number, timestamp
+// and hashes. Events are never updated or deleted afterwards.
+func (er *EventRepositoryImpl) Record(e *Event) error {
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	e.Id = uuid.NewString()
+	e.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	var err error
+	for i := 0; i < appendRetries; i++ {
+		if err = er.Db.Transaction(func(tx *gorm.DB) error {
+			return er.appendEvent(tx, e)
+		}); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (er *EventRepositoryImpl) FetchByUserId(userId string, offset int, limit int, events *[]Event) (int64, error) {
+	q := er.Db.Model(&Event{}).Where(
+		"actor_id = ? OR (target_type = ? AND target_id = ?)", userId, TargetUser, userId,
+	)
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	err := q.Order("seq DESC").Offset(offset).Limit(limit).Find(events).Error
+	return total, err
+}
+
+// FetchAll reads the head before the events, leaving out any appended in
+// between, so that a concurrent append is not mistaken for tampering.
+func (er *EventRepositoryImpl) FetchAll(events *[]Event, head *Head) error {
+	if err := er.Db.Limit(1).Find(head, headId).Error; err != nil {
+		return err
+	}
+
+	q := er.Db.Order("seq ASC")
+	if head.Seq > 0 {
+		q = q.Where("seq <= ?", head.Seq)
+	}
+	return q.Find(events).Error
+}
+
+// appendEvent continues the chain from its head rather than from the newest
+// row, so that events appended after a truncation leave a gap behind. The
+// newest row is only used when there is no head yet.
+func (er *EventRepositoryImpl) appendEvent(tx *gorm.DB, e *Event) error {
+	var head Head
+	if err := tx.Limit(1).Find(&head, headId).Error; err != nil {
+		return err
+	}
+	if head.Seq == 0 {
+		var last Event
+		if err := tx.Order("seq DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+		head.Seq, head.Hash = last.Seq, last.Hash
+	}
+
+	e.Seq = head.Seq + 1
+	e.PrevHash = head.Hash
+	if head.Seq == 0 {
+		e.PrevHash = genesisHash
+	}
+	e.Hash = er.Chain.Hash(e)
+
+	if err := tx.Create(e).Error; err != nil {
+		return err
+	}
+	return tx.Save(&Head{Id: headId, Seq: e.Seq, Hash: e.Hash}).Error
+}
@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/middleware"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupRoutes(r *gin.Engine, db *gorm.DB, sv middleware.SessionValidator, adminRole string) {
+	// Authenticated Routes
+	rg := r.Group("/api/v1/audit")
+	rg.Use(middleware.TokenAuthMiddleware(sv))
+
+	// Admin Routes
+	arg := r.Group("/api/v1/admin/audit")
+	arg.Use(middleware.TokenAuthMiddleware(sv), middleware.RoleAuthMiddleware(adminRole))
+
+	chain := NewChain(common.Cfg.AuditKey)
+	ah := AuditHandler{
+		Repo:  &EventRepositoryImpl{Db: db, Chain: chain},
+		Chain: chain,
+	}
+
+	rg.GET("/me", ah.FetchMyEvents)
+
+	arg.GET("/export", ah.ExportEvents)
+}
@@ -0,0 +1,237 @@
+// Package backup reads and writes the encrypted files vaults are exported
+// to and restored from.
+//
+// A backup is an age file (https://age-encryption.org/v1) encrypted either
+// with a passphrase, using age's scrypt recipient, or to the public key of
+// an age X25519 identity. Decrypted, it is a JSON document:
+//
+//	{
+//	  "format": "passwordly-vaults",
+//	  "version": 1,
+//	  "created_at": "2024-01-02T03:04:05Z",
+//	  "checksum": "sha256:9f86d0...",
+//	  "content": { ... }
+//	}
+//
+// format names what content holds and version its layout, which only
+// changes in ways older readers would misread. checksum is the SHA-256 of
+// content exactly as it appears in the file, verified on top of the
+// authentication age gives every chunk. The content of FormatVaults is
+// described by Vaults. Backups of the whole database are streamed in a
+// layout of their own, described at FormatDatabase.
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"filippo.io/age"
+)
+
+// Version is the layout of the documents written by Seal. Open reads this
+// version and older ones.
+const Version = 1
+
+// Scrypt costs of passphrases as log2 of N. Opening refuses files costing
+// more than MaxWorkFactor, about 256 MiB of memory, so that an uploaded
+// backup cannot tie up the server.
+const (
+	DefaultWorkFactor = 18
+	MaxWorkFactor     = 18
+)
+
+// scryptSlots lets one passphrase be stretched at a time, so that
+// concurrent exports and restores hold at most MaxWorkFactor of memory
+// between them.
+var scryptSlots = make(chan struct{}, 1)
+
+// ageMagic starts every age file.
+const ageMagic = "age-encryption.org/v1\n"
+
+var (
+	ErrNotBackup          = errors.New("The file is not an encrypted backup")
+	ErrWrongFormat        = errors.New("The backup holds a different kind of data")
+	ErrUnsupportedVersion = errors.New("The backup was written by a newer version")
+	ErrWrongKey           = errors.New("The passphrase or age identity does not open the backup")
+	ErrCorrupt            = errors.New("The backup is damaged")
+	ErrChecksum           = errors.New("The backup content does not match its checksum")
+	ErrProtection         = errors.New("Either a passphrase or an age key is required, not both")
+	ErrInvalidAgeKey      = errors.New("Invalid age key")
+)
+
+// Protection is what a backup is sealed with and opened with: a
+// passphrase, or an age recipient ("age1...") to seal and the matching
+// identity ("AGE-SECRET-KEY-1...") to open. WorkFactor is the scrypt cost
+// of sealing with a passphrase, DefaultWorkFactor when zero.
+type Protection struct {
+	Passphrase string
+	Recipient  string
+	Identity   string
+	WorkFactor int
+}
+
+// Envelope is the document inside a backup.
+type Envelope struct {
+	Format    string          `json:"format"`
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Checksum  string          `json:"checksum"`
+	Content   json.RawMessage `json:"content"`
+}
+
+// Seal encodes content as JSON in a document of the given format and
+// encrypts it.
+func Seal(format string, content any, p Protection) ([]byte, error) {
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := json.Marshal(Envelope{
+		Format:    format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Checksum:  checksum(raw),
+		Content:   raw,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := Encrypt(&buf, p)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(doc); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Open decrypts a backup, checks that it holds the given format and that
+// its content matches the checksum, and decodes the content into content.
+func Open(data []byte, format string, p Protection, content any) (Envelope, error) {
+	r, err := Decrypt(bytes.NewReader(data), p)
+	if err != nil {
+		return Envelope{}, err
+	}
+	doc, err := io.ReadAll(r)
+	if err != nil {
+		return Envelope{}, ErrCorrupt
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(doc, &env); err != nil || env.Version < 1 {
+		return Envelope{}, ErrCorrupt
+	}
+	if env.Format != format {
+		return Envelope{}, fmt.Errorf("%w: %q", ErrWrongFormat, env.Format)
+	}
+	if env.Version > Version {
+		return Envelope{}, ErrUnsupportedVersion
+	}
+	if env.Checksum != checksum(env.Content) {
+		return Envelope{}, ErrChecksum
+	}
+	if err := json.Unmarshal(env.Content, content); err != nil {
+		return Envelope{}, ErrCorrupt
+	}
+	return env, nil
+}
+
+// Encrypt returns a writer encrypting to w. Closing it flushes the last
+// chunk but leaves w open.
+func Encrypt(w io.Writer, p Protection) (io.WriteCloser, error) {
+	if (p.Passphrase == "") == (p.Recipient == "") {
+		return nil, ErrProtection
+	}
+	if p.Recipient != "" {
+		recipient, err := age.ParseX25519Recipient(p.Recipient)
+		if err != nil {
+			return nil, ErrInvalidAgeKey
+		}
+		return age.Encrypt(w, recipient)
+	}
+
+	recipient, err := age.NewScryptRecipient(p.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if p.WorkFactor > 0 {
+		recipient.SetWorkFactor(p.WorkFactor)
+	} else {
+		recipient.SetWorkFactor(DefaultWorkFactor)
+	}
+	scryptSlots <- struct{}{}
+	defer func() { <-scryptSlots }()
+	return age.Encrypt(w, recipient)
+}
+
+// Decrypt returns a reader of the plaintext of the backup read from r.
+// Reads fail with ErrCorrupt once content that was altered or cut short is
+// reached.
+func Decrypt(r io.Reader, p Protection) (io.Reader, error) {
+	if (p.Passphrase == "") == (p.Identity == "") {
+		return nil, ErrProtection
+	}
+	var identity age.Identity
+	if p.Identity != "" {
+		x25519, err := age.ParseX25519Identity(p.Identity)
+		if err != nil {
+			return nil, ErrInvalidAgeKey
+		}
+		identity = x25519
+	} else {
+		scrypt, err := age.NewScryptIdentity(p.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		scrypt.SetMaxWorkFactor(MaxWorkFactor)
+		identity = scrypt
+	}
+
+	magic := make([]byte, len(ageMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != ageMagic {
+		return nil, ErrNotBackup
+	}
+	if p.Passphrase != "" {
+		scryptSlots <- struct{}{}
+		defer func() { <-scryptSlots }()
+	}
+	plain, err := age.Decrypt(io.MultiReader(bytes.NewReader(magic), r), identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, ErrWrongKey
+		}
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return corruptReader{plain}, nil
+}
+
+// corruptReader reports the authentication failures of age as ErrCorrupt.
+type corruptReader struct {
+	r io.Reader
+}
+
+func (cr corruptReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return n, err
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
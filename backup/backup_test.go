@@ -0,0 +1,109 @@
+package backup_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/adarsh-a-tw/passwordly/backup"
+	"github.com/stretchr/testify/assert"
+)
+
+type content struct {
+	Names []string `json:"names"`
+}
+
+// cheap keeps scrypt fast in tests.
+var cheap = backup.Protection{Passphrase: "correct horse battery", WorkFactor: 10}
+
+func TestSeal_ShouldRoundTripWithPassphrase(t *testing.T) {
+	data, err := backup.Seal("test", content{Names: []string{"GitHub", "Wifi"}}, cheap)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(data, []byte("GitHub")))
+
+	var c content
+	env, err := backup.Open(data, "test", backup.Protection{Passphrase: cheap.Passphrase}, &c)
+
+	assert.NoError(t, err)
+	assert.Equal(t, content{Names: []string{"GitHub", "Wifi"}}, c)
+	assert.Equal(t, backup.Version, env.Version)
+	assert.Regexp(t, "^sha256:[0-9a-f]{64}$", env.Checksum)
+
+	_, err = backup.Open(data, "test", backup.Protection{Passphrase: "wrong horse"}, &c)
+	assert.ErrorIs(t, err, backup.ErrWrongKey)
+	_, err = backup.Open(data, "other", backup.Protection{Passphrase: cheap.Passphrase}, &c)
+	assert.ErrorIs(t, err, backup.ErrWrongFormat)
+}
+
+func TestSeal_ShouldRoundTripWithAgeKeys(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+
+	data, err := backup.Seal("test", content{Names: []string{"GitHub"}}, backup.Protection{Recipient: identity.Recipient().String()})
+	assert.NoError(t, err)
+
+	var c content
+	_, err = backup.Open(data, "test", backup.Protection{Identity: identity.String()}, &c)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GitHub"}, c.Names)
+
+	_, err = backup.Open(data, "test", backup.Protection{Identity: other.String()}, &c)
+	assert.ErrorIs(t, err, backup.ErrWrongKey)
+	_, err = backup.Open(data, "test", backup.Protection{Passphrase: "correct horse battery"}, &c)
+	assert.ErrorIs(t, err, backup.ErrWrongKey)
+	_, err = backup.Open(data, "test", backup.Protection{Identity: "AGE-SECRET-KEY-1NOPE"}, &c)
+	assert.ErrorIs(t, err, backup.ErrInvalidAgeKey)
+}
+
+func TestOpen_ShouldVerifyIntegrity(t *testing.T) {
+	data, err := backup.Seal("test", content{Names: []string{"GitHub"}}, cheap)
+	assert.NoError(t, err)
+	open := backup.Protection{Passphrase: cheap.Passphrase}
+	var c content
+
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-5] ^= 1
+	_, err = backup.Open(tampered, "test", open, &c)
+	assert.ErrorIs(t, err, backup.ErrCorrupt)
+
+	_, err = backup.Open(data[:len(data)-20], "test", open, &c)
+	assert.ErrorIs(t, err, backup.ErrCorrupt)
+
+	_, err = backup.Open([]byte(`{"format": "test"}`), "test", open, &c)
+	assert.ErrorIs(t, err, backup.ErrNotBackup)
+
+	emptyChecksum := "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	for _, tc := range []struct {
+		doc      string
+		expected error
+	}{
+		{`{"format": "test", "version": 1, "checksum": "` + emptyChecksum + `", "content": {}}`, nil},
+		{`{"format": "test", "version": 1, "checksum": "` + emptyChecksum + `", "content": {"names": []}}`, backup.ErrChecksum},
+		{`{"format": "test", "version": 2, "checksum": "` + emptyChecksum + `", "content": {}}`, backup.ErrUnsupportedVersion},
+		{`{"format": "test", "content": {}}`, backup.ErrCorrupt},
+	} {
+		var buf bytes.Buffer
+		w, err := backup.Encrypt(&buf, cheap)
+		assert.NoError(t, err)
+		w.Write([]byte(tc.doc))
+		assert.NoError(t, w.Close())
+
+		_, err = backup.Open(buf.Bytes(), "test", open, &c)
+		if tc.expected == nil {
+			assert.NoError(t, err, tc.doc)
+		} else {
+			assert.ErrorIs(t, err, tc.expected, tc.doc)
+		}
+	}
+}
+
+func TestSeal_ShouldRequireOneProtection(t *testing.T) {
+	_, err := backup.Seal("test", content{}, backup.Protection{})
+	assert.ErrorIs(t, err, backup.ErrProtection)
+	_, err = backup.Seal("test", content{}, backup.Protection{Passphrase: "pw", Recipient: "age1x"})
+	assert.ErrorIs(t, err, backup.ErrProtection)
+	_, err = backup.Seal("test", content{}, backup.Protection{Recipient: "age1x"})
+	assert.ErrorIs(t, err, backup.ErrInvalidAgeKey)
+}
@@ -0,0 +1,361 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// FormatDatabase is the format of the server backups written by Dump.
+//
+// A database holds far more than fits comfortably in one document, so
+// these backups are JSON lines inside the age file rather than an
+// envelope: a header naming the tables in the order they are loaded, a
+// line for every row and a trailer.
+//
+//	{"format":"passwordly-database","version":1,"created_at":"...","tables":["users",...]}
+//	{"table":"users","row":{"id":"...","username":"...",...}}
+//	...
+//	{"rows":1234,"checksum":"sha256:9f86d0..."}
+//
+// A row holds every column by name, encoded as JSON the way the Go type of
+// its model field encodes: bytes in base64, times in RFC 3339 and NULL as
+// null. Nothing of the database a backup came from is kept, so it loads
+// into any database gorm supports. checksum is the SHA-256 of every line
+// before the trailer, newlines included.
+const FormatDatabase = "passwordly-database"
+
+// maxBatchValues bounds the values of one INSERT below what every
+// supported database accepts.
+const maxBatchValues = 900
+
+var ErrNotEmpty = errors.New("The database is not empty")
+
+// TableRows counts the rows of one table in a backup.
+type TableRows struct {
+	Table string
+	Rows  int
+}
+
+type databaseHeader struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []string  `json:"tables"`
+}
+
+type databaseRow struct {
+	Table string                     `json:"table"`
+	Row   map[string]json.RawMessage `json:"row"`
+}
+
+type databaseTrailer struct {
+	Rows     int    `json:"rows"`
+	Checksum string `json:"checksum"`
+}
+
+// Dump writes every row of the tables behind models, soft deleted ones
+// included, to an encrypted backup. Tables are written in the order of
+// models, which Load keeps, so models should come after those they refer
+// to.
+func Dump(db *gorm.DB, models []any, w io.Writer, p Protection) ([]TableRows, error) {
+	schemas, err := parseModels(db, models)
+	if err != nil {
+		return nil, err
+	}
+	ew, err := Encrypt(w, p)
+	if err != nil {
+		return nil, err
+	}
+	out := bufio.NewWriter(ew)
+	hash := sha256.New()
+	writeLine := func(v any) error {
+		line, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		hash.Write(line)
+		_, err = out.Write(line)
+		return err
+	}
+
+	header := databaseHeader{Format: FormatDatabase, Version: Version, CreatedAt: time.Now().UTC().Truncate(time.Second)}
+	for _, s := range schemas {
+		header.Tables = append(header.Tables, s.Table)
+	}
+	if err := writeLine(header); err != nil {
+		return nil, err
+	}
+
+	// Every table is read in one transaction, so that a backup of a running
+	// server holds no row without those it refers to.
+	counts := make([]TableRows, len(schemas))
+	total := 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for i, s := range schemas {
+			counts[i].Table = s.Table
+			err := dumpTable(tx, s, func(row map[string]json.RawMessage) error {
+				counts[i].Rows++
+				return writeLine(databaseRow{Table: s.Table, Row: row})
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", s.Table, err)
+			}
+			total += counts[i].Rows
+		}
+		return nil
+	}, snapshotOptions(db))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeLine(databaseTrailer{Rows: total, Checksum: "sha256:" + hex.EncodeToString(hash.Sum(nil))}); err != nil {
+		return nil, err
+	}
+	if err := out.Flush(); err != nil {
+		return nil, err
+	}
+	return counts, ew.Close()
+}
+
+// snapshotOptions are the options of a read only transaction that sees the
+// database as of its first read. SQLite only offers serializable
+// transactions, which see it that way too.
+func snapshotOptions(db *gorm.DB) *sql.TxOptions {
+	if db.Dialector.Name() == "sqlite" {
+		return &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+	}
+	return &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+}
+
+// dumpTable reads the rows of a table in primary key order into its model
+// and passes each on with its columns encoded.
+func dumpTable(db *gorm.DB, s *schema.Schema, write func(row map[string]json.RawMessage) error) error {
+	query := db.Unscoped().Table(s.Table)
+	for _, name := range s.PrimaryFieldDBNames {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: name}})
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ctx := context.Background()
+	for rows.Next() {
+		record := reflect.New(s.ModelType)
+		if err := db.ScanRows(rows, record.Interface()); err != nil {
+			return err
+		}
+		row := make(map[string]json.RawMessage, len(s.DBNames))
+		for _, name := range s.DBNames {
+			value, _ := s.FieldsByDBName[name].ValueOf(ctx, record.Elem())
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			row[name] = raw
+		}
+		if err := write(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Load restores a backup written by Dump into a database that holds no
+// rows yet, with its tables already migrated. Rows are inserted as they
+// are, without hooks or associations, in a transaction that is only
+// committed once the trailer has been checked, so that a damaged backup
+// leaves the database empty.
+func Load(db *gorm.DB, models []any, r io.Reader, p Protection) ([]TableRows, error) {
+	schemas, err := parseModels(db, models)
+	if err != nil {
+		return nil, err
+	}
+	byTable := make(map[string]*schema.Schema, len(schemas))
+	for i, s := range schemas {
+		byTable[s.Table] = s
+		var count int64
+		if err := db.Unscoped().Model(models[i]).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("%w: %s has rows", ErrNotEmpty, s.Table)
+		}
+	}
+
+	plain, err := Decrypt(r, p)
+	if err != nil {
+		return nil, err
+	}
+	in := bufio.NewReader(plain)
+	hash := sha256.New()
+	readLine := func() ([]byte, error) {
+		line, err := in.ReadBytes('\n')
+		if err == io.EOF && len(line) > 0 {
+			return nil, ErrCorrupt
+		}
+		return line, err
+	}
+
+	line, err := readLine()
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	hash.Write(line)
+	var header databaseHeader
+	if err := json.Unmarshal(line, &header); err != nil || header.Version < 1 {
+		return nil, ErrCorrupt
+	}
+	if header.Format != FormatDatabase {
+		return nil, fmt.Errorf("%w: %q", ErrWrongFormat, header.Format)
+	}
+	if header.Version > Version {
+		return nil, ErrUnsupportedVersion
+	}
+	counts := make([]TableRows, len(header.Tables))
+	index := make(map[string]int, len(header.Tables))
+	for i, table := range header.Tables {
+		if _, ok := byTable[table]; !ok {
+			return nil, fmt.Errorf("%w: unknown table %s", ErrUnsupportedVersion, table)
+		}
+		counts[i].Table = table
+		index[table] = i
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var batch reflect.Value
+		var batchSchema *schema.Schema
+		flush := func() error {
+			if batchSchema == nil || batch.Len() == 0 {
+				return nil
+			}
+			records := reflect.New(batch.Type())
+			records.Elem().Set(batch)
+			insert := tx.Session(&gorm.Session{SkipHooks: true}).Omit(clause.Associations)
+			if err := insert.Create(records.Interface()).Error; err != nil {
+				return fmt.Errorf("%s: %w", batchSchema.Table, err)
+			}
+			batch = batch.Slice(0, 0)
+			return nil
+		}
+
+		total := 0
+		for {
+			line, err := readLine()
+			if err != nil {
+				return ErrCorrupt
+			}
+			var row databaseRow
+			if err := json.Unmarshal(line, &row); err != nil {
+				return ErrCorrupt
+			}
+			if row.Table == "" {
+				if err := flush(); err != nil {
+					return err
+				}
+				return checkTrailer(line, total, hex.EncodeToString(hash.Sum(nil)), in)
+			}
+			hash.Write(line)
+
+			i, ok := index[row.Table]
+			if !ok {
+				return ErrCorrupt
+			}
+			s := byTable[row.Table]
+			record, err := decodeRow(s, row.Row)
+			if err != nil {
+				return err
+			}
+			if s != batchSchema {
+				if err := flush(); err != nil {
+					return err
+				}
+				batch = reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(s.ModelType)), 0, batchSize(s))
+				batchSchema = s
+			}
+			batch = reflect.Append(batch, record)
+			if batch.Len() == batch.Cap() {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			counts[i].Rows++
+			total++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// checkTrailer checks the trailer line against the rows read before it and
+// that nothing follows it.
+func checkTrailer(line []byte, rows int, checksum string, rest io.Reader) error {
+	var trailer databaseTrailer
+	if err := json.Unmarshal(line, &trailer); err != nil {
+		return ErrCorrupt
+	}
+	if trailer.Rows != rows || trailer.Checksum != "sha256:"+checksum {
+		return ErrChecksum
+	}
+	if n, err := rest.Read(make([]byte, 1)); n > 0 || err != io.EOF {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// decodeRow fills a new record of the model of s with the columns of a
+// row. Columns the model lacks are refused rather than dropped.
+func decodeRow(s *schema.Schema, row map[string]json.RawMessage) (reflect.Value, error) {
+	ctx := context.Background()
+	record := reflect.New(s.ModelType)
+	for name, raw := range row {
+		field, ok := s.FieldsByDBName[name]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%w: unknown column %s.%s", ErrUnsupportedVersion, s.Table, name)
+		}
+		if bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		if err := json.Unmarshal(raw, field.ReflectValueOf(ctx, record.Elem()).Addr().Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf("%w: %s.%s", ErrCorrupt, s.Table, name)
+		}
+	}
+	return record, nil
+}
+
+func batchSize(s *schema.Schema) int {
+	if size := maxBatchValues / len(s.DBNames); size > 1 {
+		return size
+	}
+	return 1
+}
+
+func parseModels(db *gorm.DB, models []any) ([]*schema.Schema, error) {
+	schemas := make([]*schema.Schema, len(models))
+	for i, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		schemas[i] = stmt.Schema
+	}
+	return schemas, nil
+}
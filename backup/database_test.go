@@ -0,0 +1,191 @@
+package backup_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/backup"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type owner struct {
+	Id        string `gorm:"primaryKey"`
+	Name      string `json:"-"`
+	Admin     bool
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+type item struct {
+	Id         string `gorm:"primaryKey"`
+	Value      []byte
+	Note       *string
+	OwnerRefer string
+	Owner      owner `gorm:"foreignKey:OwnerRefer"`
+	Schedule
+}
+
+type Schedule struct {
+	ExpiresAt *time.Time
+	Days      int
+}
+
+type link struct {
+	ItemId string `gorm:"primaryKey"`
+	Token  string `gorm:"primaryKey"`
+	Seq    uint64
+}
+
+var models = []any{&owner{}, &item{}, &link{}}
+
+func TestDump_ShouldLoadIntoAnotherDatabase(t *testing.T) {
+	source := openDatabase(t)
+	note := "rotate soon"
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 678000, time.UTC)
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	for _, record := range []any{
+		&owner{Id: "o1", Name: "Ada", Admin: true, CreatedAt: created},
+		&owner{Id: "o2", Name: "Bob", CreatedAt: created, DeletedAt: gorm.DeletedAt{Time: created, Valid: true}},
+		&item{Id: "i1", Value: []byte{0, 1, 2, 255}, Note: &note, OwnerRefer: "o1", Schedule: Schedule{ExpiresAt: &expires, Days: 30}},
+		&item{Id: "i2", OwnerRefer: "o2"},
+		&link{ItemId: "i1", Token: "b", Seq: 1 << 60},
+		&link{ItemId: "i1", Token: "a", Seq: 2},
+	} {
+		assert.NoError(t, source.Create(record).Error)
+	}
+
+	var archive bytes.Buffer
+	counts, err := backup.Dump(source, models, &archive, cheap)
+	assert.NoError(t, err)
+	assert.Equal(t, []backup.TableRows{{Table: "owners", Rows: 2}, {Table: "items", Rows: 2}, {Table: "links", Rows: 2}}, counts)
+	assert.False(t, bytes.Contains(archive.Bytes(), []byte("Ada")))
+
+	target := openDatabase(t)
+	counts, err = backup.Load(target, models, bytes.NewReader(archive.Bytes()), backup.Protection{Passphrase: cheap.Passphrase})
+	assert.NoError(t, err)
+	assert.Len(t, counts, 3)
+
+	var owners []owner
+	assert.NoError(t, target.Unscoped().Order("id").Find(&owners).Error)
+	assert.Len(t, owners, 2)
+	assert.Equal(t, "Ada", owners[0].Name)
+	assert.True(t, owners[0].Admin)
+	assert.True(t, owners[0].CreatedAt.Equal(created))
+	assert.False(t, owners[0].DeletedAt.Valid)
+	assert.True(t, owners[1].DeletedAt.Valid)
+
+	var items []item
+	assert.NoError(t, target.Order("id").Find(&items).Error)
+	assert.Equal(t, []byte{0, 1, 2, 255}, items[0].Value)
+	assert.Equal(t, note, *items[0].Note)
+	assert.True(t, items[0].ExpiresAt.Equal(expires))
+	assert.Equal(t, 30, items[0].Days)
+	assert.Nil(t, items[1].Value)
+	assert.Nil(t, items[1].Note)
+	assert.Nil(t, items[1].ExpiresAt)
+
+	var links []link
+	assert.NoError(t, target.Order("token").Find(&links).Error)
+	assert.Equal(t, []link{{ItemId: "i1", Token: "a", Seq: 2}, {ItemId: "i1", Token: "b", Seq: 1 << 60}}, links)
+
+	_, err = backup.Load(target, models, bytes.NewReader(archive.Bytes()), backup.Protection{Passphrase: cheap.Passphrase})
+	assert.ErrorIs(t, err, backup.ErrNotEmpty)
+}
+
+func TestLoad_ShouldLeaveTheDatabaseEmptyOnFailure(t *testing.T) {
+	source := openDatabase(t)
+	assert.NoError(t, source.Create(&owner{Id: "o1", Name: "Ada"}).Error)
+	var archive bytes.Buffer
+	_, err := backup.Dump(source, models, &archive, cheap)
+	assert.NoError(t, err)
+	open := backup.Protection{Passphrase: cheap.Passphrase}
+
+	header := `{"format":"passwordly-database","version":1,"created_at":"2024-01-02T03:04:05Z","tables":["owners"]}` + "\n"
+	row := `{"table":"owners","row":{"id":"o1","name":"Ada"}}` + "\n"
+	sum := sha256.Sum256([]byte(header + row))
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+	tampered := append([]byte(nil), archive.Bytes()...)
+	tampered[len(tampered)-5] ^= 1
+
+	for _, tc := range []struct {
+		archive  []byte
+		expected error
+	}{
+		{tampered, backup.ErrCorrupt},
+		{archive.Bytes()[:archive.Len()-20], backup.ErrCorrupt},
+		{seal(t, header+row), backup.ErrCorrupt},
+		{seal(t, header+row+`{"rows":1,"checksum":"sha256:00"}`+"\n"), backup.ErrChecksum},
+		{seal(t, header+row+`{"rows":2,"checksum":"`+checksum+`"}`+"\n"), backup.ErrChecksum},
+		{seal(t, header+row+`{"rows":1,"checksum":"`+checksum+`"}`+"\n"+row), backup.ErrCorrupt},
+		{seal(t, header+`{"table":"owners","row":{"id":"o1","nickname":"Ada"}}`+"\n"), backup.ErrUnsupportedVersion},
+		{seal(t, `{"format":"passwordly-database","version":1,"tables":["users"]}`+"\n"), backup.ErrUnsupportedVersion},
+		{seal(t, `{"format":"passwordly-database","version":2,"tables":[]}`+"\n"), backup.ErrUnsupportedVersion},
+		{seal(t, `{"format":"passwordly-vaults","version":1,"tables":[]}`+"\n"), backup.ErrWrongFormat},
+	} {
+		target := openDatabase(t)
+		_, err := backup.Load(target, models, bytes.NewReader(tc.archive), open)
+		assert.ErrorIs(t, err, tc.expected)
+
+		var count int64
+		target.Unscoped().Model(&owner{}).Count(&count)
+		assert.Zero(t, count)
+	}
+
+	target := openDatabase(t)
+	_, err = backup.Load(target, models, bytes.NewReader(seal(t, header+row+`{"rows":1,"checksum":"`+checksum+`"}`+"\n")), open)
+	assert.NoError(t, err)
+	_, err = backup.Load(openDatabase(t), models, bytes.NewReader(archive.Bytes()), backup.Protection{Passphrase: "wrong horse"})
+	assert.ErrorIs(t, err, backup.ErrWrongKey)
+}
+
+func TestDump_ShouldReadOneSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwordly.db") + "?_journal_mode=WAL"
+	source, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, source.AutoMigrate(models...))
+	assert.NoError(t, source.Create(&owner{Id: "o1", Name: "Ada"}).Error)
+	writer, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	assert.NoError(t, err)
+
+	// Another connection adds an owner and its item after the owners have
+	// been read and before the items are, as a running server could.
+	written := false
+	assert.NoError(t, source.Callback().Row().Before("gorm:row").Register("test:write", func(tx *gorm.DB) {
+		if tx.Statement.Table != "items" || written {
+			return
+		}
+		written = true
+		assert.NoError(t, writer.Create(&owner{Id: "o2", Name: "Bob"}).Error)
+		assert.NoError(t, writer.Create(&item{Id: "i2", OwnerRefer: "o2"}).Error)
+	}))
+
+	var archive bytes.Buffer
+	counts, err := backup.Dump(source, models, &archive, cheap)
+
+	assert.NoError(t, err)
+	assert.True(t, written)
+	assert.Equal(t, []backup.TableRows{{Table: "owners", Rows: 1}, {Table: "items", Rows: 0}, {Table: "links", Rows: 0}}, counts)
+}
+
+func openDatabase(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "passwordly.db")), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(models...))
+	return db
+}
+
+// seal encrypts lines as a database backup would be.
+func seal(t *testing.T, lines string) []byte {
+	var buf bytes.Buffer
+	w, err := backup.Encrypt(&buf, cheap)
+	assert.NoError(t, err)
+	w.Write([]byte(lines))
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
@@ -0,0 +1,125 @@
+package backup
+
+import "time"
+
+// FormatVaults is the format of vault exports, holding Vaults.
+const FormatVaults = "passwordly-vaults"
+
+// Vaults is the content of a vault export: one or more vaults with their
+// folders and secrets, every value in plaintext. Attachments, issued
+// certificates and SSH CA roles are not exported.
+type Vaults struct {
+	Vaults []Vault `json:"vaults"`
+}
+
+// Vault is an exported vault. Ids are those of the exporting server and
+// only tie folders and secrets together; restoring assigns new ones.
+type Vault struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Folders   []Folder  `json:"folders"`
+	Secrets   []Secret  `json:"secrets"`
+}
+
+// Folder is a folder of a vault. ParentId is empty at the top level.
+type Folder struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	ParentId string `json:"parent_id,omitempty"`
+}
+
+// Secret is a secret of any type. Type is one of CREDENTIAL, KEY,
+// DOCUMENT, TOTP, SSH_KEY, CERTIFICATE_AUTHORITY, CARD or IDENTITY, and
+// the member of the same name holds what is particular to it.
+type Secret struct {
+	Id              string     `json:"id"`
+	Type            string     `json:"type"`
+	Name            string     `json:"name"`
+	FolderId        string     `json:"folder_id,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	RotateEveryDays int        `json:"rotate_every_days,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Fields          []Field    `json:"fields,omitempty"`
+
+	Credential           *Credential           `json:"credential,omitempty"`
+	Key                  *Key                  `json:"key,omitempty"`
+	Document             *Document             `json:"document,omitempty"`
+	Totp                 *Totp                 `json:"totp,omitempty"`
+	SshKey               *SshKey               `json:"ssh_key,omitempty"`
+	CertificateAuthority *CertificateAuthority `json:"certificate_authority,omitempty"`
+	Card                 *Card                 `json:"card,omitempty"`
+	Identity             *Identity             `json:"identity,omitempty"`
+}
+
+// Field is a custom field. Type is one of TEXT, HIDDEN, BOOLEAN or URL.
+type Field struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type Credential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type Key struct {
+	Value string `json:"value"`
+}
+
+type Document struct {
+	Content string `json:"content"`
+}
+
+// Totp is a 2FA seed in base32 with the parameters of its codes.
+type Totp struct {
+	Issuer      string `json:"issuer,omitempty"`
+	AccountName string `json:"account_name,omitempty"`
+	Secret      string `json:"secret"`
+	Algorithm   string `json:"algorithm"`
+	Digits      int    `json:"digits"`
+	Period      int    `json:"period"`
+}
+
+// SshKey is an OpenSSH private key with its public key.
+type SshKey struct {
+	KeyType     string `json:"key_type"`
+	Bits        int    `json:"bits,omitempty"`
+	PrivateKey  string `json:"private_key"`
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// CertificateAuthority is an X.509 CA: its PEM certificate, PKCS#8 private
+// key and the constraints of the certificates it issues.
+type CertificateAuthority struct {
+	Subject         string   `json:"subject"`
+	Certificate     string   `json:"certificate"`
+	PrivateKey      string   `json:"private_key"`
+	AllowedDomains  []string `json:"allowed_domains,omitempty"`
+	AllowSubdomains bool     `json:"allow_subdomains"`
+	AllowIpSans     bool     `json:"allow_ip_sans"`
+	MaxTtlSeconds   int      `json:"max_ttl_seconds"`
+}
+
+type Card struct {
+	CardholderName string `json:"cardholder_name,omitempty"`
+	Number         string `json:"number"`
+	Brand          string `json:"brand,omitempty"`
+	ExpiryMonth    int    `json:"expiry_month"`
+	ExpiryYear     int    `json:"expiry_year"`
+	Cvv            string `json:"cvv,omitempty"`
+}
+
+type Identity struct {
+	FullName       string `json:"full_name"`
+	Email          string `json:"email,omitempty"`
+	Phone          string `json:"phone,omitempty"`
+	Address        string `json:"address,omitempty"`
+	PassportNumber string `json:"passport_number,omitempty"`
+	LicenceNumber  string `json:"licence_number,omitempty"`
+}
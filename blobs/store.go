@@ -0,0 +1,46 @@
+package blobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+)
+
+var ErrNotFound = errors.New("Blob not found")
+
+// BlobStore keeps opaque objects under string keys. Callers encrypt what
+// they store; backends never see plaintext. Put is given the length of r,
+// or -1 when it is only known once r is drained.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// NewBlobStore builds the backend selected by BLOB_STORE.
+func NewBlobStore() (BlobStore, error) {
+	switch common.Cfg.BlobStore {
+	case BackendLocal, "":
+		return &LocalStore{Dir: common.Cfg.BlobDir}, nil
+	case BackendS3:
+		if common.Cfg.S3Endpoint == "" || common.Cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required for the %s blob store", BackendS3)
+		}
+		return &S3Store{
+			Endpoint:  common.Cfg.S3Endpoint,
+			Region:    common.Cfg.S3Region,
+			Bucket:    common.Cfg.S3Bucket,
+			AccessKey: common.Cfg.S3AccessKey,
+			SecretKey: common.Cfg.S3SecretKey,
+		}, nil
+	}
+	return nil, fmt.Errorf("Unknown blob store %q", common.Cfg.BlobStore)
+}
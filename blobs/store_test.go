@@ -0,0 +1,145 @@
+package blobs_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/blobs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore_ShouldRoundTripBlobs(t *testing.T) {
+	ls := &blobs.LocalStore{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	assert.NoError(t, ls.Put(ctx, "vault/attachment", strings.NewReader("ciphertext"), 10))
+
+	r, err := ls.Get(ctx, "vault/attachment")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "ciphertext", string(body))
+
+	assert.NoError(t, ls.Delete(ctx, "vault/attachment"))
+	_, err = ls.Get(ctx, "vault/attachment")
+	assert.ErrorIs(t, err, blobs.ErrNotFound)
+}
+
+func TestLocalStore_ShouldRejectShortWritesAndEscapingKeys(t *testing.T) {
+	ls := &blobs.LocalStore{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	assert.Error(t, ls.Put(ctx, "short", strings.NewReader("abc"), 10))
+	_, err := ls.Get(ctx, "short")
+	assert.ErrorIs(t, err, blobs.ErrNotFound)
+
+	assert.Error(t, ls.Put(ctx, "../outside", strings.NewReader("abc"), 3))
+}
+
+// The get-vanilla case of the AWS Signature Version 4 test suite.
+func TestSigner_ShouldMatchAwsTestSuite(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	signer := blobs.Signer{
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Service:   "service",
+	}
+
+	signer.Sign(req, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"),
+	)
+}
+
+func TestS3Store_ShouldRoundTripBlobsAgainstS3StandIn(t *testing.T) {
+	server := newS3StandIn(t, "minio-access", "minio-secret")
+	defer server.Close()
+
+	s3 := &blobs.S3Store{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		Bucket:    "passwordly",
+		AccessKey: "minio-access",
+		SecretKey: "minio-secret",
+	}
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("x"), 1<<20)
+
+	assert.NoError(t, s3.Put(ctx, "vault/attachment", bytes.NewReader(data), int64(len(data))))
+	assert.NoError(t, s3.Put(ctx, "vault/streamed", io.MultiReader(bytes.NewReader(data)), -1))
+
+	r, err := s3.Get(ctx, "vault/attachment")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, data, body)
+	r, err = s3.Get(ctx, "vault/streamed")
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, data, body)
+
+	assert.NoError(t, s3.Delete(ctx, "vault/attachment"))
+	_, err = s3.Get(ctx, "vault/attachment")
+	assert.ErrorIs(t, err, blobs.ErrNotFound)
+}
+
+func TestS3Store_ShouldFailWithWrongCredentials(t *testing.T) {
+	server := newS3StandIn(t, "minio-access", "minio-secret")
+	defer server.Close()
+
+	s3 := &blobs.S3Store{Endpoint: server.URL, Bucket: "passwordly", AccessKey: "minio-access", SecretKey: "wrong"}
+
+	assert.Error(t, s3.Put(context.Background(), "vault/attachment", strings.NewReader("abc"), 3))
+}
+
+// newS3StandIn serves a minimal path-style S3 API from memory, rejecting
+// requests whose signature does not match the given credentials.
+func newS3StandIn(t *testing.T, accessKey string, secretKey string) *httptest.Server {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	signer := blobs.Signer{AccessKey: accessKey, SecretKey: secretKey, Region: "us-east-1", Service: "s3"}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signedAt, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+		assert.NoError(t, err)
+		expected, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+		expected.Header.Set("X-Amz-Content-Sha256", r.Header.Get("X-Amz-Content-Sha256"))
+		signer.Sign(expected, signedAt)
+		if expected.Header.Get("Authorization") != r.Header.Get("Authorization") {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, r.ContentLength, int64(len(body)))
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/adarsh-a-tw/passwordly/backup"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/passwords"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+)
+
+// commands are run instead of the server when named as the first argument.
+var commands = map[string]func(args []string){
+	"hibp-index":     buildBreachIndex,
+	"search-reindex": reindexSearch,
+	"backup":         backupDatabase,
+	"restore":        restoreDatabase,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  passwordly                                 start the server")
+	fmt.Fprintln(os.Stderr, "  passwordly hibp-index <dataset> <output>   build a breached password index")
+	fmt.Fprintln(os.Stderr, "  passwordly search-reindex                  rebuild the secret search index")
+	fmt.Fprintln(os.Stderr, "  passwordly backup <file>                   write an encrypted backup of the database")
+	fmt.Fprintln(os.Stderr, "  passwordly restore <file>                  restore a backup into an empty database")
+	os.Exit(2)
+}
+
+// buildBreachIndex converts a downloaded Pwned Passwords dataset, either a
+// range directory or a single file ordered by hash, into the compact index
+// that HIBP_DATASET_PATH can point at.
+func buildBreachIndex(args []string) {
+	if len(args) != 2 {
+		usage()
+	}
+	records, err := passwords.BuildBreachIndex(args[0], args[1])
+	if err != nil {
+		os.Remove(args[1])
+		log.Fatal("Could not build breached password index: ", err)
+	}
+	log.Printf("Wrote %d hashes to %s", records, args[1])
+}
+
+// reindexSearch rebuilds the search index of every vault. It is needed once
+// after upgrading and whenever SEARCH_INDEX_KEY changes.
+func reindexSearch(args []string) {
+	if len(args) != 0 {
+		usage()
+	}
+	common.LoadConfig()
+	connectDB()
+	migrate()
+
+	db := common.DB()
+	var vaultIds []string
+	if err := db.Model(&vaults.Vault{}).Pluck("id", &vaultIds).Error; err != nil {
+		log.Fatal("Could not list vaults: ", err)
+	}
+	indexer := vaults.NewSearchIndexer(db, utils.NewBlindIndex(common.Cfg.SearchIndexKey))
+	for _, id := range vaultIds {
+		if err := indexer.ReindexVault(id); err != nil {
+			log.Fatalf("Could not reindex vault %s: %v", id, err)
+		}
+	}
+	log.Printf("Reindexed %d vaults", len(vaultIds))
+}
+
+// backupDatabase writes every table to an encrypted backup, protected with
+// BACKUP_PASSPHRASE or encrypted to the age recipient BACKUP_RECIPIENT,
+// that restore loads into a database of either driver. Secrets stay
+// encrypted with ENCRYPTION_KEY, which the restored server needs as well,
+// and attachments are left in the blob store to be backed up with it.
+func backupDatabase(args []string) {
+	if len(args) != 1 {
+		usage()
+	}
+	common.LoadConfig()
+	connectDB()
+	migrate()
+
+	file, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		log.Fatal("Could not create backup: ", err)
+	}
+	counts, err := backup.Dump(common.DB(), vaults.Models, file, backup.Protection{
+		Passphrase: common.Cfg.BackupPassphrase,
+		Recipient:  common.Cfg.BackupRecipient,
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(args[0])
+		log.Fatal("Could not back up the database: ", err)
+	}
+	log.Printf("Wrote %d rows of %d tables to %s", totalRows(counts), len(counts), args[0])
+}
+
+// restoreDatabase loads a backup, opened with BACKUP_PASSPHRASE or the age
+// identity BACKUP_IDENTITY, into an empty database. Nothing is written
+// unless the whole backup checks out.
+func restoreDatabase(args []string) {
+	if len(args) != 1 {
+		usage()
+	}
+	common.LoadConfig()
+	connectDB()
+	migrate()
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		log.Fatal("Could not open backup: ", err)
+	}
+	defer file.Close()
+	counts, err := backup.Load(common.DB(), vaults.Models, file, backup.Protection{
+		Passphrase: common.Cfg.BackupPassphrase,
+		Identity:   common.Cfg.BackupIdentity,
+	})
+	if err != nil {
+		log.Fatal("Could not restore the backup: ", err)
+	}
+	log.Printf("Restored %d rows of %d tables from %s", totalRows(counts), len(counts), args[0])
+}
+
+func totalRows(counts []backup.TableRows) int {
+	total := 0
+	for _, c := range counts {
+		total += c.Rows
+	}
+	return total
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +17,58 @@ type Config struct {
 	JwtSecretKey  string
 	IsProduction  bool
 	EncryptionKey string
+	AdminUsername string
+
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+
+	PasswordMinLength        int
+	PasswordMaxLength        int
+	PasswordRequireLowercase bool
+	PasswordRequireUppercase bool
+	PasswordRequireDigit     bool
+	PasswordRequireSymbol    bool
+	PasswordMinScore         int
+	PasswordForbidUserInfo   bool
+	PasswordHistorySize      int
+	PasswordRotationDays     int
+
+	HibpDatasetPath string
+
+	Notifier             string
+	WebhookUrl           string
+	WebhookSecret        string
+	SmtpHost             string
+	SmtpPort             int
+	SmtpUsername         string
+	SmtpPassword         string
+	SmtpFrom             string
+	ReminderIntervalMins int
+	ReminderWindowDays   int
+
+	BlobStore          string
+	BlobDir            string
+	S3Endpoint         string
+	S3Region           string
+	S3Bucket           string
+	S3AccessKey        string
+	S3SecretKey        string
+	AttachmentMaxBytes int64
+	ImportMaxBytes     int64
+
+	SearchIndexKey string
+	AuditKey       string
+
+	PageSizeDefault int
+	PageSizeMax     int
+
+	TrashRetentionDays     int
+	TrashPurgeIntervalMins int
+
+	BackupPassphrase string
+	BackupRecipient  string
+	BackupIdentity   string
 }
 
 func LoadConfig() {
@@ -29,7 +82,68 @@ func LoadConfig() {
 		JwtSecretKey:  loadEnv("JWT_SECRET_KEY"),
 		EncryptionKey: loadEnv("ENCRYPTION_KEY"),
 		IsProduction:  loadEnv("IS_PRODUCTION") == "true",
+		AdminUsername: loadOptionalEnv("ADMIN_USERNAME", ""),
+
+		Argon2Memory:      uint32(loadOptionalUintEnv("ARGON2_MEMORY_KIB", 64*1024, 32)),
+		Argon2Iterations:  uint32(loadOptionalUintEnv("ARGON2_ITERATIONS", 3, 32)),
+		Argon2Parallelism: uint8(loadOptionalUintEnv("ARGON2_PARALLELISM", 2, 8)),
+
+		PasswordMinLength:        loadOptionalIntEnv("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:        loadOptionalIntEnv("PASSWORD_MAX_LENGTH", 128),
+		PasswordRequireLowercase: loadOptionalBoolEnv("PASSWORD_REQUIRE_LOWERCASE", true),
+		PasswordRequireUppercase: loadOptionalBoolEnv("PASSWORD_REQUIRE_UPPERCASE", true),
+		PasswordRequireDigit:     loadOptionalBoolEnv("PASSWORD_REQUIRE_DIGIT", true),
+		PasswordRequireSymbol:    loadOptionalBoolEnv("PASSWORD_REQUIRE_SYMBOL", true),
+		PasswordMinScore:         loadOptionalIntEnv("PASSWORD_MIN_SCORE", 2),
+		PasswordForbidUserInfo:   loadOptionalBoolEnv("PASSWORD_FORBID_USER_INFO", true),
+		PasswordHistorySize:      loadOptionalIntEnv("PASSWORD_HISTORY_SIZE", 5),
+		PasswordRotationDays:     loadOptionalIntEnv("PASSWORD_ROTATION_DAYS", 90),
+
+		HibpDatasetPath: loadOptionalEnv("HIBP_DATASET_PATH", ""),
+
+		Notifier:             loadOptionalEnv("NOTIFIER", "log"),
+		WebhookUrl:           loadOptionalEnv("NOTIFY_WEBHOOK_URL", ""),
+		WebhookSecret:        loadOptionalEnv("NOTIFY_WEBHOOK_SECRET", ""),
+		SmtpHost:             loadOptionalEnv("SMTP_HOST", ""),
+		SmtpPort:             loadOptionalIntEnv("SMTP_PORT", 587),
+		SmtpUsername:         loadOptionalEnv("SMTP_USERNAME", ""),
+		SmtpPassword:         loadOptionalEnv("SMTP_PASSWORD", ""),
+		SmtpFrom:             loadOptionalEnv("SMTP_FROM", ""),
+		ReminderIntervalMins: loadOptionalIntEnv("REMINDER_INTERVAL_MINUTES", 60),
+		ReminderWindowDays:   loadOptionalIntEnv("REMINDER_WINDOW_DAYS", 14),
+
+		BlobStore:          loadOptionalEnv("BLOB_STORE", "local"),
+		BlobDir:            loadOptionalEnv("BLOB_DIR", "data/blobs"),
+		S3Endpoint:         loadOptionalEnv("S3_ENDPOINT", ""),
+		S3Region:           loadOptionalEnv("S3_REGION", "us-east-1"),
+		S3Bucket:           loadOptionalEnv("S3_BUCKET", ""),
+		S3AccessKey:        loadOptionalEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:        loadOptionalEnv("S3_SECRET_KEY", ""),
+		AttachmentMaxBytes: int64(loadOptionalIntEnv("ATTACHMENT_MAX_BYTES", 25*1024*1024)),
+		ImportMaxBytes:     int64(loadOptionalIntEnv("IMPORT_MAX_BYTES", 10*1024*1024)),
+
+		PageSizeDefault: loadOptionalIntEnv("PAGE_SIZE_DEFAULT", 50),
+		PageSizeMax:     loadOptionalIntEnv("PAGE_SIZE_MAX", 200),
+
+		TrashRetentionDays:     loadOptionalIntEnv("TRASH_RETENTION_DAYS", 30),
+		TrashPurgeIntervalMins: loadOptionalIntEnv("TRASH_PURGE_INTERVAL_MINUTES", 60),
+
+		BackupPassphrase: loadOptionalEnv("BACKUP_PASSPHRASE", ""),
+		BackupRecipient:  loadOptionalEnv("BACKUP_RECIPIENT", ""),
+		BackupIdentity:   loadOptionalEnv("BACKUP_IDENTITY", ""),
 	}
+	if Cfg.PageSizeMax == 0 || Cfg.PageSizeDefault == 0 || Cfg.PageSizeDefault > Cfg.PageSizeMax {
+		panic("PAGE_SIZE_DEFAULT must be between 1 and PAGE_SIZE_MAX.")
+	}
+	if Cfg.TrashRetentionDays == 0 {
+		panic("TRASH_RETENTION_DAYS must be at least 1.")
+	}
+	// Search tokens are keyed with the encryption key unless a dedicated
+	// key is set. Changing it requires running search-reindex.
+	Cfg.SearchIndexKey = loadOptionalEnv("SEARCH_INDEX_KEY", Cfg.EncryptionKey)
+	// Audit events are chained with the encryption key unless a dedicated
+	// key is set. Changing it breaks verification of every recorded event.
+	Cfg.AuditKey = loadOptionalEnv("AUDIT_KEY", Cfg.EncryptionKey)
 }
 
 func loadEnv(envVarName string) string {
@@ -39,3 +153,44 @@ func loadEnv(envVarName string) string {
 	}
 	return env
 }
+
+func loadOptionalEnv(envVarName string, fallback string) string {
+	if env, exists := os.LookupEnv(envVarName); exists && env != "" {
+		return env
+	}
+	return fallback
+}
+
+func loadOptionalIntEnv(envVarName string, fallback int) int {
+	env := loadOptionalEnv(envVarName, "")
+	if env == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(env)
+	if err != nil || value < 0 {
+		panic(fmt.Sprintf("Env variable %s must be a non-negative integer.", envVarName))
+	}
+	return value
+}
+
+// loadOptionalUintEnv reads a positive integer that fits in bitSize bits, so
+// that it cannot wrap around when narrowed.
+func loadOptionalUintEnv(envVarName string, fallback uint64, bitSize int) uint64 {
+	env := loadOptionalEnv(envVarName, "")
+	if env == "" {
+		return fallback
+	}
+	value, err := strconv.ParseUint(env, 10, bitSize)
+	if err != nil || value == 0 {
+		panic(fmt.Sprintf("Env variable %s must be between 1 and %d.", envVarName, uint64(1)<<bitSize-1))
+	}
+	return value
+}
+
+func loadOptionalBoolEnv(envVarName string, fallback bool) bool {
+	env := loadOptionalEnv(envVarName, "")
+	if env == "" {
+		return fallback
+	}
+	return env == "true"
+}
@@ -0,0 +1,146 @@
+package imports
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Bitwarden item and custom field types.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+	bitwardenSshKey     = 5
+
+	bitwardenFieldHidden = 1
+	bitwardenFieldLinked = 3
+)
+
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	Type     int    `json:"type"`
+	Name     string `json:"name"`
+	Notes    string `json:"notes"`
+	FolderId string `json:"folderId"`
+	Fields   []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Type  int    `json:"type"`
+	} `json:"fields"`
+	Login *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Totp     string `json:"totp"`
+		Uris     []struct {
+			Uri string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+	SshKey *struct {
+		PrivateKey string `json:"privateKey"`
+	} `json:"sshKey"`
+}
+
+// parseBitwardenJSON reads an unencrypted JSON export. Nested folders are
+// named with their full path, separated by "/".
+func parseBitwardenJSON(data []byte) (Result, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil || export.Items == nil {
+		return Result{}, ErrInvalidFile
+	}
+	if export.Encrypted {
+		return Result{}, ErrEncrypted
+	}
+
+	folders := make(map[string][]string, len(export.Folders))
+	for _, f := range export.Folders {
+		folders[f.Id] = splitPath(f.Name, "/")
+	}
+
+	var result Result
+	for i, item := range export.Items {
+		e := Entry{Row: i + 1, Name: item.Name, Notes: item.Notes, Folder: folders[item.FolderId]}
+		for _, f := range item.Fields {
+			if f.Type != bitwardenFieldLinked {
+				e.Fields = append(e.Fields, Field{Name: f.Name, Value: f.Value, Hidden: f.Type == bitwardenFieldHidden})
+			}
+		}
+
+		switch item.Type {
+		case bitwardenLogin:
+			e.Kind = KindLogin
+			if item.Login != nil {
+				e.Username = item.Login.Username
+				e.Password = item.Login.Password
+				e.Totp = item.Login.Totp
+				for _, u := range item.Login.Uris {
+					e.addUrls([]string{u.Uri})
+				}
+			}
+		case bitwardenSecureNote:
+			e.Kind = KindNote
+		case bitwardenSshKey:
+			e.Kind = KindKey
+			if item.SshKey != nil {
+				e.Key = item.SshKey.PrivateKey
+			}
+		case bitwardenCard, bitwardenIdentity:
+			result.fail(e.Row, e.Name, "Cards and identities are not imported")
+			continue
+		default:
+			result.fail(e.Row, e.Name, fmt.Sprintf("Unknown item type %d", item.Type))
+			continue
+		}
+		result.add(e)
+	}
+	return result, nil
+}
+
+// parseBitwardenCSV reads a CSV export, which only holds logins and notes.
+// Custom fields are written one per line as "name: value".
+func parseBitwardenCSV(data []byte) (Result, error) {
+	var result Result
+	_, records, err := readCSV(data, &result, "type", "name")
+	if err != nil {
+		return Result{}, err
+	}
+
+	for _, record := range records {
+		e := Entry{
+			Row:      record.line,
+			Name:     record.get("name"),
+			Username: record.get("login_username"),
+			Password: record.get("login_password"),
+			Totp:     record.get("login_totp"),
+			Notes:    record.get("notes"),
+			Folder:   splitPath(record.get("folder"), "/"),
+		}
+		e.addUrls(strings.Split(record.get("login_uri"), ","))
+		for _, line := range strings.Split(record.get("fields"), "\n") {
+			if name, value, ok := strings.Cut(line, ": "); ok {
+				e.Fields = append(e.Fields, Field{Name: name, Value: value})
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(record.get("type"))) {
+		case "login":
+			e.Kind = KindLogin
+		case "note":
+			e.Kind = KindNote
+		default:
+			result.fail(e.Row, e.Name, fmt.Sprintf("Unknown item type %q", record.get("type")))
+			continue
+		}
+		result.add(e)
+	}
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package imports
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseCSV reads any CSV file with a header row, taking the columns named
+// in mapping.
+func parseCSV(data []byte, mapping Mapping) (Result, error) {
+	if mapping.Name == "" {
+		return Result{}, ErrNoNameColumn
+	}
+	mapped := make(map[string]bool)
+	var required []string
+	for _, column := range []string{mapping.Name, mapping.Type, mapping.Username, mapping.Password, mapping.Url,
+		mapping.Totp, mapping.Notes, mapping.Folder, mapping.Tags} {
+		if column = strings.ToLower(strings.TrimSpace(column)); column != "" {
+			mapped[column] = true
+			required = append(required, column)
+		}
+	}
+
+	var result Result
+	header, records, err := readCSV(data, &result, required...)
+	if err != nil {
+		return Result{}, err
+	}
+
+	for _, record := range records {
+		e := Entry{
+			Row:      record.line,
+			Name:     record.get(mapping.Name),
+			Username: record.get(mapping.Username),
+			Password: record.get(mapping.Password),
+			Totp:     record.get(mapping.Totp),
+			Notes:    record.get(mapping.Notes),
+			Folder:   splitPath(record.get(mapping.Folder), "/"),
+			Tags:     splitTags(record.get(mapping.Tags)),
+		}
+		e.addUrls([]string{record.get(mapping.Url)})
+		for _, column := range header {
+			if value := record.get(column); !mapped[strings.ToLower(column)] && value != "" {
+				e.Fields = append(e.Fields, Field{Name: column, Value: value})
+			}
+		}
+
+		switch kind := Kind(strings.ToLower(strings.TrimSpace(record.get(mapping.Type)))); kind {
+		case KindLogin, KindNote:
+			e.Kind = kind
+		case KindKey:
+			e.Kind, e.Key, e.Password = KindKey, e.Password, ""
+		case "":
+			e.Kind = KindNote
+			if e.Password != "" {
+				e.Kind = KindLogin
+			}
+		default:
+			result.fail(e.Row, e.Name, fmt.Sprintf("Unknown entry type %q", record.get(mapping.Type)))
+			continue
+		}
+		result.add(e)
+	}
+	return result, nil
+}
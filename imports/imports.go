@@ -0,0 +1,287 @@
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/adarsh-a-tw/passwordly/keepass"
+)
+
+// Format is the tool and file type an export comes from.
+type Format string
+
+const (
+	FormatBitwardenJSON Format = "bitwarden_json"
+	FormatBitwardenCSV  Format = "bitwarden_csv"
+	Format1Password1PUX Format = "1password_1pux"
+	Format1PasswordCSV  Format = "1password_csv"
+	FormatLastPassCSV   Format = "lastpass_csv"
+	FormatCSV           Format = "csv"
+	FormatKeePass       Format = "keepass_kdbx"
+)
+
+func (f Format) IsValid() bool {
+	switch f {
+	case FormatBitwardenJSON, FormatBitwardenCSV, Format1Password1PUX, Format1PasswordCSV, FormatLastPassCSV, FormatCSV, FormatKeePass:
+		return true
+	}
+	return false
+}
+
+// Kind is what an entry holds: a login, a free-form note or a key such as
+// an API token or an SSH private key.
+type Kind string
+
+const (
+	KindLogin Kind = "login"
+	KindNote  Kind = "note"
+	KindKey   Kind = "key"
+)
+
+var (
+	ErrUnknownFormat = errors.New("Unknown import format")
+	ErrInvalidFile   = errors.New("The file is not a valid export in this format")
+	ErrEncrypted     = errors.New("Password protected exports cannot be imported")
+	ErrNoNameColumn  = errors.New("The column mapping must name the column holding entry names")
+)
+
+// Entry is one item of an export, independent of the tool it came from.
+// Row is the line of a CSV file or the position of the item in any other
+// export, counted from 1. Folder is the path of folder names from the top
+// level down.
+type Entry struct {
+	Row      int
+	Kind     Kind
+	Name     string
+	Username string
+	Password string
+	Key      string
+	Url      string
+	Totp     string
+	Notes    string
+	Folder   []string
+	Tags     []string
+	Fields   []Field
+}
+
+// Field is a custom field of an entry. Hidden ones were concealed in the
+// tool they come from.
+type Field struct {
+	Name   string
+	Value  string
+	Hidden bool
+}
+
+// RowError reports an entry that could not be read.
+type RowError struct {
+	Row     int
+	Name    string
+	Message string
+}
+
+// Result is everything read from an export: the entries that can be
+// imported and the rows that cannot.
+type Result struct {
+	Entries []Entry
+	Errors  []RowError
+}
+
+// Mapping names the columns of a generic CSV file. Only Name is required.
+// Type holds login, note or key and defaults to login when there is a
+// password and to note otherwise; the Password column holds the key of a
+// key entry. Folder paths are separated with "/" and tags with ";".
+// Columns that are not mapped become custom fields.
+type Mapping struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Url      string `json:"url"`
+	Totp     string `json:"totp"`
+	Notes    string `json:"notes"`
+	Folder   string `json:"folder"`
+	Tags     string `json:"tags"`
+}
+
+// Options are what some formats need besides the file: the column mapping
+// of a generic CSV file and the master password and key file of a KeePass
+// database. MaxSize bounds what compressed exports may unpack to and
+// defaults to DefaultMaxSize.
+type Options struct {
+	Mapping  Mapping
+	Password string
+	KeyFile  []byte
+	MaxSize  int64
+}
+
+const DefaultMaxSize = 10 * 1024 * 1024
+
+// Parse reads an export. Problems with single entries are reported in the
+// result; an error means the file as a whole could not be read.
+func Parse(format Format, data []byte, options Options) (Result, error) {
+	if options.MaxSize <= 0 {
+		options.MaxSize = DefaultMaxSize
+	}
+	if format == FormatKeePass {
+		return parseKeePass(data, keepass.Credentials{Password: options.Password, KeyFile: options.KeyFile}, options.MaxSize)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	switch format {
+	case FormatBitwardenJSON:
+		return parseBitwardenJSON(data)
+	case FormatBitwardenCSV:
+		return parseBitwardenCSV(data)
+	case Format1Password1PUX:
+		return parse1PUX(data, options.MaxSize)
+	case Format1PasswordCSV:
+		return parse1PasswordCSV(data)
+	case FormatLastPassCSV:
+		return parseLastPassCSV(data)
+	case FormatCSV:
+		return parseCSV(data, options.Mapping)
+	}
+	return Result{}, ErrUnknownFormat
+}
+
+// add checks an entry before it is taken, reporting it as a row error when
+// there is nothing to import.
+func (r *Result) add(e Entry) {
+	e.Name = strings.TrimSpace(e.Name)
+	if e.Name == "" {
+		e.Name = hostname(e.Url)
+	}
+
+	var message string
+	switch {
+	case e.Name == "":
+		message = "Entry has no name"
+	case e.Kind == KindLogin && e.Password == "":
+		message = "Login has no password"
+	case e.Kind == KindKey && e.Key == "":
+		message = "Key entry has no key"
+	case e.Kind == KindNote && e.Notes == "" && e.Totp == "" && len(e.Fields) == 0:
+		message = "Note is empty"
+	}
+	if message != "" {
+		r.fail(e.Row, e.Name, message)
+		return
+	}
+	r.Entries = append(r.Entries, e)
+}
+
+func (r *Result) fail(row int, name string, message string) {
+	r.Errors = append(r.Errors, RowError{Row: row, Name: name, Message: message})
+}
+
+// csvRecord is a data row of a CSV file with its values keyed by the lower
+// cased column name. Values are kept as they are, as surrounding spaces may
+// be part of a password.
+type csvRecord struct {
+	line   int
+	values map[string]string
+}
+
+func (cr csvRecord) get(column string) string {
+	return cr.values[strings.ToLower(strings.TrimSpace(column))]
+}
+
+// readCSV reads a CSV file with a header row, returning the column names
+// and the rows. Rows with the wrong number of columns are reported in
+// result rather than returned.
+func readCSV(data []byte, result *Result, required ...string) ([]string, []csvRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, ErrInvalidFile
+	}
+	keys := make([]string, len(header))
+	columns := make(map[string]bool, len(header))
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		keys[i] = strings.ToLower(header[i])
+		columns[keys[i]] = true
+	}
+	for _, name := range required {
+		if !columns[name] {
+			return nil, nil, fmt.Errorf("%w: missing column %s", ErrInvalidFile, name)
+		}
+	}
+
+	var records []csvRecord
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, ErrInvalidFile
+			}
+			if !errors.Is(err, csv.ErrFieldCount) {
+				// Quoting errors leave the reader unable to find where the
+				// next row starts.
+				return nil, nil, fmt.Errorf("%w: line %d: %v", ErrInvalidFile, parseErr.Line, parseErr.Err)
+			}
+			result.fail(parseErr.StartLine, "", fmt.Sprintf("Row has %d columns instead of %d", len(values), len(header)))
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		record := csvRecord{line: line, values: make(map[string]string, len(values))}
+		for i, value := range values {
+			record.values[keys[i]] = value
+		}
+		records = append(records, record)
+	}
+	return header, records, nil
+}
+
+// splitPath turns a folder path into its names, dropping blank ones.
+func splitPath(path string, separator string) []string {
+	var names []string
+	for _, name := range strings.Split(path, separator) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// splitTags splits a list of tags on semicolons and commas.
+func splitTags(tags string) []string {
+	var split []string
+	for _, tag := range strings.FieldsFunc(tags, func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			split = append(split, tag)
+		}
+	}
+	return split
+}
+
+// addUrls keeps the first URL of an entry as its website and the others
+// as fields.
+func (e *Entry) addUrls(urls []string) {
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u == "" {
+			continue
+		}
+		if e.Url == "" {
+			e.Url = u
+			continue
+		}
+		e.Fields = append(e.Fields, Field{Name: "Website", Value: u})
+	}
+}
+
+func hostname(rawUrl string) string {
+	u, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,63 @@
+package imports
+
+import (
+	"strings"
+
+	"github.com/adarsh-a-tw/passwordly/keepass"
+)
+
+// Names KeePass and KeePassXC store one-time password seeds under.
+var keePassTotpFields = map[string]bool{"otp": true, "TimeOtp-Secret-Base32": true}
+
+// keePassExpansion is how many times MaxSize the compressed XML of a
+// KeePass database may unpack to; XML compresses well.
+const keePassExpansion = 10
+
+// parseKeePass reads a KDBX 4 database. Groups become folders below the
+// root group, which is the database itself; entries are numbered in the
+// order of the file.
+func parseKeePass(data []byte, creds keepass.Credentials, maxSize int64) (Result, error) {
+	db, err := keepass.Read(data, creds, maxSize*keePassExpansion)
+	if err != nil {
+		return Result{}, err
+	}
+	var result Result
+	row := 0
+	var walk func(g keepass.Group, folder []string)
+	walk = func(g keepass.Group, folder []string) {
+		for _, ke := range g.Entries {
+			row++
+			result.add(keePassEntry(row, ke, folder))
+		}
+		for _, sub := range g.Groups {
+			walk(sub, append(folder[:len(folder):len(folder)], splitPath(sub.Name, "/")...))
+		}
+	}
+	walk(db.Root, nil)
+	return result, nil
+}
+
+func keePassEntry(row int, ke keepass.Entry, folder []string) Entry {
+	e := Entry{
+		Row:      row,
+		Kind:     KindNote,
+		Name:     ke.Title,
+		Username: ke.UserName,
+		Password: ke.Password,
+		Notes:    ke.Notes,
+		Folder:   folder,
+		Tags:     ke.Tags,
+	}
+	if e.Password != "" {
+		e.Kind = KindLogin
+	}
+	e.addUrls([]string{ke.URL})
+	for _, f := range ke.Fields {
+		if keePassTotpFields[f.Key] && e.Totp == "" {
+			e.Totp = strings.TrimSpace(f.Value)
+			continue
+		}
+		e.Fields = append(e.Fields, Field{Name: f.Key, Value: f.Value, Hidden: f.Protected})
+	}
+	return e
+}
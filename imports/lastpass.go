@@ -0,0 +1,38 @@
+package imports
+
+import "strings"
+
+// lastPassNoteUrl marks secure notes in a LastPass export.
+const lastPassNoteUrl = "http://sn"
+
+// parseLastPassCSV reads a LastPass CSV export. Folders ("grouping") are
+// nested with backslashes.
+func parseLastPassCSV(data []byte) (Result, error) {
+	var result Result
+	_, records, err := readCSV(data, &result, "url", "name")
+	if err != nil {
+		return Result{}, err
+	}
+
+	for _, record := range records {
+		e := Entry{
+			Row:      record.line,
+			Kind:     KindLogin,
+			Name:     record.get("name"),
+			Username: record.get("username"),
+			Password: record.get("password"),
+			Totp:     record.get("totp"),
+			Notes:    record.get("extra"),
+		}
+		if grouping := record.get("grouping"); grouping != "(none)" {
+			e.Folder = splitPath(grouping, `\`)
+		}
+		if url := strings.TrimSpace(record.get("url")); url == lastPassNoteUrl {
+			e.Kind = KindNote
+		} else {
+			e.addUrls([]string{url})
+		}
+		result.add(e)
+	}
+	return result, nil
+}
@@ -0,0 +1,212 @@
+package imports
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 1Password item categories that are imported.
+const (
+	onePasswordLogin         = "001"
+	onePasswordSecureNote    = "003"
+	onePasswordPassword      = "005"
+	onePasswordApiCredential = "112"
+	onePasswordSshKey        = "114"
+)
+
+type onePasswordExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePasswordItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePasswordItem struct {
+	CategoryUuid string `json:"categoryUuid"`
+	Overview     struct {
+		Title string `json:"title"`
+		Url   string `json:"url"`
+		Urls  []struct {
+			Url string `json:"url"`
+		} `json:"urls"`
+		Tags []string `json:"tags"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Designation string `json:"designation"`
+			Name        string `json:"name"`
+			Value       string `json:"value"`
+			FieldType   string `json:"fieldType"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Title  string `json:"title"`
+			Fields []struct {
+				Title string                     `json:"title"`
+				Id    string                     `json:"id"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+	} `json:"details"`
+}
+
+// parse1PUX reads a 1PUX export, a zip archive holding the items as JSON in
+// export.data. Each 1Password vault becomes a top level folder. export.data
+// may unpack to at most maxSize bytes.
+func parse1PUX(data []byte, maxSize int64) (Result, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Result{}, ErrInvalidFile
+	}
+	var export onePasswordExport
+	found := false
+	for _, f := range archive.File {
+		if f.Name != "export.data" {
+			continue
+		}
+		if f.UncompressedSize64 > uint64(maxSize) {
+			return Result{}, ErrInvalidFile
+		}
+		r, err := f.Open()
+		if err != nil {
+			return Result{}, ErrInvalidFile
+		}
+		// The size in the zip header is not to be trusted.
+		content, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+		r.Close()
+		if err != nil || int64(len(content)) > maxSize || json.Unmarshal(content, &export) != nil {
+			return Result{}, ErrInvalidFile
+		}
+		found = true
+	}
+	if !found {
+		return Result{}, ErrInvalidFile
+	}
+
+	var result Result
+	row := 0
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, item := range vault.Items {
+				row++
+				e := Entry{
+					Row:      row,
+					Name:     item.Overview.Title,
+					Notes:    item.Details.NotesPlain,
+					Password: item.Details.Password,
+					Folder:   splitPath(vault.Attrs.Name, "/"),
+					Tags:     item.Overview.Tags,
+				}
+				e.addUrls([]string{item.Overview.Url})
+				for _, u := range item.Overview.Urls {
+					if u.Url != item.Overview.Url {
+						e.addUrls([]string{u.Url})
+					}
+				}
+				for _, f := range item.Details.LoginFields {
+					switch f.Designation {
+					case "username":
+						e.Username = f.Value
+					case "password":
+						e.Password = f.Value
+					}
+				}
+				e.readSections(item)
+
+				switch item.CategoryUuid {
+				case onePasswordLogin, onePasswordPassword:
+					e.Kind = KindLogin
+				case onePasswordSecureNote:
+					e.Kind = KindNote
+				case onePasswordApiCredential, onePasswordSshKey:
+					e.Kind = KindKey
+				default:
+					result.fail(row, e.Name, fmt.Sprintf("1Password category %s is not imported", item.CategoryUuid))
+					continue
+				}
+				result.add(e)
+			}
+		}
+	}
+	return result, nil
+}
+
+// readSections takes the key of API credentials and SSH keys, the first
+// one-time password and keeps every other field of the item's sections.
+func (e *Entry) readSections(item onePasswordItem) {
+	for _, section := range item.Details.Sections {
+		for _, f := range section.Fields {
+			for kind, raw := range f.Value {
+				var value string
+				switch kind {
+				case "sshKey":
+					var key struct {
+						PrivateKey string `json:"privateKey"`
+					}
+					json.Unmarshal(raw, &key)
+					value = key.PrivateKey
+				default:
+					if json.Unmarshal(raw, &value) != nil {
+						value = strings.Trim(string(raw), `"`)
+					}
+				}
+				if value == "" {
+					continue
+				}
+
+				switch {
+				case kind == "sshKey" || (item.CategoryUuid == onePasswordApiCredential && f.Id == "credential"):
+					e.Key = value
+				case kind == "totp" && e.Totp == "":
+					e.Totp = value
+				case item.CategoryUuid == onePasswordApiCredential && f.Id == "username":
+					e.Username = value
+				default:
+					name := f.Title
+					if name == "" {
+						name = section.Title
+					}
+					e.Fields = append(e.Fields, Field{Name: name, Value: value, Hidden: kind == "concealed" || kind == "creditCardNumber"})
+				}
+			}
+		}
+	}
+}
+
+// parse1PasswordCSV reads a CSV export. Rows with a password are logins and
+// the others notes.
+func parse1PasswordCSV(data []byte) (Result, error) {
+	var result Result
+	_, records, err := readCSV(data, &result, "title")
+	if err != nil {
+		return Result{}, err
+	}
+
+	for _, record := range records {
+		e := Entry{
+			Row:      record.line,
+			Kind:     KindNote,
+			Name:     record.get("title"),
+			Username: record.get("username"),
+			Password: record.get("password"),
+			Totp:     record.get("otpauth"),
+			Notes:    record.get("notes"),
+			Tags:     splitTags(record.get("tags")),
+		}
+		e.addUrls([]string{record.get("url")})
+		if e.Password != "" {
+			e.Kind = KindLogin
+		}
+		result.add(e)
+	}
+	return result, nil
+}
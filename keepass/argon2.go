@@ -0,0 +1,263 @@
+package keepass
+
+import (
+	"encoding/binary"
+	"math/bits"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// argon2Mode is the variant of Argon2 (RFC 9106). KeePass defaults to
+// Argon2d, which golang.org/x/crypto/argon2 does not offer, so all
+// variants are implemented here.
+type argon2Mode uint32
+
+const (
+	argon2d  argon2Mode = 0
+	argon2i  argon2Mode = 1
+	argon2id argon2Mode = 2
+)
+
+const (
+	argon2Version10 = 0x10
+	argon2Version13 = 0x13
+
+	argon2BlockWords = 128
+	argon2SyncPoints = 4
+)
+
+type argon2Block [argon2BlockWords]uint64
+
+// argon2Params are the inputs of Argon2 besides the password. Memory is
+// in KiB.
+type argon2Params struct {
+	mode        argon2Mode
+	version     uint32
+	salt        []byte
+	secret      []byte
+	data        []byte
+	iterations  uint32
+	memory      uint32
+	parallelism uint32
+	keyLen      uint32
+}
+
+func argon2Key(password []byte, p argon2Params) []byte {
+	h0 := argon2InitialHash(password, p)
+
+	blocks := p.memory / (argon2SyncPoints * p.parallelism) * (argon2SyncPoints * p.parallelism)
+	if blocks < 2*argon2SyncPoints*p.parallelism {
+		blocks = 2 * argon2SyncPoints * p.parallelism
+	}
+	laneLength := blocks / p.parallelism
+	segmentLength := laneLength / argon2SyncPoints
+	memory := make([]argon2Block, blocks)
+
+	var input [blake2b.Size + 8]byte
+	copy(input[:], h0)
+	for lane := uint32(0); lane < p.parallelism; lane++ {
+		binary.LittleEndian.PutUint32(input[blake2b.Size+4:], lane)
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(input[blake2b.Size:], i)
+			memory[lane*laneLength+i].load(argon2Hash(input[:], 1024))
+		}
+	}
+
+	for pass := uint32(0); pass < p.iterations; pass++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			for lane := uint32(0); lane < p.parallelism; lane++ {
+				argon2FillSegment(memory, p, blocks, laneLength, segmentLength, pass, slice, lane)
+			}
+		}
+	}
+
+	final := memory[laneLength-1]
+	for lane := uint32(1); lane < p.parallelism; lane++ {
+		last := &memory[lane*laneLength+laneLength-1]
+		for i := range final {
+			final[i] ^= last[i]
+		}
+	}
+	var out [1024]byte
+	for i, w := range final {
+		binary.LittleEndian.PutUint64(out[i*8:], w)
+	}
+	return argon2Hash(out[:], p.keyLen)
+}
+
+func argon2InitialHash(password []byte, p argon2Params) []byte {
+	h, _ := blake2b.New512(nil)
+	var word [4]byte
+	for _, v := range []uint32{p.parallelism, p.keyLen, p.memory, p.iterations, p.version, uint32(p.mode)} {
+		binary.LittleEndian.PutUint32(word[:], v)
+		h.Write(word[:])
+	}
+	for _, b := range [][]byte{password, p.salt, p.secret, p.data} {
+		binary.LittleEndian.PutUint32(word[:], uint32(len(b)))
+		h.Write(word[:])
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// argon2Hash is the variable length hash H' of the specification.
+func argon2Hash(in []byte, size uint32) []byte {
+	var prefix [4]byte
+	binary.LittleEndian.PutUint32(prefix[:], size)
+	if size <= blake2b.Size {
+		h, _ := blake2b.New(int(size), nil)
+		h.Write(prefix[:])
+		h.Write(in)
+		return h.Sum(nil)
+	}
+
+	out := make([]byte, 0, size)
+	h, _ := blake2b.New512(nil)
+	h.Write(prefix[:])
+	h.Write(in)
+	v := h.Sum(nil)
+	for uint32(len(out))+blake2b.Size < size {
+		out = append(out, v[:32]...)
+		if remaining := size - uint32(len(out)); remaining <= blake2b.Size {
+			h, _ = blake2b.New(int(remaining), nil)
+		} else {
+			h, _ = blake2b.New512(nil)
+		}
+		h.Write(v)
+		v = h.Sum(nil)
+	}
+	return append(out, v...)
+}
+
+func argon2FillSegment(memory []argon2Block, p argon2Params, blocks, laneLength, segmentLength, pass, slice, lane uint32) {
+	independent := p.mode == argon2i || (p.mode == argon2id && pass == 0 && slice < argon2SyncPoints/2)
+	var address, input, zero argon2Block
+	if independent {
+		input[0] = uint64(pass)
+		input[1] = uint64(lane)
+		input[2] = uint64(slice)
+		input[3] = uint64(blocks)
+		input[4] = uint64(p.iterations)
+		input[5] = uint64(p.mode)
+	}
+	nextAddresses := func() {
+		input[6]++
+		argon2Compress(&address, &zero, &input, false)
+		argon2Compress(&address, &zero, &address, false)
+	}
+
+	start := uint32(0)
+	if pass == 0 && slice == 0 {
+		start = 2
+		if independent {
+			nextAddresses()
+		}
+	}
+
+	offset := lane*laneLength + slice*segmentLength + start
+	for index := start; index < segmentLength; index, offset = index+1, offset+1 {
+		prev := offset - 1
+		if offset%laneLength == 0 {
+			prev = offset + laneLength - 1
+		}
+
+		var random uint64
+		if independent {
+			if index%argon2BlockWords == 0 {
+				nextAddresses()
+			}
+			random = address[index%argon2BlockWords]
+		} else {
+			random = memory[prev][0]
+		}
+
+		refLane := uint32(random>>32) % p.parallelism
+		if pass == 0 && slice == 0 {
+			refLane = lane
+		}
+		refIndex := argon2ReferenceIndex(uint32(random), refLane == lane, laneLength, segmentLength, pass, slice, index)
+
+		argon2Compress(&memory[offset], &memory[prev], &memory[refLane*laneLength+refIndex], pass > 0 && p.version == argon2Version13)
+	}
+}
+
+// argon2ReferenceIndex maps the pseudo-random value j1 onto the blocks of
+// the reference lane that may be referenced from the current position.
+func argon2ReferenceIndex(j1 uint32, sameLane bool, laneLength, segmentLength, pass, slice, index uint32) uint32 {
+	var area uint32
+	if pass == 0 {
+		area = slice * segmentLength
+	} else {
+		area = laneLength - segmentLength
+	}
+	if sameLane {
+		area += index - 1
+	} else if index == 0 {
+		area--
+	}
+
+	x := uint64(j1) * uint64(j1) >> 32
+	relative := uint64(area) - 1 - (uint64(area) * x >> 32)
+
+	startPosition := uint64(0)
+	if pass != 0 && slice != argon2SyncPoints-1 {
+		startPosition = uint64((slice + 1) * segmentLength)
+	}
+	return uint32((startPosition + relative) % uint64(laneLength))
+}
+
+func (b *argon2Block) load(data []byte) {
+	for i := range b {
+		b[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+}
+
+// argon2Compress sets out to G(x, y), XORed into its previous content when
+// xor is set as version 1.3 does from the second pass on.
+func argon2Compress(out, x, y *argon2Block, xor bool) {
+	var r, z argon2Block
+	for i := range r {
+		r[i] = x[i] ^ y[i]
+	}
+	z = r
+	if xor {
+		for i := range z {
+			z[i] ^= out[i]
+		}
+	}
+
+	for i := 0; i < 8; i++ {
+		blamkaRound(&r, 16*i, 16*i+1, 16*i+2, 16*i+3, 16*i+4, 16*i+5, 16*i+6, 16*i+7,
+			16*i+8, 16*i+9, 16*i+10, 16*i+11, 16*i+12, 16*i+13, 16*i+14, 16*i+15)
+	}
+	for i := 0; i < 8; i++ {
+		blamkaRound(&r, 2*i, 2*i+1, 2*i+16, 2*i+17, 2*i+32, 2*i+33, 2*i+48, 2*i+49,
+			2*i+64, 2*i+65, 2*i+80, 2*i+81, 2*i+96, 2*i+97, 2*i+112, 2*i+113)
+	}
+
+	for i := range out {
+		out[i] = z[i] ^ r[i]
+	}
+}
+
+func blamkaRound(b *argon2Block, v0, v1, v2, v3, v4, v5, v6, v7, v8, v9, v10, v11, v12, v13, v14, v15 int) {
+	blamka(b, v0, v4, v8, v12)
+	blamka(b, v1, v5, v9, v13)
+	blamka(b, v2, v6, v10, v14)
+	blamka(b, v3, v7, v11, v15)
+	blamka(b, v0, v5, v10, v15)
+	blamka(b, v1, v6, v11, v12)
+	blamka(b, v2, v7, v8, v13)
+	blamka(b, v3, v4, v9, v14)
+}
+
+func blamka(b *argon2Block, a, bi, c, d int) {
+	b[a] += b[bi] + 2*uint64(uint32(b[a]))*uint64(uint32(b[bi]))
+	b[d] = bits.RotateLeft64(b[d]^b[a], -32)
+	b[c] += b[d] + 2*uint64(uint32(b[c]))*uint64(uint32(b[d]))
+	b[bi] = bits.RotateLeft64(b[bi]^b[c], -24)
+	b[a] += b[bi] + 2*uint64(uint32(b[a]))*uint64(uint32(b[bi]))
+	b[d] = bits.RotateLeft64(b[d]^b[a], -16)
+	b[c] += b[d] + 2*uint64(uint32(b[c]))*uint64(uint32(b[d]))
+	b[bi] = bits.RotateLeft64(b[bi]^b[c], -63)
+}
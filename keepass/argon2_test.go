@@ -0,0 +1,35 @@
+package keepass
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestArgon2Key_ShouldMatchTheTestVectors checks every variant against the
+// test vectors of RFC 9106, section 5.
+func TestArgon2Key_ShouldMatchTheTestVectors(t *testing.T) {
+	testCases := map[argon2Mode]string{
+		argon2d:  "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb",
+		argon2i:  "c814d9d1dc7f37aa13f0d77f2494bda1c8de6b016dd388d29952a4c4672b6ce8",
+		argon2id: "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659",
+	}
+
+	for mode, want := range testCases {
+		tag := argon2Key(bytes.Repeat([]byte{0x01}, 32), argon2Params{
+			mode:        mode,
+			version:     argon2Version13,
+			salt:        bytes.Repeat([]byte{0x02}, 16),
+			secret:      bytes.Repeat([]byte{0x03}, 8),
+			data:        bytes.Repeat([]byte{0x04}, 12),
+			iterations:  3,
+			memory:      32,
+			parallelism: 4,
+			keyLen:      32,
+		})
+
+		assert.Equal(t, want, hex.EncodeToString(tag), mode)
+	}
+}
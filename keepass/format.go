@@ -0,0 +1,406 @@
+package keepass
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/salsa20/salsa"
+)
+
+const (
+	signature1    = 0x9AA2D903
+	signature2    = 0xB54BFB67
+	versionMajor4 = 4
+	version40     = 0x00040000
+
+	blockSize = 1 << 20
+)
+
+// Outer header fields.
+const (
+	headerEnd         = 0
+	headerCipherId    = 2
+	headerCompression = 3
+	headerMasterSeed  = 4
+	headerIV          = 7
+	headerKdf         = 11
+)
+
+// Inner header fields, at the start of the decrypted content.
+const (
+	innerEnd       = 0
+	innerStreamId  = 1
+	innerStreamKey = 2
+	innerBinary    = 3
+)
+
+const (
+	innerStreamSalsa20  = 2
+	innerStreamChaCha20 = 3
+)
+
+var (
+	cipherAES      = []byte{0x31, 0xC1, 0xF2, 0xE6, 0xBF, 0x71, 0x43, 0x50, 0xBE, 0x58, 0x05, 0x21, 0x6A, 0xFC, 0x5A, 0xFF}
+	cipherChaCha20 = []byte{0xD6, 0x03, 0x8A, 0x2B, 0x8B, 0x6F, 0x4C, 0xB5, 0xA5, 0x24, 0x33, 0x9A, 0x31, 0xDB, 0xB5, 0x9A}
+
+	salsa20Nonce = []byte{0xE8, 0x30, 0x09, 0x4B, 0x97, 0x20, 0x5D, 0x2A}
+)
+
+// readOuter checks the header and blocks of a file and returns its
+// decrypted, decompressed payload.
+func readOuter(data []byte, compositeKey []byte, maxSize int64) ([]byte, error) {
+	r := bytes.NewReader(data)
+	var prefix struct{ Sig1, Sig2, Version uint32 }
+	if err := binary.Read(r, binary.LittleEndian, &prefix); err != nil || prefix.Sig1 != signature1 || prefix.Sig2 != signature2 {
+		return nil, ErrNotKeePass
+	}
+	if prefix.Version>>16 != versionMajor4 {
+		return nil, ErrUnsupportedVersion
+	}
+
+	fields := make(map[byte][]byte)
+	for {
+		id, value, err := readField(r)
+		if err != nil {
+			return nil, ErrCorrupt
+		}
+		if id == headerEnd {
+			break
+		}
+		fields[id] = value
+	}
+	header := data[:len(data)-r.Len()]
+
+	var hash, mac [32]byte
+	if _, err := io.ReadFull(r, hash[:]); err != nil {
+		return nil, ErrCorrupt
+	}
+	if _, err := io.ReadFull(r, mac[:]); err != nil {
+		return nil, ErrCorrupt
+	}
+	if sum := sha256.Sum256(header); !hmac.Equal(sum[:], hash[:]) {
+		return nil, ErrCorrupt
+	}
+
+	kdf, err := readVariantDictionary(fields[headerKdf])
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	transformed, err := transformKey(compositeKey, kdf)
+	if err != nil {
+		return nil, err
+	}
+	seed := fields[headerMasterSeed]
+	if len(seed) != 32 {
+		return nil, ErrCorrupt
+	}
+	hmacKey := sha512.Sum512(concat(seed, transformed, []byte{1}))
+	if !hmac.Equal(blockMAC(hmacKey[:], math.MaxUint64, header), mac[:]) {
+		return nil, ErrInvalidCredentials
+	}
+
+	var encrypted bytes.Buffer
+	for index := uint64(0); ; index++ {
+		var blockMac [32]byte
+		var size int32
+		if _, err := io.ReadFull(r, blockMac[:]); err != nil {
+			return nil, ErrCorrupt
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil || size < 0 || int(size) > r.Len() {
+			return nil, ErrCorrupt
+		}
+		block := make([]byte, size)
+		io.ReadFull(r, block)
+		if !hmac.Equal(blockMAC(hmacKey[:], index, block), blockMac[:]) {
+			return nil, ErrCorrupt
+		}
+		if size == 0 {
+			break
+		}
+		encrypted.Write(block)
+	}
+
+	encryptionKey := sha256.Sum256(concat(seed, transformed))
+	payload, err := decrypt(fields[headerCipherId], encryptionKey[:], fields[headerIV], encrypted.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	compression := fields[headerCompression]
+	if len(compression) != 4 {
+		return nil, ErrCorrupt
+	}
+	switch binary.LittleEndian.Uint32(compression) {
+	case 0:
+		return payload, nil
+	case 1:
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, ErrCorrupt
+		}
+		payload, err = io.ReadAll(io.LimitReader(zr, maxSize+1))
+		if err != nil || int64(len(payload)) > maxSize {
+			return nil, ErrCorrupt
+		}
+		return payload, nil
+	}
+	return nil, ErrUnsupported
+}
+
+// writeOuter compresses and encrypts a payload and frames it with the
+// header and authenticated blocks.
+func writeOuter(payload []byte, compositeKey []byte, settings Settings) ([]byte, error) {
+	var ivSize int
+	var cipherId []byte
+	switch settings.Cipher {
+	case CipherAES:
+		cipherId, ivSize = cipherAES, aes.BlockSize
+	case CipherChaCha20:
+		cipherId, ivSize = cipherChaCha20, chacha20.NonceSize
+	default:
+		return nil, ErrUnsupportedSettings
+	}
+	kdf, err := newKdfParameters(settings)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := randomBytes(ivSize)
+	if err != nil {
+		return nil, err
+	}
+	transformed, err := transformKey(compositeKey, kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, []uint32{signature1, signature2, version40})
+	compression := binary.LittleEndian.AppendUint32(nil, 1)
+	writeField(&header, headerCipherId, cipherId)
+	writeField(&header, headerCompression, compression)
+	writeField(&header, headerMasterSeed, seed)
+	writeField(&header, headerIV, iv)
+	writeField(&header, headerKdf, kdf.bytes())
+	writeField(&header, headerEnd, []byte("\r\n\r\n"))
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(payload)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	encryptionKey := sha256.Sum256(concat(seed, transformed))
+	encrypted, err := encrypt(cipherId, encryptionKey[:], iv, compressed.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	hmacKey := sha512.Sum512(concat(seed, transformed, []byte{1}))
+	out := bytes.NewBuffer(nil)
+	out.Write(header.Bytes())
+	hash := sha256.Sum256(header.Bytes())
+	out.Write(hash[:])
+	out.Write(blockMAC(hmacKey[:], math.MaxUint64, header.Bytes()))
+	for index := uint64(0); ; index++ {
+		block := encrypted[:min(len(encrypted), blockSize)]
+		encrypted = encrypted[len(block):]
+		out.Write(blockMAC(hmacKey[:], index, block))
+		binary.Write(out, binary.LittleEndian, int32(len(block)))
+		out.Write(block)
+		if len(block) == 0 {
+			break
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// blockMAC authenticates a block of the payload, or the header as block
+// MaxUint64, with a key specific to the block.
+func blockMAC(hmacKey []byte, index uint64, data []byte) []byte {
+	var prefix [8]byte
+	binary.LittleEndian.PutUint64(prefix[:], index)
+	key := sha512.Sum512(concat(prefix[:], hmacKey))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write(prefix[:])
+	binary.Write(mac, binary.LittleEndian, int32(len(data)))
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func decrypt(cipherId, key, iv, data []byte) ([]byte, error) {
+	switch {
+	case bytes.Equal(cipherId, cipherAES):
+		if len(iv) != aes.BlockSize || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+			return nil, ErrCorrupt
+		}
+		block, _ := aes.NewCipher(key)
+		plain := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+		padding := int(plain[len(plain)-1])
+		if padding == 0 || padding > aes.BlockSize {
+			return nil, ErrCorrupt
+		}
+		for _, b := range plain[len(plain)-padding:] {
+			if int(b) != padding {
+				return nil, ErrCorrupt
+			}
+		}
+		return plain[:len(plain)-padding], nil
+	case bytes.Equal(cipherId, cipherChaCha20):
+		stream, err := chacha20.NewUnauthenticatedCipher(key, iv)
+		if err != nil {
+			return nil, ErrCorrupt
+		}
+		plain := make([]byte, len(data))
+		stream.XORKeyStream(plain, data)
+		return plain, nil
+	}
+	return nil, ErrUnsupported
+}
+
+func encrypt(cipherId, key, iv, data []byte) ([]byte, error) {
+	if bytes.Equal(cipherId, cipherAES) {
+		padding := aes.BlockSize - len(data)%aes.BlockSize
+		padded := append(data, bytes.Repeat([]byte{byte(padding)}, padding)...)
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+		return padded, nil
+	}
+	stream, err := chacha20.NewUnauthenticatedCipher(key, iv)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	stream.XORKeyStream(out, data)
+	return out, nil
+}
+
+// readInnerHeader splits the payload into the inner random stream that
+// protects values in the XML and the XML itself. Binaries are skipped.
+func readInnerHeader(payload []byte) ([]byte, cipher.Stream, error) {
+	r := bytes.NewReader(payload)
+	var streamId uint32
+	var streamKey []byte
+	for {
+		id, value, err := readField(r)
+		if err != nil {
+			return nil, nil, ErrCorrupt
+		}
+		if id == innerEnd {
+			break
+		}
+		switch id {
+		case innerStreamId:
+			if len(value) != 4 {
+				return nil, nil, ErrCorrupt
+			}
+			streamId = binary.LittleEndian.Uint32(value)
+		case innerStreamKey:
+			streamKey = value
+		}
+	}
+	stream, err := newInnerStream(streamId, streamKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload[len(payload)-r.Len():], stream, nil
+}
+
+func writeInnerHeader(streamKey []byte, content []byte) []byte {
+	var buf bytes.Buffer
+	writeField(&buf, innerStreamId, binary.LittleEndian.AppendUint32(nil, innerStreamChaCha20))
+	writeField(&buf, innerStreamKey, streamKey)
+	writeField(&buf, innerEnd, nil)
+	buf.Write(content)
+	return buf.Bytes()
+}
+
+func newInnerStream(id uint32, key []byte) (cipher.Stream, error) {
+	switch id {
+	case innerStreamChaCha20:
+		h := sha512.Sum512(key)
+		return chacha20.NewUnauthenticatedCipher(h[:32], h[32:44])
+	case innerStreamSalsa20:
+		h := sha256.Sum256(key)
+		return &salsa20Stream{key: h}, nil
+	}
+	return nil, ErrUnsupported
+}
+
+// salsa20Stream is the keystream of Salsa20 with the fixed nonce KDBX
+// uses, kept across calls unlike salsa20.XORKeyStream.
+type salsa20Stream struct {
+	key     [32]byte
+	counter uint64
+	block   []byte
+}
+
+func (s *salsa20Stream) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if len(s.block) == 0 {
+			var in [16]byte
+			copy(in[:], salsa20Nonce)
+			binary.LittleEndian.PutUint64(in[8:], s.counter)
+			s.counter++
+			s.block = make([]byte, 64)
+			salsa.XORKeyStream(s.block, s.block, &in, &s.key)
+		}
+		dst[i] = src[i] ^ s.block[0]
+		s.block = s.block[1:]
+	}
+}
+
+func readField(r *bytes.Reader) (byte, []byte, error) {
+	id, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return 0, nil, err
+	}
+	if int64(size) > int64(r.Len()) {
+		return 0, nil, errors.New("field exceeds the file")
+	}
+	value := make([]byte, size)
+	io.ReadFull(r, value)
+	return id, value, nil
+}
+
+func writeField(buf *bytes.Buffer, id byte, value []byte) {
+	buf.WriteByte(id)
+	binary.Write(buf, binary.LittleEndian, uint32(len(value)))
+	buf.Write(value)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,237 @@
+package keepass
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var (
+	kdfAES      = []byte{0xC9, 0xD9, 0xF3, 0x9A, 0x62, 0x8A, 0x44, 0x60, 0xBF, 0x74, 0x0D, 0x08, 0xC1, 0x8A, 0x4F, 0xEA}
+	kdfArgon2d  = []byte{0xEF, 0x63, 0x6D, 0xDF, 0x8C, 0x29, 0x44, 0x4B, 0x91, 0xF7, 0xA9, 0xA4, 0x03, 0xE3, 0x0A, 0x0C}
+	kdfArgon2id = []byte{0x9E, 0x29, 0x8B, 0x19, 0x56, 0xDB, 0x47, 0x73, 0xB2, 0x3D, 0xFC, 0x3E, 0xC6, 0xF0, 0xA1, 0xE6}
+)
+
+// Limits on the key derivation of databases being read, so that an
+// uploaded file cannot tie up the server. Argon2 may use twice the memory
+// of DefaultSettings and ten times its work.
+const (
+	maxArgon2Memory = 128 << 20
+	maxArgon2Work   = 64 << 20 * 100
+	maxAESRounds    = 100_000_000
+)
+
+// kdfSlots bounds how many keys are derived at once, and with it the memory
+// Argon2 holds across concurrent imports.
+var kdfSlots = make(chan struct{}, 2)
+
+// Value types of a variant dictionary.
+const (
+	variantUint32 = 0x04
+	variantUint64 = 0x05
+	variantBool   = 0x08
+	variantInt32  = 0x0C
+	variantInt64  = 0x0D
+	variantString = 0x18
+	variantBytes  = 0x42
+
+	variantVersion = 0x0100
+)
+
+type variantValue struct {
+	kind  byte
+	value []byte
+}
+
+// variantDictionary is the typed key-value map KDBX 4 stores the key
+// derivation parameters in. Keys keep their order for writing.
+type variantDictionary struct {
+	keys   []string
+	values map[string]variantValue
+}
+
+func readVariantDictionary(data []byte) (*variantDictionary, error) {
+	r := bytes.NewReader(data)
+	var version uint16
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil || version&0xFF00 > variantVersion {
+		return nil, errors.New("unsupported variant dictionary")
+	}
+	vd := &variantDictionary{values: make(map[string]variantValue)}
+	for {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if kind == 0 {
+			return vd, nil
+		}
+		key, err := readSized(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readSized(r)
+		if err != nil {
+			return nil, err
+		}
+		vd.set(string(key), kind, value)
+	}
+}
+
+func readSized(r *bytes.Reader) ([]byte, error) {
+	var size int32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 0 || int(size) > r.Len() {
+		return nil, errors.New("value exceeds the dictionary")
+	}
+	b := make([]byte, size)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+func (vd *variantDictionary) set(key string, kind byte, value []byte) {
+	if _, ok := vd.values[key]; !ok {
+		vd.keys = append(vd.keys, key)
+	}
+	vd.values[key] = variantValue{kind: kind, value: value}
+}
+
+func (vd *variantDictionary) setUint32(key string, v uint32) {
+	vd.set(key, variantUint32, binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (vd *variantDictionary) setUint64(key string, v uint64) {
+	vd.set(key, variantUint64, binary.LittleEndian.AppendUint64(nil, v))
+}
+
+func (vd *variantDictionary) bytes() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint16(variantVersion))
+	for _, key := range vd.keys {
+		v := vd.values[key]
+		buf.WriteByte(v.kind)
+		binary.Write(&buf, binary.LittleEndian, int32(len(key)))
+		buf.WriteString(key)
+		binary.Write(&buf, binary.LittleEndian, int32(len(v.value)))
+		buf.Write(v.value)
+	}
+	buf.WriteByte(0)
+	return buf.Bytes()
+}
+
+func (vd *variantDictionary) getBytes(key string) []byte {
+	if v, ok := vd.values[key]; ok && v.kind == variantBytes {
+		return v.value
+	}
+	return nil
+}
+
+func (vd *variantDictionary) getUint32(key string) (uint32, bool) {
+	v, ok := vd.values[key]
+	if !ok || v.kind != variantUint32 || len(v.value) != 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(v.value), true
+}
+
+func (vd *variantDictionary) getUint64(key string) (uint64, bool) {
+	v, ok := vd.values[key]
+	if !ok || v.kind != variantUint64 || len(v.value) != 8 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(v.value), true
+}
+
+// newKdfParameters picks fresh salts for the key derivation of settings.
+func newKdfParameters(settings Settings) (*variantDictionary, error) {
+	salt, err := randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	vd := &variantDictionary{values: make(map[string]variantValue)}
+	switch settings.KDF {
+	case KDFAES:
+		if settings.Rounds == 0 {
+			return nil, ErrUnsupportedSettings
+		}
+		vd.set("$UUID", variantBytes, kdfAES)
+		vd.setUint64("R", settings.Rounds)
+		vd.set("S", variantBytes, salt)
+	case KDFArgon2d, KDFArgon2id:
+		if settings.Iterations == 0 || settings.Parallelism == 0 || settings.Memory < 8*1024*uint64(settings.Parallelism) {
+			return nil, ErrUnsupportedSettings
+		}
+		if settings.KDF == KDFArgon2d {
+			vd.set("$UUID", variantBytes, kdfArgon2d)
+		} else {
+			vd.set("$UUID", variantBytes, kdfArgon2id)
+		}
+		vd.set("S", variantBytes, salt)
+		vd.setUint32("P", settings.Parallelism)
+		vd.setUint64("M", settings.Memory)
+		vd.setUint64("I", settings.Iterations)
+		vd.setUint32("V", argon2Version13)
+	default:
+		return nil, ErrUnsupportedSettings
+	}
+	return vd, nil
+}
+
+// transformKey derives the key the database is encrypted with from the
+// composite key.
+func transformKey(compositeKey []byte, kdf *variantDictionary) ([]byte, error) {
+	kdfSlots <- struct{}{}
+	defer func() { <-kdfSlots }()
+
+	uuid := kdf.getBytes("$UUID")
+	switch {
+	case bytes.Equal(uuid, kdfAES):
+		rounds, ok := kdf.getUint64("R")
+		seed := kdf.getBytes("S")
+		if !ok || len(seed) != 32 {
+			return nil, ErrCorrupt
+		}
+		if rounds > maxAESRounds {
+			return nil, ErrTooCostly
+		}
+		return aesKdf(compositeKey, seed, rounds), nil
+
+	case bytes.Equal(uuid, kdfArgon2d), bytes.Equal(uuid, kdfArgon2id):
+		p := argon2Params{mode: argon2d, salt: kdf.getBytes("S"), secret: kdf.getBytes("K"), data: kdf.getBytes("A"), keyLen: 32}
+		if bytes.Equal(uuid, kdfArgon2id) {
+			p.mode = argon2id
+		}
+		parallelism, okP := kdf.getUint32("P")
+		memory, okM := kdf.getUint64("M")
+		iterations, okI := kdf.getUint64("I")
+		version, okV := kdf.getUint32("V")
+		if !okP || !okM || !okI || !okV || len(p.salt) < 8 || parallelism == 0 || parallelism > 1<<24 || iterations == 0 ||
+			memory/1024 < 8*uint64(parallelism) || (version != argon2Version10 && version != argon2Version13) {
+			return nil, ErrCorrupt
+		}
+		if memory > maxArgon2Memory || iterations > maxArgon2Work/memory {
+			return nil, ErrTooCostly
+		}
+		p.parallelism, p.memory, p.iterations, p.version = parallelism, uint32(memory/1024), uint32(iterations), version
+		return argon2Key(compositeKey, p), nil
+	}
+	return nil, ErrUnsupported
+}
+
+// aesKdf encrypts the key with AES-256 in ECB mode for the given number of
+// rounds and hashes the result.
+func aesKdf(key []byte, seed []byte, rounds uint64) []byte {
+	block, _ := aes.NewCipher(seed)
+	var out [32]byte
+	copy(out[:], key)
+	for i := uint64(0); i < rounds; i++ {
+		block.Encrypt(out[:16], out[:16])
+		block.Encrypt(out[16:], out[16:])
+	}
+	sum := sha256.Sum256(out[:])
+	return sum[:]
+}
@@ -0,0 +1,230 @@
+// Package keepass reads and writes KeePass databases in the KDBX 4 format.
+package keepass
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotKeePass          = errors.New("The file is not a KeePass database")
+	ErrUnsupportedVersion  = errors.New("Only KDBX 4 databases are supported")
+	ErrUnsupported         = errors.New("The database uses an unsupported cipher or key derivation")
+	ErrTooCostly           = errors.New("The key derivation settings of the database are too costly")
+	ErrInvalidCredentials  = errors.New("The master password or key file is wrong")
+	ErrCorrupt             = errors.New("The database is damaged")
+	ErrMissingCredentials  = errors.New("A master password or key file is required")
+	ErrInvalidKeyFile      = errors.New("The key file is not valid")
+	ErrUnsupportedSettings = errors.New("Unsupported cipher or key derivation settings")
+)
+
+// Cipher encrypts the database content.
+type Cipher string
+
+const (
+	CipherAES      Cipher = "aes256"
+	CipherChaCha20 Cipher = "chacha20"
+)
+
+// KDF derives the encryption key from the master key.
+type KDF string
+
+const (
+	KDFArgon2d  KDF = "argon2d"
+	KDFArgon2id KDF = "argon2id"
+	KDFAES      KDF = "aes"
+)
+
+// Settings choose how Write protects a database. Memory is in bytes and
+// applies to Argon2 along with Iterations and Parallelism; Rounds applies
+// to AES-KDF.
+type Settings struct {
+	Cipher      Cipher
+	KDF         KDF
+	Iterations  uint64
+	Memory      uint64
+	Parallelism uint32
+	Rounds      uint64
+}
+
+// DefaultSettings take around a second to derive the key, like the
+// defaults KeePassXC benchmarks its settings to.
+var DefaultSettings = Settings{
+	Cipher:      CipherChaCha20,
+	KDF:         KDFArgon2id,
+	Iterations:  10,
+	Memory:      64 << 20,
+	Parallelism: 2,
+}
+
+// Credentials unlock a database: a master password, a key file or both.
+type Credentials struct {
+	Password string
+	KeyFile  []byte
+}
+
+// Database is the content of a KeePass file: a tree of groups holding
+// entries. The root group is the database itself.
+type Database struct {
+	Name string
+	Root Group
+}
+
+type Group struct {
+	Name    string
+	Notes   string
+	Groups  []Group
+	Entries []Entry
+}
+
+// Entry is a KeePass entry. The standard strings have their own fields;
+// any others are kept in Fields in the order of the file. Protected fields
+// are the ones KeePass encrypts in memory and hides by default.
+type Entry struct {
+	Title      string
+	UserName   string
+	Password   string
+	URL        string
+	Notes      string
+	Tags       []string
+	Fields     []Field
+	CreatedAt  time.Time
+	ModifiedAt time.Time
+}
+
+type Field struct {
+	Key       string
+	Value     string
+	Protected bool
+}
+
+// Read opens a KDBX 4 database whose content unpacks to at most maxSize
+// bytes. The recycle bin, entry history and attachments are left out.
+func Read(data []byte, creds Credentials, maxSize int64) (*Database, error) {
+	key, err := creds.compositeKey()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := readOuter(data, key, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	content, stream, err := readInnerHeader(payload)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := cryptProtected(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), stream, true)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	var file xmlFile
+	if err := xml.Unmarshal(plain, &file); err != nil {
+		return nil, ErrCorrupt
+	}
+	return file.database(), nil
+}
+
+// Write stores db as a KDBX 4 file protected by creds.
+func Write(db *Database, creds Credentials, settings Settings) ([]byte, error) {
+	key, err := creds.compositeKey()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := xml.MarshalIndent(newXMLFile(db), "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	streamKey, err := randomBytes(64)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := newInnerStream(innerStreamChaCha20, streamKey)
+	if err != nil {
+		return nil, err
+	}
+	content, err := cryptProtected(append([]byte(xml.Header), plain...), stream, false)
+	if err != nil {
+		return nil, err
+	}
+	return writeOuter(writeInnerHeader(streamKey, content), key, settings)
+}
+
+// compositeKey combines the hashes of the password and the key file as
+// KDBX 4 does.
+func (c Credentials) compositeKey() ([]byte, error) {
+	if c.Password == "" && len(c.KeyFile) == 0 {
+		return nil, ErrMissingCredentials
+	}
+	h := sha256.New()
+	if c.Password != "" {
+		pw := sha256.Sum256([]byte(c.Password))
+		h.Write(pw[:])
+	}
+	if len(c.KeyFile) > 0 {
+		key, err := keyFileKey(c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		h.Write(key)
+	}
+	return h.Sum(nil), nil
+}
+
+// keyFileKey reads the 32 byte key of a key file: an XML key file of
+// version 1 or 2, 32 raw bytes, 64 hex digits or else the hash of any
+// other file.
+func keyFileKey(data []byte) ([]byte, error) {
+	if bytes.Contains(data[:min(len(data), 512)], []byte("<KeyFile")) {
+		var kf struct {
+			Version string `xml:"Meta>Version"`
+			Data    struct {
+				Hash  string `xml:"Hash,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"Key>Data"`
+		}
+		if err := xml.Unmarshal(data, &kf); err != nil {
+			return nil, ErrInvalidKeyFile
+		}
+		value := strings.Join(strings.Fields(kf.Data.Value), "")
+		if strings.HasPrefix(kf.Version, "2.") {
+			key, err := hex.DecodeString(value)
+			if err != nil || len(key) != 32 {
+				return nil, ErrInvalidKeyFile
+			}
+			sum := sha256.Sum256(key)
+			if kf.Data.Hash != "" && !strings.EqualFold(hex.EncodeToString(sum[:4]), kf.Data.Hash) {
+				return nil, ErrInvalidKeyFile
+			}
+			return key, nil
+		}
+		key, err := base64Decode(value)
+		if err != nil {
+			return nil, ErrInvalidKeyFile
+		}
+		return key, nil
+	}
+
+	if len(data) == 32 {
+		return data, nil
+	}
+	if len(data) == 64 {
+		if key, err := hex.DecodeString(string(data)); err == nil {
+			return key, nil
+		}
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,246 @@
+package keepass_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/keepass"
+	"github.com/stretchr/testify/assert"
+)
+
+// cheap keeps the key derivation of tests fast.
+var cheap = map[string]keepass.Settings{
+	"AES with AES-KDF":           {Cipher: keepass.CipherAES, KDF: keepass.KDFAES, Rounds: 1000},
+	"ChaCha20 with Argon2d":      {Cipher: keepass.CipherChaCha20, KDF: keepass.KDFArgon2d, Iterations: 2, Memory: 64 << 10, Parallelism: 2},
+	"AES with Argon2id":          {Cipher: keepass.CipherAES, KDF: keepass.KDFArgon2id, Iterations: 1, Memory: 32 << 10, Parallelism: 1},
+	"ChaCha20 with AES-KDF":      {Cipher: keepass.CipherChaCha20, KDF: keepass.KDFAES, Rounds: 1},
+	"AES with many Argon2 lanes": {Cipher: keepass.CipherAES, KDF: keepass.KDFArgon2d, Iterations: 1, Memory: 256 << 10, Parallelism: 4},
+}
+
+// maxSize is how large test databases may unpack to.
+const maxSize = 1 << 20
+
+var created = time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+
+func sampleDatabase() *keepass.Database {
+	return &keepass.Database{
+		Name: "Team",
+		Root: keepass.Group{
+			Name: "Team",
+			Entries: []keepass.Entry{
+				{Title: "GitHub", UserName: "octocat", Password: "hunter2 <&>", URL: "https://github.com", Notes: "line one\nline two",
+					Tags: []string{"dev", "work"}, CreatedAt: created, ModifiedAt: created.Add(time.Hour),
+					Fields: []keepass.Field{{Key: "otp", Value: "otpauth://totp/GitHub?secret=JBSWY3DPEHPK3PXP", Protected: true}, {Key: "Team", Value: "platform"}}},
+			},
+			Groups: []keepass.Group{
+				{Name: "Servers", Notes: "Production only", Groups: []keepass.Group{
+					{Name: "Databases", Entries: []keepass.Entry{
+						{Title: "Postgres", UserName: "postgres", Password: "päss wörd", CreatedAt: created, ModifiedAt: created},
+						{Title: "Empty", CreatedAt: created, ModifiedAt: created},
+					}},
+				}},
+				{Name: "Keys", Entries: []keepass.Entry{
+					{Title: "Stripe", Password: "sk_live_1", CreatedAt: created, ModifiedAt: created,
+						Fields: []keepass.Field{{Key: "Secondary", Value: "sk_live_2", Protected: true}}},
+				}},
+			},
+		},
+	}
+}
+
+func TestWrite_ShouldRoundTripWithEverySetting(t *testing.T) {
+	for name, settings := range cheap {
+		creds := keepass.Credentials{Password: "correct horse"}
+		data, err := keepass.Write(sampleDatabase(), creds, settings)
+		assert.NoError(t, err, name)
+
+		db, err := keepass.Read(data, creds, maxSize)
+
+		assert.NoError(t, err, name)
+		assert.Equal(t, sampleDatabase(), db, name)
+	}
+}
+
+func TestWrite_ShouldNotStoreProtectedValuesInTheClear(t *testing.T) {
+	data, err := keepass.Write(sampleDatabase(), keepass.Credentials{Password: "pw"}, cheap["AES with AES-KDF"])
+	assert.NoError(t, err)
+
+	assert.Equal(t, []byte{0x03, 0xD9, 0xA2, 0x9A, 0x67, 0xFB, 0x4B, 0xB5, 0x00, 0x00, 0x04, 0x00}, data[:12])
+	assert.False(t, bytes.Contains(data, []byte("GitHub")))
+}
+
+func TestRead_ShouldUseKeyFiles(t *testing.T) {
+	raw := bytes.Repeat([]byte{7}, 32)
+	for name, keyFile := range map[string][]byte{
+		"XML version 1": []byte(`<?xml version="1.0" encoding="utf-8"?>
+<KeyFile><Meta><Version>1.00</Version></Meta><Key><Data>BwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwc=</Data></Key></KeyFile>`),
+		"XML version 2": []byte(`<?xml version="1.0" encoding="utf-8"?>
+<KeyFile><Meta><Version>2.0</Version></Meta><Key><Data Hash="` + hashPrefix(raw) + `">
+	07070707 07070707 07070707 07070707
+	07070707 07070707 07070707 07070707
+</Data></Key></KeyFile>`),
+		"raw":      raw,
+		"hex":      []byte(hex.EncodeToString(raw)),
+		"any file": []byte("-----BEGIN CERTIFICATE-----"),
+	} {
+		creds := keepass.Credentials{Password: "pw", KeyFile: keyFile}
+		data, err := keepass.Write(sampleDatabase(), creds, cheap["ChaCha20 with AES-KDF"])
+		assert.NoError(t, err, name)
+
+		_, err = keepass.Read(data, creds, maxSize)
+		assert.NoError(t, err, name)
+		_, err = keepass.Read(data, keepass.Credentials{Password: "pw"}, maxSize)
+		assert.ErrorIs(t, err, keepass.ErrInvalidCredentials, name)
+	}
+
+	// The same key in different file formats opens the same database.
+	data, err := keepass.Write(sampleDatabase(), keepass.Credentials{KeyFile: raw}, cheap["ChaCha20 with AES-KDF"])
+	assert.NoError(t, err)
+	_, err = keepass.Read(data, keepass.Credentials{KeyFile: []byte(hex.EncodeToString(raw))}, maxSize)
+	assert.NoError(t, err)
+
+	_, err = keepass.Read(data, keepass.Credentials{KeyFile: []byte(`<KeyFile><Meta><Version>2.0</Version></Meta><Key><Data Hash="00000000">` +
+		hex.EncodeToString(raw) + `</Data></Key></KeyFile>`)}, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrInvalidKeyFile)
+}
+
+// TestRead_ShouldReadFixtures reads databases with entry history, an
+// attachment and a recycle bin laid out as KeePassXC writes them. They were
+// written by a Python writer of the format rather than by KeePassXC itself;
+// the password of both is "correct horse" and the second also needs
+// keyfile.keyx.
+func TestRead_ShouldReadFixtures(t *testing.T) {
+	keyFile := mustReadFile(t, "testdata/keyfile.keyx")
+	testCases := map[string]keepass.Credentials{
+		"argon2d-chacha20.kdbx":   {Password: "correct horse"},
+		"aeskdf-aes-keyfile.kdbx": {Password: "correct horse", KeyFile: keyFile},
+	}
+
+	for name, creds := range testCases {
+		db, err := keepass.Read(mustReadFile(t, filepath.Join("testdata", name)), creds, maxSize)
+
+		assert.NoError(t, err, name)
+		assert.Equal(t, &keepass.Database{
+			Name: "Fixture",
+			Root: keepass.Group{
+				Name: "Fixture",
+				Entries: []keepass.Entry{
+					{Title: "GitHub", UserName: "octocat", Password: "hunter2 <&>", URL: "https://github.com", Notes: "line one\nline two & more",
+						Tags: []string{"dev", "work"}, CreatedAt: created, ModifiedAt: created.Add(time.Hour),
+						Fields: []keepass.Field{
+							{Key: "otp", Value: "otpauth://totp/GitHub?secret=JBSWY3DPEHPK3PXP&period=30", Protected: true},
+							{Key: "Team", Value: "platform"},
+						}},
+				},
+				Groups: []keepass.Group{
+					{Name: "Servers", Notes: "Production only", Groups: []keepass.Group{
+						{Name: "Databases", Entries: []keepass.Entry{
+							{Title: "Postgres", UserName: "postgres", Password: "päss wörd", CreatedAt: created, ModifiedAt: created},
+						}},
+					}},
+				},
+			},
+		}, db, name)
+	}
+
+	_, err := keepass.Read(mustReadFile(t, "testdata/aeskdf-aes-keyfile.kdbx"), keepass.Credentials{Password: "correct horse"}, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrInvalidCredentials)
+}
+
+func TestRead_ShouldRejectInvalidFiles(t *testing.T) {
+	creds := keepass.Credentials{Password: "pw"}
+	data, err := keepass.Write(sampleDatabase(), creds, cheap["AES with AES-KDF"])
+	assert.NoError(t, err)
+
+	_, err = keepass.Read(data, keepass.Credentials{Password: "wrong"}, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrInvalidCredentials)
+	_, err = keepass.Read(data, keepass.Credentials{}, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrMissingCredentials)
+
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-60] ^= 1
+	_, err = keepass.Read(tampered, creds, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrCorrupt)
+
+	tampered = append([]byte(nil), data...)
+	tampered[20] ^= 1
+	_, err = keepass.Read(tampered, creds, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrCorrupt)
+
+	_, err = keepass.Read(data[:len(data)-10], creds, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrCorrupt)
+
+	kdbx3 := append([]byte(nil), data...)
+	kdbx3[10] = 3
+	_, err = keepass.Read(kdbx3, creds, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrUnsupportedVersion)
+
+	_, err = keepass.Read([]byte(`{"encrypted": false}`), creds, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrNotKeePass)
+}
+
+func TestRead_ShouldRejectContentPastMaxSize(t *testing.T) {
+	creds := keepass.Credentials{Password: "pw"}
+	db := sampleDatabase()
+	db.Root.Entries[0].Notes = strings.Repeat("a", 64<<10)
+	data, err := keepass.Write(db, creds, cheap["ChaCha20 with AES-KDF"])
+	assert.NoError(t, err)
+	assert.Less(t, len(data), 32<<10)
+
+	_, err = keepass.Read(data, creds, 32<<10)
+	assert.ErrorIs(t, err, keepass.ErrCorrupt)
+	_, err = keepass.Read(data, creds, maxSize)
+	assert.NoError(t, err)
+}
+
+func TestRead_ShouldRefuseArgon2MemoryFarAboveDefaultSettings(t *testing.T) {
+	creds := keepass.Credentials{Password: "pw"}
+	data, err := keepass.Write(sampleDatabase(), creds, cheap["ChaCha20 with Argon2d"])
+	assert.NoError(t, err)
+
+	// Raise the memory of the variant dictionary and rehash the header.
+	end := 12
+	for ; end < 2048; end++ {
+		if sum := sha256.Sum256(data[:end]); bytes.Equal(sum[:], data[end:end+32]) {
+			break
+		}
+	}
+	memory := []byte{0x05, 0x01, 0, 0, 0, 'M', 0x08, 0, 0, 0}
+	at := bytes.Index(data[:end], memory) + len(memory)
+	costly := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint64(costly[at:], 256<<20)
+	sum := sha256.Sum256(costly[:end])
+	copy(costly[end:], sum[:])
+
+	_, err = keepass.Read(costly, creds, maxSize)
+	assert.ErrorIs(t, err, keepass.ErrTooCostly)
+}
+
+func TestWrite_ShouldRejectInvalidSettings(t *testing.T) {
+	for _, settings := range []keepass.Settings{
+		{Cipher: "twofish", KDF: keepass.KDFAES, Rounds: 1},
+		{Cipher: keepass.CipherAES, KDF: "scrypt"},
+		{Cipher: keepass.CipherAES, KDF: keepass.KDFArgon2d, Iterations: 1, Memory: 1024, Parallelism: 1},
+	} {
+		_, err := keepass.Write(sampleDatabase(), keepass.Credentials{Password: "pw"}, settings)
+		assert.ErrorIs(t, err, keepass.ErrUnsupportedSettings)
+	}
+}
+
+func mustReadFile(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	return data
+}
+
+func hashPrefix(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,311 @@
+package keepass
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+const generator = "Passwordly"
+
+// Standard entry strings.
+const (
+	keyTitle    = "Title"
+	keyUserName = "UserName"
+	keyPassword = "Password"
+	keyURL      = "URL"
+	keyNotes    = "Notes"
+)
+
+// epoch is where KDBX 4 counts the seconds of its timestamps from.
+var epoch = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type xmlFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    xmlMeta  `xml:"Meta"`
+	Root    struct {
+		Group xmlGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type xmlMeta struct {
+	Generator         string `xml:"Generator"`
+	DatabaseName      string `xml:"DatabaseName"`
+	MemoryProtection  *xmlMemoryProtection
+	RecycleBinEnabled string `xml:"RecycleBinEnabled,omitempty"`
+	RecycleBinUUID    string `xml:"RecycleBinUUID,omitempty"`
+}
+
+type xmlMemoryProtection struct {
+	ProtectTitle    string
+	ProtectUserName string
+	ProtectPassword string
+	ProtectURL      string
+	ProtectNotes    string
+}
+
+type xmlGroup struct {
+	UUID    string     `xml:"UUID"`
+	Name    string     `xml:"Name"`
+	Notes   string     `xml:"Notes,omitempty"`
+	Times   xmlTimes   `xml:"Times"`
+	Entries []xmlEntry `xml:"Entry"`
+	Groups  []xmlGroup `xml:"Group"`
+}
+
+type xmlEntry struct {
+	UUID    string      `xml:"UUID"`
+	Tags    string      `xml:"Tags,omitempty"`
+	Times   xmlTimes    `xml:"Times"`
+	Strings []xmlString `xml:"String"`
+}
+
+type xmlTimes struct {
+	CreationTime         string `xml:"CreationTime"`
+	LastModificationTime string `xml:"LastModificationTime"`
+	LastAccessTime       string `xml:"LastAccessTime"`
+	ExpiryTime           string `xml:"ExpiryTime"`
+	Expires              string `xml:"Expires"`
+	UsageCount           int    `xml:"UsageCount"`
+	LocationChanged      string `xml:"LocationChanged"`
+}
+
+type xmlString struct {
+	Key   string `xml:"Key"`
+	Value struct {
+		Text string `xml:",chardata"`
+		// ProtectInMemory marks values that are Protected in the file.
+		// Protected values are decrypted before unmarshalling and
+		// encrypted after marshalling, so the structs only see this.
+		ProtectInMemory string `xml:"ProtectInMemory,attr,omitempty"`
+	} `xml:"Value"`
+}
+
+func (f *xmlFile) database() *Database {
+	recycleBin := ""
+	if !strings.EqualFold(f.Meta.RecycleBinEnabled, "False") {
+		recycleBin = f.Meta.RecycleBinUUID
+	}
+	return &Database{Name: f.Meta.DatabaseName, Root: f.Root.Group.group(recycleBin)}
+}
+
+func (xg *xmlGroup) group(recycleBin string) Group {
+	g := Group{Name: xg.Name, Notes: xg.Notes}
+	for _, xe := range xg.Entries {
+		g.Entries = append(g.Entries, xe.entry())
+	}
+	for _, sub := range xg.Groups {
+		if recycleBin != "" && sub.UUID == recycleBin {
+			continue
+		}
+		g.Groups = append(g.Groups, sub.group(recycleBin))
+	}
+	return g
+}
+
+func (xe *xmlEntry) entry() Entry {
+	e := Entry{
+		Tags:       splitTags(xe.Tags),
+		CreatedAt:  parseTime(xe.Times.CreationTime),
+		ModifiedAt: parseTime(xe.Times.LastModificationTime),
+	}
+	for _, s := range xe.Strings {
+		value := s.Value.Text
+		switch s.Key {
+		case keyTitle:
+			e.Title = value
+		case keyUserName:
+			e.UserName = value
+		case keyPassword:
+			e.Password = value
+		case keyURL:
+			e.URL = value
+		case keyNotes:
+			e.Notes = value
+		default:
+			e.Fields = append(e.Fields, Field{Key: s.Key, Value: value, Protected: strings.EqualFold(s.Value.ProtectInMemory, "True")})
+		}
+	}
+	return e
+}
+
+func newXMLFile(db *Database) *xmlFile {
+	f := &xmlFile{Meta: xmlMeta{
+		Generator:    generator,
+		DatabaseName: db.Name,
+		MemoryProtection: &xmlMemoryProtection{
+			ProtectTitle: "False", ProtectUserName: "False", ProtectPassword: "True", ProtectURL: "False", ProtectNotes: "False",
+		},
+		RecycleBinEnabled: "False",
+	}}
+	f.Root.Group = newXMLGroup(db.Root)
+	return f
+}
+
+func newXMLGroup(g Group) xmlGroup {
+	now := time.Now()
+	xg := xmlGroup{UUID: newUUID(), Name: g.Name, Notes: g.Notes, Times: newXMLTimes(now, now)}
+	for _, e := range g.Entries {
+		xg.Entries = append(xg.Entries, newXMLEntry(e))
+	}
+	for _, sub := range g.Groups {
+		xg.Groups = append(xg.Groups, newXMLGroup(sub))
+	}
+	return xg
+}
+
+func newXMLEntry(e Entry) xmlEntry {
+	xe := xmlEntry{UUID: newUUID(), Tags: strings.Join(e.Tags, ";"), Times: newXMLTimes(e.CreatedAt, e.ModifiedAt)}
+	add := func(key, value string, protected bool) {
+		var s xmlString
+		s.Key, s.Value.Text = key, value
+		if protected {
+			s.Value.ProtectInMemory = "True"
+		}
+		xe.Strings = append(xe.Strings, s)
+	}
+	add(keyTitle, e.Title, false)
+	add(keyUserName, e.UserName, false)
+	add(keyPassword, e.Password, true)
+	add(keyURL, e.URL, false)
+	add(keyNotes, e.Notes, false)
+	for _, f := range e.Fields {
+		add(f.Key, f.Value, f.Protected)
+	}
+	return xe
+}
+
+func newXMLTimes(created, modified time.Time) xmlTimes {
+	if created.IsZero() {
+		created = time.Now()
+	}
+	if modified.IsZero() {
+		modified = created
+	}
+	return xmlTimes{
+		CreationTime:         formatTime(created),
+		LastModificationTime: formatTime(modified),
+		LastAccessTime:       formatTime(modified),
+		ExpiryTime:           formatTime(modified),
+		Expires:              "False",
+		LocationChanged:      formatTime(modified),
+	}
+}
+
+// formatTime encodes a time as KDBX 4 does: the seconds since epoch as a
+// little endian int64 in base64.
+func formatTime(t time.Time) string {
+	seconds := t.UTC().Unix() - epoch.Unix()
+	return base64.StdEncoding.EncodeToString(binary.LittleEndian.AppendUint64(nil, uint64(seconds)))
+}
+
+// parseTime also reads the ISO 8601 times of KDBX 3 and XML exports,
+// leaving anything else zero.
+func parseTime(s string) time.Time {
+	if b, err := base64Decode(s); err == nil && len(b) == 8 {
+		return time.Unix(int64(binary.LittleEndian.Uint64(b))+epoch.Unix(), 0).UTC()
+	}
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+func splitTags(tags string) []string {
+	var split []string
+	for _, tag := range strings.FieldsFunc(tags, func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			split = append(split, tag)
+		}
+	}
+	return split
+}
+
+func newUUID() string {
+	b, _ := randomBytes(16)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func base64Decode(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+}
+
+// cryptProtected rewrites the values of an XML document that are XORed
+// with the inner random stream. Reading, values marked Protected are
+// decrypted and marked ProtectInMemory instead; writing does the reverse.
+// The stream runs through the values in document order, history included.
+func cryptProtected(doc []byte, stream cipher.Stream, reading bool) ([]byte, error) {
+	from, to := "ProtectInMemory", "Protected"
+	if reading {
+		from, to = to, from
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	var out bytes.Buffer
+	e := xml.NewEncoder(&out)
+	var protected bool
+	var text []byte
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "Value" {
+				t = t.Copy()
+				for i, attr := range t.Attr {
+					if attr.Name.Local == from && strings.EqualFold(attr.Value, "True") {
+						t.Attr[i] = xml.Attr{Name: xml.Name{Local: to}, Value: "True"}
+						protected, text = true, nil
+					}
+				}
+				token = t
+			}
+		case xml.CharData:
+			if protected {
+				text = append(text, t...)
+				continue
+			}
+		case xml.EndElement:
+			if protected {
+				value, err := cryptValue(text, stream, reading)
+				if err != nil {
+					return nil, err
+				}
+				if err := e.EncodeToken(xml.CharData(value)); err != nil {
+					return nil, err
+				}
+				protected = false
+			}
+		}
+		if err := e.EncodeToken(xml.CopyToken(token)); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func cryptValue(text []byte, stream cipher.Stream, reading bool) ([]byte, error) {
+	if !reading {
+		value := make([]byte, len(text))
+		stream.XORKeyStream(value, text)
+		return []byte(base64.StdEncoding.EncodeToString(value)), nil
+	}
+	value, err := base64Decode(string(text))
+	if err != nil {
+		return nil, err
+	}
+	stream.XORKeyStream(value, value)
+	return value, nil
+}
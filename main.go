@@ -1,26 +1,23 @@
 package main
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"os"
 
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/blobs"
 	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/notify"
+	"github.com/adarsh-a-tw/passwordly/passwords"
 	"github.com/adarsh-a-tw/passwordly/users"
+	"github.com/adarsh-a-tw/passwordly/utils"
 	"github.com/adarsh-a-tw/passwordly/vaults"
 	"github.com/gin-gonic/gin"
 )
 
-func migrate() {
-	db := common.DB()
-	db.AutoMigrate(&users.User{})
-	db.AutoMigrate(&vaults.Vault{})
-	db.AutoMigrate(&vaults.Credential{})
-	db.AutoMigrate(&vaults.Key{})
-	db.AutoMigrate(&vaults.Document{})
-}
-
-func main() {
-	common.LoadConfig()
-
+func connectDB() {
 	if common.Cfg.DBDriver == "postgres" {
 		common.ConfigureDB(
 			common.PostgresDB,
@@ -37,7 +34,85 @@ func main() {
 			},
 		)
 	}
+}
+
+func migrate() {
+	db := common.DB()
+	ep, err := utils.NewEncryptionProvider()
+	if err != nil {
+		log.Fatalln("Could not set up encryption:", err)
+	}
+	if err := vaults.EncryptPlaintextSecrets(db, ep); err != nil {
+		log.Fatalln("Could not encrypt plain text secrets:", err)
+	}
+	for _, model := range vaults.Models {
+		if err := db.AutoMigrate(model); err != nil {
+			log.Fatalln("Could not migrate the database:", err)
+		}
+	}
+	if err := audit.RekeyLegacyChain(db, audit.NewChain(common.Cfg.AuditKey)); err != nil {
+		log.Println("Could not rekey the audit log:", err)
+	}
+}
+
+// bootstrapAdmin promotes the configured account so that the first
+// administrator does not have to be created with raw SQL.
+func bootstrapAdmin() {
+	if common.Cfg.AdminUsername == "" {
+		return
+	}
+	err := common.DB().Model(&users.User{}).
+		Where("username = ?", common.Cfg.AdminUsername).
+		Update("role", users.RoleAdmin).Error
+	if err != nil {
+		log.Println("Could not promote admin user:", err)
+	}
+}
+
+// startReminders runs the expiry and rotation reminder scheduler in the
+// background. Setting REMINDER_INTERVAL_MINUTES to 0 turns it off.
+func startReminders() {
+	if common.Cfg.ReminderIntervalMins == 0 {
+		return
+	}
+	notifier, err := notify.NewNotifier()
+	if err != nil {
+		log.Fatalln("Could not set up the reminder notifier:", err)
+	}
+	go vaults.NewReminderScheduler(common.DB(), notifier).Run(context.Background())
+}
+
+// startTrashPurge permanently deletes vaults and secrets that have been in
+// the trash for longer than TRASH_RETENTION_DAYS. Setting
+// TRASH_PURGE_INTERVAL_MINUTES to 0 turns it off.
+func startTrashPurge(blobStore blobs.BlobStore) {
+	if common.Cfg.TrashPurgeIntervalMins == 0 {
+		return
+	}
+	go vaults.NewPurgeScheduler(common.DB(), blobStore).Run(context.Background())
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			usage()
+		}
+		command(os.Args[2:])
+		return
+	}
+
+	common.LoadConfig()
+	connectDB()
 	migrate()
+	bootstrapAdmin()
+	blobStore, err := blobs.NewBlobStore()
+	if err != nil {
+		log.Fatalln("Could not set up the attachment blob store:", err)
+	}
+
+	startReminders()
+	startTrashPurge(blobStore)
 
 	if common.Cfg.IsProduction {
 		gin.SetMode(gin.ReleaseMode)
@@ -47,11 +122,21 @@ func main() {
 
 	users.RegisterValidations()
 	vaults.RegisterValidations()
+	passwords.RegisterValidations()
 
 	db := common.DB()
 
-	users.SetupRoutes(r, db)
-	vaults.SetupRoutes(r, db)
+	sv := &users.SessionValidatorImpl{Repo: &users.UserRepositoryImpl{Db: db}}
+
+	breaches, err := passwords.NewBreachChecker()
+	if err != nil {
+		log.Fatalln("Could not open the breached password dataset at HIBP_DATASET_PATH:", err)
+	}
+
+	users.SetupRoutes(r, db, breaches)
+	vaults.SetupRoutes(r, db, breaches, blobStore)
+	audit.SetupRoutes(r, db, sv, string(users.RoleAdmin))
+	passwords.SetupRoutes(r, sv)
 
 	r.GET("/health", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
 
@@ -9,13 +9,68 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func TokenAuthMiddleware(ctx *gin.Context) {
-	tokenStr := extractTokenFromHeader(ctx.Request.Header.Get("authorization"))
+// Session is the current state of the account a token was issued to.
+type Session struct {
+	Role                  string
+	Active                bool
+	PasswordResetRequired bool
+}
+
+// SessionValidator looks up the current state of the account a token was issued to.
+type SessionValidator interface {
+	ValidateSession(userId string, sessionVersion int) (Session, error)
+}
+
+// TokenAuthMiddleware refuses accounts that must reset their password with
+// 403 until they have, see PasswordResetAuthMiddleware.
+func TokenAuthMiddleware(sv SessionValidator) gin.HandlerFunc {
+	return tokenAuth(sv, false)
+}
+
+// PasswordResetAuthMiddleware authenticates like TokenAuthMiddleware but
+// also lets in accounts that must reset their password, for the routes they
+// need to do so.
+func PasswordResetAuthMiddleware(sv SessionValidator) gin.HandlerFunc {
+	return tokenAuth(sv, true)
+}
+
+func tokenAuth(sv SessionValidator, allowPasswordReset bool) gin.HandlerFunc {
 	ap := utils.AuthProviderImpl{}
-	if uid, err := ap.VerifyAccessToken(tokenStr); err != nil {
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, common.ErrorResponse{Message: "Invalid Token"})
-	} else {
-		ctx.Set("user_id", uid)
+	return func(ctx *gin.Context) {
+		tokenStr := extractTokenFromHeader(ctx.Request.Header.Get("authorization"))
+		claims, err := ap.VerifyAccessToken(tokenStr)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, common.ErrorResponse{Message: "Invalid Token"})
+			return
+		}
+
+		session, err := sv.ValidateSession(claims.UserId, claims.SessionVersion)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		if !session.Active {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, common.ErrorResponse{Message: "Invalid Token"})
+			return
+		}
+		if session.PasswordResetRequired && !allowPasswordReset {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, common.ErrorResponse{Message: "Password reset required"})
+			return
+		}
+
+		ctx.Set("user_id", claims.UserId)
+		ctx.Set("user_role", session.Role)
+		ctx.Next()
+	}
+}
+
+// RoleAuthMiddleware must run after TokenAuthMiddleware.
+func RoleAuthMiddleware(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetString("user_role") != role {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, common.ErrorResponse{Message: "Forbidden"})
+			return
+		}
 		ctx.Next()
 	}
 }
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailNotifier sends plain text mail through an SMTP relay. Notifications
+// without a recipient address are skipped.
+type EmailNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+
+	// SendMail defaults to smtp.SendMail and is replaced in tests.
+	SendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func (en *EmailNotifier) Notify(n Notification) error {
+	if n.Recipient == "" {
+		return nil
+	}
+
+	port := en.Port
+	if port == 0 {
+		port = 587
+	}
+
+	var auth smtp.Auth
+	if en.Username != "" {
+		auth = smtp.PlainAuth("", en.Username, en.Password, en.Host)
+	}
+
+	send := en.SendMail
+	if send == nil {
+		send = smtp.SendMail
+	}
+	return send(net.JoinHostPort(en.Host, strconv.Itoa(port)), auth, en.From, []string{n.Recipient}, en.message(n))
+}
+
+func (en *EmailNotifier) message(n Notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", en.From)
+	fmt.Fprintf(&b, "To: %s\r\n", n.Recipient)
+	fmt.Fprintf(&b, "Subject: %s\r\n", stripNewlines(n.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(n.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// stripNewlines keeps user controlled text from injecting headers.
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,18 @@
+package notify
+
+import "log"
+
+// LogNotifier writes notifications to the application log. It is the
+// default, so reminders are visible without any transport configured.
+type LogNotifier struct {
+	Logger *log.Logger
+}
+
+func (ln *LogNotifier) Notify(n Notification) error {
+	logger := ln.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("Notification for user %s: %s\n%s", n.UserId, n.Subject, n.Body)
+	return nil
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"fmt"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+)
+
+// Notification is addressed to one user. Data is the structured form of
+// Body, sent as is by transports that carry JSON.
+type Notification struct {
+	UserId    string `json:"user_id"`
+	Recipient string `json:"recipient"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	Data      any    `json:"data,omitempty"`
+}
+
+type Notifier interface {
+	Notify(n Notification) error
+}
+
+const (
+	TransportLog     = "log"
+	TransportWebhook = "webhook"
+	TransportEmail   = "email"
+)
+
+// NewNotifier builds the transport selected by NOTIFIER.
+func NewNotifier() (Notifier, error) {
+	switch common.Cfg.Notifier {
+	case TransportLog, "":
+		return &LogNotifier{}, nil
+	case TransportWebhook:
+		if common.Cfg.WebhookUrl == "" {
+			return nil, fmt.Errorf("NOTIFY_WEBHOOK_URL is required for the %s notifier", TransportWebhook)
+		}
+		return &WebhookNotifier{
+			Url:    common.Cfg.WebhookUrl,
+			Secret: common.Cfg.WebhookSecret,
+		}, nil
+	case TransportEmail:
+		if common.Cfg.SmtpHost == "" || common.Cfg.SmtpFrom == "" {
+			return nil, fmt.Errorf("SMTP_HOST and SMTP_FROM are required for the %s notifier", TransportEmail)
+		}
+		return &EmailNotifier{
+			Host:     common.Cfg.SmtpHost,
+			Port:     common.Cfg.SmtpPort,
+			Username: common.Cfg.SmtpUsername,
+			Password: common.Cfg.SmtpPassword,
+			From:     common.Cfg.SmtpFrom,
+		}, nil
+	}
+	return nil, fmt.Errorf("Unknown notifier %q", common.Cfg.Notifier)
+}
@@ -0,0 +1,114 @@
+package notify_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+var notification = notify.Notification{
+	UserId:    "mock_user_id",
+	Recipient: "jane@example.com",
+	Subject:   "2 secrets are expiring\r\nBcc: attacker@example.com",
+	Body:      "- Build bot\n- Payments API",
+	Data:      map[string]int{"count": 2},
+}
+
+func TestWebhookNotifier_Notify_ShouldPostSignedJSON(t *testing.T) {
+	var received notify.Notification
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(notify.SignatureHeader)
+		assert.Equal(t, "sha256="+notify.Sign("webhook-secret", body), signature)
+		assert.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	wn := notify.WebhookNotifier{Url: server.URL, Secret: "webhook-secret"}
+
+	assert.NoError(t, wn.Notify(notification))
+	assert.NotEmpty(t, signature)
+	assert.Equal(t, notification.Subject, received.Subject)
+}
+
+func TestWebhookNotifier_Notify_ShouldFailForErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	wn := notify.WebhookNotifier{Url: server.URL}
+
+	assert.Error(t, wn.Notify(notification))
+}
+
+func TestEmailNotifier_Notify_ShouldSendPlainTextMail(t *testing.T) {
+	var addr, from string
+	var to []string
+	var msg []byte
+	en := notify.EmailNotifier{
+		Host:     "smtp.example.com",
+		Username: "mailer",
+		Password: "secret",
+		From:     "passwordly@example.com",
+		SendMail: func(a string, _ smtp.Auth, f string, t []string, m []byte) error {
+			addr, from, to, msg = a, f, t, m
+			return nil
+		},
+	}
+
+	assert.NoError(t, en.Notify(notification))
+
+	assert.Equal(t, "smtp.example.com:587", addr)
+	assert.Equal(t, "passwordly@example.com", from)
+	assert.Equal(t, []string{"jane@example.com"}, to)
+	assert.Contains(t, string(msg), "Subject: 2 secrets are expiring  Bcc: attacker@example.com\r\n")
+	assert.Contains(t, string(msg), "\r\n\r\n- Build bot\r\n- Payments API\r\n")
+}
+
+func TestEmailNotifier_Notify_ShouldSkipUsersWithoutEmail(t *testing.T) {
+	en := notify.EmailNotifier{
+		SendMail: func(string, smtp.Auth, string, []string, []byte) error {
+			t.Fatal("mail should not be sent")
+			return nil
+		},
+	}
+
+	assert.NoError(t, en.Notify(notify.Notification{UserId: "mock_user_id"}))
+}
+
+func TestLogNotifier_Notify_ShouldWriteToLogger(t *testing.T) {
+	var buf bytes.Buffer
+	ln := notify.LogNotifier{Logger: log.New(&buf, "", 0)}
+
+	assert.NoError(t, ln.Notify(notification))
+	assert.Contains(t, buf.String(), "Notification for user mock_user_id")
+}
+
+func TestNewNotifier_ShouldValidateTransportConfig(t *testing.T) {
+	defer func(cfg common.Config) { common.Cfg = cfg }(common.Cfg)
+
+	common.Cfg = common.Config{Notifier: notify.TransportWebhook}
+	_, err := notify.NewNotifier()
+	assert.Error(t, err)
+
+	common.Cfg = common.Config{Notifier: notify.TransportEmail, SmtpHost: "smtp.example.com", SmtpFrom: "passwordly@example.com"}
+	n, err := notify.NewNotifier()
+	assert.NoError(t, err)
+	assert.IsType(t, &notify.EmailNotifier{}, n)
+
+	common.Cfg = common.Config{Notifier: "pager"}
+	_, err = notify.NewNotifier()
+	assert.Error(t, err)
+}
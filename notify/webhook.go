@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const SignatureHeader = "X-Passwordly-Signature"
+
+// WebhookNotifier POSTs each notification as JSON. When Secret is set the
+// body is signed with HMAC-SHA256 so receivers can verify the sender.
+type WebhookNotifier struct {
+	Url    string
+	Secret string
+	Client *http.Client
+}
+
+func (wn *WebhookNotifier) Notify(n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, wn.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if wn.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(wn.Secret, body))
+	}
+
+	client := wn.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,346 @@
+package passwords
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+)
+
+// BreachChecker looks passwords up in an offline copy of the Have I Been
+// Pwned "Pwned Passwords" SHA-1 dataset. Lookup returns how many times the
+// password was seen in breaches, 0 if never.
+type BreachChecker interface {
+	Lookup(password string) (count int, err error)
+}
+
+const (
+	breachIndexMagic      = "PWLYHIB1"
+	breachIndexRecordSize = sha1.Size + 4
+	// Longest dataset line: 40 hex digits, a colon, a count and CRLF.
+	maxBreachLineLength = 64
+)
+
+var ErrInvalidBreachDataset = errors.New("Invalid breached password dataset")
+
+// NewBreachChecker opens the dataset configured in HIBP_DATASET_PATH. It
+// accepts a directory of range files (ABCDE.txt holding "SUFFIX:COUNT" lines),
+// a single "HASH:COUNT" text file ordered by hash, or an index built from
+// either with BuildBreachIndex. Without a configured dataset every lookup
+// reports 0.
+func NewBreachChecker() (BreachChecker, error) {
+	return OpenBreachDataset(common.Cfg.HibpDatasetPath)
+}
+
+func OpenBreachDataset(path string) (BreachChecker, error) {
+	if path == "" {
+		return &noBreachChecker{}, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &rangeDirBreachChecker{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(breachIndexMagic))
+	if _, err := io.ReadFull(f, magic); err == nil && string(magic) == breachIndexMagic {
+		if (info.Size()-int64(len(breachIndexMagic)))%breachIndexRecordSize != 0 {
+			return nil, ErrInvalidBreachDataset
+		}
+		return &indexBreachChecker{path: path, records: (info.Size() - int64(len(breachIndexMagic))) / breachIndexRecordSize}, nil
+	}
+	return &sortedFileBreachChecker{path: path, size: info.Size()}, nil
+}
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+type noBreachChecker struct{}
+
+func (*noBreachChecker) Lookup(string) (int, error) {
+	return 0, nil
+}
+
+// rangeDirBreachChecker reads the layout produced by the official
+// PwnedPasswordsDownloader: one small file per 5 character hash prefix.
+type rangeDirBreachChecker struct {
+	dir string
+}
+
+func (c *rangeDirBreachChecker) Lookup(password string) (int, error) {
+	hash := sha1Hex(password)
+	f, err := os.Open(filepath.Join(c.dir, hash[:5]+".txt"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		suffix, count, err := parseBreachLine(scanner.Text())
+		if err != nil {
+			return 0, err
+		}
+		if suffix == hash[5:] {
+			return count, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// sortedFileBreachChecker binary searches a "HASH:COUNT" file ordered by hash
+// in place, so even the full dataset needs no memory beyond a line buffer.
+type sortedFileBreachChecker struct {
+	path string
+	size int64
+}
+
+func (c *sortedFileBreachChecker) Lookup(password string) (int, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	hash := sha1Hex(password)
+	lo, hi := int64(0), c.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := readLineFrom(f, mid, c.size)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		lineHash, count, err := parseBreachLine(string(line))
+		if err != nil {
+			return 0, err
+		}
+		switch strings.Compare(strings.ToUpper(lineHash), hash) {
+		case 0:
+			return count, nil
+		case -1:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+// readLineFrom returns the first complete line starting at or after offset.
+func readLineFrom(r io.ReaderAt, offset int64, size int64) (int64, []byte, error) {
+	start := offset
+	if offset > 0 {
+		// Step back one byte so a line starting exactly at offset is found.
+		buf := make([]byte, maxBreachLineLength)
+		n, err := r.ReadAt(buf, offset-1)
+		if err != nil && err != io.EOF {
+			return 0, nil, err
+		}
+		i := bytes.IndexByte(buf[:n], '\n')
+		if i < 0 {
+			return size, nil, nil
+		}
+		start = offset + int64(i)
+	}
+	if start >= size {
+		return size, nil, nil
+	}
+
+	buf := make([]byte, maxBreachLineLength)
+	n, err := r.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+	line := buf[:n]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	return start, bytes.TrimRight(line, "\r"), nil
+}
+
+// indexBreachChecker binary searches fixed size records of a 20 byte SHA-1
+// digest followed by a big endian uint32 count.
+type indexBreachChecker struct {
+	path    string
+	records int64
+}
+
+func (c *indexBreachChecker) Lookup(password string) (int, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	target := sha1.Sum([]byte(password))
+	record := make([]byte, breachIndexRecordSize)
+	var readErr error
+	i := sort.Search(int(c.records), func(i int) bool {
+		if readErr != nil {
+			return true
+		}
+		if _, readErr = f.ReadAt(record, int64(len(breachIndexMagic))+int64(i)*breachIndexRecordSize); readErr != nil {
+			return true
+		}
+		return bytes.Compare(record[:sha1.Size], target[:]) >= 0
+	})
+	if readErr != nil {
+		return 0, readErr
+	}
+	if int64(i) >= c.records {
+		return 0, nil
+	}
+
+	if _, err := f.ReadAt(record, int64(len(breachIndexMagic))+int64(i)*breachIndexRecordSize); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(record[:sha1.Size], target[:]) {
+		return 0, nil
+	}
+	return int(binary.BigEndian.Uint32(record[sha1.Size:])), nil
+}
+
+// BuildBreachIndex converts a range directory or a sorted "HASH:COUNT" file
+// into the compact index format and returns the number of records written.
+// The input must be ordered by hash, as the published dataset is.
+func BuildBreachIndex(inputPath string, outputPath string) (int, error) {
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	w := bufio.NewWriter(out)
+	if _, err := w.WriteString(breachIndexMagic); err != nil {
+		return 0, err
+	}
+
+	ib := indexBuilder{w: w}
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return 0, err
+	}
+	if info.IsDir() {
+		err = ib.addRangeDir(inputPath)
+	} else {
+		err = ib.addFile(inputPath, "")
+	}
+	if err != nil {
+		return ib.records, err
+	}
+
+	if err := w.Flush(); err != nil {
+		return ib.records, err
+	}
+	return ib.records, out.Close()
+}
+
+type indexBuilder struct {
+	w       *bufio.Writer
+	last    []byte
+	records int
+}
+
+func (ib *indexBuilder) addRangeDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	// ReadDir sorts by name, which for hex prefixes is hash order.
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix := strings.TrimSuffix(name, filepath.Ext(name))
+		if entry.IsDir() || len(prefix) != 5 {
+			continue
+		}
+		if err := ib.addFile(filepath.Join(dir, name), strings.ToUpper(prefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ib *indexBuilder) addFile(path string, prefix string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		hash, count, err := parseBreachLine(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if err := ib.add(prefix+hash, count); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (ib *indexBuilder) add(hash string, count int) error {
+	digest, err := hex.DecodeString(hash)
+	if err != nil || len(digest) != sha1.Size {
+		return fmt.Errorf("%w: bad hash %q", ErrInvalidBreachDataset, hash)
+	}
+	if ib.last != nil && bytes.Compare(digest, ib.last) <= 0 {
+		return fmt.Errorf("%w: hashes are not sorted at %s", ErrInvalidBreachDataset, hash)
+	}
+	ib.last = digest
+
+	record := make([]byte, breachIndexRecordSize)
+	copy(record, digest)
+	binary.BigEndian.PutUint32(record[sha1.Size:], uint32(count))
+	if _, err := ib.w.Write(record); err != nil {
+		return err
+	}
+	ib.records++
+	return nil
+}
+
+func parseBreachLine(line string) (string, int, error) {
+	hash, countStr, found := strings.Cut(strings.TrimSpace(line), ":")
+	if !found {
+		return "", 0, fmt.Errorf("%w: bad line %q", ErrInvalidBreachDataset, line)
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: bad count in line %q", ErrInvalidBreachDataset, line)
+	}
+	return strings.ToUpper(hash), count, nil
+}
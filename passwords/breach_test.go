@@ -0,0 +1,134 @@
+package passwords_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/passwords"
+	"github.com/stretchr/testify/assert"
+)
+
+var breachedPasswords = map[string]int{
+	"password":  9545824,
+	"123456":    37359195,
+	"qwerty":    10556095,
+	"letmein":   507680,
+	"dragon":    1183657,
+	"P@ssw0rd!": 1287,
+}
+
+func breachHash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func sortedBreachLines() []string {
+	lines := make([]string, 0, len(breachedPasswords))
+	for password, count := range breachedPasswords {
+		lines = append(lines, fmt.Sprintf("%s:%d", breachHash(password), count))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// prepareSortedDataset writes the single file "HASH:COUNT" layout.
+func prepareSortedDataset(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+	content := strings.Join(sortedBreachLines(), "\r\n") + "\r\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// prepareRangeDataset writes one "SUFFIX:COUNT" file per hash prefix.
+func prepareRangeDataset(t *testing.T) string {
+	dir := t.TempDir()
+	ranges := map[string][]string{}
+	for _, line := range sortedBreachLines() {
+		ranges[line[:5]] = append(ranges[line[:5]], line[5:])
+	}
+	for prefix, lines := range ranges {
+		if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func assertBreachLookups(t *testing.T, checker passwords.BreachChecker) {
+	for password, expected := range breachedPasswords {
+		count, err := checker.Lookup(password)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, count, password)
+	}
+	for _, password := range []string{"", "correct horse battery staple", "Unlisted#Secret9", "PASSWORD"} {
+		count, err := checker.Lookup(password)
+		assert.NoError(t, err)
+		assert.Zero(t, count, password)
+	}
+}
+
+func TestOpenBreachDataset_ShouldSearchSortedFile(t *testing.T) {
+	checker, err := passwords.OpenBreachDataset(prepareSortedDataset(t))
+
+	assert.NoError(t, err)
+	assertBreachLookups(t, checker)
+}
+
+func TestOpenBreachDataset_ShouldSearchRangeDirectory(t *testing.T) {
+	checker, err := passwords.OpenBreachDataset(prepareRangeDataset(t))
+
+	assert.NoError(t, err)
+	assertBreachLookups(t, checker)
+}
+
+func TestOpenBreachDataset_ShouldDisableChecksWithoutDataset(t *testing.T) {
+	checker, err := passwords.OpenBreachDataset("")
+
+	assert.NoError(t, err)
+	count, err := checker.Lookup("password")
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestOpenBreachDataset_ShouldFailForMissingDataset(t *testing.T) {
+	_, err := passwords.OpenBreachDataset(filepath.Join(t.TempDir(), "missing.txt"))
+
+	assert.Error(t, err)
+}
+
+func TestBuildBreachIndex_ShouldBuildSearchableIndex(t *testing.T) {
+	for name, input := range map[string]string{"sorted file": prepareSortedDataset(t), "range directory": prepareRangeDataset(t)} {
+		t.Run(name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "hibp.idx")
+
+			records, err := passwords.BuildBreachIndex(input, output)
+			assert.NoError(t, err)
+			assert.Equal(t, len(breachedPasswords), records)
+
+			checker, err := passwords.OpenBreachDataset(output)
+			assert.NoError(t, err)
+			assertBreachLookups(t, checker)
+		})
+	}
+}
+
+func TestBuildBreachIndex_ShouldRejectUnsortedDataset(t *testing.T) {
+	lines := sortedBreachLines()
+	lines[0], lines[1] = lines[1], lines[0]
+	input := filepath.Join(t.TempDir(), "unsorted.txt")
+	if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := passwords.BuildBreachIndex(input, filepath.Join(t.TempDir(), "hibp.idx"))
+
+	assert.ErrorIs(t, err, passwords.ErrInvalidBreachDataset)
+}
@@ -0,0 +1,47 @@
+package passwords
+
+type PolicyErrorResponse struct {
+	Message    string      `json:"message"`
+	Violations []Violation `json:"violations"`
+}
+
+func NewPolicyErrorResponse(violations []Violation) PolicyErrorResponse {
+	return PolicyErrorResponse{
+		Message:    "Password does not meet the password policy",
+		Violations: violations,
+	}
+}
+
+type GenerateRequest struct {
+	Mode             GeneratorMode  `json:"mode,omitempty" binding:"omitempty,generator_mode"`
+	Length           int            `json:"length,omitempty" binding:"omitempty,min=4,max=128"`
+	CharacterSets    []CharacterSet `json:"character_sets,omitempty" binding:"omitempty,dive,character_set"`
+	ExcludeAmbiguous bool           `json:"exclude_ambiguous,omitempty"`
+	Words            int            `json:"words,omitempty" binding:"omitempty,min=3,max=20"`
+	Separator        string         `json:"separator,omitempty" binding:"omitempty,max=3"`
+}
+
+func (gr *GenerateRequest) Options() GeneratorOptions {
+	return GeneratorOptions{
+		Mode:             gr.Mode,
+		Length:           gr.Length,
+		CharacterSets:    gr.CharacterSets,
+		ExcludeAmbiguous: gr.ExcludeAmbiguous,
+		Words:            gr.Words,
+		Separator:        gr.Separator,
+	}
+}
+
+type GenerateResponse struct {
+	Password    string  `json:"password"`
+	EntropyBits float64 `json:"entropy_bits"`
+	Score       int     `json:"score"`
+	CrackTime   string  `json:"crack_time"`
+}
+
+func (gr *GenerateResponse) load(g Generated) {
+	gr.Password = g.Password
+	gr.EntropyBits = g.EntropyBits
+	gr.Score = g.Estimate.Score
+	gr.CrackTime = g.Estimate.CrackTime
+}
@@ -0,0 +1,28 @@
+package passwords
+
+import (
+	"github.com/nbutton23/zxcvbn-go"
+)
+
+// Estimate is a zxcvbn strength estimate. Score runs from 0 (too guessable)
+// to 4 (very unguessable); Bits is the estimated entropy of the cheapest
+// guessing strategy found.
+type Estimate struct {
+	Bits      float64
+	Score     int
+	CrackTime string
+}
+
+// EstimateStrength rates password with zxcvbn. userInputs, such as the
+// username or email, are treated as dictionary words an attacker would try.
+func EstimateStrength(password string, userInputs ...string) Estimate {
+	if password == "" {
+		return Estimate{CrackTime: "instant"}
+	}
+	result := zxcvbn.PasswordStrength(password, userInputs)
+	return Estimate{
+		Bits:      result.Entropy,
+		Score:     result.Score,
+		CrackTime: result.CrackTimeDisplay,
+	}
+}
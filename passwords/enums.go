@@ -0,0 +1,31 @@
+package passwords
+
+type GeneratorMode string
+
+const (
+	ModeRandom        GeneratorMode = "RANDOM"
+	ModePassphrase    GeneratorMode = "PASSPHRASE"
+	ModePronounceable GeneratorMode = "PRONOUNCEABLE"
+)
+
+func (m GeneratorMode) IsValid() bool {
+	switch m {
+	case ModeRandom, ModePassphrase, ModePronounceable:
+		return true
+	}
+	return false
+}
+
+type CharacterSet string
+
+const (
+	SetLowercase CharacterSet = "LOWERCASE"
+	SetUppercase CharacterSet = "UPPERCASE"
+	SetDigits    CharacterSet = "DIGITS"
+	SetSymbols   CharacterSet = "SYMBOLS"
+)
+
+func (cs CharacterSet) IsValid() bool {
+	_, ok := characterSets[cs]
+	return ok
+}
@@ -0,0 +1,294 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	DefaultLength    = 20
+	DefaultWordCount = 6
+	DefaultSeparator = "-"
+
+	// ambiguousCharacters are easily confused when read or typed by hand.
+	ambiguousCharacters = "Il1|O0o`'\""
+)
+
+var characterSets = map[CharacterSet]string{
+	SetLowercase: "abcdefghijklmnopqrstuvwxyz",
+	SetUppercase: "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	SetDigits:    "0123456789",
+	SetSymbols:   "!@#$%^&*()-_=+[]{};:,.<>/?~",
+}
+
+// Pronounceable passwords alternate between these so they read as syllables.
+const (
+	consonants = "bcdfghjklmnprstvwxz"
+	vowels     = "aeiouy"
+)
+
+// effLargeWordlist is the EFF large diceware list, published by the
+// Electronic Frontier Foundation under CC BY 3.0 US.
+//
+//go:embed wordlists/eff_large_wordlist.txt
+var effLargeWordlist string
+
+var wordlist = parseWordlist(effLargeWordlist)
+
+var ErrInvalidGeneratorOptions = errors.New("Invalid generator options")
+
+type GeneratorOptions struct {
+	Mode             GeneratorMode
+	Length           int
+	CharacterSets    []CharacterSet
+	ExcludeAmbiguous bool
+	Words            int
+	Separator        string
+}
+
+// Generated carries the password along with the entropy of the process that
+// produced it and how a guesser would rate the result.
+type Generated struct {
+	Password    string
+	EntropyBits float64
+	Estimate    Estimate
+}
+
+type Generator interface {
+	Generate(opts GeneratorOptions) (Generated, error)
+}
+
+// GeneratorImpl draws from crypto/rand unless Rand is set.
+type GeneratorImpl struct {
+	Rand io.Reader
+}
+
+func (g *GeneratorImpl) Generate(opts GeneratorOptions) (Generated, error) {
+	var password string
+	var bits float64
+	var err error
+
+	switch opts.Mode {
+	case ModeRandom, "":
+		password, bits, err = g.random(opts)
+	case ModePassphrase:
+		password, bits, err = g.passphrase(opts)
+	case ModePronounceable:
+		password, bits, err = g.pronounceable(opts)
+	default:
+		err = ErrInvalidGeneratorOptions
+	}
+	if err != nil {
+		return Generated{}, err
+	}
+
+	return Generated{
+		Password:    password,
+		EntropyBits: math.Round(bits*100) / 100,
+		Estimate:    EstimateStrength(password),
+	}, nil
+}
+
+// random includes at least one character of every requested set, then
+// shuffles so the guaranteed characters are not always up front.
+func (g *GeneratorImpl) random(opts GeneratorOptions) (string, float64, error) {
+	length := opts.Length
+	if length == 0 {
+		length = DefaultLength
+	}
+	sets := opts.CharacterSets
+	if len(sets) == 0 {
+		sets = []CharacterSet{SetLowercase, SetUppercase, SetDigits, SetSymbols}
+	}
+
+	var alphabets []string
+	var all strings.Builder
+	seen := map[CharacterSet]bool{}
+	for _, set := range sets {
+		chars, ok := characterSets[set]
+		if !ok {
+			return "", 0, ErrInvalidGeneratorOptions
+		}
+		if seen[set] {
+			continue
+		}
+		seen[set] = true
+		if opts.ExcludeAmbiguous {
+			chars = removeAmbiguous(chars)
+		}
+		alphabets = append(alphabets, chars)
+		all.WriteString(chars)
+	}
+	if length < len(alphabets) {
+		return "", 0, ErrInvalidGeneratorOptions
+	}
+
+	password := make([]byte, 0, length)
+	for _, alphabet := range alphabets {
+		c, err := g.pick(alphabet)
+		if err != nil {
+			return "", 0, err
+		}
+		password = append(password, c)
+	}
+	for len(password) < length {
+		c, err := g.pick(all.String())
+		if err != nil {
+			return "", 0, err
+		}
+		password = append(password, c)
+	}
+	if err := g.shuffle(password); err != nil {
+		return "", 0, err
+	}
+
+	return string(password), float64(length) * math.Log2(float64(all.Len())), nil
+}
+
+func (g *GeneratorImpl) passphrase(opts GeneratorOptions) (string, float64, error) {
+	count := opts.Words
+	if count == 0 {
+		count = DefaultWordCount
+	}
+	separator := opts.Separator
+	if separator == "" {
+		separator = DefaultSeparator
+	}
+
+	words := make([]string, count)
+	for i := range words {
+		n, err := g.intn(len(wordlist))
+		if err != nil {
+			return "", 0, err
+		}
+		words[i] = wordlist[n]
+	}
+
+	return strings.Join(words, separator), float64(count) * math.Log2(float64(len(wordlist))), nil
+}
+
+// pronounceable alternates consonants and vowels. Uppercase and digits, when
+// requested, replace the casing of a letter and extend the tail respectively,
+// so the result still satisfies typical composition rules.
+func (g *GeneratorImpl) pronounceable(opts GeneratorOptions) (string, float64, error) {
+	length := opts.Length
+	if length == 0 {
+		length = DefaultLength
+	}
+
+	withUpper, withDigits := false, false
+	for _, set := range opts.CharacterSets {
+		switch set {
+		case SetUppercase:
+			withUpper = true
+		case SetDigits:
+			withDigits = true
+		}
+	}
+
+	letters := length
+	digits := ""
+	if withDigits {
+		digits = characterSets[SetDigits]
+		if opts.ExcludeAmbiguous {
+			digits = removeAmbiguous(digits)
+		}
+		letters = length - 2
+	}
+	if letters < 2 {
+		return "", 0, ErrInvalidGeneratorOptions
+	}
+
+	var bits float64
+	password := make([]byte, 0, length)
+	for i := 0; i < letters; i++ {
+		alphabet := consonants
+		if i%2 == 1 {
+			alphabet = vowels
+		}
+		c, err := g.pick(alphabet)
+		if err != nil {
+			return "", 0, err
+		}
+		password = append(password, c)
+		bits += math.Log2(float64(len(alphabet)))
+	}
+	if withUpper {
+		i, err := g.intn(letters)
+		if err != nil {
+			return "", 0, err
+		}
+		password[i] = strings.ToUpper(string(password[i]))[0]
+		bits += math.Log2(float64(letters))
+	}
+	for len(password) < length {
+		c, err := g.pick(digits)
+		if err != nil {
+			return "", 0, err
+		}
+		password = append(password, c)
+		bits += math.Log2(float64(len(digits)))
+	}
+
+	return string(password), bits, nil
+}
+
+func (g *GeneratorImpl) intn(n int) (int, error) {
+	reader := g.Rand
+	if reader == nil {
+		reader = rand.Reader
+	}
+	i, err := rand.Int(reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+func (g *GeneratorImpl) pick(alphabet string) (byte, error) {
+	i, err := g.intn(len(alphabet))
+	if err != nil {
+		return 0, err
+	}
+	return alphabet[i], nil
+}
+
+// shuffle is a Fisher-Yates shuffle driven by the generator's random source.
+func (g *GeneratorImpl) shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := g.intn(i + 1)
+		if err != nil {
+			return err
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
+
+func removeAmbiguous(chars string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(ambiguousCharacters, r) {
+			return -1
+		}
+		return r
+	}, chars)
+}
+
+// parseWordlist reads diceware lines of the form "11111\tabacus".
+func parseWordlist(content string) []string {
+	var words []string
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			words = append(words, fields[len(fields)-1])
+		}
+	}
+	return words
+}
@@ -0,0 +1,101 @@
+package passwords_test
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/passwords"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratorImpl_Generate_ShouldUseEveryRequestedCharacterSet(t *testing.T) {
+	g := passwords.GeneratorImpl{}
+	pc := passwords.PolicyChecker{Policy: characterPolicy}
+
+	for i := 0; i < 50; i++ {
+		generated, err := g.Generate(passwords.GeneratorOptions{Length: 8})
+
+		assert.NoError(t, err)
+		assert.Len(t, generated.Password, 8)
+		assert.Empty(t, pc.Check(generated.Password, passwords.Subject{}))
+	}
+}
+
+func TestGeneratorImpl_Generate_ShouldRespectCharacterSetsAndAmbiguity(t *testing.T) {
+	g := passwords.GeneratorImpl{}
+
+	generated, err := g.Generate(passwords.GeneratorOptions{
+		Length:           100,
+		CharacterSets:    []passwords.CharacterSet{passwords.SetUppercase, passwords.SetDigits},
+		ExcludeAmbiguous: true,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, generated.Password, 100)
+	assert.Empty(t, strings.Trim(generated.Password, "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"))
+	// 100 characters from a 32 character alphabet.
+	assert.Equal(t, 500.0, generated.EntropyBits)
+}
+
+func TestGeneratorImpl_Generate_ShouldBuildPassphraseFromWordlist(t *testing.T) {
+	g := passwords.GeneratorImpl{}
+
+	generated, err := g.Generate(passwords.GeneratorOptions{Mode: passwords.ModePassphrase, Words: 5, Separator: "."})
+
+	assert.NoError(t, err)
+	words := strings.Split(generated.Password, ".")
+	assert.Len(t, words, 5)
+	for _, word := range words {
+		assert.Regexp(t, "^[a-z-]+$", word)
+	}
+	assert.Equal(t, math.Round(5*math.Log2(7776)*100)/100, generated.EntropyBits)
+	assert.Equal(t, 4, generated.Estimate.Score)
+}
+
+func TestGeneratorImpl_Generate_ShouldBuildPronounceablePassword(t *testing.T) {
+	g := passwords.GeneratorImpl{}
+
+	generated, err := g.Generate(passwords.GeneratorOptions{
+		Mode:          passwords.ModePronounceable,
+		Length:        12,
+		CharacterSets: []passwords.CharacterSet{passwords.SetUppercase, passwords.SetDigits},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, generated.Password, 12)
+	assert.Regexp(t, "^([bcdfghjklmnprstvwxzBCDFGHJKLMNPRSTVWXZ][aeiouyAEIOUY]){5}[0-9]{2}$", generated.Password)
+	assert.Equal(t, 1, len(regexp.MustCompile("[A-Z]").FindAllString(generated.Password, -1)))
+}
+
+func TestGeneratorImpl_Generate_ShouldRejectInvalidOptions(t *testing.T) {
+	g := passwords.GeneratorImpl{}
+
+	testCases := []passwords.GeneratorOptions{
+		{Mode: "UNKNOWN"},
+		{Length: 3, CharacterSets: []passwords.CharacterSet{passwords.SetLowercase, passwords.SetUppercase, passwords.SetDigits, passwords.SetSymbols}},
+		{CharacterSets: []passwords.CharacterSet{"EMOJI"}},
+		{Mode: passwords.ModePronounceable, Length: 3, CharacterSets: []passwords.CharacterSet{passwords.SetDigits}},
+	}
+
+	for _, opts := range testCases {
+		_, err := g.Generate(opts)
+		assert.ErrorIs(t, err, passwords.ErrInvalidGeneratorOptions)
+	}
+}
+
+func TestGeneratorImpl_Generate_ShouldFailWhenRandomSourceFails(t *testing.T) {
+	g := passwords.GeneratorImpl{Rand: failingReader{}}
+
+	_, err := g.Generate(passwords.GeneratorOptions{})
+
+	assert.Error(t, err)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("MOCK_ERROR")
+}
@@ -0,0 +1,38 @@
+package passwords
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/gin-gonic/gin"
+)
+
+type GeneratorHandler struct {
+	Generator Generator
+}
+
+func (gh *GeneratorHandler) Generate(ctx *gin.Context) {
+	var gr GenerateRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&gr); err != nil {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid request body"})
+			return
+		}
+	}
+
+	generated, err := gh.Generator.Generate(gr.Options())
+	if err != nil {
+		if errors.Is(err, ErrInvalidGeneratorOptions) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	response := GenerateResponse{}
+	response.load(generated)
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,92 @@
+package passwords_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/passwords"
+	passwords_mocks "github.com/adarsh-a-tw/passwordly/passwords/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	passwords.RegisterValidations()
+}
+
+func TestGeneratorHandler_Generate_ShouldGenerateWithDefaultsForEmptyBody(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/passwords/generate", "POST", nil)
+
+	gh := passwords.GeneratorHandler{Generator: &passwords.GeneratorImpl{}}
+
+	gh.Generate(ctx)
+
+	var actualResponse passwords.GenerateResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, actualResponse.Password, passwords.DefaultLength)
+	assert.Greater(t, actualResponse.EntropyBits, 100.0)
+	assert.Equal(t, 4, actualResponse.Score)
+}
+
+func TestGeneratorHandler_Generate_ShouldPassOptionsToGenerator(t *testing.T) {
+	gr := passwords.GenerateRequest{Mode: passwords.ModePassphrase, Words: 4, Separator: "_"}
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/passwords/generate", "POST", gr)
+
+	generator := passwords_mocks.NewGenerator(t)
+	generator.On("Generate", gr.Options()).Return(passwords.Generated{
+		Password:    "clamp_unsaid_oxidize_tamper",
+		EntropyBits: 51.7,
+		Estimate:    passwords.Estimate{Score: 4, CrackTime: "centuries"},
+	}, nil)
+
+	gh := passwords.GeneratorHandler{Generator: generator}
+
+	gh.Generate(ctx)
+
+	var actualResponse passwords.GenerateResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, passwords.GenerateResponse{
+		Password:    "clamp_unsaid_oxidize_tamper",
+		EntropyBits: 51.7,
+		Score:       4,
+		CrackTime:   "centuries",
+	}, actualResponse)
+}
+
+func TestGeneratorHandler_Generate_ShouldRejectInvalidRequest(t *testing.T) {
+	testCases := []passwords.GenerateRequest{
+		{Mode: "UNKNOWN"},
+		{Length: 2},
+		{Length: 1000},
+		{CharacterSets: []passwords.CharacterSet{"EMOJI"}},
+		{Words: 100},
+	}
+
+	for _, gr := range testCases {
+		ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/passwords/generate", "POST", gr)
+
+		gh := passwords.GeneratorHandler{}
+
+		gh.Generate(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, gr)
+	}
+}
+
+func TestGeneratorHandler_Generate_ShouldFailWhenGeneratorFails(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/passwords/generate", "POST", nil)
+
+	generator := passwords_mocks.NewGenerator(t)
+	generator.On("Generate", passwords.GeneratorOptions{}).Return(passwords.Generated{}, errors.New("MOCK_ERROR"))
+
+	gh := passwords.GeneratorHandler{Generator: generator}
+
+	gh.Generate(ctx)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,195 @@
+package passwords
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/utils"
+)
+
+type ViolationCode string
+
+const (
+	ViolationTooShort         ViolationCode = "TOO_SHORT"
+	ViolationTooLong          ViolationCode = "TOO_LONG"
+	ViolationMissingLowercase ViolationCode = "MISSING_LOWERCASE"
+	ViolationMissingUppercase ViolationCode = "MISSING_UPPERCASE"
+	ViolationMissingDigit     ViolationCode = "MISSING_DIGIT"
+	ViolationMissingSymbol    ViolationCode = "MISSING_SYMBOL"
+	ViolationTooWeak          ViolationCode = "TOO_WEAK"
+	ViolationContainsUserInfo ViolationCode = "CONTAINS_USER_INFO"
+	ViolationRecentlyUsed     ViolationCode = "RECENTLY_USED"
+	ViolationBreached         ViolationCode = "BREACHED"
+)
+
+// minUserInfoLength keeps very short usernames from rejecting most passwords.
+const minUserInfoLength = 3
+
+type Violation struct {
+	Code    ViolationCode `json:"code"`
+	Message string        `json:"message"`
+}
+
+// Policy describes what an account password must look like. Zero values
+// disable the corresponding rule.
+type Policy struct {
+	MinLength        int
+	MaxLength        int
+	RequireLowercase bool
+	RequireUppercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	MinScore         int
+	ForbidUserInfo   bool
+	HistorySize      int
+}
+
+// Subject is the account a password is being checked for.
+type Subject struct {
+	Username       string
+	Email          string
+	PreviousHashes []string
+}
+
+type Checker interface {
+	Check(password string, s Subject) []Violation
+	HistorySize() int
+}
+
+type PolicyChecker struct {
+	Policy   Policy
+	Hasher   utils.PasswordHasher
+	Breaches BreachChecker
+}
+
+func NewPolicyChecker(hasher utils.PasswordHasher, breaches BreachChecker) Checker {
+	return &PolicyChecker{
+		Policy: Policy{
+			MinLength:        common.Cfg.PasswordMinLength,
+			MaxLength:        common.Cfg.PasswordMaxLength,
+			RequireLowercase: common.Cfg.PasswordRequireLowercase,
+			RequireUppercase: common.Cfg.PasswordRequireUppercase,
+			RequireDigit:     common.Cfg.PasswordRequireDigit,
+			RequireSymbol:    common.Cfg.PasswordRequireSymbol,
+			MinScore:         common.Cfg.PasswordMinScore,
+			ForbidUserInfo:   common.Cfg.PasswordForbidUserInfo,
+			HistorySize:      common.Cfg.PasswordHistorySize,
+		},
+		Hasher:   hasher,
+		Breaches: breaches,
+	}
+}
+
+// Check returns every rule password breaks, or nil if it satisfies the policy.
+func (pc *PolicyChecker) Check(password string, s Subject) []Violation {
+	p := pc.Policy
+	var violations []Violation
+	add := func(code ViolationCode, format string, args ...any) {
+		violations = append(violations, Violation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		add(ViolationTooShort, "Password must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		// Nothing else is worth estimating or looking up for an input this long.
+		add(ViolationTooLong, "Password must be at most %d characters long", p.MaxLength)
+		return violations
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireLowercase && !hasLower {
+		add(ViolationMissingLowercase, "Password must contain a lowercase letter")
+	}
+	if p.RequireUppercase && !hasUpper {
+		add(ViolationMissingUppercase, "Password must contain an uppercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		add(ViolationMissingDigit, "Password must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		add(ViolationMissingSymbol, "Password must contain a symbol")
+	}
+
+	if p.ForbidUserInfo && containsUserInfo(password, s) {
+		add(ViolationContainsUserInfo, "Password must not contain your username or email")
+	}
+
+	if p.MinScore > 0 {
+		if estimate := EstimateStrength(password, s.Username, s.Email); estimate.Score < p.MinScore {
+			add(ViolationTooWeak, "Password is too easy to guess (strength %d of 4, %d required)", estimate.Score, p.MinScore)
+		}
+	}
+
+	if pc.wasRecentlyUsed(password, s.PreviousHashes) {
+		add(ViolationRecentlyUsed, "Password must differ from your last %d passwords", p.HistorySize)
+	}
+
+	if count := pc.timesBreached(password); count > 0 {
+		add(ViolationBreached, "Password has appeared %d times in known data breaches", count)
+	}
+
+	return violations
+}
+
+func (pc *PolicyChecker) HistorySize() int {
+	return pc.Policy.HistorySize
+}
+
+func (pc *PolicyChecker) wasRecentlyUsed(password string, previousHashes []string) bool {
+	if pc.Policy.HistorySize <= 0 || pc.Hasher == nil {
+		return false
+	}
+	if len(previousHashes) > pc.Policy.HistorySize {
+		previousHashes = previousHashes[:pc.Policy.HistorySize]
+	}
+	for _, hash := range previousHashes {
+		if pc.Hasher.ComparePassword(password, hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// timesBreached fails open: an unreadable dataset should not block every
+// registration, so lookup errors are only logged.
+func (pc *PolicyChecker) timesBreached(password string) int {
+	if pc.Breaches == nil {
+		return 0
+	}
+	count, err := pc.Breaches.Lookup(password)
+	if err != nil {
+		log.Println("Breached password lookup failed:", err)
+		return 0
+	}
+	return count
+}
+
+func containsUserInfo(password string, s Subject) bool {
+	lowered := strings.ToLower(password)
+	localPart, _, _ := strings.Cut(s.Email, "@")
+	for _, info := range []string{s.Username, localPart} {
+		info = strings.ToLower(info)
+		if len(info) >= minUserInfoLength && strings.Contains(lowered, info) {
+			return true
+		}
+	}
+	return false
+}
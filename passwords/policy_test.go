@@ -0,0 +1,107 @@
+package passwords_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/passwords"
+	passwords_mocks "github.com/adarsh-a-tw/passwordly/passwords/mocks"
+	utils_mocks "github.com/adarsh-a-tw/passwordly/utils/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+var characterPolicy = passwords.Policy{
+	MinLength:        8,
+	MaxLength:        128,
+	RequireLowercase: true,
+	RequireUppercase: true,
+	RequireDigit:     true,
+	RequireSymbol:    true,
+}
+
+func violationCodes(violations []passwords.Violation) []passwords.ViolationCode {
+	codes := make([]passwords.ViolationCode, 0)
+	for _, v := range violations {
+		codes = append(codes, v.Code)
+	}
+	return codes
+}
+
+func TestPolicyChecker_Check_CharacterRules(t *testing.T) {
+	testCases := []struct {
+		input         string
+		expectedCodes []passwords.ViolationCode
+	}{
+		{"P@ssword123", []passwords.ViolationCode{}},
+		{"P@", []passwords.ViolationCode{passwords.ViolationTooShort, passwords.ViolationMissingLowercase, passwords.ViolationMissingDigit}},
+		{"12345678910", []passwords.ViolationCode{passwords.ViolationMissingLowercase, passwords.ViolationMissingUppercase, passwords.ViolationMissingSymbol}},
+		{"abcdefghijkl", []passwords.ViolationCode{passwords.ViolationMissingUppercase, passwords.ViolationMissingDigit, passwords.ViolationMissingSymbol}},
+		{"ABCDEfghij", []passwords.ViolationCode{passwords.ViolationMissingDigit, passwords.ViolationMissingSymbol}},
+		{"ABCDEfghij1234", []passwords.ViolationCode{passwords.ViolationMissingSymbol}},
+		{"Ünïcødé-Pässwörd1", []passwords.ViolationCode{}},
+	}
+
+	pc := passwords.PolicyChecker{Policy: characterPolicy}
+
+	for _, testCase := range testCases {
+		violations := pc.Check(testCase.input, passwords.Subject{})
+		assert.Equal(t, testCase.expectedCodes, violationCodes(violations), testCase.input)
+	}
+}
+
+func TestPolicyChecker_Check_ShouldRejectTooLongPassword(t *testing.T) {
+	breaches := passwords_mocks.NewBreachChecker(t)
+	pc := passwords.PolicyChecker{Policy: passwords.Policy{MaxLength: 10, MinScore: 4, RequireSymbol: true}, Breaches: breaches}
+
+	violations := pc.Check("Password12345", passwords.Subject{})
+
+	assert.Equal(t, []passwords.ViolationCode{passwords.ViolationTooLong}, violationCodes(violations))
+}
+
+func TestPolicyChecker_Check_ShouldRejectUserInfo(t *testing.T) {
+	pc := passwords.PolicyChecker{Policy: passwords.Policy{ForbidUserInfo: true}}
+	subject := passwords.Subject{Username: "jane_doe", Email: "jane.smith@mail.com"}
+
+	assert.Equal(t, []passwords.ViolationCode{passwords.ViolationContainsUserInfo}, violationCodes(pc.Check("My-JANE_DOE-1", subject)))
+	assert.Equal(t, []passwords.ViolationCode{passwords.ViolationContainsUserInfo}, violationCodes(pc.Check("jane.smith!99", subject)))
+	assert.Empty(t, pc.Check("Unrelated#Secret9", subject))
+}
+
+func TestPolicyChecker_Check_ShouldRejectGuessablePassword(t *testing.T) {
+	pc := passwords.PolicyChecker{Policy: passwords.Policy{MinScore: 3}}
+
+	assert.Equal(t, []passwords.ViolationCode{passwords.ViolationTooWeak}, violationCodes(pc.Check("P@ssword123", passwords.Subject{})))
+	assert.Empty(t, pc.Check("correct horse battery staple", passwords.Subject{}))
+}
+
+func TestPolicyChecker_Check_ShouldRejectRecentlyUsedPassword(t *testing.T) {
+	hasher := utils_mocks.NewPasswordHasher(t)
+	hasher.On("ComparePassword", "P@ssword123", "Hash1").Return(false)
+	hasher.On("ComparePassword", "P@ssword123", "Hash2").Return(true)
+
+	pc := passwords.PolicyChecker{Policy: passwords.Policy{HistorySize: 2}, Hasher: hasher}
+
+	violations := pc.Check("P@ssword123", passwords.Subject{PreviousHashes: []string{"Hash1", "Hash2", "Hash3"}})
+
+	assert.Equal(t, []passwords.ViolationCode{passwords.ViolationRecentlyUsed}, violationCodes(violations))
+}
+
+func TestEstimateStrength_ShouldScoreCommonPasswordsLow(t *testing.T) {
+	assert.Equal(t, 0, passwords.EstimateStrength("password").Score)
+	assert.Equal(t, 0, passwords.EstimateStrength("").Score)
+	assert.Equal(t, 4, passwords.EstimateStrength("correct horse battery staple").Score)
+	assert.Less(t, passwords.EstimateStrength("jane_doe2023", "jane_doe").Bits, passwords.EstimateStrength("jane_doe2023").Bits)
+}
+
+func TestPolicyChecker_Check_ShouldRejectBreachedPassword(t *testing.T) {
+	breaches := passwords_mocks.NewBreachChecker(t)
+	breaches.On("Lookup", "P@ssword123").Return(42, nil)
+	breaches.On("Lookup", "Unlisted#Secret9").Return(0, nil)
+	breaches.On("Lookup", "Unreadable#Secret9").Return(0, errors.New("MOCK_ERROR"))
+
+	pc := passwords.PolicyChecker{Breaches: breaches}
+
+	assert.Equal(t, []passwords.ViolationCode{passwords.ViolationBreached}, violationCodes(pc.Check("P@ssword123", passwords.Subject{})))
+	assert.Empty(t, pc.Check("Unlisted#Secret9", passwords.Subject{}))
+	assert.Empty(t, pc.Check("Unreadable#Secret9", passwords.Subject{}))
+}
@@ -0,0 +1,18 @@
+package passwords
+
+import (
+	"github.com/adarsh-a-tw/passwordly/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func SetupRoutes(r *gin.Engine, sv middleware.SessionValidator) {
+	// Authenticated Routes
+	rg := r.Group("/api/v1/passwords")
+	rg.Use(middleware.TokenAuthMiddleware(sv))
+
+	gh := GeneratorHandler{
+		Generator: &GeneratorImpl{},
+	}
+
+	rg.POST("/generate", gh.Generate)
+}
@@ -0,0 +1,47 @@
+package passwords
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	validator "github.com/go-playground/validator/v10"
+)
+
+func validateGeneratorMode(fl validator.FieldLevel) bool {
+	mode, ok := fl.Field().Interface().(GeneratorMode)
+	if !ok {
+		return false
+	}
+
+	return mode.IsValid()
+}
+
+func validateCharacterSet(fl validator.FieldLevel) bool {
+	set, ok := fl.Field().Interface().(CharacterSet)
+	if !ok {
+		return false
+	}
+
+	return set.IsValid()
+}
+
+func RegisterValidations() {
+
+	validators := []struct {
+		name      string
+		validator validator.Func
+	}{
+		{
+			name:      "generator_mode",
+			validator: validateGeneratorMode,
+		},
+		{
+			name:      "character_set",
+			validator: validateCharacterSet,
+		},
+	}
+
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		for _, val := range validators {
+			v.RegisterValidation(val.name, val.validator)
+		}
+	}
+}
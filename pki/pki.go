@@ -0,0 +1,345 @@
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultCATTL    = 10 * 365 * 24 * time.Hour
+	DefaultLeafTTL  = 30 * 24 * time.Hour
+	DefaultMaxTTL   = 90 * 24 * time.Hour
+	DefaultCRLValid = 24 * time.Hour
+
+	// backdate allows for clock skew between the CA and relying parties.
+	backdate = 5 * time.Minute
+)
+
+var (
+	ErrInvalidCertificate = errors.New("Invalid CA certificate")
+	ErrInvalidPrivateKey  = errors.New("Invalid CA private key")
+	ErrKeyMismatch        = errors.New("Private key does not match the CA certificate")
+	ErrInvalidCSR         = errors.New("Invalid certificate signing request")
+	ErrNotPermitted       = errors.New("Request is not permitted by the CA constraints")
+)
+
+// oidReasonCode identifies the CRL entry extension holding the revocation
+// reason (RFC 5280, section 5.3.1).
+var oidReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+type CA struct {
+	Certificate *x509.Certificate
+	Key         crypto.Signer
+}
+
+type Subject struct {
+	CommonName   string
+	Organization string
+}
+
+// Constraints limit what a CA issues. An empty AllowedDomains list permits
+// any DNS name.
+type Constraints struct {
+	AllowedDomains  []string
+	AllowSubdomains bool
+	AllowIPs        bool
+	MaxTTL          time.Duration
+}
+
+// LeafRequest describes a certificate to issue for PublicKey. A zero TTL
+// means DefaultLeafTTL, capped by the constraints.
+type LeafRequest struct {
+	PublicKey   crypto.PublicKey
+	CommonName  string
+	DNSNames    []string
+	IPAddresses []net.IP
+	TTL         time.Duration
+}
+
+type Revocation struct {
+	Serial    *big.Int
+	RevokedAt time.Time
+	Reason    int
+}
+
+// NewCA creates a self-signed root certificate for key.
+func NewCA(key crypto.Signer, subject Subject, ttl time.Duration, now time.Time) (*CA, error) {
+	if ttl == 0 {
+		ttl = DefaultCATTL
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	name := pkix.Name{CommonName: subject.CommonName}
+	if subject.Organization != "" {
+		name.Organization = []string{subject.Organization}
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               name,
+		NotBefore:             now.Add(-backdate),
+		NotAfter:              now.Add(ttl),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Certificate: cert, Key: key}, nil
+}
+
+// LoadCA parses a PEM certificate and private key, checking that the
+// certificate may sign certificates and belongs to the key.
+func LoadCA(certPEM, keyPEM []byte) (*CA, error) {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return nil, ErrInvalidCertificate
+	}
+	if !cert.IsCA || cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return nil, ErrInvalidCertificate
+	}
+	key, err := ParsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(cert.PublicKey) {
+		return nil, ErrKeyMismatch
+	}
+	return &CA{Certificate: cert, Key: key}, nil
+}
+
+func ParseCertificate(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, ErrInvalidCertificate
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// ParsePrivateKey accepts PKCS#8, PKCS#1 and SEC 1 PEM encodings.
+func ParsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, ErrInvalidPrivateKey
+	}
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrInvalidPrivateKey
+	}
+	return signer, nil
+}
+
+// EncodePrivateKey returns key as a PKCS#8 PEM block.
+func EncodePrivateKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func EncodeCertificate(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// ParseCSR verifies a PEM certificate signing request and turns it into a
+// LeafRequest. Only the key and names are taken from the CSR; any requested
+// extensions are ignored.
+func ParseCSR(csrPEM []byte) (LeafRequest, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return LeafRequest{}, ErrInvalidCSR
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return LeafRequest{}, ErrInvalidCSR
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return LeafRequest{}, ErrInvalidCSR
+	}
+	return LeafRequest{
+		PublicKey:   csr.PublicKey,
+		CommonName:  csr.Subject.CommonName,
+		DNSNames:    csr.DNSNames,
+		IPAddresses: csr.IPAddresses,
+	}, nil
+}
+
+// Issue signs a leaf certificate usable for both TLS servers and clients.
+// The common name is always included as a SAN, and the validity never
+// extends past the CA's own.
+func (ca *CA) Issue(req LeafRequest, c Constraints, now time.Time) (*x509.Certificate, error) {
+	if req.PublicKey == nil {
+		return nil, ErrInvalidCSR
+	}
+	dnsNames, ips := req.DNSNames, req.IPAddresses
+	if req.CommonName != "" {
+		if ip := net.ParseIP(req.CommonName); ip != nil {
+			if !containsIP(ips, ip) {
+				ips = append(ips, ip)
+			}
+		} else if !containsName(dnsNames, req.CommonName) {
+			dnsNames = append([]string{req.CommonName}, dnsNames...)
+		}
+	}
+	if len(dnsNames) == 0 && len(ips) == 0 {
+		return nil, fmt.Errorf("%w: at least one name is required", ErrNotPermitted)
+	}
+	for _, name := range dnsNames {
+		if !c.allowsName(name) {
+			return nil, fmt.Errorf("%w: name %q", ErrNotPermitted, name)
+		}
+	}
+	if len(ips) > 0 && !c.AllowIPs {
+		return nil, fmt.Errorf("%w: IP addresses", ErrNotPermitted)
+	}
+
+	maxTTL := c.MaxTTL
+	if maxTTL == 0 {
+		maxTTL = DefaultMaxTTL
+	}
+	ttl := req.TTL
+	if ttl == 0 {
+		ttl = DefaultLeafTTL
+		if ttl > maxTTL {
+			ttl = maxTTL
+		}
+	}
+	if ttl < 0 || ttl > maxTTL {
+		return nil, fmt.Errorf("%w: ttl exceeds %s", ErrNotPermitted, maxTTL)
+	}
+	notAfter := now.Add(ttl)
+	if notAfter.After(ca.Certificate.NotAfter) {
+		notAfter = ca.Certificate.NotAfter
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := req.PublicKey.(*rsa.PublicKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: req.CommonName},
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+		NotBefore:             now.Add(-backdate),
+		NotAfter:              notAfter,
+		KeyUsage:              keyUsage,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, req.PublicKey, ca.Key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+// CRL returns a PEM encoded revocation list. The CRL number is taken from
+// the issue time so that it only ever increases.
+func (ca *CA) CRL(revoked []Revocation, now time.Time) ([]byte, error) {
+	entries := make([]pkix.RevokedCertificate, len(revoked))
+	for i, r := range revoked {
+		entries[i] = pkix.RevokedCertificate{SerialNumber: r.Serial, RevocationTime: r.RevokedAt.UTC()}
+		if r.Reason > 0 {
+			reason, err := asn1.Marshal(asn1.Enumerated(r.Reason))
+			if err != nil {
+				return nil, err
+			}
+			entries[i].Extensions = []pkix.Extension{{Id: oidReasonCode, Value: reason}}
+		}
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificates: entries,
+		Number:              big.NewInt(now.UnixNano()),
+		ThisUpdate:          now,
+		NextUpdate:          now.Add(DefaultCRLValid),
+	}, ca.Certificate, ca.Key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), nil
+}
+
+func (c Constraints) allowsName(name string) bool {
+	if len(c.AllowedDomains) == 0 {
+		return true
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, domain := range c.AllowedDomains {
+		domain = strings.ToLower(domain)
+		if name == domain {
+			return true
+		}
+		if c.AllowSubdomains && strings.HasSuffix(name, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, i := range ips {
+		if bytes.Equal(i.To16(), ip.To16()) {
+			return true
+		}
+	}
+	return false
+}
+
+// randomSerial returns a positive serial number of up to 128 bits.
+func randomSerial() (*big.Int, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	return n.Add(n, big.NewInt(1)), nil
+}
@@ -0,0 +1,128 @@
+package pki_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/pki"
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newCA(t *testing.T) *pki.CA {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca, err := pki.NewCA(key, pki.Subject{CommonName: "Passwordly Test CA", Organization: "ACME"}, 0, now)
+	assert.NoError(t, err)
+	return ca
+}
+
+func csr(t *testing.T, cn string, dnsNames ...string) []byte {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: cn},
+		DNSNames: dnsNames,
+	}, key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func TestNewCA_ShouldRoundTripThroughPEM(t *testing.T) {
+	ca := newCA(t)
+	keyPEM, err := pki.EncodePrivateKey(ca.Key)
+	assert.NoError(t, err)
+
+	loaded, err := pki.LoadCA(pki.EncodeCertificate(ca.Certificate), keyPEM)
+	assert.NoError(t, err)
+	assert.True(t, loaded.Certificate.IsCA)
+	assert.Equal(t, "Passwordly Test CA", loaded.Certificate.Subject.CommonName)
+	assert.Equal(t, now.Add(pki.DefaultCATTL), loaded.Certificate.NotAfter)
+
+	other := newCA(t)
+	otherKey, _ := pki.EncodePrivateKey(other.Key)
+	_, err = pki.LoadCA(pki.EncodeCertificate(ca.Certificate), otherKey)
+	assert.ErrorIs(t, err, pki.ErrKeyMismatch)
+}
+
+func TestLoadCA_ShouldRejectLeafCertificates(t *testing.T) {
+	ca := newCA(t)
+	req, _ := pki.ParseCSR(csr(t, "api.internal"))
+	leaf, err := ca.Issue(req, pki.Constraints{}, now)
+	assert.NoError(t, err)
+	keyPEM, _ := pki.EncodePrivateKey(ca.Key)
+
+	_, err = pki.LoadCA(pki.EncodeCertificate(leaf), keyPEM)
+	assert.ErrorIs(t, err, pki.ErrInvalidCertificate)
+}
+
+func TestIssue_ShouldIssueLeafThatChainsToCA(t *testing.T) {
+	ca := newCA(t)
+	req, err := pki.ParseCSR(csr(t, "api.internal.acme.com", "www.internal.acme.com"))
+	assert.NoError(t, err)
+	req.TTL = 7 * 24 * time.Hour
+
+	leaf, err := ca.Issue(req, pki.Constraints{AllowedDomains: []string{"acme.com"}, AllowSubdomains: true}, now)
+	assert.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate)
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: "www.internal.acme.com", Roots: roots, CurrentTime: now})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api.internal.acme.com", "www.internal.acme.com"}, leaf.DNSNames)
+	assert.Equal(t, now.Add(7*24*time.Hour), leaf.NotAfter)
+	assert.False(t, leaf.IsCA)
+}
+
+func TestIssue_ShouldEnforceConstraints(t *testing.T) {
+	ca := newCA(t)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	constraints := pki.Constraints{AllowedDomains: []string{"acme.com"}, MaxTTL: 24 * time.Hour}
+
+	testCases := map[string]pki.LeafRequest{
+		"no names":  {PublicKey: key.Public()},
+		"subdomain": {PublicKey: key.Public(), CommonName: "api.acme.com"},
+		"domain":    {PublicKey: key.Public(), CommonName: "acme.org"},
+		"ip":        {PublicKey: key.Public(), CommonName: "acme.com", IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}},
+		"ttl":       {PublicKey: key.Public(), CommonName: "acme.com", TTL: 48 * time.Hour},
+	}
+
+	for name, req := range testCases {
+		_, err := ca.Issue(req, constraints, now)
+		assert.ErrorIs(t, err, pki.ErrNotPermitted, name)
+	}
+
+	leaf, err := ca.Issue(pki.LeafRequest{PublicKey: key.Public(), CommonName: "acme.com"}, constraints, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(24*time.Hour), leaf.NotAfter)
+}
+
+func TestParseCSR_ShouldRejectTamperedRequests(t *testing.T) {
+	block, _ := pem.Decode(csr(t, "api.internal"))
+	block.Bytes[len(block.Bytes)-1] ^= 0xff
+
+	_, err := pki.ParseCSR(pem.EncodeToMemory(block))
+	assert.ErrorIs(t, err, pki.ErrInvalidCSR)
+}
+
+func TestCRL_ShouldListRevokedSerials(t *testing.T) {
+	ca := newCA(t)
+
+	crlPEM, err := ca.CRL([]pki.Revocation{{Serial: big.NewInt(42), RevokedAt: now, Reason: 1}}, now)
+	assert.NoError(t, err)
+
+	block, _ := pem.Decode(crlPEM)
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	assert.NoError(t, err)
+	assert.NoError(t, crl.CheckSignatureFrom(ca.Certificate))
+	assert.Len(t, crl.RevokedCertificates, 1)
+	assert.Equal(t, big.NewInt(42), crl.RevokedCertificates[0].SerialNumber)
+	assert.Equal(t, now.Add(pki.DefaultCRLValid), crl.NextUpdate)
+}
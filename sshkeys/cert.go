@@ -0,0 +1,240 @@
+package sshkeys
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type CertType string
+
+const (
+	CertTypeUser CertType = "USER"
+	CertTypeHost CertType = "HOST"
+)
+
+func (ct CertType) IsValid() bool {
+	switch ct {
+	case CertTypeUser, CertTypeHost:
+		return true
+	}
+	return false
+}
+
+const (
+	DefaultCertTTL    = time.Hour
+	DefaultMaxCertTTL = 24 * time.Hour
+
+	// certBackdate allows for clock skew between the CA and the servers
+	// that verify its certificates.
+	certBackdate = 5 * time.Minute
+
+	// AnyPrincipal in AllowedPrincipals lets a role sign for any name.
+	AnyPrincipal = "*"
+)
+
+var (
+	ErrInvalidPublicKey = errors.New("Invalid public key")
+	ErrInvalidPolicy    = errors.New("Invalid signing policy")
+	ErrPolicyViolation  = errors.New("Request is not permitted by the signing policy")
+)
+
+// Policy limits what a CA will sign. Extensions only apply to user
+// certificates; OpenSSH ignores them on host certificates.
+type Policy struct {
+	CertType               CertType
+	AllowedPrincipals      []string
+	DefaultPrincipals      []string
+	MaxTTL                 time.Duration
+	DefaultTTL             time.Duration
+	AllowedExtensions      []string
+	DefaultExtensions      []string
+	AllowedCriticalOptions []string
+}
+
+// CertificateRequest describes the certificate a caller asks for. Zero
+// values fall back to the policy defaults; a nil Extensions slice means the
+// default extensions while an empty one requests none.
+type CertificateRequest struct {
+	PublicKey       string
+	KeyId           string
+	Principals      []string
+	TTL             time.Duration
+	Extensions      []string
+	CriticalOptions map[string]string
+}
+
+// Certificate is a signed certificate together with the details worth
+// recording about it.
+type Certificate struct {
+	Certificate string
+	Serial      uint64
+	KeyId       string
+	Principals  []string
+	ValidAfter  time.Time
+	ValidBefore time.Time
+}
+
+// Validate checks that a policy is internally consistent. Entries are later
+// stored as comma separated lists, so they may not contain commas or spaces.
+func (p Policy) Validate() error {
+	if !p.CertType.IsValid() {
+		return fmt.Errorf("%w: unknown certificate type", ErrInvalidPolicy)
+	}
+	if len(p.AllowedPrincipals) == 0 {
+		return fmt.Errorf("%w: at least one allowed principal is required", ErrInvalidPolicy)
+	}
+	if p.MaxTTL <= 0 || p.DefaultTTL <= 0 || p.DefaultTTL > p.MaxTTL {
+		return fmt.Errorf("%w: default ttl must be positive and not exceed max ttl", ErrInvalidPolicy)
+	}
+	if p.CertType == CertTypeHost && (len(p.AllowedExtensions) > 0 || len(p.DefaultExtensions) > 0) {
+		return fmt.Errorf("%w: host certificates do not carry extensions", ErrInvalidPolicy)
+	}
+	lists := [][]string{p.AllowedPrincipals, p.DefaultPrincipals, p.AllowedExtensions, p.DefaultExtensions, p.AllowedCriticalOptions}
+	for _, list := range lists {
+		for _, entry := range list {
+			if entry == "" || strings.ContainsAny(entry, ", \t\n") {
+				return fmt.Errorf("%w: invalid entry %q", ErrInvalidPolicy, entry)
+			}
+		}
+	}
+	for _, principal := range p.DefaultPrincipals {
+		if !p.allowsPrincipal(principal) {
+			return fmt.Errorf("%w: default principal %q is not allowed", ErrInvalidPolicy, principal)
+		}
+	}
+	for _, ext := range p.DefaultExtensions {
+		if !contains(p.AllowedExtensions, ext) {
+			return fmt.Errorf("%w: default extension %q is not allowed", ErrInvalidPolicy, ext)
+		}
+	}
+	return nil
+}
+
+// Sign issues a certificate for req with the ca key, refusing anything the
+// policy does not permit. Certificates are backdated slightly so they are
+// usable immediately on servers whose clocks run behind.
+func (p Policy) Sign(ca crypto.Signer, req CertificateRequest, now time.Time) (Certificate, error) {
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+	if err != nil {
+		return Certificate{}, ErrInvalidPublicKey
+	}
+	if _, ok := pub.(*ssh.Certificate); ok {
+		return Certificate{}, ErrInvalidPublicKey
+	}
+
+	principals := req.Principals
+	if len(principals) == 0 {
+		principals = p.DefaultPrincipals
+	}
+	// A certificate without principals is valid for every user or host,
+	// which is never what a role is meant to hand out.
+	if len(principals) == 0 {
+		return Certificate{}, fmt.Errorf("%w: at least one principal is required", ErrPolicyViolation)
+	}
+	for _, principal := range principals {
+		if principal == "" || principal == AnyPrincipal || !p.allowsPrincipal(principal) {
+			return Certificate{}, fmt.Errorf("%w: principal %q", ErrPolicyViolation, principal)
+		}
+	}
+
+	ttl := req.TTL
+	if ttl == 0 {
+		ttl = p.DefaultTTL
+	}
+	if ttl < 0 || ttl > p.MaxTTL {
+		return Certificate{}, fmt.Errorf("%w: ttl exceeds %s", ErrPolicyViolation, p.MaxTTL)
+	}
+
+	extensions := map[string]string{}
+	if p.CertType == CertTypeUser {
+		requested := req.Extensions
+		if requested == nil {
+			requested = p.DefaultExtensions
+		}
+		for _, ext := range requested {
+			if !contains(p.AllowedExtensions, ext) {
+				return Certificate{}, fmt.Errorf("%w: extension %q", ErrPolicyViolation, ext)
+			}
+			extensions[ext] = ""
+		}
+	} else if len(req.Extensions) > 0 {
+		return Certificate{}, fmt.Errorf("%w: host certificates do not carry extensions", ErrPolicyViolation)
+	}
+
+	options := map[string]string{}
+	for name, value := range req.CriticalOptions {
+		if !contains(p.AllowedCriticalOptions, name) {
+			return Certificate{}, fmt.Errorf("%w: critical option %q", ErrPolicyViolation, name)
+		}
+		options[name] = value
+	}
+
+	signer, err := ssh.NewSignerFromSigner(ca)
+	if err != nil {
+		return Certificate{}, ErrUnsupportedKey
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return Certificate{}, err
+	}
+
+	certType := uint32(ssh.UserCert)
+	if p.CertType == CertTypeHost {
+		certType = ssh.HostCert
+	}
+	validAfter := now.Add(-certBackdate).Truncate(time.Second)
+	validBefore := now.Add(ttl).Truncate(time.Second)
+	cert := &ssh.Certificate{
+		Key:             pub,
+		Serial:          serial,
+		CertType:        certType,
+		KeyId:           req.KeyId,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+		Permissions: ssh.Permissions{
+			CriticalOptions: options,
+			Extensions:      extensions,
+		},
+	}
+	if err := cert.SignCert(rand.Reader, signer); err != nil {
+		return Certificate{}, err
+	}
+
+	return Certificate{
+		Certificate: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))),
+		Serial:      serial,
+		KeyId:       req.KeyId,
+		Principals:  principals,
+		ValidAfter:  validAfter,
+		ValidBefore: validBefore,
+	}, nil
+}
+
+func (p Policy) allowsPrincipal(principal string) bool {
+	return contains(p.AllowedPrincipals, AnyPrincipal) || contains(p.AllowedPrincipals, principal)
+}
+
+func randomSerial() (uint64, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
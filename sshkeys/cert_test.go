@@ -0,0 +1,128 @@
+package sshkeys_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func userPolicy() sshkeys.Policy {
+	return sshkeys.Policy{
+		CertType:               sshkeys.CertTypeUser,
+		AllowedPrincipals:      []string{"deploy", "ops"},
+		DefaultPrincipals:      []string{"deploy"},
+		MaxTTL:                 8 * time.Hour,
+		DefaultTTL:             time.Hour,
+		AllowedExtensions:      []string{"permit-pty", "permit-port-forwarding"},
+		DefaultExtensions:      []string{"permit-pty"},
+		AllowedCriticalOptions: []string{"source-address"},
+	}
+}
+
+func publicKey(t *testing.T) string {
+	info, err := sshkeys.Describe(generate(t, sshkeys.KeyTypeEd25519), "")
+	assert.NoError(t, err)
+	return info.PublicKey
+}
+
+func TestPolicySign_ShouldIssueVerifiableUserCertificate(t *testing.T) {
+	ca := generate(t, sshkeys.KeyTypeEd25519)
+	caPub, _ := ssh.NewPublicKey(ca.Public())
+	now := time.Unix(1700000000, 0)
+
+	issued, err := userPolicy().Sign(ca, sshkeys.CertificateRequest{
+		PublicKey:       publicKey(t),
+		KeyId:           "alice",
+		CriticalOptions: map[string]string{"source-address": "10.0.0.0/8"},
+	}, now)
+	assert.NoError(t, err)
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(issued.Certificate))
+	assert.NoError(t, err)
+	cert := pub.(*ssh.Certificate)
+
+	checker := ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(caPub.Marshal())
+		},
+		Clock: func() time.Time { return now },
+	}
+	assert.NoError(t, checker.CheckCert("deploy", cert))
+	assert.Error(t, checker.CheckCert("root", cert))
+
+	assert.Equal(t, uint32(ssh.UserCert), cert.CertType)
+	assert.Equal(t, issued.Serial, cert.Serial)
+	assert.Equal(t, "alice", cert.KeyId)
+	assert.Equal(t, []string{"deploy"}, issued.Principals)
+	assert.Equal(t, map[string]string{"permit-pty": ""}, cert.Extensions)
+	assert.Equal(t, "10.0.0.0/8", cert.CriticalOptions["source-address"])
+	assert.Equal(t, now.Add(time.Hour), issued.ValidBefore)
+	assert.True(t, issued.ValidAfter.Before(now))
+}
+
+func TestPolicySign_ShouldIssueHostCertificateWithRSAAuthority(t *testing.T) {
+	ca := generate(t, sshkeys.KeyTypeRSA)
+	policy := sshkeys.Policy{
+		CertType:          sshkeys.CertTypeHost,
+		AllowedPrincipals: []string{sshkeys.AnyPrincipal},
+		MaxTTL:            sshkeys.DefaultMaxCertTTL,
+		DefaultTTL:        sshkeys.DefaultCertTTL,
+	}
+
+	issued, err := policy.Sign(ca, sshkeys.CertificateRequest{
+		PublicKey:  publicKey(t),
+		Principals: []string{"web-1.internal"},
+	}, time.Now())
+	assert.NoError(t, err)
+
+	pub, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(issued.Certificate))
+	cert := pub.(*ssh.Certificate)
+	assert.Equal(t, uint32(ssh.HostCert), cert.CertType)
+	// SHA-1 signatures are refused by current OpenSSH releases.
+	assert.NotEqual(t, ssh.KeyAlgoRSA, cert.Signature.Format)
+	assert.Empty(t, cert.Extensions)
+}
+
+func TestPolicySign_ShouldRejectRequestsOutsidePolicy(t *testing.T) {
+	ca := generate(t, sshkeys.KeyTypeEd25519)
+	pub := publicKey(t)
+
+	testCases := map[string]sshkeys.CertificateRequest{
+		"principal":       {PublicKey: pub, Principals: []string{"root"}},
+		"wildcard":        {PublicKey: pub, Principals: []string{sshkeys.AnyPrincipal}},
+		"ttl":             {PublicKey: pub, TTL: 9 * time.Hour},
+		"extension":       {PublicKey: pub, Extensions: []string{"permit-agent-forwarding"}},
+		"critical option": {PublicKey: pub, CriticalOptions: map[string]string{"force-command": "/bin/true"}},
+	}
+
+	for name, req := range testCases {
+		_, err := userPolicy().Sign(ca, req, time.Now())
+		assert.ErrorIs(t, err, sshkeys.ErrPolicyViolation, name)
+	}
+
+	_, err := userPolicy().Sign(ca, sshkeys.CertificateRequest{PublicKey: "ssh-ed25519 garbage"}, time.Now())
+	assert.ErrorIs(t, err, sshkeys.ErrInvalidPublicKey)
+}
+
+func TestPolicyValidate_ShouldRejectInconsistentPolicies(t *testing.T) {
+	assert.NoError(t, userPolicy().Validate())
+
+	testCases := map[string]func(p *sshkeys.Policy){
+		"cert type":         func(p *sshkeys.Policy) { p.CertType = "CLIENT" },
+		"no principals":     func(p *sshkeys.Policy) { p.AllowedPrincipals = nil },
+		"default ttl":       func(p *sshkeys.Policy) { p.DefaultTTL = 9 * time.Hour },
+		"default principal": func(p *sshkeys.Policy) { p.DefaultPrincipals = []string{"root"} },
+		"default extension": func(p *sshkeys.Policy) { p.DefaultExtensions = []string{"permit-X11-forwarding"} },
+		"comma":             func(p *sshkeys.Policy) { p.AllowedPrincipals = []string{"deploy,root"} },
+		"host extensions":   func(p *sshkeys.Policy) { p.CertType = sshkeys.CertTypeHost },
+	}
+
+	for name, mutate := range testCases {
+		policy := userPolicy()
+		mutate(&policy)
+		assert.ErrorIs(t, policy.Validate(), sshkeys.ErrInvalidPolicy, name)
+	}
+}
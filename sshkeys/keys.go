@@ -0,0 +1,194 @@
+package sshkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type KeyType string
+
+const (
+	KeyTypeEd25519 KeyType = "ED25519"
+	KeyTypeRSA     KeyType = "RSA"
+	KeyTypeECDSA   KeyType = "ECDSA"
+)
+
+type Format string
+
+const (
+	FormatOpenSSH Format = "OPENSSH"
+	FormatPEM     Format = "PEM"
+)
+
+const (
+	DefaultRSABits   = 3072
+	DefaultECDSABits = 256
+)
+
+var (
+	ErrUnsupportedKey    = errors.New("Unsupported key type or size")
+	ErrInvalidPrivateKey = errors.New("Invalid private key")
+	ErrPassphraseNeeded  = errors.New("Private key is passphrase protected")
+	ErrWrongPassphrase   = errors.New("Incorrect passphrase for private key")
+	ErrPEMPassphrase     = errors.New("Passphrase protection is only available for the OPENSSH format")
+)
+
+// Info is what can be shown about a key without touching the private half.
+type Info struct {
+	Type        KeyType
+	Bits        int
+	PublicKey   string
+	Fingerprint string
+}
+
+// Generate creates a keypair. Bits is ignored for ed25519 and falls back to
+// a sensible default for RSA and ECDSA when zero.
+func Generate(keyType KeyType, bits int) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeEd25519, "":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	case KeyTypeRSA:
+		if bits == 0 {
+			bits = DefaultRSABits
+		}
+		if bits != 2048 && bits != 3072 && bits != 4096 {
+			return nil, ErrUnsupportedKey
+		}
+		return rsa.GenerateKey(rand.Reader, bits)
+	case KeyTypeECDSA:
+		if bits == 0 {
+			bits = DefaultECDSABits
+		}
+		curve, ok := curves[bits]
+		if !ok {
+			return nil, ErrUnsupportedKey
+		}
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	}
+	return nil, ErrUnsupportedKey
+}
+
+var curves = map[int]elliptic.Curve{
+	256: elliptic.P256(),
+	384: elliptic.P384(),
+	521: elliptic.P521(),
+}
+
+// Parse reads a private key in OpenSSH, PKCS#1, PKCS#8 or SEC1 PEM form.
+// passphrase is only used when the key is encrypted.
+func Parse(pemBytes []byte, passphrase string) (crypto.Signer, error) {
+	var raw any
+	var err error
+	if passphrase == "" {
+		raw, err = ssh.ParseRawPrivateKey(pemBytes)
+	} else {
+		raw, err = ssh.ParseRawPrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	}
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		switch {
+		case errors.As(err, &missing):
+			return nil, ErrPassphraseNeeded
+		case errors.Is(err, x509.IncorrectPasswordError):
+			return nil, ErrWrongPassphrase
+		}
+		return nil, ErrInvalidPrivateKey
+	}
+
+	switch key := raw.(type) {
+	case *ed25519.PrivateKey:
+		return *key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		if _, ok := curves[key.Curve.Params().BitSize]; !ok {
+			return nil, ErrUnsupportedKey
+		}
+		return key, nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+// Describe returns the authorized_keys line and SHA256 fingerprint of key.
+func Describe(key crypto.Signer, comment string) (Info, error) {
+	pub, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		return Info{}, ErrUnsupportedKey
+	}
+
+	info := Info{Fingerprint: ssh.FingerprintSHA256(pub)}
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	if comment != "" {
+		authorized += " " + comment
+	}
+	info.PublicKey = authorized
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		info.Type, info.Bits = KeyTypeEd25519, 256
+	case *rsa.PrivateKey:
+		info.Type, info.Bits = KeyTypeRSA, k.N.BitLen()
+	case *ecdsa.PrivateKey:
+		info.Type, info.Bits = KeyTypeECDSA, k.Curve.Params().BitSize
+	}
+	return info, nil
+}
+
+// Export encodes key for use outside the vault. OpenSSH output can be
+// protected with a passphrase; PEM output uses PKCS#8 for ed25519, PKCS#1
+// for RSA and SEC1 for ECDSA, the forms most tools expect.
+func Export(key crypto.Signer, format Format, passphrase string, comment string) ([]byte, error) {
+	switch format {
+	case FormatOpenSSH, "":
+		var block *pem.Block
+		var err error
+		if passphrase == "" {
+			block, err = ssh.MarshalPrivateKey(key, comment)
+		} else {
+			block, err = ssh.MarshalPrivateKeyWithPassphrase(key, comment, []byte(passphrase))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(block), nil
+	case FormatPEM:
+		if passphrase != "" {
+			return nil, ErrPEMPassphrase
+		}
+		var block *pem.Block
+		switch k := key.(type) {
+		case ed25519.PrivateKey:
+			der, err := x509.MarshalPKCS8PrivateKey(k)
+			if err != nil {
+				return nil, err
+			}
+			block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+		case *rsa.PrivateKey:
+			block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+		case *ecdsa.PrivateKey:
+			der, err := x509.MarshalECPrivateKey(k)
+			if err != nil {
+				return nil, err
+			}
+			block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+		default:
+			return nil, ErrUnsupportedKey
+		}
+		return pem.EncodeToMemory(block), nil
+	}
+	return nil, fmt.Errorf("%w: format %s", ErrUnsupportedKey, format)
+}
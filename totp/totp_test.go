@@ -0,0 +1,109 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from RFC 6238, appendix B. Each algorithm uses a seed of the
+// ASCII digits repeated to the hash length.
+func rfcSecret(seed string) string {
+	return base32.StdEncoding.EncodeToString([]byte(seed))
+}
+
+func TestKey_Code_ShouldMatchRFC6238Vectors(t *testing.T) {
+	seeds := map[totp.Algorithm]string{
+		totp.AlgorithmSHA1:   "12345678901234567890",
+		totp.AlgorithmSHA256: "12345678901234567890123456789012",
+		totp.AlgorithmSHA512: "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	testCases := []struct {
+		unix     int64
+		expected map[totp.Algorithm]string
+	}{
+		{59, map[totp.Algorithm]string{totp.AlgorithmSHA1: "94287082", totp.AlgorithmSHA256: "46119246", totp.AlgorithmSHA512: "90693936"}},
+		{1111111109, map[totp.Algorithm]string{totp.AlgorithmSHA1: "07081804", totp.AlgorithmSHA256: "68084774", totp.AlgorithmSHA512: "25091201"}},
+		{1234567890, map[totp.Algorithm]string{totp.AlgorithmSHA1: "89005924", totp.AlgorithmSHA256: "91819424", totp.AlgorithmSHA512: "93441116"}},
+		{20000000000, map[totp.Algorithm]string{totp.AlgorithmSHA1: "65353130", totp.AlgorithmSHA256: "77737706", totp.AlgorithmSHA512: "47863826"}},
+	}
+
+	for _, testCase := range testCases {
+		for algorithm, expected := range testCase.expected {
+			k, err := totp.NewKey(rfcSecret(seeds[algorithm]), algorithm, 8, 30)
+			assert.NoError(t, err)
+
+			code, _, err := k.Code(time.Unix(testCase.unix, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, code, "%s at %d", algorithm, testCase.unix)
+		}
+	}
+}
+
+func TestKey_Code_ShouldReportSecondsRemaining(t *testing.T) {
+	k, err := totp.NewKey("JBSWY3DPEHPK3PXP", "", 0, 0)
+	assert.NoError(t, err)
+
+	code, remaining, err := k.Code(time.Unix(1699999985, 0))
+
+	assert.NoError(t, err)
+	assert.Len(t, code, 6)
+	assert.Equal(t, 25, remaining)
+}
+
+func TestNewKey_ShouldNormaliseAndValidateSecret(t *testing.T) {
+	k, err := totp.NewKey("jbsw y3dp ehpk 3pxp", "", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", k.Secret)
+	assert.Equal(t, totp.Key{Secret: "JBSWY3DPEHPK3PXP", Algorithm: totp.AlgorithmSHA1, Digits: 6, Period: 30}, k)
+
+	_, err = totp.NewKey("not base32!", "", 0, 0)
+	assert.ErrorIs(t, err, totp.ErrInvalidSecret)
+
+	_, err = totp.NewKey("JBSWY3DPEHPK3PXP", "MD5", 0, 0)
+	assert.ErrorIs(t, err, totp.ErrInvalidParams)
+
+	_, err = totp.NewKey("JBSWY3DPEHPK3PXP", "", 9, 0)
+	assert.ErrorIs(t, err, totp.ErrInvalidParams)
+}
+
+func TestParseURI_ShouldReadParameters(t *testing.T) {
+	k, err := totp.ParseURI("otpauth://totp/ACME%20Co:john.doe@email.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA256&digits=8&period=60")
+
+	assert.NoError(t, err)
+	assert.Equal(t, totp.Key{
+		Secret:      "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+		Issuer:      "ACME Co",
+		AccountName: "john.doe@email.com",
+		Algorithm:   totp.AlgorithmSHA256,
+		Digits:      8,
+		Period:      60,
+	}, k)
+}
+
+func TestParseURI_ShouldRejectInvalidURIs(t *testing.T) {
+	for _, uri := range []string{
+		"https://example.com/?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://hotp/Example:alice?secret=JBSWY3DPEHPK3PXP&counter=1",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&digits=six",
+		"otpauth://totp/Example:alice",
+	} {
+		_, err := totp.ParseURI(uri)
+		assert.Error(t, err, uri)
+	}
+}
+
+func TestKey_URI_ShouldRoundTripThroughParseURI(t *testing.T) {
+	for _, k := range []totp.Key{
+		{Secret: "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ", Issuer: "ACME Co", AccountName: "john.doe@email.com", Algorithm: totp.AlgorithmSHA256, Digits: 8, Period: 60},
+		{Secret: "JBSWY3DPEHPK3PXP", AccountName: "alice", Algorithm: totp.AlgorithmSHA1, Digits: 6, Period: 30},
+	} {
+		parsed, err := totp.ParseURI(k.URI())
+
+		assert.NoError(t, err)
+		assert.Equal(t, k, parsed)
+	}
+}
@@ -0,0 +1,148 @@
+package users
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+)
+
+// AdminHandler serves account administration endpoints. None of them touch
+// vault contents; operators only ever see account level data.
+type AdminHandler struct {
+	Repo  UserRepository
+	Audit audit.Recorder
+}
+
+func (ah *AdminHandler) ListUsers(ctx *gin.Context) {
+	var lur ListUsersRequest
+	if err := ctx.ShouldBindQuery(&lur); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid query parameters"})
+		return
+	}
+	if lur.Page == 0 {
+		lur.Page = 1
+	}
+	if lur.PageSize == 0 {
+		lur.PageSize = defaultPageSize
+	}
+
+	var users []User
+	total, err := ah.Repo.Search(lur.Query, (lur.Page-1)*lur.PageSize, lur.PageSize, &users)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	response := UserListResponse{Page: lur.Page, PageSize: lur.PageSize, Total: total}
+	response.load(users)
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ah *AdminHandler) FetchAccountMetadata(ctx *gin.Context) {
+	u, ok := ah.findTargetUser(ctx)
+	if !ok {
+		return
+	}
+
+	vaultCount, err := ah.Repo.CountVaults(u.Id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	response := AccountMetadataResponse{VaultCount: vaultCount}
+	response.load(*u)
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (ah *AdminHandler) DisableUser(ctx *gin.Context) {
+	ah.updateTargetUser(ctx, false, audit.ActionUserDisable, "User disabled successfully", func(u *User) {
+		u.Disabled = true
+		u.RevokeSessions()
+	})
+}
+
+func (ah *AdminHandler) EnableUser(ctx *gin.Context) {
+	ah.updateTargetUser(ctx, true, audit.ActionUserEnable, "User enabled successfully", func(u *User) {
+		u.Disabled = false
+	})
+}
+
+func (ah *AdminHandler) ForcePasswordReset(ctx *gin.Context) {
+	ah.updateTargetUser(ctx, true, audit.ActionPasswordResetForce, "Password reset enforced successfully", func(u *User) {
+		u.PasswordResetRequired = true
+		u.RevokeSessions()
+	})
+}
+
+func (ah *AdminHandler) RevokeSessions(ctx *gin.Context) {
+	ah.updateTargetUser(ctx, true, audit.ActionSessionsRevoke, "Sessions revoked successfully", func(u *User) {
+		u.RevokeSessions()
+	})
+}
+
+func (ah *AdminHandler) UpdateRole(ctx *gin.Context) {
+	var urr UpdateRoleRequest
+	if err := ctx.ShouldBindJSON(&urr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	ah.updateTargetUser(ctx, false, audit.ActionRoleUpdate, "Role updated successfully", func(u *User) {
+		u.Role = urr.Role
+	})
+}
+
+// private methods
+
+func (ah *AdminHandler) findTargetUser(ctx *gin.Context) (*User, bool) {
+	var u User
+	if err := ah.Repo.FindById(ctx.Param("id"), &u); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithStatus(http.StatusNotFound)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		}
+		return nil, false
+	}
+	return &u, true
+}
+
+// updateTargetUser applies update to the user named in the path. Actions that
+// could lock an administrator out are refused when aimed at their own account.
+func (ah *AdminHandler) updateTargetUser(ctx *gin.Context, allowSelf bool, action audit.Action, message string, update func(u *User)) {
+	if !allowSelf && ctx.Param("id") == ctx.GetString("user_id") {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Cannot perform this action on your own account"})
+		return
+	}
+
+	u, ok := ah.findTargetUser(ctx)
+	if !ok {
+		return
+	}
+
+	update(u)
+
+	if err := ah.Repo.Update(u); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(ah.Audit, ctx, audit.Event{
+		Action:     action,
+		TargetType: audit.TargetUser,
+		TargetId:   u.Id,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"message": message})
+}
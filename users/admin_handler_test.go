@@ -0,0 +1,217 @@
+package users_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/users"
+	user_mocks "github.com/adarsh-a-tw/passwordly/users/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestAdminHandler_ListUsers_ShouldListUsersWithPagination(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/users?q=mock&page=2&page_size=10", "GET", nil)
+
+	repo := &user_mocks.UserRepository{}
+	repo.On("Search", "mock", 10, 10, mock.AnythingOfType("*[]users.User")).Return(int64(11), nil).Run(func(args mock.Arguments) {
+		arg := args.Get(3).(*[]users.User)
+		*arg = []users.User{*mockUser()}
+	})
+
+	ah := users.AdminHandler{
+		Repo: repo,
+	}
+
+	ah.ListUsers(ctx)
+
+	var actualResponse users.UserListResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	repo.AssertCalled(t, "Search", "mock", 10, 10, mock.AnythingOfType("*[]users.User"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, actualResponse.Page)
+	assert.Equal(t, 10, actualResponse.PageSize)
+	assert.Equal(t, int64(11), actualResponse.Total)
+	assert.Len(t, actualResponse.Users, 1)
+	assert.Equal(t, "mock_username", actualResponse.Users[0].Username)
+}
+
+func TestAdminHandler_ListUsers_ShouldUseDefaultPagination(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/users", "GET", nil)
+
+	repo := &user_mocks.UserRepository{}
+	repo.On("Search", "", 0, 20, mock.AnythingOfType("*[]users.User")).Return(int64(0), nil)
+
+	ah := users.AdminHandler{
+		Repo: repo,
+	}
+
+	ah.ListUsers(ctx)
+
+	var actualResponse users.UserListResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, actualResponse.Page)
+	assert.Equal(t, 20, actualResponse.PageSize)
+	assert.Empty(t, actualResponse.Users)
+}
+
+func TestAdminHandler_ListUsers_ShouldThrowErrorForInvalidPageSize(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/users?page_size=1000", "GET", nil)
+
+	ah := users.AdminHandler{}
+
+	ah.ListUsers(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAdminHandler_FetchAccountMetadata_ShouldReturnMetadata(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/users/mock_id", "GET", nil)
+	ctx.AddParam("id", "mock_id")
+
+	repo := &user_mocks.UserRepository{}
+	repo.On("FindById", "mock_id", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*users.User)
+		*arg = *mockUser()
+		arg.Role = users.RoleUser
+	})
+	repo.On("CountVaults", "mock_id").Return(int64(3), nil)
+
+	ah := users.AdminHandler{
+		Repo: repo,
+	}
+
+	ah.FetchAccountMetadata(ctx)
+
+	var actualResponse users.AccountMetadataResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "mock_id", actualResponse.Id)
+	assert.Equal(t, users.RoleUser, actualResponse.Role)
+	assert.Equal(t, int64(3), actualResponse.VaultCount)
+	assert.Nil(t, actualResponse.LastLoginAt)
+}
+
+func TestAdminHandler_FetchAccountMetadata_ShouldThrowNotFoundForUnknownUser(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/users/unknown", "GET", nil)
+	ctx.AddParam("id", "unknown")
+
+	repo := &user_mocks.UserRepository{}
+	repo.On("FindById", "unknown", mock.AnythingOfType("*users.User")).Return(gorm.ErrRecordNotFound)
+
+	ah := users.AdminHandler{
+		Repo: repo,
+	}
+
+	ah.FetchAccountMetadata(ctx)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAdminHandler_DisableUser_ShouldDisableUserAndRevokeSessions(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/users/mock_id/disable", "POST", nil)
+	ctx.AddParam("id", "mock_id")
+	ctx.Set("user_id", "admin_id")
+
+	repo := &user_mocks.UserRepository{}
+	repo.On("FindById", "mock_id", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*users.User)
+		*arg = *mockUser()
+	})
+	repo.On("Update", mock.AnythingOfType("*users.User")).Return(nil)
+
+	ah := users.AdminHandler{
+		Repo: repo,
+	}
+
+	ah.DisableUser(ctx)
+
+	repo.AssertCalled(t, "Update", mock.MatchedBy(func(u *users.User) bool {
+		return u.Disabled && u.SessionVersion == 1
+	}))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAdminHandler_DisableUser_ShouldNotDisableOwnAccount(t *testing.T) {
+	expectedResponse := common.ErrorResponse{Message: "Cannot perform this action on your own account"}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/users/admin_id/disable", "POST", nil)
+	ctx.AddParam("id", "admin_id")
+	ctx.Set("user_id", "admin_id")
+
+	repo := &user_mocks.UserRepository{}
+
+	ah := users.AdminHandler{
+		Repo: repo,
+	}
+
+	ah.DisableUser(ctx)
+
+	var actualResponse common.ErrorResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	repo.AssertNotCalled(t, "Update", mock.Anything)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, expectedResponse, actualResponse)
+}
+
+func TestAdminHandler_ForcePasswordReset_ShouldFlagUser(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/users/mock_id/password-reset", "POST", nil)
+	ctx.AddParam("id", "mock_id")
+	ctx.Set("user_id", "admin_id")
+
+	repo := &user_mocks.UserRepository{}
+	repo.On("FindById", "mock_id", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*users.User)
+		*arg = *mockUser()
+	})
+	repo.On("Update", mock.AnythingOfType("*users.User")).Return(nil)
+
+	ah := users.AdminHandler{
+		Repo: repo,
+	}
+
+	ah.ForcePasswordReset(ctx)
+
+	repo.AssertCalled(t, "Update", mock.MatchedBy(func(u *users.User) bool {
+		return u.PasswordResetRequired && u.SessionVersion == 1
+	}))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAdminHandler_UpdateRole_ShouldThrowInternalServerErrorIfUpdateFails(t *testing.T) {
+	expectedResponse := common.ErrorResponse{Message: "Something went wrong. Try again."}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/admin/users/mock_id/role", "PATCH", users.UpdateRoleRequest{Role: users.RoleAdmin})
+	ctx.AddParam("id", "mock_id")
+	ctx.Set("user_id", "admin_id")
+
+	repo := &user_mocks.UserRepository{}
+	repo.On("FindById", "mock_id", mock.AnythingOfType("*users.User")).Return(nil)
+	repo.On("Update", mock.AnythingOfType("*users.User")).Return(errors.New("MOCK_ERROR"))
+
+	ah := users.AdminHandler{
+		Repo: repo,
+	}
+
+	ah.UpdateRole(ctx)
+
+	var actualResponse common.ErrorResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	repo.AssertCalled(t, "Update", mock.MatchedBy(func(u *users.User) bool { return u.Role == users.RoleAdmin }))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, expectedResponse, actualResponse)
+}
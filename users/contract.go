@@ -3,7 +3,7 @@ package users
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required,username"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,password"`
+	Password string `json:"password" binding:"required"`
 }
 
 type LoginUserRequest struct {
@@ -20,13 +20,14 @@ type AccessTokenSuccessResponse struct {
 }
 
 type LoginUserSuccessResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken           string `json:"access_token"`
+	RefreshToken          string `json:"refresh_token"`
+	PasswordResetRequired bool   `json:"password_reset_required,omitempty"`
 }
 
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
-	NewPassword     string `json:"new_password" binding:"required,password"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
 
 type UpdateUserRequest struct {
@@ -35,7 +36,71 @@ type UpdateUserRequest struct {
 }
 
 type UserResponse struct {
-	Id       string `json:"id"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
+	Id                    string `json:"id"`
+	Username              string `json:"username"`
+	Email                 string `json:"email"`
+	Role                  Role   `json:"role,omitempty"`
+	PasswordResetRequired bool   `json:"password_reset_required,omitempty"`
+}
+
+type ListUsersRequest struct {
+	Query    string `form:"q"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type UpdateRoleRequest struct {
+	Role Role `json:"role" binding:"required,user_role"`
+}
+
+// AdminUserResponse is the account view exposed to administrators. It never
+// carries password hashes or anything stored inside the user's vaults.
+type AdminUserResponse struct {
+	Id                    string `json:"id"`
+	Username              string `json:"username"`
+	Email                 string `json:"email"`
+	Role                  Role   `json:"role"`
+	Disabled              bool   `json:"disabled"`
+	PasswordResetRequired bool   `json:"password_reset_required"`
+	LastLoginAt           *int64 `json:"last_login_at"`
+	CreatedAt             int64  `json:"created_at"`
+	UpdatedAt             int64  `json:"updated_at"`
+}
+
+func (aur *AdminUserResponse) load(u User) {
+	aur.Id = u.Id
+	aur.Username = u.Username
+	aur.Email = u.Email
+	aur.Role = u.Role
+	aur.Disabled = u.Disabled
+	aur.PasswordResetRequired = u.PasswordResetRequired
+	aur.CreatedAt = u.CreatedAt.Unix()
+	aur.UpdatedAt = u.UpdatedAt.Unix()
+	aur.LastLoginAt = nil
+	if u.LastLoginAt != nil {
+		lastLogin := u.LastLoginAt.Unix()
+		aur.LastLoginAt = &lastLogin
+	}
+}
+
+type UserListResponse struct {
+	Users    []AdminUserResponse `json:"users"`
+	Page     int                 `json:"page"`
+	PageSize int                 `json:"page_size"`
+	Total    int64               `json:"total"`
+}
+
+func (ulr *UserListResponse) load(users []User) {
+	var userResponses = make([]AdminUserResponse, 0)
+	for _, u := range users {
+		aur := AdminUserResponse{}
+		aur.load(u)
+		userResponses = append(userResponses, aur)
+	}
+	ulr.Users = userResponses
+}
+
+type AccountMetadataResponse struct {
+	AdminUserResponse
+	VaultCount int64 `json:"vault_count"`
 }
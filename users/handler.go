@@ -1,9 +1,13 @@
 package users
 
 import (
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/adarsh-a-tw/passwordly/audit"
 	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/passwords"
 	"github.com/adarsh-a-tw/passwordly/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -13,6 +17,8 @@ type UserHandler struct {
 	Repo           UserRepository
 	AuthProvider   utils.AuthProvider
 	PasswordHasher utils.PasswordHasher
+	PasswordPolicy passwords.Checker
+	Audit          audit.Recorder
 }
 
 func (uh *UserHandler) Create(ctx *gin.Context) {
@@ -22,6 +28,15 @@ func (uh *UserHandler) Create(ctx *gin.Context) {
 		return
 	}
 
+	violations := uh.PasswordPolicy.Check(cur.Password, passwords.Subject{
+		Username: cur.Username,
+		Email:    cur.Email,
+	})
+	if len(violations) > 0 {
+		ctx.JSON(http.StatusBadRequest, passwords.NewPolicyErrorResponse(violations))
+		return
+	}
+
 	c1 := make(chan struct {
 		bool
 		error
@@ -53,13 +68,18 @@ func (uh *UserHandler) Create(ctx *gin.Context) {
 		return
 	}
 
-	hashedPassword := uh.PasswordHasher.HashPassword(cur.Password)
+	hashedPassword, err := uh.PasswordHasher.HashPassword(cur.Password)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
 
 	u := User{
 		Id:       uuid.NewString(),
 		Username: cur.Username,
 		Email:    cur.Email,
 		Password: hashedPassword,
+		Role:     RoleUser,
 	}
 
 	if err := uh.Repo.Create(&u); err != nil {
@@ -71,6 +91,7 @@ func (uh *UserHandler) Create(ctx *gin.Context) {
 		Id:       u.Id,
 		Username: u.Username,
 		Email:    u.Email,
+		Role:     u.Role,
 	})
 }
 
@@ -83,23 +104,61 @@ func (uh *UserHandler) Login(ctx *gin.Context) {
 
 	var u User
 	if err := uh.Repo.Find(lur.Username, &u); err != nil {
+		audit.Log(uh.Audit, ctx, audit.Event{
+			Action:     audit.ActionLoginFailed,
+			TargetType: audit.TargetUser,
+			Detail:     "unknown username",
+			Outcome:    audit.OutcomeFailure,
+		})
 		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Credentials"})
 		return
 	}
 
 	if uh.PasswordHasher.ComparePassword(lur.Password, u.Password) {
-		tokenPair, err := uh.AuthProvider.GenerateTokenPair(u.Id)
+		if u.Disabled {
+			audit.Log(uh.Audit, ctx, audit.Event{
+				Action:     audit.ActionLoginFailed,
+				TargetType: audit.TargetUser,
+				TargetId:   u.Id,
+				Detail:     "account disabled",
+				Outcome:    audit.OutcomeFailure,
+			})
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse{Message: "Account is disabled"})
+			return
+		}
+		tokenPair, err := uh.AuthProvider.GenerateTokenPair(u.Id, u.SessionVersion)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
 			return
 		}
+		now := time.Now()
+		u.LastLoginAt = &now
+		uh.upgradePasswordHash(&u, lur.Password)
+		if err := uh.Repo.Update(&u); err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		audit.Log(uh.Audit, ctx, audit.Event{
+			ActorId:    u.Id,
+			Action:     audit.ActionLogin,
+			TargetType: audit.TargetUser,
+			TargetId:   u.Id,
+		})
 		ctx.JSON(http.StatusOK, LoginUserSuccessResponse{
-			AccessToken:  tokenPair.AccessToken,
-			RefreshToken: tokenPair.RefreshToken,
+			AccessToken:           tokenPair.AccessToken,
+			RefreshToken:          tokenPair.RefreshToken,
+			PasswordResetRequired: u.PasswordResetRequired,
 		})
 		return
 	}
 
+	audit.Log(uh.Audit, ctx, audit.Event{
+		Action:     audit.ActionLoginFailed,
+		TargetType: audit.TargetUser,
+		TargetId:   u.Id,
+		Detail:     "invalid password",
+		Outcome:    audit.OutcomeFailure,
+	})
 	ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Credentials"})
 }
 
@@ -115,9 +174,11 @@ func (uh *UserHandler) FetchUser(ctx *gin.Context) {
 	ctx.JSON(
 		http.StatusOK,
 		UserResponse{
-			Id:       u.Id,
-			Username: u.Username,
-			Email:    u.Email,
+			Id:                    u.Id,
+			Username:              u.Username,
+			Email:                 u.Email,
+			Role:                  u.Role,
+			PasswordResetRequired: u.PasswordResetRequired,
 		},
 	)
 
@@ -138,6 +199,13 @@ func (uh *UserHandler) ChangePassword(ctx *gin.Context) {
 	}
 
 	if !uh.PasswordHasher.ComparePassword(cpr.CurrentPassword, u.Password) {
+		audit.Log(uh.Audit, ctx, audit.Event{
+			Action:     audit.ActionPasswordChange,
+			TargetType: audit.TargetUser,
+			TargetId:   u.Id,
+			Detail:     "current password mismatch",
+			Outcome:    audit.OutcomeFailure,
+		})
 		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Current password does not match"})
 		return
 	}
@@ -147,13 +215,49 @@ func (uh *UserHandler) ChangePassword(ctx *gin.Context) {
 		return
 	}
 
-	u.Password = uh.PasswordHasher.HashPassword(cpr.NewPassword)
+	previousHashes := []string{u.Password}
+	if historySize := uh.PasswordPolicy.HistorySize(); historySize > 1 {
+		var history []PasswordHistory
+		if err := uh.Repo.FetchPasswordHistory(u.Id, historySize-1, &history); err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		for _, h := range history {
+			previousHashes = append(previousHashes, h.Hash)
+		}
+	}
+
+	violations := uh.PasswordPolicy.Check(cpr.NewPassword, passwords.Subject{
+		Username:       u.Username,
+		Email:          u.Email,
+		PreviousHashes: previousHashes,
+	})
+	if len(violations) > 0 {
+		ctx.JSON(http.StatusBadRequest, passwords.NewPolicyErrorResponse(violations))
+		return
+	}
 
-	if err := uh.Repo.Update(&u); err != nil {
+	hashedPassword, err := uh.PasswordHasher.HashPassword(cpr.NewPassword)
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
 		return
 	}
 
+	replacedHash := u.Password
+	u.Password = hashedPassword
+	u.PasswordResetRequired = false
+
+	if err := uh.Repo.UpdatePassword(&u, replacedHash, uh.PasswordPolicy.HistorySize()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(uh.Audit, ctx, audit.Event{
+		Action:     audit.ActionPasswordChange,
+		TargetType: audit.TargetUser,
+		TargetId:   u.Id,
+	})
+
 	ctx.JSON(http.StatusOK, nil)
 }
 
@@ -225,13 +329,58 @@ func (uh *UserHandler) FetchAccessToken(ctx *gin.Context) {
 		return
 	}
 
+	claims, err := uh.AuthProvider.VerifyRefreshToken(far.RefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	var u User
+	if err := uh.Repo.FindById(claims.UserId, &u); err != nil {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse{Message: "Invalid AuthToken"})
+		return
+	}
+
+	if !u.HasActiveSession(claims.SessionVersion) {
+		audit.Log(uh.Audit, ctx, audit.Event{
+			ActorId:    u.Id,
+			Action:     audit.ActionTokenRefresh,
+			TargetType: audit.TargetUser,
+			TargetId:   u.Id,
+			Detail:     "session revoked",
+			Outcome:    audit.OutcomeFailure,
+		})
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse{Message: "Session has been revoked"})
+		return
+	}
+
 	if accessToken, err := uh.AuthProvider.GenerateAccessToken(far.RefreshToken); err != nil {
 		ctx.JSON(http.StatusForbidden, common.ErrorResponse{Message: err.Error()})
 	} else {
+		audit.Log(uh.Audit, ctx, audit.Event{
+			ActorId:    u.Id,
+			Action:     audit.ActionTokenRefresh,
+			TargetType: audit.TargetUser,
+			TargetId:   u.Id,
+		})
 		ctx.JSON(http.StatusOK, AccessTokenSuccessResponse{AccessToken: accessToken})
 	}
 }
 
+// upgradePasswordHash rehashes the password with the current algorithm and
+// parameters while the plaintext is at hand. The caller persists the user.
+func (uh *UserHandler) upgradePasswordHash(u *User, rawPwd string) {
+	if !uh.PasswordHasher.NeedsRehash(u.Password) {
+		return
+	}
+	hashedPassword, err := uh.PasswordHasher.HashPassword(rawPwd)
+	if err != nil {
+		log.Println("Could not upgrade password hash:", err)
+		return
+	}
+	u.Password = hashedPassword
+}
+
 // Concurrent db query methods
 
 func (uh *UserHandler) checkExistingUsername(
@@ -6,7 +6,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/adarsh-a-tw/passwordly/audit"
+	audit_mocks "github.com/adarsh-a-tw/passwordly/audit/mocks"
 	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/passwords"
+	passwords_mocks "github.com/adarsh-a-tw/passwordly/passwords/mocks"
 	"github.com/adarsh-a-tw/passwordly/users"
 	user_mocks "github.com/adarsh-a-tw/passwordly/users/mocks"
 	"github.com/adarsh-a-tw/passwordly/utils"
@@ -36,9 +40,10 @@ func TestUserHandler_Create_ShouldCreateUserSuccessfully(t *testing.T) {
 	repo.On("EmailAlreadyExists", "test@email.com").Return(false, nil)
 	repo.On("Create", mock.AnythingOfType("*users.User")).Return(nil)
 
-	hasher.On("HashPassword", "P@ssword123").Return("HashedPassword")
+	hasher.On("HashPassword", "P@ssword123").Return("HashedPassword", nil)
 
 	uh := users.UserHandler{
+		PasswordPolicy: mockPasswordPolicy(nil, 0),
 		Repo:           repo,
 		PasswordHasher: hasher,
 	}
@@ -75,7 +80,8 @@ func TestUserHandler_Create_ShouldNotCreateUserWithAlreadyExistingUsername(t *te
 	repo.On("EmailAlreadyExists", "test@email.com").Return(false, nil)
 
 	uh := users.UserHandler{
-		Repo: repo,
+		PasswordPolicy: mockPasswordPolicy(nil, 0),
+		Repo:           repo,
 	}
 
 	uh.Create(ctx)
@@ -105,7 +111,8 @@ func TestUserHandler_Create_ShouldNotCreateUserWithAlreadyExistingEmail(t *testi
 	repo.On("EmailAlreadyExists", "test@email.com").Return(true, nil)
 
 	uh := users.UserHandler{
-		Repo: repo,
+		PasswordPolicy: mockPasswordPolicy(nil, 0),
+		Repo:           repo,
 	}
 
 	uh.Create(ctx)
@@ -156,7 +163,8 @@ func TestUserHandler_Create_ShouldThrowInternalServerErrorIfUsernameAlreadyExist
 	repo.On("EmailAlreadyExists", "test@email.com").Return(false, nil)
 
 	uh := users.UserHandler{
-		Repo: repo,
+		PasswordPolicy: mockPasswordPolicy(nil, 0),
+		Repo:           repo,
 	}
 
 	uh.Create(ctx)
@@ -186,7 +194,8 @@ func TestUserHandler_Create_ShouldThrowInternalServerErrorIfEmailAlreadyExistsMe
 	repo.On("EmailAlreadyExists", "test@email.com").Return(false, errors.New("MOCK_ERROR"))
 
 	uh := users.UserHandler{
-		Repo: repo,
+		PasswordPolicy: mockPasswordPolicy(nil, 0),
+		Repo:           repo,
 	}
 
 	uh.Create(ctx)
@@ -218,9 +227,10 @@ func TestUserHandler_Create_ShouldThrowInternalServerErrorIfCreateMethodFails(t
 	repo.On("EmailAlreadyExists", "test@email.com").Return(false, nil)
 	repo.On("Create", mock.AnythingOfType("*users.User")).Return(errors.New("MOCK_ERROR"))
 
-	hasher.On("HashPassword", "P@ssword123").Return("HashedPassword")
+	hasher.On("HashPassword", "P@ssword123").Return("HashedPassword", nil)
 
 	uh := users.UserHandler{
+		PasswordPolicy: mockPasswordPolicy(nil, 0),
 		Repo:           repo,
 		PasswordHasher: hasher,
 	}
@@ -272,8 +282,10 @@ func TestUserHandler_Login_ShouldLoginUserSuccessfully(t *testing.T) {
 		arg.Email = mu.Email
 		arg.Password = "HashedPassword"
 	})
-	ap.On("GenerateTokenPair", "mock_id").Return(mockTokenPair, nil).Once()
+	ap.On("GenerateTokenPair", "mock_id", 0).Return(mockTokenPair, nil).Once()
 	hasher.On("ComparePassword", "mockPassword@123", "HashedPassword").Return(true)
+	hasher.On("NeedsRehash", "HashedPassword").Return(false)
+	repo.On("Update", mock.AnythingOfType("*users.User")).Return(nil)
 
 	uh := users.UserHandler{
 		Repo:           repo,
@@ -287,8 +299,9 @@ func TestUserHandler_Login_ShouldLoginUserSuccessfully(t *testing.T) {
 	common.DecodeJSONResponse(t, rec, &actualResponse)
 
 	repo.AssertCalled(t, "Find", "mock_username", mock.AnythingOfType("*users.User"))
-	ap.AssertCalled(t, "GenerateTokenPair", "mock_id")
+	ap.AssertCalled(t, "GenerateTokenPair", "mock_id", 0)
 	hasher.AssertCalled(t, "ComparePassword", "mockPassword@123", "HashedPassword")
+	repo.AssertCalled(t, "Update", mock.MatchedBy(func(u *users.User) bool { return u.LastLoginAt != nil }))
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Equal(t, expectedResponse, actualResponse)
@@ -316,7 +329,7 @@ func TestUserHandler_Login_ShouldThrowErrorForInternalServerError(t *testing.T)
 		arg.Email = mu.Email
 		arg.Password = "HashedPassword"
 	})
-	ap.On("GenerateTokenPair", "mock_id").Return(
+	ap.On("GenerateTokenPair", "mock_id", 0).Return(
 		utils.AuthTokenPair{}, errors.New("Something went wrong. Try again."),
 	)
 	hasher.On("ComparePassword", "mockPassword@123", "HashedPassword").Return(true)
@@ -333,7 +346,7 @@ func TestUserHandler_Login_ShouldThrowErrorForInternalServerError(t *testing.T)
 	common.DecodeJSONResponse(t, rec, &actualResponse)
 
 	repo.AssertCalled(t, "Find", "mock_username", mock.AnythingOfType("*users.User"))
-	ap.AssertCalled(t, "GenerateTokenPair", "mock_id")
+	ap.AssertCalled(t, "GenerateTokenPair", "mock_id", 0)
 	hasher.AssertCalled(t, "ComparePassword", "mockPassword@123", "HashedPassword")
 
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
@@ -352,6 +365,7 @@ func TestUserHandler_Login_ShouldThrowErrorForInvalidPassword(t *testing.T) {
 
 	repo := &user_mocks.UserRepository{}
 	hasher := &utils_mocks.PasswordHasher{}
+	recorder := &audit_mocks.EventRepository{}
 
 	repo.On("Find", "mock_username", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
 		arg := args.Get(1).(*users.User)
@@ -362,10 +376,12 @@ func TestUserHandler_Login_ShouldThrowErrorForInvalidPassword(t *testing.T) {
 		arg.Password = "HashedPassword"
 	})
 	hasher.On("ComparePassword", "mockPassword@123", "HashedPassword").Return(false)
+	recorder.On("Record", mock.AnythingOfType("*audit.Event")).Return(nil)
 
 	uh := users.UserHandler{
 		Repo:           repo,
 		PasswordHasher: hasher,
+		Audit:          recorder,
 	}
 
 	uh.Login(ctx)
@@ -375,6 +391,9 @@ func TestUserHandler_Login_ShouldThrowErrorForInvalidPassword(t *testing.T) {
 
 	repo.AssertCalled(t, "Find", "mock_username", mock.AnythingOfType("*users.User"))
 	hasher.AssertCalled(t, "ComparePassword", "mockPassword@123", "HashedPassword")
+	recorder.AssertCalled(t, "Record", mock.MatchedBy(func(e *audit.Event) bool {
+		return e.Action == audit.ActionLoginFailed && e.TargetId == "mock_id" && e.Outcome == audit.OutcomeFailure
+	}))
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 	assert.Equal(t, expectedResponse, actualResponse)
@@ -510,14 +529,15 @@ func TestUserHandler_ChangePassword_ShouldChangePasswordSuccessfully(t *testing.
 		arg.Password = "HashedPassword"
 	})
 
-	repo.On("Update", mock.AnythingOfType("*users.User")).Return(nil)
+	repo.On("UpdatePassword", mock.AnythingOfType("*users.User"), "HashedPassword", 0).Return(nil)
 	hasher.On("ComparePassword", "mockPassword@123", "HashedPassword").Return(true)
-	hasher.On("HashPassword", "mockPassword@1234").Return("HashedPassword2")
+	hasher.On("HashPassword", "mockPassword@1234").Return("HashedPassword2", nil)
 
 	// Mocking TokenAuthMiddleware
 	ctx.Set("user_id", "mock_id")
 
 	uh := users.UserHandler{
+		PasswordPolicy: mockPasswordPolicy(nil, 0),
 		Repo:           repo,
 		PasswordHasher: hasher,
 	}
@@ -525,7 +545,7 @@ func TestUserHandler_ChangePassword_ShouldChangePasswordSuccessfully(t *testing.
 	uh.ChangePassword(ctx)
 
 	repo.AssertCalled(t, "FindById", "mock_id", mock.AnythingOfType("*users.User"))
-	repo.AssertCalled(t, "Update", mock.AnythingOfType("*users.User"))
+	repo.AssertCalled(t, "UpdatePassword", mock.AnythingOfType("*users.User"), "HashedPassword", 0)
 
 	hasher.AssertCalled(t, "ComparePassword", "mockPassword@123", "HashedPassword")
 	hasher.AssertCalled(t, "HashPassword", "mockPassword@1234")
@@ -689,14 +709,15 @@ func TestUserHandler_ChangePassword_ShouldThrowErrorForUpdateFailure(t *testing.
 		arg.Password = "HashedPassword"
 	})
 
-	repo.On("Update", mock.AnythingOfType("*users.User")).Return(errors.New("Mock Error"))
+	repo.On("UpdatePassword", mock.AnythingOfType("*users.User"), "HashedPassword", 0).Return(errors.New("Mock Error"))
 	hasher.On("ComparePassword", "mockPassword@123", "HashedPassword").Return(true)
-	hasher.On("HashPassword", "mockPassword@1234").Return("HashedPassword2")
+	hasher.On("HashPassword", "mockPassword@1234").Return("HashedPassword2", nil)
 
 	// Mocking TokenAuthMiddleware
 	ctx.Set("user_id", "mock_id")
 
 	uh := users.UserHandler{
+		PasswordPolicy: mockPasswordPolicy(nil, 0),
 		Repo:           repo,
 		PasswordHasher: hasher,
 	}
@@ -707,7 +728,7 @@ func TestUserHandler_ChangePassword_ShouldThrowErrorForUpdateFailure(t *testing.
 	common.DecodeJSONResponse(t, rec, &actualResponse)
 
 	repo.AssertCalled(t, "FindById", "mock_id", mock.AnythingOfType("*users.User"))
-	repo.AssertCalled(t, "Update", mock.AnythingOfType("*users.User"))
+	repo.AssertCalled(t, "UpdatePassword", mock.AnythingOfType("*users.User"), "HashedPassword", 0)
 	hasher.AssertCalled(t, "ComparePassword", "mockPassword@123", "HashedPassword")
 	hasher.AssertCalled(t, "HashPassword", "mockPassword@1234")
 
@@ -856,10 +877,14 @@ func TestUserHandler_FetchAccessToken_ShoulFetchAccessTokenSuccessfully(t *testi
 	}
 
 	ap := &utils_mocks.AuthProvider{}
+	repo := &user_mocks.UserRepository{}
 	uh := users.UserHandler{
+		Repo:         repo,
 		AuthProvider: ap,
 	}
 
+	ap.On("VerifyRefreshToken", mrt).Return(utils.AuthTokenClaims{UserId: "mock_id"}, nil)
+	repo.On("FindById", "mock_id", mock.AnythingOfType("*users.User")).Return(nil)
 	ap.On("GenerateAccessToken", mrt).Return(mat, nil)
 
 	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/users/access_token", "POST", far)
@@ -888,13 +913,14 @@ func TestUserHandler_FetchAccessToken_ShoulThrowErrorForBadToken(t *testing.T) {
 		AuthProvider: ap,
 	}
 
-	ap.On("GenerateAccessToken", mrt).Return("", errors.New("MOCK_ERROR"))
+	ap.On("VerifyRefreshToken", mrt).Return(utils.AuthTokenClaims{}, errors.New("MOCK_ERROR"))
 
 	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/users/access_token", "POST", far)
 
 	uh.FetchAccessToken(ctx)
 
-	ap.AssertCalled(t, "GenerateAccessToken", mrt)
+	ap.AssertCalled(t, "VerifyRefreshToken", mrt)
+	ap.AssertNotCalled(t, "GenerateAccessToken", mrt)
 
 	var actualResponse common.ErrorResponse
 	common.DecodeJSONResponse(t, rec, &actualResponse)
@@ -918,6 +944,232 @@ func TestUserHandler_FetchAccessToken_ShoulThrowErrorForInvalidBody(t *testing.T
 	assert.Equal(t, expectedResponse, actualResponse)
 }
 
+func TestUserHandler_Login_ShouldNotLoginDisabledUser(t *testing.T) {
+	expectedResponse := common.ErrorResponse{Message: "Account is disabled"}
+
+	lur := users.LoginUserRequest{
+		Username: "mock_username",
+		Password: "mockPassword@123",
+	}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/users/login", "POST", lur)
+
+	repo := &user_mocks.UserRepository{}
+	ap := &utils_mocks.AuthProvider{}
+	hasher := &utils_mocks.PasswordHasher{}
+
+	repo.On("Find", "mock_username", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*users.User)
+		arg.Id = "mock_id"
+		arg.Password = "HashedPassword"
+		arg.Disabled = true
+	})
+	hasher.On("ComparePassword", "mockPassword@123", "HashedPassword").Return(true)
+
+	uh := users.UserHandler{
+		Repo:           repo,
+		AuthProvider:   ap,
+		PasswordHasher: hasher,
+	}
+
+	uh.Login(ctx)
+
+	var actualResponse common.ErrorResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	ap.AssertNotCalled(t, "GenerateTokenPair", "mock_id", 0)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, expectedResponse, actualResponse)
+}
+
+func TestUserHandler_FetchAccessToken_ShoulThrowErrorForRevokedSession(t *testing.T) {
+	mrt := "MOCK_REFRESH_TOKEN"
+	expectedResponse := common.ErrorResponse{Message: "Session has been revoked"}
+
+	far := users.FetchAccessTokenRequest{
+		RefreshToken: mrt,
+	}
+
+	ap := &utils_mocks.AuthProvider{}
+	repo := &user_mocks.UserRepository{}
+	uh := users.UserHandler{
+		Repo:         repo,
+		AuthProvider: ap,
+	}
+
+	ap.On("VerifyRefreshToken", mrt).Return(utils.AuthTokenClaims{UserId: "mock_id", SessionVersion: 1}, nil)
+	repo.On("FindById", "mock_id", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*users.User)
+		arg.Id = "mock_id"
+		arg.SessionVersion = 2
+	})
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/users/access_token", "POST", far)
+
+	uh.FetchAccessToken(ctx)
+
+	ap.AssertNotCalled(t, "GenerateAccessToken", mrt)
+
+	var actualResponse common.ErrorResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, expectedResponse, actualResponse)
+}
+
+func TestUserHandler_Login_ShouldUpgradeOutdatedPasswordHash(t *testing.T) {
+	lur := users.LoginUserRequest{
+		Username: "mock_username",
+		Password: "mockPassword@123",
+	}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/users/login", "POST", lur)
+
+	repo := &user_mocks.UserRepository{}
+	ap := &utils_mocks.AuthProvider{}
+	hasher := &utils_mocks.PasswordHasher{}
+
+	repo.On("Find", "mock_username", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*users.User)
+		arg.Id = "mock_id"
+		arg.Password = "BcryptHash"
+	})
+	repo.On("Update", mock.AnythingOfType("*users.User")).Return(nil)
+	ap.On("GenerateTokenPair", "mock_id", 0).Return(utils.AuthTokenPair{}, nil)
+	hasher.On("ComparePassword", "mockPassword@123", "BcryptHash").Return(true)
+	hasher.On("NeedsRehash", "BcryptHash").Return(true)
+	hasher.On("HashPassword", "mockPassword@123").Return("Argon2Hash", nil)
+
+	uh := users.UserHandler{
+		Repo:           repo,
+		AuthProvider:   ap,
+		PasswordHasher: hasher,
+	}
+
+	uh.Login(ctx)
+
+	repo.AssertCalled(t, "Update", mock.MatchedBy(func(u *users.User) bool { return u.Password == "Argon2Hash" }))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestUserHandler_Create_ShouldThrowInternalServerErrorIfHashingFails(t *testing.T) {
+	expectedResponse := common.ErrorResponse{Message: "Something went wrong. Try again."}
+	cur := users.CreateUserRequest{
+		Username: "mock_username",
+		Password: "P@ssword123",
+		Email:    "test@email.com",
+	}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/users", "POST", cur)
+
+	repo := &user_mocks.UserRepository{}
+	hasher := &utils_mocks.PasswordHasher{}
+
+	repo.On("UsernameAlreadyExists", "mock_username").Return(false, nil)
+	repo.On("EmailAlreadyExists", "test@email.com").Return(false, nil)
+	hasher.On("HashPassword", "P@ssword123").Return("", errors.New("MOCK_ERROR"))
+
+	uh := users.UserHandler{
+		PasswordPolicy: mockPasswordPolicy(nil, 0),
+		Repo:           repo,
+		PasswordHasher: hasher,
+	}
+
+	uh.Create(ctx)
+
+	var actualResponse common.ErrorResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	repo.AssertNotCalled(t, "Create", mock.AnythingOfType("*users.User"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, expectedResponse, actualResponse)
+}
+
+func TestUserHandler_Create_ShouldNotCreateUserViolatingPasswordPolicy(t *testing.T) {
+	violations := []passwords.Violation{{Code: passwords.ViolationTooShort, Message: "Password must be at least 8 characters long"}}
+	expectedResponse := passwords.NewPolicyErrorResponse(violations)
+	cur := users.CreateUserRequest{
+		Username: "mock_username",
+		Password: "P@s1",
+		Email:    "test@email.com",
+	}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/users", "POST", cur)
+
+	repo := &user_mocks.UserRepository{}
+	policy := mockPasswordPolicy(violations, 0)
+
+	uh := users.UserHandler{
+		Repo:           repo,
+		PasswordPolicy: policy,
+	}
+
+	uh.Create(ctx)
+
+	var actualResponse passwords.PolicyErrorResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	policy.AssertCalled(t, "Check", "P@s1", passwords.Subject{Username: "mock_username", Email: "test@email.com"})
+	repo.AssertNotCalled(t, "Create", mock.AnythingOfType("*users.User"))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, expectedResponse, actualResponse)
+}
+
+func TestUserHandler_ChangePassword_ShouldCheckPasswordHistory(t *testing.T) {
+	violations := []passwords.Violation{{Code: passwords.ViolationRecentlyUsed, Message: "Password must differ from your last 3 passwords"}}
+	cpr := users.ChangePasswordRequest{
+		CurrentPassword: "mockPassword@123",
+		NewPassword:     "mockPassword@1234",
+	}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/users/me/password", "PATCH", cpr)
+
+	repo := &user_mocks.UserRepository{}
+	hasher := &utils_mocks.PasswordHasher{}
+	policy := mockPasswordPolicy(violations, 3)
+
+	repo.On("FindById", "mock_id", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*users.User)
+		arg.Id = "mock_id"
+		arg.Username = "mock_username"
+		arg.Password = "HashedPassword"
+	})
+	repo.On("FetchPasswordHistory", "mock_id", 2, mock.AnythingOfType("*[]users.PasswordHistory")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(2).(*[]users.PasswordHistory)
+		*arg = []users.PasswordHistory{{Hash: "OldHash1"}, {Hash: "OldHash2"}}
+	})
+	hasher.On("ComparePassword", "mockPassword@123", "HashedPassword").Return(true)
+
+	ctx.Set("user_id", "mock_id")
+
+	uh := users.UserHandler{
+		Repo:           repo,
+		PasswordHasher: hasher,
+		PasswordPolicy: policy,
+	}
+
+	uh.ChangePassword(ctx)
+
+	policy.AssertCalled(t, "Check", "mockPassword@1234", passwords.Subject{
+		Username:       "mock_username",
+		PreviousHashes: []string{"HashedPassword", "OldHash1", "OldHash2"},
+	})
+	repo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func mockPasswordPolicy(violations []passwords.Violation, historySize int) *passwords_mocks.Checker {
+	policy := &passwords_mocks.Checker{}
+	policy.On("Check", mock.AnythingOfType("string"), mock.AnythingOfType("passwords.Subject")).Return(violations)
+	policy.On("HistorySize").Return(historySize)
+	return policy
+}
+
 func mockUser() *users.User {
 	return &users.User{
 		Id:        "mock_id",
@@ -12,6 +12,30 @@ type UserRepository struct {
 	mock.Mock
 }
 
+// CountVaults provides a mock function with given fields: userId
+func (_m *UserRepository) CountVaults(userId string) (int64, error) {
+	ret := _m.Called(userId)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int64, error)); ok {
+		return rf(userId)
+	}
+	if rf, ok := ret.Get(0).(func(string) int64); ok {
+		r0 = rf(userId)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: u
 func (_m *UserRepository) Create(u *users.User) error {
 	ret := _m.Called(u)
@@ -50,6 +74,20 @@ func (_m *UserRepository) EmailAlreadyExists(email string) (bool, error) {
 	return r0, r1
 }
 
+// FetchPasswordHistory provides a mock function with given fields: userId, limit, history
+func (_m *UserRepository) FetchPasswordHistory(userId string, limit int, history *[]users.PasswordHistory) error {
+	ret := _m.Called(userId, limit, history)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int, *[]users.PasswordHistory) error); ok {
+		r0 = rf(userId, limit, history)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Find provides a mock function with given fields: username, u
 func (_m *UserRepository) Find(username string, u *users.User) error {
 	ret := _m.Called(username, u)
@@ -78,6 +116,30 @@ func (_m *UserRepository) FindById(id string, u *users.User) error {
 	return r0
 }
 
+// Search provides a mock function with given fields: query, offset, limit, matches
+func (_m *UserRepository) Search(query string, offset int, limit int, matches *[]users.User) (int64, error) {
+	ret := _m.Called(query, offset, limit, matches)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int, int, *[]users.User) (int64, error)); ok {
+		return rf(query, offset, limit, matches)
+	}
+	if rf, ok := ret.Get(0).(func(string, int, int, *[]users.User) int64); ok {
+		r0 = rf(query, offset, limit, matches)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int, int, *[]users.User) error); ok {
+		r1 = rf(query, offset, limit, matches)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: u
 func (_m *UserRepository) Update(u *users.User) error {
 	ret := _m.Called(u)
@@ -92,6 +154,20 @@ func (_m *UserRepository) Update(u *users.User) error {
 	return r0
 }
 
+// UpdatePassword provides a mock function with given fields: u, replacedHash, keep
+func (_m *UserRepository) UpdatePassword(u *users.User, replacedHash string, keep int) error {
+	ret := _m.Called(u, replacedHash, keep)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*users.User, string, int) error); ok {
+		r0 = rf(u, replacedHash, keep)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UsernameAlreadyExists provides a mock function with given fields: username
 func (_m *UserRepository) UsernameAlreadyExists(username string) (bool, error) {
 	ret := _m.Called(username)
@@ -20,7 +20,7 @@ func RegisterMockValidations() {
 			validator: alwaysValid,
 		},
 		{
-			name:      "password",
+			name:      "user_role",
 			validator: alwaysValid,
 		},
 	}
@@ -3,10 +3,39 @@ package users
 import "time"
 
 type User struct {
-	Id        string    `json:"id" gorm:"primaryKey"`
-	Username  string    `json:"username" gorm:"unique;notNull"`
-	Email     string    `json:"email" gorm:"unique;notNull"`
-	Password  string    `gorm:"notNull"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Id                    string     `json:"id" gorm:"primaryKey"`
+	Username              string     `json:"username" gorm:"unique;notNull"`
+	Email                 string     `json:"email" gorm:"unique;notNull"`
+	Password              string     `gorm:"notNull"`
+	Role                  Role       `json:"role" gorm:"notNull;default:USER"`
+	Disabled              bool       `json:"disabled" gorm:"notNull;default:false"`
+	PasswordResetRequired bool       `json:"password_reset_required" gorm:"notNull;default:false"`
+	SessionVersion        int        `gorm:"notNull;default:0"`
+	LastLoginAt           *time.Time `json:"last_login_at"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// RevokeSessions invalidates every token issued to the user so far.
+func (u *User) RevokeSessions() {
+	u.SessionVersion++
+}
+
+// HasActiveSession reports whether a token carrying sessionVersion may still be used.
+func (u *User) HasActiveSession(sessionVersion int) bool {
+	return !u.Disabled && u.SessionVersion == sessionVersion
+}
+
+// PasswordHistory keeps hashes of passwords a user has replaced, so the
+// password policy can refuse recently used ones.
+type PasswordHistory struct {
+	Id        string `gorm:"primaryKey"`
+	UserRefer string `gorm:"index;notNull"`
+	User      User   `gorm:"foreignKey:UserRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Hash      string `gorm:"notNull"`
+	CreatedAt time.Time
 }
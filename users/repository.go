@@ -1,6 +1,9 @@
 package users
 
 import (
+	"strings"
+
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -11,6 +14,10 @@ type UserRepository interface {
 	Update(u *User) error
 	UsernameAlreadyExists(username string) (bool, error)
 	EmailAlreadyExists(email string) (bool, error)
+	Search(query string, offset int, limit int, matches *[]User) (int64, error)
+	CountVaults(userId string) (int64, error)
+	FetchPasswordHistory(userId string, limit int, history *[]PasswordHistory) error
+	UpdatePassword(u *User, replacedHash string, keep int) error
 }
 
 type UserRepositoryImpl struct {
@@ -44,3 +51,64 @@ func (ur *UserRepositoryImpl) EmailAlreadyExists(email string) (bool, error) {
 	err := ur.Db.Raw("SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", email).Scan(&Exists).Error
 	return Exists, err
 }
+
+func (ur *UserRepositoryImpl) Search(query string, offset int, limit int, matches *[]User) (int64, error) {
+	q := ur.Db.Model(&User{})
+	if query != "" {
+		pattern := "%" + strings.ToLower(query) + "%"
+		q = q.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	err := q.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(matches).Error
+	return total, err
+}
+
+func (ur *UserRepositoryImpl) CountVaults(userId string) (int64, error) {
+	var count int64
+	err := ur.Db.Raw("SELECT COUNT(*) FROM vaults WHERE user_refer = ? AND deleted_at IS NULL", userId).Scan(&count).Error
+	return count, err
+}
+
+func (ur *UserRepositoryImpl) FetchPasswordHistory(userId string, limit int, history *[]PasswordHistory) error {
+	return ur.Db.Where("user_refer = ?", userId).Order("created_at DESC, id DESC").Limit(limit).Find(history).Error
+}
+
+// UpdatePassword saves u and records replacedHash in its password history,
+// keeping only the newest keep entries.
+func (ur *UserRepositoryImpl) UpdatePassword(u *User, replacedHash string, keep int) error {
+	return ur.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(u).Error; err != nil {
+			return err
+		}
+
+		if keep <= 0 {
+			return tx.Where("user_refer = ?", u.Id).Delete(&PasswordHistory{}).Error
+		}
+
+		if err := tx.Create(&PasswordHistory{
+			Id:        uuid.NewString(),
+			UserRefer: u.Id,
+			Hash:      replacedHash,
+		}).Error; err != nil {
+			return err
+		}
+
+		var stale []string
+		if err := tx.Model(&PasswordHistory{}).
+			Where("user_refer = ?", u.Id).
+			Order("created_at DESC, id DESC").
+			Offset(keep).
+			Pluck("id", &stale).Error; err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", stale).Delete(&PasswordHistory{}).Error
+	})
+}
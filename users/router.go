@@ -1,26 +1,50 @@
 package users
 
 import (
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/common"
 	"github.com/adarsh-a-tw/passwordly/middleware"
+	"github.com/adarsh-a-tw/passwordly/passwords"
 	"github.com/adarsh-a-tw/passwordly/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(r *gin.Engine, db *gorm.DB) {
+func SetupRoutes(r *gin.Engine, db *gorm.DB, breaches passwords.BreachChecker) {
+	repo := &UserRepositoryImpl{
+		Db: db,
+	}
+	sv := &SessionValidatorImpl{Repo: repo}
+	auditRepo := &audit.EventRepositoryImpl{Db: db, Chain: audit.NewChain(common.Cfg.AuditKey)}
+
 	// Unauthenticated Routes
 	urg := r.Group("/api/v1/users")
 
 	// Authenticated Routes
 	rg := r.Group("/api/v1/users")
-	rg.Use(middleware.TokenAuthMiddleware)
+	rg.Use(middleware.TokenAuthMiddleware(sv))
+
+	// Authenticated Routes open to accounts that must reset their password
+	prg := r.Group("/api/v1/users")
+	prg.Use(middleware.PasswordResetAuthMiddleware(sv))
+
+	// Admin Routes
+	arg := r.Group("/api/v1/admin/users")
+	arg.Use(middleware.TokenAuthMiddleware(sv), middleware.RoleAuthMiddleware(string(RoleAdmin)))
+
+	hasher := utils.NewPasswordHasher()
 
 	uh := UserHandler{
-		Repo: &UserRepositoryImpl{
-			Db: db,
-		},
+		Repo:           repo,
 		AuthProvider:   &utils.AuthProviderImpl{},
-		PasswordHasher: &utils.PasswordHasherImpl{},
+		PasswordHasher: hasher,
+		PasswordPolicy: passwords.NewPolicyChecker(hasher, breaches),
+		Audit:          auditRepo,
+	}
+
+	ah := AdminHandler{
+		Repo:  repo,
+		Audit: auditRepo,
 	}
 
 	urg.POST("", uh.Create)
@@ -28,5 +52,13 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	urg.POST("/access-token", uh.FetchAccessToken)
 
 	rg.GET("/me", uh.FetchUser)
-	rg.PATCH("/me/password", uh.ChangePassword)
+	prg.PATCH("/me/password", uh.ChangePassword)
+
+	arg.GET("", ah.ListUsers)
+	arg.GET("/:id", ah.FetchAccountMetadata)
+	arg.PATCH("/:id/role", ah.UpdateRole)
+	arg.POST("/:id/disable", ah.DisableUser)
+	arg.POST("/:id/enable", ah.EnableUser)
+	arg.POST("/:id/password-reset", ah.ForcePasswordReset)
+	arg.POST("/:id/sessions/revoke", ah.RevokeSessions)
 }
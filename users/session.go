@@ -0,0 +1,30 @@
+package users
+
+import (
+	"errors"
+
+	"github.com/adarsh-a-tw/passwordly/middleware"
+	"gorm.io/gorm"
+)
+
+// SessionValidatorImpl lets TokenAuthMiddleware reject tokens of disabled
+// accounts and of sessions revoked after the token was issued, and hold back
+// accounts that must reset their password.
+type SessionValidatorImpl struct {
+	Repo UserRepository
+}
+
+func (sv *SessionValidatorImpl) ValidateSession(userId string, sessionVersion int) (middleware.Session, error) {
+	var u User
+	if err := sv.Repo.FindById(userId, &u); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return middleware.Session{}, nil
+		}
+		return middleware.Session{}, err
+	}
+	return middleware.Session{
+		Role:                  string(u.Role),
+		Active:                u.HasActiveSession(sessionVersion),
+		PasswordResetRequired: u.PasswordResetRequired,
+	}, nil
+}
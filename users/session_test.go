@@ -0,0 +1,49 @@
+package users_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/middleware"
+	"github.com/adarsh-a-tw/passwordly/users"
+	user_mocks "github.com/adarsh-a-tw/passwordly/users/mocks"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTokenAuthMiddleware_ShouldOnlyAllowPasswordChangeUntilResetIsDone(t *testing.T) {
+	common.Cfg.JwtSecretKey = "mock_secret"
+	u := mockUser()
+	u.PasswordResetRequired = true
+
+	repo := &user_mocks.UserRepository{}
+	repo.On("FindById", "mock_id", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*users.User) = *u
+	})
+	sv := &users.SessionValidatorImpl{Repo: repo}
+
+	r := gin.New()
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusOK) }
+	r.GET("/api/v1/users/me", middleware.TokenAuthMiddleware(sv), ok)
+	r.PATCH("/api/v1/users/me/password", middleware.PasswordResetAuthMiddleware(sv), ok)
+
+	tokens, err := (&utils.AuthProviderImpl{}).GenerateTokenPair(u.Id, u.SessionVersion)
+	assert.NoError(t, err)
+	request := func(method string, url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusForbidden, request("GET", "/api/v1/users/me").Code)
+	assert.Equal(t, http.StatusOK, request("PATCH", "/api/v1/users/me/password").Code)
+
+	u.PasswordResetRequired = false
+	assert.Equal(t, http.StatusOK, request("GET", "/api/v1/users/me").Code)
+}
@@ -1,41 +1,18 @@
 package users
 
 import (
-	"regexp"
-
 	"github.com/adarsh-a-tw/passwordly/utils"
 	"github.com/gin-gonic/gin/binding"
 	validator "github.com/go-playground/validator/v10"
 )
 
-func validatePassword(fl validator.FieldLevel) bool {
-	password, ok := fl.Field().Interface().(string)
+func validateRole(fl validator.FieldLevel) bool {
+	role, ok := fl.Field().Interface().(Role)
 	if !ok {
 		return false
 	}
-	if len(password) < 8 {
-		return false
-	}
-	done, err := regexp.MatchString("([a-z])+", password)
-	if err != nil || !done {
-		return false
-	}
-
-	done, err = regexp.MatchString("([A-Z])+", password)
-	if err != nil || !done {
-		return false
-	}
-	done, err = regexp.MatchString("([0-9])+", password)
-	if err != nil || !done {
-		return false
-	}
-
-	done, err = regexp.MatchString("([!@#$%^&*.?-])+", password)
-	if err != nil || !done {
-		return false
-	}
 
-	return true
+	return role.IsValid()
 }
 
 func RegisterValidations() {
@@ -51,8 +28,8 @@ func RegisterValidations() {
 			validator: utils.RegexValidator(usernamePattern),
 		},
 		{
-			name:      "password",
-			validator: validatePassword,
+			name:      "user_role",
+			validator: validateRole,
 		},
 	}
 
@@ -36,26 +36,6 @@ func TestUserValidator_CreateUserRequest(t *testing.T) {
 			`{"username": "test_12345", "password": "P@ssword123", "email": "@mail.com"}`,
 			"Key: 'CreateUserRequest.Email' Error:Field validation for 'Email' failed on the 'email' tag",
 		},
-		{
-			`{"username": "test_123", "password": "P@", "email": "test@mail.com"}`,
-			"Key: 'CreateUserRequest.Password' Error:Field validation for 'Password' failed on the 'password' tag",
-		},
-		{
-			`{"username": "test_123", "password": "12345678910", "email": "test@mail.com"}`,
-			"Key: 'CreateUserRequest.Password' Error:Field validation for 'Password' failed on the 'password' tag",
-		},
-		{
-			`{"username": "test_123", "password": "abcdefghijkl", "email": "test@mail.com"}`,
-			"Key: 'CreateUserRequest.Password' Error:Field validation for 'Password' failed on the 'password' tag",
-		},
-		{
-			`{"username": "test_123", "password": "ABCDEfghij", "email": "test@mail.com"}`,
-			"Key: 'CreateUserRequest.Password' Error:Field validation for 'Password' failed on the 'password' tag",
-		},
-		{
-			`{"username": "test_123", "password": "ABCDEfghij1234", "email": "test@mail.com"}`,
-			"Key: 'CreateUserRequest.Password' Error:Field validation for 'Password' failed on the 'password' tag",
-		},
 	}
 
 	for _, testCase := range testCases {
@@ -82,8 +62,8 @@ func TestUserValidator_ChangePasswordRequest(t *testing.T) {
 			"",
 		},
 		{
-			`{"current_password": "P@ssword123", "new_password": "P@ssword"}`,
-			"Key: 'ChangePasswordRequest.NewPassword' Error:Field validation for 'NewPassword' failed on the 'password' tag",
+			`{"current_password": "P@ssword123"}`,
+			"Key: 'ChangePasswordRequest.NewPassword' Error:Field validation for 'NewPassword' failed on the 'required' tag",
 		},
 	}
 
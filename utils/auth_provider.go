@@ -10,9 +10,10 @@ import (
 )
 
 type AuthProvider interface {
-	GenerateTokenPair(uid string) (tokenPair AuthTokenPair, err error)
+	GenerateTokenPair(uid string, sessionVersion int) (tokenPair AuthTokenPair, err error)
 	GenerateAccessToken(refreshToken string) (accessToken string, err error)
-	VerifyAccessToken(accessToken string) (uid string, err error)
+	VerifyAccessToken(accessToken string) (claims AuthTokenClaims, err error)
+	VerifyRefreshToken(refreshToken string) (claims AuthTokenClaims, err error)
 }
 
 type AuthTokenPair struct {
@@ -20,6 +21,13 @@ type AuthTokenPair struct {
 	RefreshToken string
 }
 
+// AuthTokenClaims are the verified contents of an auth token. SessionVersion
+// lets the server revoke every token issued before it was last bumped.
+type AuthTokenClaims struct {
+	UserId         string
+	SessionVersion int
+}
+
 type AuthTokenType string
 
 const (
@@ -28,22 +36,23 @@ const (
 )
 
 type parsedAuthToken struct {
-	uid       string
-	tokenType AuthTokenType
+	uid            string
+	tokenType      AuthTokenType
+	sessionVersion int
 }
 
 type AuthProviderImpl struct{}
 
-func (ap *AuthProviderImpl) GenerateTokenPair(uid string) (tokenPair AuthTokenPair, err error) {
+func (ap *AuthProviderImpl) GenerateTokenPair(uid string, sessionVersion int) (tokenPair AuthTokenPair, err error) {
 	authTokenPair := AuthTokenPair{}
 
-	if accessToken, err := generateJwtTokenString(uid, AccessToken, time.Now().Add(10*time.Minute)); err != nil {
+	if accessToken, err := generateJwtTokenString(uid, sessionVersion, AccessToken, time.Now().Add(10*time.Minute)); err != nil {
 		return authTokenPair, err
 	} else {
 		authTokenPair.AccessToken = accessToken
 	}
 
-	if refreshToken, err := generateJwtTokenString(uid, RefreshToken, time.Now().Add(24*time.Hour)); err != nil {
+	if refreshToken, err := generateJwtTokenString(uid, sessionVersion, RefreshToken, time.Now().Add(24*time.Hour)); err != nil {
 		return authTokenPair, err
 	} else {
 		authTokenPair.RefreshToken = refreshToken
@@ -63,30 +72,39 @@ func (ap *AuthProviderImpl) GenerateAccessToken(refreshToken string) (accessToke
 		return
 	}
 
-	accessToken, err = generateJwtTokenString(pat.uid, AccessToken, time.Now().Add(10*time.Minute))
+	accessToken, err = generateJwtTokenString(pat.uid, pat.sessionVersion, AccessToken, time.Now().Add(10*time.Minute))
 	return
 }
 
-func (ap *AuthProviderImpl) VerifyAccessToken(accessToken string) (uid string, err error) {
+func (ap *AuthProviderImpl) VerifyAccessToken(accessToken string) (claims AuthTokenClaims, err error) {
+	return verifyJwtTokenString(accessToken, AccessToken)
+}
+
+func (ap *AuthProviderImpl) VerifyRefreshToken(refreshToken string) (claims AuthTokenClaims, err error) {
+	return verifyJwtTokenString(refreshToken, RefreshToken)
+}
+
+func verifyJwtTokenString(tokenStr string, tokenType AuthTokenType) (claims AuthTokenClaims, err error) {
 	var pat parsedAuthToken
-	if pat, err = parseJwtTokenString(accessToken); err != nil {
+	if pat, err = parseJwtTokenString(tokenStr); err != nil {
 		return
 	}
 
-	if pat.tokenType == RefreshToken {
+	if pat.tokenType != tokenType {
 		err = errors.New("Invalid AuthToken Type")
 		return
 	}
 
-	uid = pat.uid
+	claims = AuthTokenClaims{UserId: pat.uid, SessionVersion: pat.sessionVersion}
 	return
 }
 
-func generateJwtTokenString(uid string, tokenType AuthTokenType, ttl time.Time) (string, error) {
+func generateJwtTokenString(uid string, sessionVersion int, tokenType AuthTokenType, ttl time.Time) (string, error) {
 	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": uid,
-		"type":    tokenType,
-		"exp":     jwt.NewNumericDate(ttl),
+		"user_id":         uid,
+		"type":            tokenType,
+		"session_version": sessionVersion,
+		"exp":             jwt.NewNumericDate(ttl),
 	}).SignedString([]byte(common.Cfg.JwtSecretKey))
 }
 
@@ -105,6 +123,10 @@ func parseJwtTokenString(tokenStr string) (pat parsedAuthToken, err error) {
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		pat.uid = fmt.Sprintf("%s", claims["user_id"])
+		// Tokens issued before session versioning carry no claim and map to version 0.
+		if sv, ok := claims["session_version"].(float64); ok {
+			pat.sessionVersion = int(sv)
+		}
 		pat.tokenType, err = getAuthType(fmt.Sprintf("%s", claims["type"]))
 		return
 	}
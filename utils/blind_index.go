@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// blindIndexLength is how many bytes of the HMAC are kept. Collisions only
+// cost a false positive in search results, which 128 bits make negligible.
+const blindIndexLength = 16
+
+// BlindIndex turns search terms into keyed hashes, so that an index can be
+// queried for exact terms without storing the terms themselves.
+type BlindIndex struct {
+	key []byte
+}
+
+// NewBlindIndex derives a dedicated key from secret, which may be shared
+// with other uses such as the encryption key.
+func NewBlindIndex(secret string) *BlindIndex {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("passwordly blind index"))
+	return &BlindIndex{key: mac.Sum(nil)}
+}
+
+func (bi *BlindIndex) Token(term string) string {
+	mac := hmac.New(sha256.New, bi.key)
+	mac.Write([]byte(term))
+	return hex.EncodeToString(mac.Sum(nil)[:blindIndexLength])
+}
@@ -36,23 +36,23 @@ func (_m *AuthProvider) GenerateAccessToken(refreshToken string) (string, error)
 	return r0, r1
 }
 
-// GenerateTokenPair provides a mock function with given fields: uid
-func (_m *AuthProvider) GenerateTokenPair(uid string) (utils.AuthTokenPair, error) {
-	ret := _m.Called(uid)
+// GenerateTokenPair provides a mock function with given fields: uid, sessionVersion
+func (_m *AuthProvider) GenerateTokenPair(uid string, sessionVersion int) (utils.AuthTokenPair, error) {
+	ret := _m.Called(uid, sessionVersion)
 
 	var r0 utils.AuthTokenPair
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (utils.AuthTokenPair, error)); ok {
-		return rf(uid)
+	if rf, ok := ret.Get(0).(func(string, int) (utils.AuthTokenPair, error)); ok {
+		return rf(uid, sessionVersion)
 	}
-	if rf, ok := ret.Get(0).(func(string) utils.AuthTokenPair); ok {
-		r0 = rf(uid)
+	if rf, ok := ret.Get(0).(func(string, int) utils.AuthTokenPair); ok {
+		r0 = rf(uid, sessionVersion)
 	} else {
 		r0 = ret.Get(0).(utils.AuthTokenPair)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(uid)
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(uid, sessionVersion)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -61,18 +61,18 @@ func (_m *AuthProvider) GenerateTokenPair(uid string) (utils.AuthTokenPair, erro
 }
 
 // VerifyAccessToken provides a mock function with given fields: accessToken
-func (_m *AuthProvider) VerifyAccessToken(accessToken string) (string, error) {
+func (_m *AuthProvider) VerifyAccessToken(accessToken string) (utils.AuthTokenClaims, error) {
 	ret := _m.Called(accessToken)
 
-	var r0 string
+	var r0 utils.AuthTokenClaims
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+	if rf, ok := ret.Get(0).(func(string) (utils.AuthTokenClaims, error)); ok {
 		return rf(accessToken)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
+	if rf, ok := ret.Get(0).(func(string) utils.AuthTokenClaims); ok {
 		r0 = rf(accessToken)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(utils.AuthTokenClaims)
 	}
 
 	if rf, ok := ret.Get(1).(func(string) error); ok {
@@ -84,6 +84,30 @@ func (_m *AuthProvider) VerifyAccessToken(accessToken string) (string, error) {
 	return r0, r1
 }
 
+// VerifyRefreshToken provides a mock function with given fields: refreshToken
+func (_m *AuthProvider) VerifyRefreshToken(refreshToken string) (utils.AuthTokenClaims, error) {
+	ret := _m.Called(refreshToken)
+
+	var r0 utils.AuthTokenClaims
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (utils.AuthTokenClaims, error)); ok {
+		return rf(refreshToken)
+	}
+	if rf, ok := ret.Get(0).(func(string) utils.AuthTokenClaims); ok {
+		r0 = rf(refreshToken)
+	} else {
+		r0 = ret.Get(0).(utils.AuthTokenClaims)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(refreshToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewAuthProvider interface {
 	mock.TestingT
 	Cleanup(func())
@@ -24,16 +24,40 @@ func (_m *PasswordHasher) ComparePassword(rawPwd string, hashedPwd string) bool
 }
 
 // HashPassword provides a mock function with given fields: pwd
-func (_m *PasswordHasher) HashPassword(pwd string) string {
+func (_m *PasswordHasher) HashPassword(pwd string) (string, error) {
 	ret := _m.Called(pwd)
 
 	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(pwd)
+	}
 	if rf, ok := ret.Get(0).(func(string) string); ok {
 		r0 = rf(pwd)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pwd)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NeedsRehash provides a mock function with given fields: hashedPwd
+func (_m *PasswordHasher) NeedsRehash(hashedPwd string) bool {
+	ret := _m.Called(hashedPwd)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(hashedPwd)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
 	return r0
 }
 
@@ -1,27 +1,169 @@
 package utils
 
 import (
-	"log"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/adarsh-a-tw/passwordly/common"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type PasswordHasher interface {
-	HashPassword(pwd string) string
+	HashPassword(pwd string) (string, error)
 	ComparePassword(rawPwd string, hashedPwd string) bool
+	NeedsRehash(hashedPwd string) bool
 }
 
-type PasswordHasherImpl struct{}
+// Argon2Params are the argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
 
-func (ph *PasswordHasherImpl) HashPassword(pwd string) string {
-	hash, err := bcrypt.GenerateFromPassword([]byte(pwd), bcrypt.DefaultCost)
-	if err != nil {
-		log.Fatalln("Hashing failed")
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var ErrInvalidHash = errors.New("Invalid password hash")
+
+// PasswordHasherImpl hashes new passwords with argon2id, encoded in the PHC
+// string format ($argon2id$v=19$m=...,t=...,p=...$salt$hash) so that the
+// parameters travel with the hash. Existing bcrypt hashes still verify and are
+// reported by NeedsRehash so callers can upgrade them on the next login.
+type PasswordHasherImpl struct {
+	Params Argon2Params
+}
+
+func NewPasswordHasher() PasswordHasher {
+	return &PasswordHasherImpl{
+		Params: Argon2Params{
+			Memory:      common.Cfg.Argon2Memory,
+			Iterations:  common.Cfg.Argon2Iterations,
+			Parallelism: common.Cfg.Argon2Parallelism,
+			SaltLength:  DefaultArgon2Params.SaltLength,
+			KeyLength:   DefaultArgon2Params.KeyLength,
+		},
 	}
-	return string(hash)
+}
+
+func (ph *PasswordHasherImpl) HashPassword(pwd string) (string, error) {
+	p := ph.params()
+
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(pwd), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.Memory,
+		p.Iterations,
+		p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
 func (ph *PasswordHasherImpl) ComparePassword(rawPwd string, hashedPwd string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(rawPwd))
-	return err == nil
+	if isBcryptHash(hashedPwd) {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(rawPwd)) == nil
+	}
+
+	p, salt, key, err := decodeArgon2Hash(hashedPwd)
+	if err != nil {
+		return false
+	}
+
+	otherKey := argon2.IDKey([]byte(rawPwd), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return subtle.ConstantTimeCompare(key, otherKey) == 1
+}
+
+func (ph *PasswordHasherImpl) NeedsRehash(hashedPwd string) bool {
+	if isBcryptHash(hashedPwd) {
+		return true
+	}
+
+	p, _, _, err := decodeArgon2Hash(hashedPwd)
+	if err != nil {
+		return false
+	}
+
+	current := ph.params()
+	return p.Memory != current.Memory ||
+		p.Iterations != current.Iterations ||
+		p.Parallelism != current.Parallelism ||
+		p.KeyLength != current.KeyLength
+}
+
+func (ph *PasswordHasherImpl) params() Argon2Params {
+	p := ph.Params
+	if p.Memory == 0 {
+		p.Memory = DefaultArgon2Params.Memory
+	}
+	if p.Iterations == 0 {
+		p.Iterations = DefaultArgon2Params.Iterations
+	}
+	if p.Parallelism == 0 {
+		p.Parallelism = DefaultArgon2Params.Parallelism
+	}
+	if p.SaltLength == 0 {
+		p.SaltLength = DefaultArgon2Params.SaltLength
+	}
+	if p.KeyLength == 0 {
+		p.KeyLength = DefaultArgon2Params.KeyLength
+	}
+	return p
+}
+
+func isBcryptHash(hashedPwd string) bool {
+	return strings.HasPrefix(hashedPwd, "$2a$") ||
+		strings.HasPrefix(hashedPwd, "$2b$") ||
+		strings.HasPrefix(hashedPwd, "$2y$")
+}
+
+func decodeArgon2Hash(hashedPwd string) (p Argon2Params, salt []byte, key []byte, err error) {
+	parts := strings.Split(hashedPwd, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		err = ErrInvalidHash
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return
+	}
+	if version != argon2.Version {
+		err = ErrInvalidHash
+		return
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return
 }
@@ -0,0 +1,54 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testParams = utils.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestPasswordHasher_ShouldHashAndCompareWithArgon2id(t *testing.T) {
+	ph := &utils.PasswordHasherImpl{Params: testParams}
+
+	hash, err := ph.HashPassword("P@ssword123")
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	assert.True(t, ph.ComparePassword("P@ssword123", hash))
+	assert.False(t, ph.ComparePassword("P@ssword124", hash))
+	assert.False(t, ph.NeedsRehash(hash))
+}
+
+func TestPasswordHasher_ShouldVerifyLegacyBcryptHashes(t *testing.T) {
+	ph := &utils.PasswordHasherImpl{Params: testParams}
+	legacy, err := bcrypt.GenerateFromPassword([]byte("P@ssword123"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	assert.True(t, ph.ComparePassword("P@ssword123", string(legacy)))
+	assert.False(t, ph.ComparePassword("wrong", string(legacy)))
+	assert.True(t, ph.NeedsRehash(string(legacy)))
+}
+
+func TestPasswordHasher_ShouldRequireRehashWhenParametersChange(t *testing.T) {
+	old := &utils.PasswordHasherImpl{Params: testParams}
+	hash, err := old.HashPassword("P@ssword123")
+	assert.NoError(t, err)
+
+	current := &utils.PasswordHasherImpl{Params: utils.Argon2Params{Memory: 2048, Iterations: 1, Parallelism: 1}}
+
+	assert.True(t, current.ComparePassword("P@ssword123", hash))
+	assert.True(t, current.NeedsRehash(hash))
+}
+
+func TestPasswordHasher_ShouldRejectMalformedHashes(t *testing.T) {
+	ph := &utils.PasswordHasherImpl{Params: testParams}
+
+	for _, hash := range []string{"", "plain", "$argon2id$v=19$m=1024$abc$def", "$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5"} {
+		assert.False(t, ph.ComparePassword("P@ssword123", hash))
+		assert.False(t, ph.NeedsRehash(hash))
+	}
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Files are encrypted in fixed-size chunks, each sealed with AES-256-GCM
+// under a nonce made of a random per-file prefix, the chunk counter and a
+// flag marking the final chunk. Reordered, dropped or truncated chunks
+// therefore fail to decrypt. The final chunk is always shorter than
+// StreamChunkSize, and empty when the plaintext fills every chunk.
+const (
+	StreamChunkSize = 64 * 1024
+	DataKeySize     = 32
+
+	streamVersion    = 1
+	streamPrefixSize = 7
+	streamHeaderSize = 1 + streamPrefixSize
+	streamTagSize    = 16
+)
+
+var ErrCorruptStream = errors.New("Encrypted stream is corrupt or truncated")
+
+// NewDataKey returns a random key for encrypting a single file.
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, DataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// EncryptedSize is the exact length EncryptStream produces for plainSize
+// bytes of input.
+func EncryptedSize(plainSize int64) int64 {
+	chunks := plainSize/StreamChunkSize + 1
+	return streamHeaderSize + plainSize + chunks*streamTagSize
+}
+
+// EncryptStream encrypts src into dst with key.
+func EncryptStream(dst io.Writer, src io.Reader, key []byte) error {
+	gcm, err := streamCipher(key)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, streamHeaderSize)
+	header[0] = streamVersion
+	if _, err := io.ReadFull(rand.Reader, header[1:]); err != nil {
+		return err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+
+	buf := make([]byte, StreamChunkSize)
+	sealed := make([]byte, 0, StreamChunkSize+streamTagSize)
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(src, buf)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		sealed = gcm.Seal(sealed[:0], streamNonce(header[1:], counter, last), buf[:n], nil)
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		if counter == ^uint32(0) {
+			return errors.New("Stream is too long to encrypt")
+		}
+	}
+}
+
+// DecryptStream decrypts src into dst with key. Each chunk is written only
+// once it has been authenticated, but a stream that turns out to be corrupt
+// part way through has still written the chunks before it.
+func DecryptStream(dst io.Writer, src io.Reader, key []byte) error {
+	gcm, err := streamCipher(key)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(src, header); err != nil || header[0] != streamVersion {
+		return ErrCorruptStream
+	}
+
+	buf := make([]byte, StreamChunkSize+streamTagSize)
+	plain := make([]byte, 0, StreamChunkSize)
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(src, buf)
+		last := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return err
+		}
+		if plain, err = gcm.Open(plain[:0], streamNonce(header[1:], counter, last), buf[:n], nil); err != nil {
+			return ErrCorruptStream
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+func streamCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != DataKeySize {
+		return nil, errors.New("Data key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func streamNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[streamPrefixSize:], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
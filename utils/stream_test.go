@@ -0,0 +1,47 @@
+package utils_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptStream_ShouldRoundTripAcrossChunkBoundaries(t *testing.T) {
+	key, err := utils.NewDataKey()
+	assert.NoError(t, err)
+
+	for _, size := range []int{0, 1, utils.StreamChunkSize, 3*utils.StreamChunkSize + 17} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		var encrypted bytes.Buffer
+		assert.NoError(t, utils.EncryptStream(&encrypted, bytes.NewReader(plain), key))
+		assert.Equal(t, utils.EncryptedSize(int64(size)), int64(encrypted.Len()), size)
+
+		var decrypted bytes.Buffer
+		assert.NoError(t, utils.DecryptStream(&decrypted, &encrypted, key))
+		assert.True(t, bytes.Equal(plain, decrypted.Bytes()), size)
+	}
+}
+
+func TestDecryptStream_ShouldRejectTamperedAndTruncatedStreams(t *testing.T) {
+	key, _ := utils.NewDataKey()
+	plain := make([]byte, 2*utils.StreamChunkSize)
+	var encrypted bytes.Buffer
+	assert.NoError(t, utils.EncryptStream(&encrypted, bytes.NewReader(plain), key))
+	data := encrypted.Bytes()
+
+	tampered := append([]byte{}, data...)
+	tampered[len(tampered)/2] ^= 1
+	assert.ErrorIs(t, utils.DecryptStream(&bytes.Buffer{}, bytes.NewReader(tampered), key), utils.ErrCorruptStream)
+
+	// Dropping the empty final chunk leaves only whole chunks.
+	truncated := data[:len(data)-16]
+	assert.ErrorIs(t, utils.DecryptStream(&bytes.Buffer{}, bytes.NewReader(truncated), key), utils.ErrCorruptStream)
+
+	otherKey, _ := utils.NewDataKey()
+	assert.ErrorIs(t, utils.DecryptStream(&bytes.Buffer{}, bytes.NewReader(data), otherKey), utils.ErrCorruptStream)
+}
@@ -0,0 +1,292 @@
+package vaults
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/blobs"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// multipartOverhead is allowed on top of MaxSize for the multipart framing
+// around an uploaded file.
+const multipartOverhead = 64 * 1024
+
+// AttachmentHandler stores files attached to secrets. Every file is
+// encrypted with its own data key while streaming to the blob store; only
+// the encrypted data key is kept in the database.
+type AttachmentHandler struct {
+	Ep         utils.EncryptionProvider
+	Repo       AttachmentRepository
+	SecretRepo SecretRepository
+	VaultRepo  VaultRepository
+	Blobs      blobs.BlobStore
+	MaxSize    int64
+	Audit      audit.Recorder
+}
+
+func (ah *AttachmentHandler) UploadAttachment(ctx *gin.Context) {
+	vaultId, secretId, ok := requireSecret(ctx, ah.VaultRepo, ah.SecretRepo)
+	if !ok {
+		return
+	}
+
+	// The file is read straight from the request rather than through
+	// ctx.FormFile, which would spool large files to disk unencrypted.
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, ah.MaxSize+multipartOverhead)
+	part, err := filePart(ctx.Request, "file")
+	if err != nil {
+		if isTooLarge(err) {
+			ah.respondTooLarge(ctx)
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+	defer part.Close()
+
+	dataKey, err := utils.NewDataKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	wrappedKey, err := ah.Ep.Encrypt(string(dataKey))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	contentType := part.Header.Get("Content-Type")
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		contentType = "application/octet-stream"
+	}
+	a := Attachment{
+		Id:          uuid.NewString(),
+		SecretId:    secretId,
+		Name:        attachmentName(part.FileName()),
+		ContentType: contentType,
+		DataKey:     []byte(wrappedKey),
+		VaultRefer:  vaultId,
+	}
+	a.StorageKey = vaultId + "/" + a.Id
+
+	file := &uploadReader{r: part, limit: ah.MaxSize}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(utils.EncryptStream(pw, file, dataKey))
+	}()
+	err = ah.Blobs.Put(ctx.Request.Context(), a.StorageKey, pr, -1)
+	pr.CloseWithError(err)
+	if err != nil {
+		if isTooLarge(err) {
+			ah.respondTooLarge(ctx)
+			return
+		}
+		log.Printf("Could not store attachment %s: %v", a.Id, err)
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	a.Size = file.n
+
+	if err := ah.Repo.Create(&a); err != nil {
+		ah.deleteBlob(ctx, a)
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(ah.Audit, ctx, audit.Event{
+		Action:     audit.ActionAttachmentUpload,
+		TargetType: audit.TargetAttachment,
+		TargetId:   a.Id,
+		Detail:     fmt.Sprintf("secret=%s name=%q size=%d", secretId, a.Name, a.Size),
+	})
+
+	var resp AttachmentResponse
+	resp.load(a)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (ah *AttachmentHandler) FetchAttachments(ctx *gin.Context) {
+	_, secretId, ok := requireSecret(ctx, ah.VaultRepo, ah.SecretRepo)
+	if !ok {
+		return
+	}
+
+	var attachments []Attachment
+	if err := ah.Repo.FindAttachments(secretId, &attachments); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	resp := AttachmentListResponse{Attachments: make([]AttachmentResponse, len(attachments))}
+	for i, a := range attachments {
+		resp.Attachments[i].load(a)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// DownloadAttachment streams the decrypted file. Chunks are authenticated
+// before they are sent, so a corrupted blob cuts the download short rather
+// than delivering altered content.
+func (ah *AttachmentHandler) DownloadAttachment(ctx *gin.Context) {
+	a, ok := ah.findAttachment(ctx)
+	if !ok {
+		return
+	}
+
+	dataKey, err := ah.Ep.Decrypt(string(a.DataKey))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	blob, err := ah.Blobs.Get(ctx.Request.Context(), a.StorageKey)
+	if err != nil {
+		log.Printf("Could not read attachment %s: %v", a.Id, err)
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	defer blob.Close()
+
+	audit.Log(ah.Audit, ctx, audit.Event{
+		Action:     audit.ActionAttachmentDownload,
+		TargetType: audit.TargetAttachment,
+		TargetId:   a.Id,
+		Detail:     fmt.Sprintf("secret=%s name=%q", a.SecretId, a.Name),
+	})
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", a.ContentType)
+	header.Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	if err := utils.DecryptStream(ctx.Writer, blob, []byte(dataKey)); err != nil {
+		log.Printf("Could not decrypt attachment %s: %v", a.Id, err)
+		if !ctx.Writer.Written() {
+			header.Del("Content-Disposition")
+			header.Del("Content-Length")
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		ctx.Abort()
+	}
+}
+
+func (ah *AttachmentHandler) DeleteAttachment(ctx *gin.Context) {
+	a, ok := ah.findAttachment(ctx)
+	if !ok {
+		return
+	}
+
+	if err := ah.Repo.Delete(a); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	ah.deleteBlob(ctx, *a)
+
+	audit.Log(ah.Audit, ctx, audit.Event{
+		Action:     audit.ActionAttachmentDelete,
+		TargetType: audit.TargetAttachment,
+		TargetId:   a.Id,
+		Detail:     fmt.Sprintf("secret=%s name=%q", a.SecretId, a.Name),
+	})
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// private methods
+
+func (ah *AttachmentHandler) findAttachment(ctx *gin.Context) (*Attachment, bool) {
+	_, secretId, ok := requireSecret(ctx, ah.VaultRepo, ah.SecretRepo)
+	if !ok {
+		return nil, false
+	}
+
+	var a Attachment
+	if err := ah.Repo.FindAttachmentById(ctx.Param("attachmentId"), secretId, &a); err != nil {
+		handleGormError(ctx, err)
+		return nil, false
+	}
+	return &a, true
+}
+
+// deleteBlob removes a stored file. Failures are only logged: without its
+// data key the blob can no longer be decrypted.
+func (ah *AttachmentHandler) deleteBlob(ctx *gin.Context, a Attachment) {
+	if err := ah.Blobs.Delete(ctx.Request.Context(), a.StorageKey); err != nil {
+		log.Printf("Could not delete attachment blob %s: %v", a.StorageKey, err)
+	}
+}
+
+func (ah *AttachmentHandler) respondTooLarge(ctx *gin.Context) {
+	ctx.JSON(http.StatusRequestEntityTooLarge, common.ErrorResponse{
+		Message: fmt.Sprintf("Attachments are limited to %d bytes", ah.MaxSize),
+	})
+}
+
+// attachmentName keeps the base name of an uploaded file, as browsers may
+// send a full client-side path.
+func attachmentName(filename string) string {
+	name := filename[strings.LastIndexAny(filename, `/\`)+1:]
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:255], "")
+	}
+	if name == "" || name == "." || name == ".." {
+		return "attachment"
+	}
+	return name
+}
+
+// filePart returns the part of a multipart request holding the file named
+// name, skipping the parts before it without buffering them.
+func filePart(req *http.Request, name string) (*multipart.Part, error) {
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == name && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+var errAttachmentTooLarge = errors.New("Attachment is too large")
+
+// uploadReader counts what is read from an upload and fails once it grows
+// past limit, so an oversized file is never stored.
+type uploadReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (ur *uploadReader) Read(p []byte) (int, error) {
+	n, err := ur.r.Read(p)
+	ur.n += int64(n)
+	if ur.n > ur.limit {
+		return n, errAttachmentTooLarge
+	}
+	return n, err
+}
+
+func isTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge) || errors.Is(err, errAttachmentTooLarge)
+}
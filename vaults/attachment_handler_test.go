@@ -0,0 +1,150 @@
+package vaults_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/blobs"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	utils_mocks "github.com/adarsh-a-tw/passwordly/utils/mocks"
+	v "github.com/adarsh-a-tw/passwordly/vaults"
+	vm "github.com/adarsh-a-tw/passwordly/vaults/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAttachmentHandler_ShouldEncryptUploadsAndDecryptDownloads(t *testing.T) {
+	h, dir := prepareAttachmentHandler(t)
+	content := bytes.Repeat([]byte("keystore "), 20000)
+
+	var dataKey string
+	h.Ep.(*utils_mocks.EncryptionProvider).On("Encrypt", mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		dataKey = args.String(0)
+	}).Return("WRAPPED_KEY", nil)
+	var stored v.Attachment
+	h.Repo.(*vm.AttachmentRepository).On("Create", mock.AnythingOfType("*vaults.Attachment")).Run(func(args mock.Arguments) {
+		stored = *args.Get(0).(*v.Attachment)
+	}).Return(nil)
+
+	ctx, rec := prepareUpload(t, `C:\Users\jane\release.jks`, content)
+	h.UploadAttachment(ctx)
+
+	var resp v.AttachmentResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "release.jks", resp.Name)
+	assert.Equal(t, int64(len(content)), resp.Size)
+	assert.Equal(t, "WRAPPED_KEY", string(stored.DataKey))
+	assert.Equal(t, mockVault.Id+"/"+resp.Id, stored.StorageKey)
+
+	blob, err := os.ReadFile(filepath.Join(dir, mockVault.Id, resp.Id))
+	assert.NoError(t, err)
+	assert.Equal(t, utils.EncryptedSize(int64(len(content))), int64(len(blob)))
+	assert.NotContains(t, string(blob), "keystore")
+
+	h.Ep.(*utils_mocks.EncryptionProvider).On("Decrypt", "WRAPPED_KEY").Return(dataKey, nil)
+	h.Repo.(*vm.AttachmentRepository).On("FindAttachmentById", resp.Id, "mock_secret", mock.AnythingOfType("*vaults.Attachment")).Run(func(args mock.Arguments) {
+		*args.Get(2).(*v.Attachment) = stored
+	}).Return(nil)
+
+	ctx, rec = prepareAttachmentRequest(t, "GET", "/"+resp.Id)
+	ctx.AddParam("attachmentId", resp.Id)
+	h.DownloadAttachment(ctx)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename=release.jks`, rec.Header().Get("Content-Disposition"))
+	assert.True(t, bytes.Equal(content, rec.Body.Bytes()))
+}
+
+func TestAttachmentHandler_UploadAttachment_ShouldRejectLargeFiles(t *testing.T) {
+	h, dir := prepareAttachmentHandler(t)
+	h.Ep.(*utils_mocks.EncryptionProvider).On("Encrypt", mock.AnythingOfType("string")).Return("WRAPPED_KEY", nil)
+
+	for _, size := range []int64{h.MaxSize + 1, h.MaxSize + 256*1024} {
+		ctx, rec := prepareUpload(t, "recovery.pdf", make([]byte, size))
+		h.UploadAttachment(ctx)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	}
+	h.Repo.(*vm.AttachmentRepository).AssertNotCalled(t, "Create", mock.Anything)
+	left, err := os.ReadDir(filepath.Join(dir, mockVault.Id))
+	assert.NoError(t, err)
+	assert.Empty(t, left)
+}
+
+func TestAttachmentHandler_DownloadAttachment_ShouldFailForTamperedBlobs(t *testing.T) {
+	h, _ := prepareAttachmentHandler(t)
+	key, _ := utils.NewDataKey()
+	var encrypted bytes.Buffer
+	utils.EncryptStream(&encrypted, bytes.NewReader([]byte("recovery codes")), key)
+	blob := encrypted.Bytes()
+	blob[len(blob)-1] ^= 1
+	h.Blobs.Put(context.Background(), "vault/tampered", bytes.NewReader(blob), int64(len(blob)))
+
+	h.Ep.(*utils_mocks.EncryptionProvider).On("Decrypt", "WRAPPED_KEY").Return(string(key), nil)
+	h.Repo.(*vm.AttachmentRepository).On("FindAttachmentById", "tampered", "mock_secret", mock.AnythingOfType("*vaults.Attachment")).Run(func(args mock.Arguments) {
+		a := args.Get(2).(*v.Attachment)
+		a.Id = "tampered"
+		a.Size = 14
+		a.StorageKey = "vault/tampered"
+		a.DataKey = []byte("WRAPPED_KEY")
+	}).Return(nil)
+
+	ctx, rec := prepareAttachmentRequest(t, "GET", "/tampered")
+	ctx.AddParam("attachmentId", "tampered")
+	h.DownloadAttachment(ctx)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "recovery codes")
+}
+
+// prepareAttachmentHandler wires a handler to a local blob store in a
+// temporary directory, for the "mock_secret" secret of mockVault.
+func prepareAttachmentHandler(t *testing.T) (*v.AttachmentHandler, string) {
+	msr := vm.SecretRepository{}
+	msr.On("FindSecretType", "mock_secret", mockVault.Id).Return(v.TypeCredential, nil)
+	mvr, _ := ownedVaultMocks()
+	dir := t.TempDir()
+
+	return &v.AttachmentHandler{
+		Ep:         &utils_mocks.EncryptionProvider{},
+		Repo:       &vm.AttachmentRepository{},
+		SecretRepo: &msr,
+		VaultRepo:  mvr,
+		Blobs:      &blobs.LocalStore{Dir: dir},
+		MaxSize:    256 * 1024,
+	}, dir
+}
+
+func prepareAttachmentRequest(t *testing.T, method string, path string) (*gin.Context, *httptest.ResponseRecorder) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/secrets/mock_secret/attachments%s", mockVault.Id, path), method, nil)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+	ctx.AddParam("secretId", "mock_secret")
+	return ctx, rec
+}
+
+func prepareUpload(t *testing.T, filename string, content []byte) (*gin.Context, *httptest.ResponseRecorder) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filename)
+	assert.NoError(t, err)
+	io.Copy(part, bytes.NewReader(content))
+	mw.Close()
+
+	ctx, rec := prepareAttachmentRequest(t, "POST", "")
+	ctx.Request = httptest.NewRequest("POST", ctx.Request.URL.String(), &body)
+	ctx.Request.Header.Set("Content-Type", mw.FormDataContentType())
+	return ctx, rec
+}
@@ -0,0 +1,35 @@
+package vaults
+
+import "gorm.io/gorm"
+
+type AttachmentRepository interface {
+	Create(a *Attachment) error
+	FindAttachments(secretId string, attachments *[]Attachment) error
+	FindAttachmentsByVault(vaultId string, attachments *[]Attachment) error
+	FindAttachmentById(id string, secretId string, a *Attachment) error
+	Delete(a *Attachment) error
+}
+
+type AttachmentRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func (ar *AttachmentRepositoryImpl) Create(a *Attachment) error {
+	return ar.Db.Create(a).Error
+}
+
+func (ar *AttachmentRepositoryImpl) FindAttachments(secretId string, attachments *[]Attachment) error {
+	return ar.Db.Where("secret_id = ?", secretId).Order("created_at ASC, id ASC").Find(attachments).Error
+}
+
+func (ar *AttachmentRepositoryImpl) FindAttachmentsByVault(vaultId string, attachments *[]Attachment) error {
+	return ar.Db.Where("vault_refer = ?", vaultId).Find(attachments).Error
+}
+
+func (ar *AttachmentRepositoryImpl) FindAttachmentById(id string, secretId string, a *Attachment) error {
+	return ar.Db.Where("id = ? AND secret_id = ?", id, secretId).First(a).Error
+}
+
+func (ar *AttachmentRepositoryImpl) Delete(a *Attachment) error {
+	return ar.Db.Delete(a).Error
+}
@@ -0,0 +1,270 @@
+package vaults
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/pki"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const batchModePartial = "partial"
+
+// BatchSecrets applies a list of create, update and delete operations to a
+// vault's secrets in one transaction and reports the outcome of each.
+// Operations refer to secrets as they were before the batch.
+func (sh *SecretHandler) BatchSecrets(ctx *gin.Context) {
+	var bsr BatchSecretsRequest
+	if err := ctx.ShouldBindJSON(&bsr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	vaultId, ok := requireVaultOwner(ctx, sh.VaultRepo)
+	if !ok {
+		return
+	}
+	var v Vault
+	if err := sh.VaultRepo.FetchById(vaultId, &v); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	atomic := bsr.Mode != batchModePartial
+
+	resp := BatchSecretsResponse{Results: make([]BatchResultResponse, len(bsr.Operations))}
+	var writes []SecretWrite
+	var positions []int
+	for i, op := range bsr.Operations {
+		resp.Results[i] = BatchResultResponse{Index: i, Op: op.Op, SecretId: op.SecretId}
+		w, err := sh.prepareWrite(op, &v)
+		if err != nil {
+			resp.Results[i].fail(err)
+			continue
+		}
+		resp.Results[i].SecretId = w.Id
+		resp.Results[i].Type = w.Type
+		writes = append(writes, w)
+		positions = append(positions, i)
+	}
+
+	if atomic && resp.failed() {
+		resp.respondRolledBack(ctx)
+		return
+	}
+
+	errs, err := sh.Repo.ApplyBatch(vaultId, writes, atomic)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	for j, err := range errs {
+		if err != nil {
+			resp.Results[positions[j]].fail(err)
+		}
+	}
+	if atomic && resp.failed() {
+		resp.respondRolledBack(ctx)
+		return
+	}
+
+	resp.Committed = true
+	var written []string
+	for j, w := range writes {
+		if errs[j] != nil {
+			continue
+		}
+		written = append(written, w.Id)
+		resp.Results[positions[j]].Status = http.StatusOK
+		action := audit.ActionSecretWrite
+		switch w.Op {
+		case BatchCreate:
+			resp.Results[positions[j]].Status = http.StatusCreated
+		case BatchDelete:
+			action = audit.ActionSecretDelete
+		}
+		audit.Log(sh.Audit, ctx, audit.Event{
+			Action:     action,
+			TargetType: audit.TargetSecret,
+			TargetId:   w.Id,
+			Detail:     fmt.Sprintf("%s in batch", w.Type),
+		})
+	}
+	indexSecrets(sh.Index, vaultId, written...)
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// prepareWrite checks an operation and builds the secret it writes, without
+// storing anything. Updates keep the keys of SSH keys and certificate
+// authorities, and merge custom fields as UpdateFields does: hidden values
+// left empty are kept, and leaving out fields altogether keeps them all.
+func (sh *SecretHandler) prepareWrite(op BatchOperationRequest, v *Vault) (SecretWrite, error) {
+	w := SecretWrite{Op: op.Op, Id: op.SecretId}
+	switch op.Op {
+	case BatchCreate:
+		if op.SecretId != "" {
+			return w, errInvalidBody
+		}
+		w.Id = uuid.NewString()
+	case BatchUpdate, BatchDelete:
+		if op.SecretId == "" {
+			return w, errInvalidBody
+		}
+		secretType, err := sh.Repo.FindSecretType(op.SecretId, v.Id)
+		if err != nil {
+			return w, err
+		}
+		w.Type = secretType
+		if op.Op == BatchDelete {
+			return w, nil
+		}
+	default:
+		return w, requestError{fmt.Sprintf("Unknown operation %q", op.Op)}
+	}
+
+	var csr CreateSecretRequest
+	if err := json.Unmarshal(op.Secret, &csr); err != nil {
+		return w, errInvalidBody
+	}
+	if err := binding.Validator.ValidateStruct(&csr); err != nil {
+		return w, errInvalidBody
+	}
+	if op.Op == BatchUpdate && csr.Type != w.Type {
+		return w, requestError{fmt.Sprintf("Secret %s is a %s and cannot change type", w.Id, w.Type)}
+	}
+	w.Type = csr.Type
+
+	if err := sh.checkSecret(&csr, v.Id); err != nil {
+		return w, err
+	}
+	var existing []CustomField
+	var s Securable
+	var err error
+	if op.Op == BatchUpdate {
+		if err := checkFieldIds(csr.Fields); err != nil {
+			return w, requestError{err.Error()}
+		}
+		if err := sh.Fields.FindFields(w.Id, &existing); err != nil {
+			return w, err
+		}
+		s, err = sh.buildUpdate(&csr, v, w.Id)
+	} else {
+		s, err = sh.buildSecret(&csr, v, w.Id)
+	}
+	if err != nil {
+		return w, err
+	}
+	w.Secret = s
+	if op.Op == BatchUpdate && csr.Fields == nil {
+		w.Fields = existing
+		return w, nil
+	}
+	if w.Fields, err = sh.buildFields(csr.Fields, w.Id, v.Id, existing); err != nil {
+		return w, err
+	}
+	return w, nil
+}
+
+// buildUpdate builds the new state of an existing secret. SSH keys and
+// certificate authorities keep their keys, since replacing them would
+// orphan the certificates already signed or issued with them; only their
+// settings change.
+func (sh *SecretHandler) buildUpdate(csr *CreateSecretRequest, v *Vault, id string) (Securable, error) {
+	keyChange := requestError{fmt.Sprintf("The key of secret %s cannot be replaced; create a new secret instead", id)}
+	switch csr.Type {
+	case TypeSshKey:
+		if csr.PrivateKey != "" {
+			return nil, keyChange
+		}
+		var k SshKey
+		if err := sh.Repo.FindSshKeyById(id, v.Id, &k); err != nil {
+			return nil, err
+		}
+		k.Name = csr.Name
+		k.Expiry = csr.expiry()
+		k.Placement = csr.placement()
+		return k, nil
+	case TypeCertificateAuthority:
+		if csr.Certificate != "" || csr.PrivateKey != "" || csr.CommonName != "" {
+			return nil, keyChange
+		}
+		for _, domain := range csr.AllowedDomains {
+			if domain == "" || strings.ContainsAny(domain, ", ") {
+				return nil, errInvalidBody
+			}
+		}
+		var ca CertificateAuthority
+		if err := sh.Repo.FindCertificateAuthorityById(id, v.Id, &ca); err != nil {
+			return nil, err
+		}
+		cert, err := pki.ParseCertificate([]byte(ca.Certificate))
+		if err != nil {
+			return nil, err
+		}
+		ca.Name = csr.Name
+		ca.AllowedDomains = strings.Join(csr.AllowedDomains, ",")
+		ca.AllowSubdomains = csr.AllowSubdomains
+		ca.AllowIpSans = csr.AllowIpSans
+		if csr.MaxTtlSeconds != 0 {
+			ca.MaxTtlSeconds = csr.MaxTtlSeconds
+		}
+		ca.Expiry = csr.expiry()
+		if ca.ExpiresAt == nil {
+			notAfter := cert.NotAfter.UTC()
+			ca.ExpiresAt = &notAfter
+		}
+		ca.Placement = csr.placement()
+		return ca, nil
+	}
+	return sh.buildSecret(csr, v, id)
+}
+
+// fail records why an operation was not applied.
+func (br *BatchResultResponse) fail(err error) {
+	var re requestError
+	switch {
+	case errors.As(err, &re):
+		br.Status = http.StatusBadRequest
+		br.Message = re.message
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		br.Status = http.StatusNotFound
+		br.Message = "Secret not found"
+	default:
+		log.Printf("Batch operation %d on secret %s failed: %v", br.Index, br.SecretId, err)
+		br.Status = http.StatusInternalServerError
+		br.Message = common.InternalServerError().Message
+	}
+}
+
+func (bsr *BatchSecretsResponse) failed() bool {
+	for _, r := range bsr.Results {
+		if r.Status != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// respondRolledBack answers an atomic batch that was not applied with the
+// status of its first failed operation.
+func (bsr *BatchSecretsResponse) respondRolledBack(ctx *gin.Context) {
+	status := 0
+	for i, r := range bsr.Results {
+		if r.Status == 0 {
+			bsr.Results[i].Status = http.StatusFailedDependency
+			bsr.Results[i].Message = "Not applied because another operation failed"
+		} else if status == 0 {
+			status = r.Status
+		}
+	}
+	ctx.JSON(status, bsr)
+}
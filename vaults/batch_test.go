@@ -0,0 +1,255 @@
+package vaults_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/pki"
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSecretHandler_BatchSecrets_ShouldApplyEveryOperation(t *testing.T) {
+	sh, db := prepareBatch(t)
+
+	resp, code := batchSecrets(t, sh, vaults.BatchSecretsRequest{Operations: []vaults.BatchOperationRequest{
+		batchOperation(t, vaults.BatchCreate, "", vaults.CreateSecretRequest{Name: "Mail", Type: vaults.TypeCredential, Username: "me", Password: "pw"}),
+		batchOperation(t, vaults.BatchUpdate, "github", vaults.CreateSecretRequest{Name: "GitHub work", Type: vaults.TypeCredential, Username: "octocat", Password: "new",
+			Tags: []string{"ci"}, Fields: []vaults.CustomFieldRequest{{Name: "Team", Type: vaults.FieldText, Value: "platform"}}}),
+		{Op: vaults.BatchDelete, SecretId: "totp"},
+	}})
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Committed)
+	assert.Equal(t, []int{http.StatusCreated, http.StatusOK, http.StatusOK}, batchStatuses(resp))
+	assert.Equal(t, vaults.TypeTotp, resp.Results[2].Type)
+
+	var created vaults.Credential
+	assert.NoError(t, db.First(&created, "id = ?", resp.Results[0].SecretId).Error)
+	assert.Equal(t, "work", created.VaultRefer)
+
+	var updated vaults.Credential
+	assert.NoError(t, db.First(&updated, "id = ?", "github").Error)
+	assert.Equal(t, "GitHub work", updated.Name)
+	assert.Equal(t, "sealed:new", string(updated.Password))
+	assert.Equal(t, "ci", updated.Tags)
+	assert.Equal(t, "work", updated.VaultRefer)
+	assert.True(t, updated.CreatedAt.Equal(batchTime))
+	assert.True(t, updated.UpdatedAt.After(batchTime))
+	var fields []vaults.CustomField
+	assert.NoError(t, db.Where("secret_id = ?", "github").Find(&fields).Error)
+	assert.Len(t, fields, 1)
+	assert.Equal(t, "Team", fields[0].Name)
+
+	var count int64
+	assert.NoError(t, db.Model(&vaults.Totp{}).Count(&count).Error)
+	assert.Zero(t, count)
+	assert.NoError(t, db.Unscoped().Model(&vaults.Totp{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count, "deleted secrets go to the trash")
+}
+
+func TestSecretHandler_BatchSecrets_AtomicBatchShouldApplyNothingWhenAnOperationFails(t *testing.T) {
+	sh, db := prepareBatch(t)
+
+	for name, tc := range map[string]struct {
+		operations []vaults.BatchOperationRequest
+		code       int
+		statuses   []int
+	}{
+		"invalid operation": {
+			operations: []vaults.BatchOperationRequest{
+				{Op: vaults.BatchDelete, SecretId: "totp"},
+				batchOperation(t, vaults.BatchCreate, "", vaults.CreateSecretRequest{Name: "Card", Type: vaults.TypeCard, CardNumber: "1234"}),
+				{Op: vaults.BatchDelete, SecretId: "missing"},
+			},
+			code:     http.StatusBadRequest,
+			statuses: []int{http.StatusFailedDependency, http.StatusBadRequest, http.StatusNotFound},
+		},
+		"failed write": {
+			operations: []vaults.BatchOperationRequest{
+				{Op: vaults.BatchDelete, SecretId: "totp"},
+				batchOperation(t, vaults.BatchCreate, "", vaults.CreateSecretRequest{Name: "Mail", Type: vaults.TypeCredential, Username: "me", Password: "pw"}),
+				{Op: vaults.BatchDelete, SecretId: "totp"},
+			},
+			code:     http.StatusNotFound,
+			statuses: []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound},
+		},
+	} {
+		resp, code := batchSecrets(t, sh, vaults.BatchSecretsRequest{Operations: tc.operations})
+
+		assert.Equal(t, tc.code, code, name)
+		assert.False(t, resp.Committed, name)
+		assert.Equal(t, tc.statuses, batchStatuses(resp), name)
+	}
+
+	var count int64
+	assert.NoError(t, db.Model(&vaults.Totp{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	assert.NoError(t, db.Model(&vaults.Credential{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestSecretHandler_BatchSecrets_PartialBatchShouldApplyOperationsThatSucceed(t *testing.T) {
+	sh, db := prepareBatch(t)
+
+	resp, code := batchSecrets(t, sh, vaults.BatchSecretsRequest{Mode: "partial", Operations: []vaults.BatchOperationRequest{
+		{Op: vaults.BatchDelete, SecretId: "totp"},
+		batchOperation(t, vaults.BatchUpdate, "github", vaults.CreateSecretRequest{Name: "GitHub", Type: vaults.TypeIdentity, FullName: "Octo Cat"}),
+		{Op: vaults.BatchDelete, SecretId: "totp"},
+		batchOperation(t, vaults.BatchCreate, "", vaults.CreateSecretRequest{Name: "Mail", Type: vaults.TypeCredential, Username: "me", Password: "pw"}),
+		{Op: "rename", SecretId: "github"},
+	}})
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.Committed)
+	assert.Equal(t, []int{http.StatusOK, http.StatusBadRequest, http.StatusNotFound, http.StatusCreated, http.StatusBadRequest}, batchStatuses(resp))
+	assert.Equal(t, "Secret github is a CREDENTIAL and cannot change type", resp.Results[1].Message)
+
+	var count int64
+	assert.NoError(t, db.Model(&vaults.Totp{}).Count(&count).Error)
+	assert.Zero(t, count)
+	assert.NoError(t, db.Model(&vaults.Credential{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+	var github vaults.Credential
+	assert.NoError(t, db.First(&github, "id = ?", "github").Error)
+	assert.Equal(t, "GitHub", github.Name)
+}
+
+func TestSecretHandler_BatchSecrets_UpdateShouldKeepKeys(t *testing.T) {
+	sh, db := prepareBatch(t)
+	key, err := sshkeys.Generate(sshkeys.KeyTypeEd25519, 0)
+	assert.NoError(t, err)
+	ca, err := pki.NewCA(key, pki.Subject{CommonName: "ACME Internal Root"}, 0, time.Now())
+	assert.NoError(t, err)
+	for _, record := range []any{
+		&vaults.SshKey{Id: "deploy", Name: "Deploy key", KeyType: "ED25519", PrivateKey: []byte("deploy-key"), PublicKey: "ssh-ed25519 AAAA deploy",
+			Fingerprint: "SHA256:deploy", Comment: "deploy", VaultRefer: "work"},
+		&vaults.CertificateAuthority{Id: "root", Name: "Root", Subject: ca.Certificate.Subject.String(), Certificate: string(pki.EncodeCertificate(ca.Certificate)),
+			PrivateKey: []byte("root-key"), AllowedDomains: "example.com", MaxTtlSeconds: 3600, VaultRefer: "work"},
+	} {
+		assert.NoError(t, db.Create(record).Error)
+	}
+
+	resp, code := batchSecrets(t, sh, vaults.BatchSecretsRequest{Operations: []vaults.BatchOperationRequest{
+		batchOperation(t, vaults.BatchUpdate, "deploy", vaults.CreateSecretRequest{Name: "Deploy key (prod)", Type: vaults.TypeSshKey, Tags: []string{"prod"}}),
+		batchOperation(t, vaults.BatchUpdate, "root", vaults.CreateSecretRequest{Name: "Internal root", Type: vaults.TypeCertificateAuthority,
+			AllowedDomains: []string{"example.com", "example.org"}}),
+	}})
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, batchStatuses(resp))
+	var k vaults.SshKey
+	assert.NoError(t, db.First(&k, "id = ?", "deploy").Error)
+	assert.Equal(t, "Deploy key (prod)", k.Name)
+	assert.Equal(t, "prod", k.Tags)
+	assert.Equal(t, "deploy-key", string(k.PrivateKey))
+	assert.Equal(t, "SHA256:deploy", k.Fingerprint)
+	var root vaults.CertificateAuthority
+	assert.NoError(t, db.First(&root, "id = ?", "root").Error)
+	assert.Equal(t, "Internal root", root.Name)
+	assert.Equal(t, "example.com,example.org", root.AllowedDomains)
+	assert.Equal(t, "root-key", string(root.PrivateKey))
+	assert.Equal(t, string(pki.EncodeCertificate(ca.Certificate)), root.Certificate)
+	assert.Equal(t, 3600, root.MaxTtlSeconds)
+	assert.True(t, root.ExpiresAt.Equal(ca.Certificate.NotAfter))
+
+	resp, code = batchSecrets(t, sh, vaults.BatchSecretsRequest{Operations: []vaults.BatchOperationRequest{
+		batchOperation(t, vaults.BatchUpdate, "root", vaults.CreateSecretRequest{Name: "Root", Type: vaults.TypeCertificateAuthority, CommonName: "New Root"}),
+	}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "The key of secret root cannot be replaced; create a new secret instead", resp.Results[0].Message)
+}
+
+func TestSecretHandler_BatchSecrets_UpdateShouldMergeFields(t *testing.T) {
+	sh, db := prepareBatch(t)
+	assert.NoError(t, db.Create(&vaults.CustomField{Id: "github_pin", SecretId: "github", Name: "PIN", Type: vaults.FieldHidden,
+		Value: []byte("sealed-pin"), Position: 1, VaultRefer: "work"}).Error)
+	credential := vaults.CreateSecretRequest{Name: "GitHub", Type: vaults.TypeCredential, Username: "octocat", Password: "pw"}
+
+	_, code := batchSecrets(t, sh, vaults.BatchSecretsRequest{Operations: []vaults.BatchOperationRequest{
+		batchOperation(t, vaults.BatchUpdate, "github", credential),
+	}})
+	assert.Equal(t, http.StatusOK, code)
+	var fields []vaults.CustomField
+	assert.NoError(t, db.Order("position").Find(&fields, "secret_id = ?", "github").Error)
+	assert.Len(t, fields, 2)
+	assert.Equal(t, "sealed-pin", string(fields[1].Value))
+
+	credential.Fields = []vaults.CustomFieldRequest{
+		{Id: "github_pin", Name: "Recovery PIN", Type: vaults.FieldHidden},
+		{Name: "Team", Type: vaults.FieldText, Value: "platform"},
+	}
+	_, code = batchSecrets(t, sh, vaults.BatchSecretsRequest{Operations: []vaults.BatchOperationRequest{
+		batchOperation(t, vaults.BatchUpdate, "github", credential),
+	}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NoError(t, db.Order("position").Find(&fields, "secret_id = ?", "github").Error)
+	assert.Len(t, fields, 2)
+	assert.Equal(t, "github_pin", fields[0].Id)
+	assert.Equal(t, "Recovery PIN", fields[0].Name)
+	assert.Equal(t, "sealed-pin", string(fields[0].Value))
+	assert.Equal(t, "Team", fields[1].Name)
+
+	credential.Fields = append(credential.Fields, vaults.CustomFieldRequest{Id: "github_pin", Name: "PIN", Type: vaults.FieldHidden})
+	resp, code := batchSecrets(t, sh, vaults.BatchSecretsRequest{Operations: []vaults.BatchOperationRequest{
+		batchOperation(t, vaults.BatchUpdate, "github", credential),
+	}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, []int{http.StatusBadRequest}, batchStatuses(resp))
+}
+
+var batchTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// prepareBatch stores a "work" vault of user_1 with a credential that has a
+// field and a TOTP seed.
+func prepareBatch(t *testing.T) (*vaults.SecretHandler, *gorm.DB) {
+	db := prepareDb(t)
+
+	for _, record := range []any{
+		&vaults.Vault{Id: "work", Name: "Work", UserRefer: "user_1"},
+		&vaults.Credential{Id: "github", Name: "GitHub", Username: "octocat", Password: []byte("x"), VaultRefer: "work",
+			CreatedAt: batchTime, UpdatedAt: batchTime},
+		&vaults.CustomField{Id: "github_url", SecretId: "github", Name: "Website", Type: vaults.FieldUrl, Value: []byte("https://github.com"), VaultRefer: "work"},
+		&vaults.Totp{Id: "totp", Name: "GitHub 2FA", Secret: []byte("x"), Algorithm: "SHA1", Digits: 6, Period: 30, VaultRefer: "work"},
+	} {
+		assert.NoError(t, db.Create(record).Error)
+	}
+
+	return &vaults.SecretHandler{
+		Ep:        sealingProvider(),
+		Repo:      &vaults.SecretRepositoryImpl{Db: db},
+		VaultRepo: &vaults.VaultRepositoryImpl{Db: db},
+		Fields:    &vaults.CustomFieldRepositoryImpl{Db: db},
+		Folders:   &vaults.FolderRepositoryImpl{Db: db},
+	}, db
+}
+
+func batchOperation(t *testing.T, op vaults.BatchOp, secretId string, csr vaults.CreateSecretRequest) vaults.BatchOperationRequest {
+	secret, err := json.Marshal(csr)
+	assert.NoError(t, err)
+	return vaults.BatchOperationRequest{Op: op, SecretId: secretId, Secret: secret}
+}
+
+func batchSecrets(t *testing.T, sh *vaults.SecretHandler, bsr vaults.BatchSecretsRequest) (vaults.BatchSecretsResponse, int) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/vaults/work/secrets/batch", "POST", bsr)
+	ctx.Set("user_id", "user_1")
+	ctx.AddParam("id", "work")
+	sh.BatchSecrets(ctx)
+
+	var resp vaults.BatchSecretsResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+	return resp, rec.Code
+}
+
+func batchStatuses(resp vaults.BatchSecretsResponse) []int {
+	statuses := make([]int, len(resp.Results))
+	for i, r := range resp.Results {
+		statuses[i] = r.Status
+	}
+	return statuses
+}
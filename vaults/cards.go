@@ -0,0 +1,110 @@
+package vaults
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cardBrands maps ranges of leading digits to card networks, e.g. Visa
+// numbers start with 4 and Mastercard's with 51-55 or 2221-2720.
+var cardBrands = []struct {
+	brand  string
+	digits int
+	low    int
+	high   int
+}{
+	{"VISA", 1, 4, 4},
+	{"MASTERCARD", 2, 51, 55},
+	{"MASTERCARD", 4, 2221, 2720},
+	{"AMEX", 2, 34, 34},
+	{"AMEX", 2, 37, 37},
+	{"DISCOVER", 4, 6011, 6011},
+	{"DISCOVER", 3, 644, 649},
+	{"DISCOVER", 2, 65, 65},
+	{"DINERS", 3, 300, 305},
+	{"DINERS", 2, 36, 36},
+	{"DINERS", 2, 38, 39},
+	{"JCB", 4, 3528, 3589},
+	{"UNIONPAY", 2, 62, 62},
+}
+
+// normalizeCardNumber strips the spaces and dashes people type between digit
+// groups. ok is false unless the result is 12 to 19 digits with a valid
+// Luhn check digit.
+func normalizeCardNumber(number string) (digits string, ok bool) {
+	digits = strings.NewReplacer(" ", "", "-", "").Replace(number)
+	if len(digits) < 12 || len(digits) > 19 {
+		return "", false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return digits, luhnValid(digits)
+}
+
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func cardBrand(digits string) string {
+	for _, cb := range cardBrands {
+		prefix, err := strconv.Atoi(digits[:cb.digits])
+		if err == nil && prefix >= cb.low && prefix <= cb.high {
+			return cb.brand
+		}
+	}
+	return ""
+}
+
+func validCvv(cvv string, brand string) bool {
+	if cvv == "" {
+		return true
+	}
+	for _, r := range cvv {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	if brand == "AMEX" {
+		return len(cvv) == 4
+	}
+	return len(cvv) == 3
+}
+
+// cardExpiresAt is the first instant after the card's expiry month, when it
+// stops being usable.
+func cardExpiresAt(month int, year int) time.Time {
+	return time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// lastFour keeps the tail used to recognise a masked value. Values too
+// short to reveal anything safely keep nothing.
+func lastFour(s string) string {
+	if len(s) <= 4 {
+		return ""
+	}
+	return s[len(s)-4:]
+}
+
+func masked(value []byte, last4 string) string {
+	if len(value) == 0 {
+		return ""
+	}
+	return strings.TrimSpace("**** " + last4)
+}
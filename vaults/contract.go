@@ -1,5 +1,17 @@
 package vaults
 
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/imports"
+	"github.com/adarsh-a-tw/passwordly/passwords"
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
+)
+
 type CreateVaultRequest struct {
 	Name string `json:"name" binding:"required"`
 }
@@ -7,29 +19,50 @@ type CreateVaultRequest struct {
 type UpdateVaultRequest CreateVaultRequest
 
 type VaultResponse struct {
-	Id        string           `json:"id"`
-	Name      string           `json:"name"`
-	Secrets   []SecretResponse `json:"secrets,omitempty"`
-	CreatedAt int64            `json:"created_at,omitempty"`
-	UpdatedAt int64            `json:"updated_at,omitempty"`
+	Id         string           `json:"id"`
+	Name       string           `json:"name"`
+	Secrets    []SecretResponse `json:"secrets,omitempty"`
+	Folders    []FolderResponse `json:"folders,omitempty"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	CreatedAt  int64            `json:"created_at,omitempty"`
+	UpdatedAt  int64            `json:"updated_at,omitempty"`
 }
 
-func (vr *VaultResponse) load(v Vault, creds []Credential) {
+// load fills in the vault's secrets along with their custom fields, which
+// are keyed by secret id.
+func (vr *VaultResponse) load(v Vault, secrets []Securable, fields map[string][]CustomField) {
 	vr.Id = v.Id
 	vr.Name = v.Name
 
 	var secretResponses = make([]SecretResponse, 0)
-	for _, cred := range creds {
+	for _, secret := range secrets {
 		sr := SecretResponse{}
-		sr.load(cred)
+		sr.load(secret)
+		sr.loadFields(fields[sr.Id])
 		secretResponses = append(secretResponses, sr)
 	}
 
 	vr.Secrets = secretResponses
 }
 
+func (vr *VaultResponse) loadFolders(folders []Folder) {
+	var flr FolderListResponse
+	flr.load(folders)
+	vr.Folders = flr.Folders
+}
+
+// ListRequest holds the paging, sorting and name filter of a listing.
+type ListRequest struct {
+	Cursor   string `form:"cursor"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1"`
+	Sort     string `form:"sort" binding:"omitempty,oneof=name created updated"`
+	Order    string `form:"order" binding:"omitempty,oneof=asc desc"`
+	Name     string `form:"name" binding:"max=255"`
+}
+
 type VaultListResponse struct {
-	Vaults []VaultResponse `json:"vaults"`
+	Vaults     []VaultResponse `json:"vaults"`
+	NextCursor string          `json:"next_cursor,omitempty"`
 }
 
 func (vlr *VaultListResponse) load(vaults []Vault) {
@@ -47,6 +80,80 @@ type CreateSecretRequest struct {
 	Password string     `json:"password,omitempty"`
 	Value    string     `json:"value,omitempty"`
 	Document string     `json:"document,omitempty"`
+
+	// Totp takes an otpauth:// URI or a raw base32 seed. Parameters in a URI
+	// take precedence over the fields below.
+	Totp      string `json:"totp,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Period    int    `json:"period,omitempty"`
+	Issuer    string `json:"issuer,omitempty"`
+
+	// SSH keys are generated from KeyType and Bits unless an existing
+	// PrivateKey is imported, decrypted with Passphrase if it has one.
+	KeyType    string `json:"key_type,omitempty"`
+	Bits       int    `json:"bits,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+
+	// Certificate authorities are generated as a self-signed root for
+	// CommonName unless an existing Certificate and PrivateKey are imported.
+	// The remaining fields constrain the certificates the CA issues.
+	CommonName      string   `json:"common_name,omitempty"`
+	Organization    string   `json:"organization,omitempty"`
+	TtlDays         int      `json:"ttl_days,omitempty" binding:"omitempty,min=1,max=36500"`
+	Certificate     string   `json:"certificate,omitempty"`
+	AllowedDomains  []string `json:"allowed_domains,omitempty"`
+	AllowSubdomains bool     `json:"allow_subdomains,omitempty"`
+	AllowIpSans     bool     `json:"allow_ip_sans,omitempty"`
+	MaxTtlSeconds   int      `json:"max_ttl_seconds,omitempty" binding:"omitempty,min=1"`
+
+	// Payment cards. The number may contain spaces or dashes and must pass
+	// the Luhn check.
+	CardholderName string `json:"cardholder_name,omitempty"`
+	CardNumber     string `json:"card_number,omitempty"`
+	ExpiryMonth    int    `json:"expiry_month,omitempty" binding:"omitempty,min=1,max=12"`
+	ExpiryYear     int    `json:"expiry_year,omitempty" binding:"omitempty,min=2000,max=2100"`
+	Cvv            string `json:"cvv,omitempty"`
+
+	// Identities.
+	FullName       string `json:"full_name,omitempty"`
+	Email          string `json:"email,omitempty" binding:"omitempty,email"`
+	Phone          string `json:"phone,omitempty"`
+	Address        string `json:"address,omitempty"`
+	PassportNumber string `json:"passport_number,omitempty"`
+	LicenceNumber  string `json:"licence_number,omitempty"`
+
+	Fields []CustomFieldRequest `json:"fields,omitempty" binding:"omitempty,max=50,dive"`
+
+	// FolderId places the secret in a folder of the vault; empty is the
+	// top level.
+	FolderId string   `json:"folder_id,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+
+	// ExpiresAt is a unix timestamp; RotateEveryDays schedules rotation
+	// reminders counted from the last update.
+	ExpiresAt       int64 `json:"expires_at,omitempty" binding:"omitempty,min=0"`
+	RotateEveryDays int   `json:"rotate_every_days,omitempty" binding:"omitempty,min=1,max=3650"`
+
+	// Generate asks the server to pick the credential password.
+	Generate        bool                       `json:"generate,omitempty"`
+	GenerateOptions *passwords.GenerateRequest `json:"generate_options,omitempty"`
+}
+
+func (csr *CreateSecretRequest) expiry() Expiry {
+	e := Expiry{RotateEveryDays: csr.RotateEveryDays}
+	if csr.ExpiresAt > 0 {
+		expiresAt := time.Unix(csr.ExpiresAt, 0).UTC()
+		e.ExpiresAt = &expiresAt
+	}
+	return e
+}
+
+// placement expects Tags to have been normalized already.
+func (csr *CreateSecretRequest) placement() Placement {
+	return Placement{FolderId: optional(csr.FolderId), Tags: strings.Join(csr.Tags, ",")}
 }
 
 type SecretResponse struct {
@@ -59,34 +166,707 @@ type SecretResponse struct {
 	Password  string     `json:"password,omitempty"`
 	Value     string     `json:"value,omitempty"`
 	Document  string     `json:"document,omitempty"`
+
+	Issuer    string `json:"issuer,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Period    int    `json:"period,omitempty"`
+
+	KeyType     string `json:"key_type,omitempty"`
+	Bits        int    `json:"bits,omitempty"`
+	PublicKey   string `json:"public_key,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	Subject         string   `json:"subject,omitempty"`
+	Certificate     string   `json:"certificate,omitempty"`
+	AllowedDomains  []string `json:"allowed_domains,omitempty"`
+	AllowSubdomains bool     `json:"allow_subdomains,omitempty"`
+	AllowIpSans     bool     `json:"allow_ip_sans,omitempty"`
+	MaxTtlSeconds   int      `json:"max_ttl_seconds,omitempty"`
+
+	// Sensitive card and identity fields are masked unless the secret is
+	// fetched on its own.
+	CardholderName string `json:"cardholder_name,omitempty"`
+	CardNumber     string `json:"card_number,omitempty"`
+	CardBrand      string `json:"card_brand,omitempty"`
+	ExpiryMonth    int    `json:"expiry_month,omitempty"`
+	ExpiryYear     int    `json:"expiry_year,omitempty"`
+	Cvv            string `json:"cvv,omitempty"`
+	FullName       string `json:"full_name,omitempty"`
+	Email          string `json:"email,omitempty"`
+	Phone          string `json:"phone,omitempty"`
+	Address        string `json:"address,omitempty"`
+	PassportNumber string `json:"passport_number,omitempty"`
+	LicenceNumber  string `json:"licence_number,omitempty"`
+
+	ExpiresAt       int64 `json:"expires_at,omitempty"`
+	RotateEveryDays int   `json:"rotate_every_days,omitempty"`
+
+	Fields []CustomFieldResponse `json:"fields,omitempty"`
+
+	FolderId string   `json:"folder_id,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
 }
 
 func (sr *SecretResponse) load(s Securable) {
-	switch s.Type() {
-	case TypeCredential:
-		cred := s.(Credential)
-		sr.Id = cred.Id
-		sr.Name = cred.Name
-		sr.Type = TypeCredential
-		sr.CreatedAt = cred.CreatedAt.Unix()
-		sr.UpdatedAt = cred.UpdatedAt.Unix()
-		sr.Username = cred.Username
-		sr.Password = string(cred.Password)
-	case TypeKey:
-		key := s.(Key)
-		sr.Id = key.Id
-		sr.Name = key.Name
-		sr.Type = TypeKey
-		sr.CreatedAt = key.CreatedAt.Unix()
-		sr.UpdatedAt = key.UpdatedAt.Unix()
-		sr.Value = key.Value
-	case TypeDocument:
-		document := s.(Document)
-		sr.Id = document.Id
-		sr.Name = document.Name
-		sr.Type = TypeDocument
-		sr.CreatedAt = document.CreatedAt.Unix()
-		sr.UpdatedAt = document.UpdatedAt.Unix()
-		sr.Document = document.Content
+	m := s.Metadata()
+	sr.Id = m.Id
+	sr.Name = m.Name
+	sr.Type = s.Type()
+	sr.CreatedAt = m.CreatedAt.Unix()
+	sr.UpdatedAt = m.UpdatedAt.Unix()
+	sr.loadExpiry(m.Expiry)
+	sr.FolderId = deref(m.Placement.FolderId)
+	sr.Tags = splitList(m.Placement.Tags)
+
+	switch secret := s.(type) {
+	case Credential:
+		sr.Username = secret.Username
+		sr.Password = string(secret.Password)
+	case Key:
+		sr.Value = string(secret.Value)
+	case Document:
+		sr.Document = string(secret.Content)
+	case Totp:
+		sr.Username = secret.AccountName
+		sr.Issuer = secret.Issuer
+		sr.Algorithm = secret.Algorithm
+		sr.Digits = secret.Digits
+		sr.Period = secret.Period
+	case SshKey:
+		sr.KeyType = secret.KeyType
+		sr.Bits = secret.Bits
+		sr.PublicKey = secret.PublicKey
+		sr.Fingerprint = secret.Fingerprint
+	case CertificateAuthority:
+		sr.Subject = secret.Subject
+		sr.Certificate = secret.Certificate
+		sr.AllowedDomains = splitList(secret.AllowedDomains)
+		sr.AllowSubdomains = secret.AllowSubdomains
+		sr.AllowIpSans = secret.AllowIpSans
+		sr.MaxTtlSeconds = secret.MaxTtlSeconds
+	case Card:
+		sr.CardholderName = secret.CardholderName
+		sr.CardNumber = masked(secret.Number, secret.Last4)
+		sr.CardBrand = secret.Brand
+		sr.ExpiryMonth = secret.ExpiryMonth
+		sr.ExpiryYear = secret.ExpiryYear
+	case Identity:
+		sr.FullName = secret.FullName
+		sr.Email = secret.Email
+		sr.Phone = secret.Phone
+		sr.PassportNumber = masked(secret.PassportNumber, secret.PassportLast4)
+		sr.LicenceNumber = masked(secret.LicenceNumber, secret.LicenceLast4)
+	}
+}
+
+// loadFields adds custom fields with hidden values masked.
+func (sr *SecretResponse) loadFields(fields []CustomField) {
+	for _, f := range fields {
+		cfr := CustomFieldResponse{}
+		cfr.load(f, "")
+		sr.Fields = append(sr.Fields, cfr)
+	}
+}
+
+func (sr *SecretResponse) loadExpiry(e Expiry) {
+	if e.ExpiresAt != nil {
+		sr.ExpiresAt = e.ExpiresAt.Unix()
 	}
+	sr.RotateEveryDays = e.RotateEveryDays
+}
+
+type BreachedSecretResponse struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Username    string `json:"username,omitempty"`
+	Occurrences int    `json:"occurrences"`
+}
+
+type BreachReportResponse struct {
+	VaultId  string                   `json:"vault_id"`
+	Checked  int                      `json:"checked"`
+	Breached []BreachedSecretResponse `json:"breached"`
+}
+
+type HealthReportRequest struct {
+	MaxAgeDays int `form:"max_age_days" binding:"omitempty,min=1,max=3650"`
+}
+
+type CredentialHealthResponse struct {
+	Id                     string `json:"id"`
+	Name                   string `json:"name"`
+	Username               string `json:"username,omitempty"`
+	VaultId                string `json:"vault_id"`
+	Score                  int    `json:"score"`
+	Weak                   bool   `json:"weak"`
+	Reused                 bool   `json:"reused"`
+	ReuseGroup             int    `json:"reuse_group,omitempty"`
+	Stale                  bool   `json:"stale"`
+	AgeDays                int    `json:"age_days"`
+	MatchesAccountPassword bool   `json:"matches_account_password"`
+}
+
+type HealthSummaryResponse struct {
+	Weak                      int `json:"weak"`
+	Reused                    int `json:"reused"`
+	Stale                     int `json:"stale"`
+	AccountPasswordDuplicates int `json:"account_password_duplicates"`
+}
+
+// HealthReportResponse lists only credentials with at least one finding.
+// Credentials sharing a password share a reuse_group number.
+type HealthReportResponse struct {
+	VaultId     string                     `json:"vault_id,omitempty"`
+	Checked     int                        `json:"checked"`
+	Summary     HealthSummaryResponse      `json:"summary"`
+	Credentials []CredentialHealthResponse `json:"credentials"`
+}
+
+type ExpirationsRequest struct {
+	WithinDays int `form:"within_days" binding:"omitempty,min=1,max=3650"`
+}
+
+type ExpirationResponse struct {
+	Id            string       `json:"id"`
+	Name          string       `json:"name"`
+	Type          SecretType   `json:"type"`
+	VaultId       string       `json:"vault_id"`
+	VaultName     string       `json:"vault_name"`
+	Kind          ReminderKind `json:"kind"`
+	DueAt         int64        `json:"due_at"`
+	DaysRemaining int          `json:"days_remaining"`
+	Overdue       bool         `json:"overdue"`
+}
+
+func (er *ExpirationResponse) load(r Reminder, now time.Time) {
+	er.Id = r.Secret.Id
+	er.Name = r.Secret.Name
+	er.Type = r.Secret.Type
+	er.VaultId = r.Secret.VaultId
+	er.VaultName = r.Secret.VaultName
+	er.Kind = r.Kind
+	er.DueAt = r.DueAt.Unix()
+	er.DaysRemaining = int(math.Ceil(r.DueAt.Sub(now).Hours() / 24))
+	er.Overdue = r.Overdue
+}
+
+type ExpirationListResponse struct {
+	Expirations []ExpirationResponse `json:"expirations"`
+}
+
+type TotpCodeResponse struct {
+	Code             string `json:"code"`
+	SecondsRemaining int    `json:"seconds_remaining"`
+	Period           int    `json:"period"`
+	Digits           int    `json:"digits"`
+}
+
+type SshKeyExportRequest struct {
+	Format     string `json:"format" binding:"omitempty,oneof=OPENSSH PEM"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// KeePassExportRequest sets the master password of an exported KeePass
+// database.
+type KeePassExportRequest struct {
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// BackupExportRequest chooses how an exported backup is encrypted: with a
+// passphrase or to an age recipient ("age1...").
+type BackupExportRequest struct {
+	Passphrase string `json:"passphrase" binding:"omitempty,min=8"`
+	Recipient  string `json:"recipient"`
+}
+
+type SshKeyExportResponse struct {
+	Format      string `json:"format"`
+	PrivateKey  string `json:"private_key"`
+	PublicKey   string `json:"public_key"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// CreateSshRoleRequest defines a signing policy for the vault's SSH CA.
+// TTLs are in seconds and default to one hour, capped at one day.
+type CreateSshRoleRequest struct {
+	Name                   string   `json:"name" binding:"required"`
+	CaKeyId                string   `json:"ca_key_id" binding:"required"`
+	CertType               string   `json:"cert_type" binding:"required,oneof=USER HOST"`
+	AllowedPrincipals      []string `json:"allowed_principals" binding:"required,min=1"`
+	DefaultPrincipals      []string `json:"default_principals,omitempty"`
+	MaxTtlSeconds          int      `json:"max_ttl_seconds,omitempty" binding:"omitempty,min=1"`
+	DefaultTtlSeconds      int      `json:"default_ttl_seconds,omitempty" binding:"omitempty,min=1"`
+	AllowedExtensions      []string `json:"allowed_extensions,omitempty"`
+	DefaultExtensions      []string `json:"default_extensions,omitempty"`
+	AllowedCriticalOptions []string `json:"allowed_critical_options,omitempty"`
+}
+
+func (csr CreateSshRoleRequest) policy() sshkeys.Policy {
+	maxTTL := sshkeys.DefaultMaxCertTTL
+	if csr.MaxTtlSeconds > 0 {
+		maxTTL = time.Duration(csr.MaxTtlSeconds) * time.Second
+	}
+	defaultTTL := sshkeys.DefaultCertTTL
+	if csr.DefaultTtlSeconds > 0 {
+		defaultTTL = time.Duration(csr.DefaultTtlSeconds) * time.Second
+	}
+	if defaultTTL > maxTTL && csr.DefaultTtlSeconds == 0 {
+		defaultTTL = maxTTL
+	}
+	return sshkeys.Policy{
+		CertType:               sshkeys.CertType(csr.CertType),
+		AllowedPrincipals:      csr.AllowedPrincipals,
+		DefaultPrincipals:      csr.DefaultPrincipals,
+		MaxTTL:                 maxTTL,
+		DefaultTTL:             defaultTTL,
+		AllowedExtensions:      csr.AllowedExtensions,
+		DefaultExtensions:      csr.DefaultExtensions,
+		AllowedCriticalOptions: csr.AllowedCriticalOptions,
+	}
+}
+
+type SshRoleResponse struct {
+	Id                     string   `json:"id"`
+	Name                   string   `json:"name"`
+	CaKeyId                string   `json:"ca_key_id"`
+	CertType               string   `json:"cert_type"`
+	AllowedPrincipals      []string `json:"allowed_principals"`
+	DefaultPrincipals      []string `json:"default_principals"`
+	MaxTtlSeconds          int      `json:"max_ttl_seconds"`
+	DefaultTtlSeconds      int      `json:"default_ttl_seconds"`
+	AllowedExtensions      []string `json:"allowed_extensions"`
+	DefaultExtensions      []string `json:"default_extensions"`
+	AllowedCriticalOptions []string `json:"allowed_critical_options"`
+	CreatedAt              int64    `json:"created_at"`
+}
+
+func (srr *SshRoleResponse) load(r SshCaRole) {
+	p := r.Policy()
+	srr.Id = r.Id
+	srr.Name = r.Name
+	srr.CaKeyId = r.CaKeyRefer
+	srr.CertType = r.CertType
+	srr.AllowedPrincipals = p.AllowedPrincipals
+	srr.DefaultPrincipals = p.DefaultPrincipals
+	srr.MaxTtlSeconds = r.MaxTtlSeconds
+	srr.DefaultTtlSeconds = r.DefaultTtlSeconds
+	srr.AllowedExtensions = p.AllowedExtensions
+	srr.DefaultExtensions = p.DefaultExtensions
+	srr.AllowedCriticalOptions = p.AllowedCriticalOptions
+	srr.CreatedAt = r.CreatedAt.Unix()
+}
+
+type SshRoleListResponse struct {
+	Roles []SshRoleResponse `json:"roles"`
+}
+
+// SignSshCertificateRequest asks a role to certify PublicKey. Omitted
+// principals, ttl and extensions fall back to the role defaults; an empty
+// extensions list requests none.
+type SignSshCertificateRequest struct {
+	PublicKey       string            `json:"public_key" binding:"required"`
+	KeyId           string            `json:"key_id,omitempty"`
+	Principals      []string          `json:"principals,omitempty"`
+	TtlSeconds      int               `json:"ttl_seconds,omitempty" binding:"omitempty,min=1"`
+	Extensions      []string          `json:"extensions"`
+	CriticalOptions map[string]string `json:"critical_options,omitempty"`
+}
+
+type SshCertificateResponse struct {
+	Certificate   string   `json:"certificate"`
+	Serial        string   `json:"serial"`
+	KeyId         string   `json:"key_id"`
+	CertType      string   `json:"cert_type"`
+	Principals    []string `json:"principals"`
+	ValidAfter    int64    `json:"valid_after"`
+	ValidBefore   int64    `json:"valid_before"`
+	CaFingerprint string   `json:"ca_fingerprint"`
+}
+
+// IssueCertificateRequest asks a CA for a leaf certificate. With a Csr the
+// key and names come from the request, though names given here take
+// precedence; without one a key is generated and returned once.
+type IssueCertificateRequest struct {
+	Csr         string   `json:"csr,omitempty"`
+	CommonName  string   `json:"common_name,omitempty"`
+	DnsNames    []string `json:"dns_names,omitempty"`
+	IpAddresses []string `json:"ip_addresses,omitempty" binding:"omitempty,dive,ip"`
+	TtlSeconds  int      `json:"ttl_seconds,omitempty" binding:"omitempty,min=1"`
+	KeyType     string   `json:"key_type,omitempty"`
+	Bits        int      `json:"bits,omitempty"`
+}
+
+func (icr IssueCertificateRequest) hasNames() bool {
+	return icr.CommonName != "" || len(icr.DnsNames) > 0 || len(icr.IpAddresses) > 0
+}
+
+func (icr IssueCertificateRequest) ips() []net.IP {
+	ips := make([]net.IP, 0, len(icr.IpAddresses))
+	for _, ip := range icr.IpAddresses {
+		ips = append(ips, net.ParseIP(ip))
+	}
+	return ips
+}
+
+type RevokeCertificateRequest struct {
+	Reason int `json:"reason,omitempty" binding:"omitempty,min=0,max=10"`
+}
+
+type IssuedCertificateResponse struct {
+	Id            string   `json:"id"`
+	SerialNumber  string   `json:"serial_number"`
+	CommonName    string   `json:"common_name,omitempty"`
+	DnsNames      []string `json:"dns_names,omitempty"`
+	IpAddresses   []string `json:"ip_addresses,omitempty"`
+	NotBefore     int64    `json:"not_before"`
+	NotAfter      int64    `json:"not_after"`
+	DaysRemaining int      `json:"days_remaining"`
+	Expired       bool     `json:"expired"`
+	RevokedAt     int64    `json:"revoked_at,omitempty"`
+	Certificate   string   `json:"certificate"`
+	IssuingCa     string   `json:"issuing_ca,omitempty"`
+	PrivateKey    string   `json:"private_key,omitempty"`
+}
+
+func (icr *IssuedCertificateResponse) load(c IssuedCertificate, now time.Time) {
+	icr.Id = c.Id
+	icr.SerialNumber = c.SerialNumber
+	icr.CommonName = c.CommonName
+	icr.DnsNames = splitList(c.DnsNames)
+	icr.IpAddresses = splitList(c.IpAddresses)
+	icr.NotBefore = c.NotBefore.Unix()
+	icr.NotAfter = c.NotAfter.Unix()
+	icr.Expired = !c.NotAfter.After(now)
+	if !icr.Expired {
+		icr.DaysRemaining = int(math.Ceil(c.NotAfter.Sub(now).Hours() / 24))
+	}
+	if c.RevokedAt != nil {
+		icr.RevokedAt = c.RevokedAt.Unix()
+	}
+	icr.Certificate = c.Certificate
+}
+
+type IssuedCertificateListResponse struct {
+	Certificates []IssuedCertificateResponse `json:"certificates"`
+}
+
+// CustomFieldRequest sets one custom field. When replacing fields, an
+// existing hidden field referenced by Id keeps its value if Value is empty,
+// so clients never need to reveal a value to leave it unchanged.
+type CustomFieldRequest struct {
+	Id    string    `json:"id,omitempty"`
+	Name  string    `json:"name" binding:"required,max=255"`
+	Type  FieldType `json:"type" binding:"required,field_type"`
+	Value string    `json:"value" binding:"max=10000"`
+}
+
+type UpdateFieldsRequest struct {
+	Fields []CustomFieldRequest `json:"fields" binding:"max=50,dive"`
+}
+
+type FieldsRequest struct {
+	Reveal bool `form:"reveal"`
+}
+
+type CustomFieldResponse struct {
+	Id    string    `json:"id"`
+	Name  string    `json:"name"`
+	Type  FieldType `json:"type"`
+	Value string    `json:"value"`
+}
+
+// maskedValue stands in for hidden field values that were not revealed.
+const maskedValue = "********"
+
+// load fills the response from f. Hidden values are masked unless their
+// decrypted value is passed as revealed.
+func (cfr *CustomFieldResponse) load(f CustomField, revealed string) {
+	cfr.Id = f.Id
+	cfr.Name = f.Name
+	cfr.Type = f.Type
+	cfr.Value = string(f.Value)
+	if f.Type == FieldHidden && len(f.Value) > 0 {
+		cfr.Value = maskedValue
+		if revealed != "" {
+			cfr.Value = revealed
+		}
+	}
+}
+
+type CustomFieldListResponse struct {
+	Fields []CustomFieldResponse `json:"fields"`
+}
+
+type AttachmentResponse struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+func (ar *AttachmentResponse) load(a Attachment) {
+	ar.Id = a.Id
+	ar.Name = a.Name
+	ar.ContentType = a.ContentType
+	ar.Size = a.Size
+	ar.CreatedAt = a.CreatedAt.Unix()
+}
+
+type AttachmentListResponse struct {
+	Attachments []AttachmentResponse `json:"attachments"`
+}
+
+// VaultDetailsRequest narrows the secrets of a vault down to one folder,
+// optionally including its subfolders, and to one tag.
+type VaultDetailsRequest struct {
+	ListRequest
+	Type      SecretType `form:"type"`
+	Folder    string     `form:"folder"`
+	Recursive bool       `form:"recursive"`
+	Tag       string     `form:"tag"`
+}
+
+type CreateFolderRequest struct {
+	Name     string `json:"name" binding:"required,max=255"`
+	ParentId string `json:"parent_id,omitempty"`
+}
+
+// UpdateFolderRequest renames or moves a folder. Omitted fields are left
+// alone; an empty ParentId moves the folder to the top level.
+type UpdateFolderRequest struct {
+	Name     *string `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+	ParentId *string `json:"parent_id,omitempty"`
+}
+
+type FolderResponse struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	ParentId  string `json:"parent_id,omitempty"`
+	Path      string `json:"path"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func (fr *FolderResponse) load(f Folder, tree folderTree) {
+	fr.Id = f.Id
+	fr.Name = f.Name
+	fr.ParentId = deref(f.ParentId)
+	fr.Path = tree.path(f.Id)
+	fr.CreatedAt = f.CreatedAt.Unix()
+}
+
+type FolderListResponse struct {
+	Folders []FolderResponse `json:"folders"`
+}
+
+func (flr *FolderListResponse) load(folders []Folder) {
+	tree := newFolderTree(folders)
+	flr.Folders = make([]FolderResponse, len(folders))
+	for i, f := range folders {
+		flr.Folders[i].load(f, tree)
+	}
+}
+
+// MoveSecretRequest moves a secret to a folder; empty is the top level.
+type MoveSecretRequest struct {
+	FolderId string `json:"folder_id"`
+}
+
+type TagSecretRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type SearchSecretsRequest struct {
+	Query    string `form:"q" binding:"required,max=200"`
+	Page     int    `form:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+type SearchResultResponse struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Type       SecretType `json:"type"`
+	VaultId    string     `json:"vault_id"`
+	VaultName  string     `json:"vault_name"`
+	FolderPath string     `json:"folder_path,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	Score      int        `json:"score"`
+	UpdatedAt  int64      `json:"updated_at"`
+}
+
+func (srr *SearchResultResponse) load(s SecretSummary, score int, tree folderTree) {
+	srr.Id = s.Id
+	srr.Name = s.Name
+	srr.Type = s.Type
+	srr.VaultId = s.VaultId
+	srr.VaultName = s.VaultName
+	srr.FolderPath = tree.path(deref(s.FolderId))
+	srr.Tags = splitList(s.Tags)
+	srr.Score = score
+	srr.UpdatedAt = s.UpdatedAt.Unix()
+}
+
+type SearchResponse struct {
+	Results  []SearchResultResponse `json:"results"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"page_size"`
+	Total    int64                  `json:"total"`
+}
+
+type TrashedVaultResponse struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	DeletedAt int64  `json:"deleted_at"`
+	PurgeAt   int64  `json:"purge_at"`
+}
+
+type TrashedSecretResponse struct {
+	Id        string     `json:"id"`
+	Name      string     `json:"name"`
+	Type      SecretType `json:"type"`
+	VaultId   string     `json:"vault_id"`
+	VaultName string     `json:"vault_name"`
+	DeletedAt int64      `json:"deleted_at"`
+	PurgeAt   int64      `json:"purge_at"`
+}
+
+type TrashResponse struct {
+	Vaults  []TrashedVaultResponse  `json:"vaults"`
+	Secrets []TrashedSecretResponse `json:"secrets"`
+}
+
+func (tr *TrashResponse) load(vaults []Vault, secrets []TrashedSecret, retention time.Duration) {
+	tr.Vaults = make([]TrashedVaultResponse, 0, len(vaults))
+	for _, v := range vaults {
+		tr.Vaults = append(tr.Vaults, TrashedVaultResponse{
+			Id:        v.Id,
+			Name:      v.Name,
+			DeletedAt: v.DeletedAt.Time.Unix(),
+			PurgeAt:   v.DeletedAt.Time.Add(retention).Unix(),
+		})
+	}
+	tr.Secrets = make([]TrashedSecretResponse, 0, len(secrets))
+	for _, s := range secrets {
+		tr.Secrets = append(tr.Secrets, TrashedSecretResponse{
+			Id:        s.Id,
+			Name:      s.Name,
+			Type:      s.Type,
+			VaultId:   s.VaultId,
+			VaultName: s.VaultName,
+			DeletedAt: s.DeletedAt.Unix(),
+			PurgeAt:   s.DeletedAt.Add(retention).Unix(),
+		})
+	}
+}
+
+// TransferSecretsRequest moves or copies secrets of the :id vault into
+// another of the caller's vaults, optionally into one of its folders.
+type TransferSecretsRequest struct {
+	SecretIds     []string `json:"secret_ids" binding:"required,min=1,max=100,unique,dive,required"`
+	TargetVaultId string   `json:"target_vault_id" binding:"required"`
+	FolderId      string   `json:"folder_id"`
+}
+
+type TransferredSecretResponse struct {
+	SourceId string `json:"source_id"`
+	Id       string `json:"id"`
+}
+
+type TransferSecretsResponse struct {
+	VaultId string                      `json:"vault_id"`
+	Secrets []TransferredSecretResponse `json:"secrets"`
+}
+
+// BatchSecretsRequest applies up to 100 operations to the secrets of a
+// vault in one transaction. In the default atomic mode they are applied
+// all or not at all; in partial mode each one succeeds or fails alone.
+type BatchSecretsRequest struct {
+	Mode       string                  `json:"mode" binding:"omitempty,oneof=atomic partial"`
+	Operations []BatchOperationRequest `json:"operations" binding:"required,min=1,max=100"`
+}
+
+// BatchOperationRequest creates, updates or deletes one secret. Secret is
+// a CreateSecretRequest, which for an update replaces the secret with
+// SecretId and must keep its type. Operations are checked one by one so
+// that a bad one is reported in its result.
+type BatchOperationRequest struct {
+	Op       BatchOp         `json:"op"`
+	SecretId string          `json:"secret_id,omitempty"`
+	Secret   json.RawMessage `json:"secret,omitempty"`
+}
+
+// BatchResultResponse is the outcome of one operation, with the HTTP
+// status the operation would have had on its own. Operations of a failed
+// atomic batch that were fine themselves get 424 Failed Dependency.
+type BatchResultResponse struct {
+	Index    int        `json:"index"`
+	Op       BatchOp    `json:"op"`
+	SecretId string     `json:"secret_id,omitempty"`
+	Type     SecretType `json:"type,omitempty"`
+	Status   int        `json:"status"`
+	Message  string     `json:"message,omitempty"`
+}
+
+type BatchSecretsResponse struct {
+	Committed bool                  `json:"committed"`
+	Results   []BatchResultResponse `json:"results"`
+}
+
+// ImportRequest holds the form fields sent along with an export file.
+// Mapping is an imports.Mapping as JSON and is only used for generic CSV
+// files. Password opens KeePass databases, along with a key file sent as
+// keyfile. Duplicates of existing secrets are skipped unless
+// ImportDuplicates is set.
+type ImportRequest struct {
+	Format           imports.Format `form:"format" binding:"required"`
+	DryRun           bool           `form:"dry_run"`
+	ImportDuplicates bool           `form:"import_duplicates"`
+	Mapping          string         `form:"mapping"`
+	Password         string         `form:"password"`
+}
+
+// RestoreRequest opens an uploaded backup, sent as file, with either its
+// passphrase or the age identity ("AGE-SECRET-KEY-1...") it was encrypted
+// to. Vault picks which backed up vault to restore into an existing one
+// and may be left out when the backup holds a single vault.
+type RestoreRequest struct {
+	Passphrase string `form:"passphrase"`
+	Identity   string `form:"identity"`
+	Vault      string `form:"vault"`
+}
+
+// RestoredVaultResponse counts the secrets restored into a vault and the
+// folders created for them.
+type RestoredVaultResponse struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Secrets int    `json:"secrets"`
+	Folders int    `json:"folders"`
+}
+
+type RestoreResponse struct {
+	Vaults []RestoredVaultResponse `json:"vaults"`
+}
+
+// ImportEntryResponse reports what happened, or in a dry run would happen,
+// to one entry of an export. Status is new, duplicate or error.
+type ImportEntryResponse struct {
+	Row         int        `json:"row"`
+	Name        string     `json:"name,omitempty"`
+	Type        SecretType `json:"type,omitempty"`
+	Folder      string     `json:"folder,omitempty"`
+	Status      string     `json:"status"`
+	Id          string     `json:"id,omitempty"`
+	DuplicateOf string     `json:"duplicate_of,omitempty"`
+	Message     string     `json:"message,omitempty"`
+}
+
+type ImportResponse struct {
+	DryRun         bool                  `json:"dry_run"`
+	Imported       int                   `json:"imported"`
+	Duplicates     int                   `json:"duplicates"`
+	Failed         int                   `json:"failed"`
+	FoldersCreated []string              `json:"folders_created"`
+	Entries        []ImportEntryResponse `json:"entries"`
 }
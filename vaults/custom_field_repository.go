@@ -0,0 +1,40 @@
+package vaults
+
+import "gorm.io/gorm"
+
+type CustomFieldRepository interface {
+	FindFields(secretId string, fields *[]CustomField) error
+	FindFieldsByVault(vaultId string, fields *[]CustomField) error
+	FindFieldsBySecrets(secretIds []string, fields *[]CustomField) error
+	ReplaceFields(secretId string, fields []CustomField) error
+}
+
+type CustomFieldRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func (cfr *CustomFieldRepositoryImpl) FindFields(secretId string, fields *[]CustomField) error {
+	return cfr.Db.Where("secret_id = ?", secretId).Order("position ASC").Find(fields).Error
+}
+
+func (cfr *CustomFieldRepositoryImpl) FindFieldsByVault(vaultId string, fields *[]CustomField) error {
+	return cfr.Db.Where("vault_refer = ?", vaultId).Order("secret_id ASC, position ASC").Find(fields).Error
+}
+
+func (cfr *CustomFieldRepositoryImpl) FindFieldsBySecrets(secretIds []string, fields *[]CustomField) error {
+	return cfr.Db.Where("secret_id IN ?", secretIds).Order("secret_id ASC, position ASC").Find(fields).Error
+}
+
+// ReplaceFields swaps the whole set of fields of a secret in one
+// transaction.
+func (cfr *CustomFieldRepositoryImpl) ReplaceFields(secretId string, fields []CustomField) error {
+	return cfr.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("secret_id = ?", secretId).Delete(&CustomField{}).Error; err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		return tx.Create(&fields).Error
+	})
+}
@@ -3,15 +3,46 @@ package vaults
 type SecretType string
 
 const (
-	TypeCredential SecretType = "CREDENTIAL"
-	TypeKey        SecretType = "KEY"
-	TypeDocument              = "DOCUMENT"
+	TypeCredential           SecretType = "CREDENTIAL"
+	TypeKey                  SecretType = "KEY"
+	TypeDocument                        = "DOCUMENT"
+	TypeTotp                 SecretType = "TOTP"
+	TypeSshKey               SecretType = "SSH_KEY"
+	TypeCertificateAuthority SecretType = "CERTIFICATE_AUTHORITY"
+	TypeCard                 SecretType = "CARD"
+	TypeIdentity             SecretType = "IDENTITY"
 )
 
 func (st SecretType) IsValid() bool {
 	switch st {
-	case TypeCredential, TypeKey, TypeDocument:
+	case TypeCredential, TypeKey, TypeDocument, TypeTotp, TypeSshKey, TypeCertificateAuthority, TypeCard, TypeIdentity:
 		return true
 	}
 	return false
 }
+
+type FieldType string
+
+const (
+	FieldText    FieldType = "TEXT"
+	FieldHidden  FieldType = "HIDDEN"
+	FieldBoolean FieldType = "BOOLEAN"
+	FieldUrl     FieldType = "URL"
+)
+
+func (ft FieldType) IsValid() bool {
+	switch ft {
+	case FieldText, FieldHidden, FieldBoolean, FieldUrl:
+		return true
+	}
+	return false
+}
+
+// BatchOp is what a batch operation does to a secret.
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
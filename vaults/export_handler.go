@@ -0,0 +1,475 @@
+package vaults
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/backup"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/keepass"
+	"github.com/adarsh-a-tw/passwordly/totp"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandler writes the secrets of a vault out, either as an encrypted
+// backup that can be restored or in formats other password managers read.
+type ExportHandler struct {
+	Ep         utils.EncryptionProvider
+	Repo       SecretRepository
+	VaultRepo  VaultRepository
+	Folders    FolderRepository
+	Fields     CustomFieldRepository
+	Audit      audit.Recorder
+	KeePass    keepass.Settings
+	WorkFactor int
+}
+
+// ExportBackup downloads every vault of the current user as an encrypted
+// backup.
+func (eh *ExportHandler) ExportBackup(ctx *gin.Context) {
+	var req BackupExportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	var vaults []Vault
+	if err := eh.VaultRepo.FetchByUserId(ctx.GetString("user_id"), &vaults); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	sort.SliceStable(vaults, func(i, j int) bool { return vaults[i].CreatedAt.Before(vaults[j].CreatedAt) })
+	filename := fmt.Sprintf("passwordly-backup-%s.age", time.Now().Format("2006-01-02"))
+	eh.sendBackup(ctx, req, vaults, audit.TargetUser, ctx.GetString("user_id"), filename)
+}
+
+// ExportVaultBackup downloads one vault as an encrypted backup.
+func (eh *ExportHandler) ExportVaultBackup(ctx *gin.Context) {
+	var req BackupExportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	vaultId, ok := requireVaultOwner(ctx, eh.VaultRepo)
+	if !ok {
+		return
+	}
+	var v Vault
+	if err := eh.VaultRepo.FetchById(vaultId, &v); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	filename := fmt.Sprintf("%s-%s.age", v.Name, time.Now().Format("2006-01-02"))
+	eh.sendBackup(ctx, req, []Vault{v}, audit.TargetVault, vaultId, filename)
+}
+
+func (eh *ExportHandler) sendBackup(ctx *gin.Context, req BackupExportRequest, vaults []Vault, targetType audit.TargetType, targetId string, filename string) {
+	content := backup.Vaults{Vaults: make([]backup.Vault, 0, len(vaults))}
+	count := 0
+	for i := range vaults {
+		bv, err := eh.backupVault(&vaults[i])
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		content.Vaults = append(content.Vaults, bv)
+		count += len(bv.Secrets)
+	}
+
+	data, err := backup.Seal(backup.FormatVaults, content, backup.Protection{
+		Passphrase: req.Passphrase,
+		Recipient:  strings.TrimSpace(req.Recipient),
+		WorkFactor: eh.WorkFactor,
+	})
+	if errors.Is(err, backup.ErrProtection) || errors.Is(err, backup.ErrInvalidAgeKey) {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(eh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretExport,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Detail:     fmt.Sprintf("%d secrets of %d vaults to %s", count, len(vaults), backup.FormatVaults),
+	})
+	eh.sendFile(ctx, filename, data)
+}
+
+// ExportKeePass downloads a vault as a KDBX 4 database protected by the
+// given master password. Folders become groups and every secret an entry;
+// attachments are left out.
+func (eh *ExportHandler) ExportKeePass(ctx *gin.Context) {
+	var req KeePassExportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	vaultId, ok := requireVaultOwner(ctx, eh.VaultRepo)
+	if !ok {
+		return
+	}
+	var v Vault
+	if err := eh.VaultRepo.FetchById(vaultId, &v); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	bv, err := eh.backupVault(&v)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	data, err := keepass.Write(keePassDatabase(bv), keepass.Credentials{Password: req.Password}, eh.KeePass)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(eh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretExport,
+		TargetType: audit.TargetVault,
+		TargetId:   vaultId,
+		Detail:     fmt.Sprintf("%d secrets to keepass_kdbx", len(bv.Secrets)),
+	})
+	eh.sendFile(ctx, v.Name+".kdbx", data)
+}
+
+func (eh *ExportHandler) sendFile(ctx *gin.Context, filename string, data []byte) {
+	header := ctx.Writer.Header()
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	ctx.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+// backupVault decrypts a vault with its folders, secrets and their fields.
+func (eh *ExportHandler) backupVault(v *Vault) (backup.Vault, error) {
+	bv := backup.Vault{Id: v.Id, Name: v.Name, CreatedAt: v.CreatedAt, Folders: []backup.Folder{}, Secrets: []backup.Secret{}}
+
+	var folders []Folder
+	if err := eh.Folders.FindFolders(v.Id, &folders); err != nil {
+		return backup.Vault{}, err
+	}
+	for _, f := range folders {
+		bv.Folders = append(bv.Folders, backup.Folder{Id: f.Id, Name: f.Name, ParentId: deref(f.ParentId)})
+	}
+
+	var fields []CustomField
+	if err := eh.Fields.FindFieldsByVault(v.Id, &fields); err != nil {
+		return backup.Vault{}, err
+	}
+	fieldsBySecret := make(map[string][]CustomField)
+	for _, f := range fields {
+		fieldsBySecret[f.SecretId] = append(fieldsBySecret[f.SecretId], f)
+	}
+
+	add := func(s Securable, build func(bs *backup.Secret) error) error {
+		m := s.Metadata()
+		bs := backup.Secret{
+			Id:              m.Id,
+			Type:            string(s.Type()),
+			Name:            m.Name,
+			FolderId:        deref(m.Placement.FolderId),
+			Tags:            splitList(m.Placement.Tags),
+			ExpiresAt:       m.Expiry.ExpiresAt,
+			RotateEveryDays: m.Expiry.RotateEveryDays,
+			CreatedAt:       m.CreatedAt,
+			UpdatedAt:       m.UpdatedAt,
+		}
+		if err := build(&bs); err != nil {
+			return err
+		}
+		for _, f := range fieldsBySecret[m.Id] {
+			value := string(f.Value)
+			if f.Type == FieldHidden {
+				var err error
+				if value, err = eh.decrypt(f.Value); err != nil {
+					return err
+				}
+			}
+			bs.Fields = append(bs.Fields, backup.Field{Name: f.Name, Type: string(f.Type), Value: value})
+		}
+		bv.Secrets = append(bv.Secrets, bs)
+		return nil
+	}
+	if err := eh.backupSecrets(v.Id, add); err != nil {
+		return backup.Vault{}, err
+	}
+	return bv, nil
+}
+
+// backupSecrets decrypts the secrets of every type in a vault and passes
+// each to add along with how to fill in its particular values.
+func (eh *ExportHandler) backupSecrets(vaultId string, add func(s Securable, build func(bs *backup.Secret) error) error) error {
+	var credentials []Credential
+	if err := eh.Repo.FindCredentials(&credentials, vaultId); err != nil {
+		return err
+	}
+	for _, c := range credentials {
+		err := add(c, func(bs *backup.Secret) error {
+			password, err := eh.decrypt(c.Password)
+			bs.Credential = &backup.Credential{Username: c.Username, Password: password}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var keys []Key
+	if err := eh.Repo.FindKeys(&keys, vaultId); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		err := add(k, func(bs *backup.Secret) error {
+			value, err := eh.decrypt(k.Value)
+			bs.Key = &backup.Key{Value: value}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var documents []Document
+	if err := eh.Repo.FindDocuments(&documents, vaultId); err != nil {
+		return err
+	}
+	for _, d := range documents {
+		err := add(d, func(bs *backup.Secret) error {
+			content, err := eh.decrypt(d.Content)
+			bs.Document = &backup.Document{Content: content}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var totps []Totp
+	if err := eh.Repo.FindTotps(&totps, vaultId); err != nil {
+		return err
+	}
+	for _, t := range totps {
+		err := add(t, func(bs *backup.Secret) error {
+			secret, err := eh.decrypt(t.Secret)
+			bs.Totp = &backup.Totp{Issuer: t.Issuer, AccountName: t.AccountName, Secret: secret, Algorithm: t.Algorithm, Digits: t.Digits, Period: t.Period}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var sshKeys []SshKey
+	if err := eh.Repo.FindSshKeys(&sshKeys, vaultId); err != nil {
+		return err
+	}
+	for _, k := range sshKeys {
+		err := add(k, func(bs *backup.Secret) error {
+			privateKey, err := eh.decrypt(k.PrivateKey)
+			bs.SshKey = &backup.SshKey{KeyType: k.KeyType, Bits: k.Bits, PrivateKey: privateKey, PublicKey: k.PublicKey, Fingerprint: k.Fingerprint, Comment: k.Comment}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var cas []CertificateAuthority
+	if err := eh.Repo.FindCertificateAuthorities(&cas, vaultId); err != nil {
+		return err
+	}
+	for _, ca := range cas {
+		err := add(ca, func(bs *backup.Secret) error {
+			privateKey, err := eh.decrypt(ca.PrivateKey)
+			bs.CertificateAuthority = &backup.CertificateAuthority{
+				Subject:         ca.Subject,
+				Certificate:     ca.Certificate,
+				PrivateKey:      privateKey,
+				AllowedDomains:  splitList(ca.AllowedDomains),
+				AllowSubdomains: ca.AllowSubdomains,
+				AllowIpSans:     ca.AllowIpSans,
+				MaxTtlSeconds:   ca.MaxTtlSeconds,
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var cards []Card
+	if err := eh.Repo.FindCards(&cards, vaultId); err != nil {
+		return err
+	}
+	for _, c := range cards {
+		err := add(c, func(bs *backup.Secret) error {
+			number, err := eh.decrypt(c.Number)
+			if err != nil {
+				return err
+			}
+			cvv, err := eh.decrypt(c.Cvv)
+			bs.Card = &backup.Card{CardholderName: c.CardholderName, Number: number, Brand: c.Brand, ExpiryMonth: c.ExpiryMonth, ExpiryYear: c.ExpiryYear, Cvv: cvv}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var identities []Identity
+	if err := eh.Repo.FindIdentities(&identities, vaultId); err != nil {
+		return err
+	}
+	for _, i := range identities {
+		err := add(i, func(bs *backup.Secret) error {
+			var values [3]string
+			for n, value := range [][]byte{i.Address, i.PassportNumber, i.LicenceNumber} {
+				var err error
+				if values[n], err = eh.decrypt(value); err != nil {
+					return err
+				}
+			}
+			bs.Identity = &backup.Identity{FullName: i.FullName, Email: i.Email, Phone: i.Phone, Address: values[0], PassportNumber: values[1], LicenceNumber: values[2]}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (eh *ExportHandler) decrypt(value []byte) (string, error) {
+	if len(value) == 0 {
+		return "", nil
+	}
+	return eh.Ep.Decrypt(string(value))
+}
+
+// keePassDatabase turns a vault into a database named after it, with a
+// group for every folder.
+func keePassDatabase(bv backup.Vault) *keepass.Database {
+	children := make(map[string][]backup.Folder)
+	known := make(map[string]bool, len(bv.Folders))
+	for _, f := range bv.Folders {
+		children[f.ParentId] = append(children[f.ParentId], f)
+		known[f.Id] = true
+	}
+	byFolder := make(map[string][]keepass.Entry)
+	for _, s := range bv.Secrets {
+		folderId := s.FolderId
+		if !known[folderId] {
+			folderId = ""
+		}
+		byFolder[folderId] = append(byFolder[folderId], keePassEntry(s))
+	}
+
+	seen := make(map[string]bool)
+	var group func(name string, id string) keepass.Group
+	group = func(name string, id string) keepass.Group {
+		g := keepass.Group{Name: name, Entries: byFolder[id]}
+		sort.SliceStable(g.Entries, func(i, j int) bool { return strings.ToLower(g.Entries[i].Title) < strings.ToLower(g.Entries[j].Title) })
+		subs := children[id]
+		sort.SliceStable(subs, func(i, j int) bool { return strings.ToLower(subs[i].Name) < strings.ToLower(subs[j].Name) })
+		for _, f := range subs {
+			if !seen[f.Id] {
+				seen[f.Id] = true
+				g.Groups = append(g.Groups, group(f.Name, f.Id))
+			}
+		}
+		return g
+	}
+	return &keepass.Database{Name: bv.Name, Root: group(bv.Name, "")}
+}
+
+// keePassEntry maps a secret to an entry. Passwords and key values become
+// the entry password, documents its notes and the values of other secret
+// types named strings, with the sensitive ones protected. The first URL
+// field becomes the entry URL.
+func keePassEntry(s backup.Secret) keepass.Entry {
+	e := keepass.Entry{Title: s.Name, Tags: s.Tags, CreatedAt: s.CreatedAt, ModifiedAt: s.UpdatedAt}
+	switch {
+	case s.Credential != nil:
+		e.UserName, e.Password = s.Credential.Username, s.Credential.Password
+	case s.Key != nil:
+		e.Password = s.Key.Value
+	case s.Document != nil:
+		e.Notes = s.Document.Content
+	case s.Totp != nil:
+		t := s.Totp
+		key := totp.Key{Secret: t.Secret, Issuer: t.Issuer, AccountName: t.AccountName, Algorithm: totp.Algorithm(t.Algorithm), Digits: t.Digits, Period: t.Period}
+		e.UserName = t.AccountName
+		addKeePassField(&e, "otp", key.URI(), true)
+	case s.SshKey != nil:
+		addKeePassField(&e, "Private key", s.SshKey.PrivateKey, true)
+		addKeePassField(&e, "Public key", s.SshKey.PublicKey, false)
+		addKeePassField(&e, "Fingerprint", s.SshKey.Fingerprint, false)
+	case s.CertificateAuthority != nil:
+		addKeePassField(&e, "Private key", s.CertificateAuthority.PrivateKey, true)
+		addKeePassField(&e, "Certificate", s.CertificateAuthority.Certificate, false)
+	case s.Card != nil:
+		c := s.Card
+		e.UserName = c.CardholderName
+		addKeePassField(&e, "Number", c.Number, true)
+		addKeePassField(&e, "Brand", c.Brand, false)
+		addKeePassField(&e, "Expiry", fmt.Sprintf("%02d/%d", c.ExpiryMonth, c.ExpiryYear), false)
+		addKeePassField(&e, "CVV", c.Cvv, true)
+	case s.Identity != nil:
+		i := s.Identity
+		addKeePassField(&e, "Full name", i.FullName, false)
+		addKeePassField(&e, "Email", i.Email, false)
+		addKeePassField(&e, "Phone", i.Phone, false)
+		addKeePassField(&e, "Address", i.Address, false)
+		addKeePassField(&e, "Passport number", i.PassportNumber, true)
+		addKeePassField(&e, "Licence number", i.LicenceNumber, true)
+	}
+
+	for _, f := range s.Fields {
+		if FieldType(f.Type) == FieldUrl && e.URL == "" {
+			e.URL = f.Value
+			continue
+		}
+		addKeePassField(&e, f.Name, f.Value, FieldType(f.Type) == FieldHidden)
+	}
+	return e
+}
+
+// addKeePassField skips empty values and numbers repeated names, as the
+// strings of a KeePass entry need unique names.
+func addKeePassField(e *keepass.Entry, name string, value string, protected bool) {
+	if value == "" {
+		return
+	}
+	taken := func(key string) bool {
+		switch key {
+		case "Title", "UserName", "Password", "URL", "Notes":
+			return true
+		}
+		for _, f := range e.Fields {
+			if f.Key == key {
+				return true
+			}
+		}
+		return false
+	}
+	key := name
+	for n := 2; taken(key); n++ {
+		key = name + " (" + strconv.Itoa(n) + ")"
+	}
+	e.Fields = append(e.Fields, keepass.Field{Key: key, Value: value, Protected: protected})
+}
@@ -0,0 +1,143 @@
+package vaults_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/keepass"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestExportHandler_ShouldExportVaultAsKeePassDatabase(t *testing.T) {
+	eh, _ := prepareExport(t)
+
+	rec := exportKeePass(eh, "work", `{"password": "correct horse"}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename=Work.kdbx`, rec.Header().Get("Content-Disposition"))
+	db, err := keepass.Read(rec.Body.Bytes(), keepass.Credentials{Password: "correct horse"}, 1<<20)
+	assert.NoError(t, err)
+	assert.Equal(t, "Work", db.Name)
+	assert.Equal(t, []string{"Stripe", "Visa"}, entryTitles(db.Root))
+	assert.Equal(t, "sk_live_1", db.Root.Entries[0].Password)
+	assert.Equal(t, []keepass.Field{
+		{Key: "Number", Value: "4111111111111111", Protected: true},
+		{Key: "Expiry", Value: "04/2030", Protected: false},
+		{Key: "CVV", Value: "123", Protected: true},
+	}, db.Root.Entries[1].Fields)
+
+	assert.Len(t, db.Root.Groups, 1)
+	servers := db.Root.Groups[0]
+	assert.Equal(t, "Servers", servers.Name)
+	assert.Equal(t, []string{"Build server", "Runbook"}, entryTitles(servers))
+	build := servers.Entries[0]
+	assert.Equal(t, "root", build.UserName)
+	assert.Equal(t, "toor", build.Password)
+	assert.Equal(t, "https://ci.example.com", build.URL)
+	assert.Equal(t, []string{"ci", "prod"}, build.Tags)
+	assert.Equal(t, []keepass.Field{
+		{Key: "PIN", Value: "1234", Protected: true},
+		{Key: "PIN (2)", Value: "5678", Protected: false},
+	}, build.Fields)
+	assert.Equal(t, "restart the agent", servers.Entries[1].Notes)
+	assert.Equal(t, []string{"GitHub 2FA"}, entryTitles(servers.Groups[0]))
+	assert.Equal(t, "otpauth://totp/GitHub:octocat?algorithm=SHA1&digits=6&issuer=GitHub&period=30&secret=JBSWY3DPEHPK3PXP",
+		servers.Groups[0].Entries[0].Fields[0].Value)
+}
+
+func TestExportHandler_ExportShouldImportBackIntoAVault(t *testing.T) {
+	eh, db := prepareExport(t)
+	rec := exportKeePass(eh, "work", `{"password": "correct horse"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	ih := &vaults.ImportHandler{Ep: eh.Ep, Repo: eh.Repo, VaultRepo: eh.VaultRepo, Folders: eh.Folders, MaxSize: 1 << 20}
+	resp, code := importExport(t, ih, "copy", map[string]string{"format": "keepass_kdbx", "password": "correct horse"}, rec.Body.String())
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, 5, resp.Imported)
+	assert.Equal(t, 0, resp.Failed)
+	assert.Equal(t, []string{"Servers", "Servers/Two factor"}, resp.FoldersCreated)
+
+	var c vaults.Credential
+	assert.NoError(t, db.First(&c, "vault_refer = ? AND name = ?", "copy", "Build server").Error)
+	assert.Equal(t, "root", c.Username)
+	assert.Equal(t, "sealed:toor", string(c.Password))
+	assert.Equal(t, "ci,prod", c.Tags)
+	var d vaults.Document
+	assert.NoError(t, db.First(&d, "vault_refer = ? AND name = ?", "copy", "Runbook").Error)
+	assert.Equal(t, "sealed:restart the agent", string(d.Content))
+
+	_, code = importExport(t, ih, "copy", map[string]string{"format": "keepass_kdbx", "password": "wrong"}, rec.Body.String())
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestExportHandler_ShouldRejectInvalidRequests(t *testing.T) {
+	eh, _ := prepareExport(t)
+
+	assert.Equal(t, http.StatusBadRequest, exportKeePass(eh, "work", `{"password": "short"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, exportKeePass(eh, "work", `{}`).Code)
+	assert.Equal(t, http.StatusNotFound, exportKeePass(eh, "other", `{"password": "correct horse"}`).Code)
+}
+
+// prepareExport stores a "work" vault of user_1 with secrets of several
+// types in nested folders, an empty "copy" vault and a vault of user_2.
+// Encrypted values are stored as "sealed:" followed by the plaintext.
+func prepareExport(t *testing.T) (*vaults.ExportHandler, *gorm.DB) {
+	db := prepareDb(t)
+
+	servers, twoFactor := "servers", "two_factor"
+	for _, record := range []any{
+		&vaults.Vault{Id: "work", Name: "Work", UserRefer: "user_1"},
+		&vaults.Vault{Id: "copy", Name: "Copy", UserRefer: "user_1"},
+		&vaults.Vault{Id: "other", Name: "Other", UserRefer: "user_2"},
+		&vaults.Folder{Id: servers, Name: "Servers", VaultRefer: "work"},
+		&vaults.Folder{Id: twoFactor, Name: "Two factor", ParentId: &servers, VaultRefer: "work"},
+		&vaults.Credential{Id: "build", Name: "Build server", Username: "root", Password: []byte("sealed:toor"), VaultRefer: "work",
+			Placement: vaults.Placement{FolderId: &servers, Tags: "ci,prod"}},
+		&vaults.CustomField{Id: "f1", SecretId: "build", Name: "Website", Type: vaults.FieldUrl, Value: []byte("https://ci.example.com"), Position: 0, VaultRefer: "work"},
+		&vaults.CustomField{Id: "f2", SecretId: "build", Name: "PIN", Type: vaults.FieldHidden, Value: []byte("sealed:1234"), Position: 1, VaultRefer: "work"},
+		&vaults.CustomField{Id: "f3", SecretId: "build", Name: "PIN", Type: vaults.FieldText, Value: []byte("5678"), Position: 2, VaultRefer: "work"},
+		&vaults.Key{Id: "stripe", Name: "Stripe", Value: []byte("sealed:sk_live_1"), VaultRefer: "work"},
+		&vaults.Document{Id: "runbook", Name: "Runbook", Content: []byte("sealed:restart the agent"), VaultRefer: "work",
+			Placement: vaults.Placement{FolderId: &servers}},
+		&vaults.Totp{Id: "github_2fa", Name: "GitHub 2FA", Issuer: "GitHub", AccountName: "octocat", Secret: []byte("sealed:JBSWY3DPEHPK3PXP"),
+			Algorithm: "SHA1", Digits: 6, Period: 30, VaultRefer: "work", Placement: vaults.Placement{FolderId: &twoFactor}},
+		&vaults.Card{Id: "visa", Name: "Visa", Number: []byte("sealed:4111111111111111"), Last4: "1111", ExpiryMonth: 4, ExpiryYear: 2030,
+			Cvv: []byte("sealed:123"), VaultRefer: "work"},
+	} {
+		assert.NoError(t, db.Create(record).Error)
+	}
+
+	return &vaults.ExportHandler{
+		Ep:        sealingProvider(),
+		Repo:      &vaults.SecretRepositoryImpl{Db: db},
+		VaultRepo: &vaults.VaultRepositoryImpl{Db: db},
+		Folders:   &vaults.FolderRepositoryImpl{Db: db},
+		Fields:    &vaults.CustomFieldRepositoryImpl{Db: db},
+		KeePass:   keepass.Settings{Cipher: keepass.CipherChaCha20, KDF: keepass.KDFArgon2id, Iterations: 1, Memory: 64 << 10, Parallelism: 1},
+	}, db
+}
+
+func exportKeePass(eh *vaults.ExportHandler, vaultId string, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest("POST", "/api/v1/vaults/"+vaultId+"/export/keepass", bytes.NewBufferString(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Set("user_id", "user_1")
+	ctx.AddParam("id", vaultId)
+	eh.ExportKeePass(ctx)
+	return rec
+}
+
+func entryTitles(g keepass.Group) []string {
+	titles := make([]string, len(g.Entries))
+	for i, e := range g.Entries {
+		titles[i] = e.Title
+	}
+	return titles
+}
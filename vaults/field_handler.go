@@ -0,0 +1,191 @@
+package vaults
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// FetchFields lists the custom fields of a secret. Hidden values stay masked
+// unless ?reveal=true is passed, which is audited as a read.
+func (sh *SecretHandler) FetchFields(ctx *gin.Context) {
+	var fr FieldsRequest
+	if err := ctx.ShouldBindQuery(&fr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid query parameters"})
+		return
+	}
+
+	_, secretId, ok := requireSecret(ctx, sh.VaultRepo, sh.Repo)
+	if !ok {
+		return
+	}
+
+	var fields []CustomField
+	if err := sh.Fields.FindFields(secretId, &fields); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	resp := CustomFieldListResponse{Fields: make([]CustomFieldResponse, len(fields))}
+	for i, f := range fields {
+		var revealed string
+		if fr.Reveal && f.Type == FieldHidden {
+			var err error
+			if revealed, err = sh.decryptOptional(f.Value); err != nil {
+				ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+				return
+			}
+		}
+		resp.Fields[i].load(f, revealed)
+	}
+
+	if fr.Reveal {
+		audit.Log(sh.Audit, ctx, audit.Event{
+			Action:     audit.ActionSecretRead,
+			TargetType: audit.TargetSecret,
+			TargetId:   secretId,
+			Detail:     fmt.Sprintf("%d custom fields", len(fields)),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// UpdateFields replaces every custom field of a secret with the ones in the
+// request, in the order given.
+func (sh *SecretHandler) UpdateFields(ctx *gin.Context) {
+	var ufr UpdateFieldsRequest
+	if err := ctx.ShouldBindJSON(&ufr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+	if err := validateFields(ufr.Fields); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err := checkFieldIds(ufr.Fields); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	vaultId, secretId, ok := requireSecret(ctx, sh.VaultRepo, sh.Repo)
+	if !ok {
+		return
+	}
+
+	var existing []CustomField
+	if err := sh.Fields.FindFields(secretId, &existing); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	fields, err := sh.buildFields(ufr.Fields, secretId, vaultId, existing)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	if err := sh.Fields.ReplaceFields(secretId, fields); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	indexSecrets(sh.Index, vaultId, secretId)
+
+	audit.Log(sh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretWrite,
+		TargetType: audit.TargetSecret,
+		TargetId:   secretId,
+		Detail:     fmt.Sprintf("%d custom fields", len(fields)),
+	})
+
+	resp := CustomFieldListResponse{Fields: make([]CustomFieldResponse, len(fields))}
+	for i, f := range fields {
+		resp.Fields[i].load(f, "")
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// private methods
+
+// buildFields turns requests into fields of a secret, encrypting hidden
+// values. A hidden field matching one of existing by Id keeps its stored
+// value when the request leaves Value empty.
+func (sh *SecretHandler) buildFields(reqs []CustomFieldRequest, secretId string, vaultId string, existing []CustomField) ([]CustomField, error) {
+	stored := make(map[string]CustomField, len(existing))
+	for _, f := range existing {
+		stored[f.Id] = f
+	}
+
+	fields := make([]CustomField, len(reqs))
+	for i, req := range reqs {
+		f := CustomField{
+			Id:         uuid.NewString(),
+			SecretId:   secretId,
+			Name:       req.Name,
+			Type:       req.Type,
+			Value:      []byte(req.Value),
+			Position:   i,
+			VaultRefer: vaultId,
+		}
+		if old, ok := stored[req.Id]; ok {
+			f.Id = old.Id
+			f.CreatedAt = old.CreatedAt
+		}
+
+		if f.Type == FieldHidden {
+			if old, ok := stored[req.Id]; ok && req.Value == "" && old.Type == FieldHidden {
+				f.Value = old.Value
+			} else {
+				value, err := sh.encryptOptional(req.Value)
+				if err != nil {
+					return nil, err
+				}
+				f.Value = value
+			}
+		}
+		fields[i] = f
+	}
+	return fields, nil
+}
+
+// validateFields checks the values of typed fields beyond what binding
+// tags can express.
+func validateFields(reqs []CustomFieldRequest) error {
+	for _, req := range reqs {
+		if req.Value == "" {
+			continue
+		}
+		switch req.Type {
+		case FieldBoolean:
+			if req.Value != "true" && req.Value != "false" {
+				return fmt.Errorf("field %q must be true or false", req.Name)
+			}
+		case FieldUrl:
+			u, err := url.Parse(req.Value)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("field %q must be an absolute URL", req.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// checkFieldIds refuses requests that name the same stored field more than
+// once, which would otherwise store two fields under one id.
+func checkFieldIds(reqs []CustomFieldRequest) error {
+	seen := make(map[string]bool, len(reqs))
+	for _, req := range reqs {
+		if req.Id == "" {
+			continue
+		}
+		if seen[req.Id] {
+			return fmt.Errorf("field id %q is given more than once", req.Id)
+		}
+		seen[req.Id] = true
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package vaults_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/users"
+	utils_mocks "github.com/adarsh-a-tw/passwordly/utils/mocks"
+	v "github.com/adarsh-a-tw/passwordly/vaults"
+	vm "github.com/adarsh-a-tw/passwordly/vaults/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestSecretHandler_CreateSecret_ShouldStoreCustomFields(t *testing.T) {
+	csr := v.CreateSecretRequest{
+		Name:     "Router admin",
+		Type:     v.TypeCredential,
+		Username: "admin",
+		Password: "test",
+		Fields: []v.CustomFieldRequest{
+			{Name: "Admin URL", Type: v.FieldUrl, Value: "https://192.168.1.1/admin"},
+			{Name: "Recovery PIN", Type: v.FieldHidden, Value: "4321"},
+		},
+	}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/secrets", mockVault.Id), "POST", csr)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+
+	msr := vm.SecretRepository{}
+	msr.On("CreateCredential", mock.AnythingOfType("*vaults.Credential"), mock.MatchedBy(func(fields []v.CustomField) bool {
+		return len(fields) == 2 && string(fields[0].Value) == "https://192.168.1.1/admin" &&
+			string(fields[1].Value) == "ENCRYPTED_PIN" && fields[1].Position == 1 && fields[1].VaultRefer == mockVault.Id
+	})).Return(nil)
+	mvr, mur := ownedVaultMocks()
+	mfr := vm.CustomFieldRepository{}
+
+	ep := utils_mocks.NewEncryptionProvider(t)
+	ep.On("Encrypt", "test").Return("test", nil)
+	ep.On("Encrypt", "4321").Return("ENCRYPTED_PIN", nil)
+
+	h := v.SecretHandler{
+		Ep:        ep,
+		Repo:      &msr,
+		VaultRepo: mvr,
+		UserRepo:  mur,
+		Fields:    &mfr,
+	}
+
+	h.CreateSecret(ctx)
+
+	var resp v.SecretResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Len(t, resp.Fields, 2)
+	assert.Equal(t, "https://192.168.1.1/admin", resp.Fields[0].Value)
+	assert.Equal(t, "********", resp.Fields[1].Value)
+	assert.NotContains(t, rec.Body.String(), "4321")
+}
+
+func TestSecretHandler_CreateSecret_ShouldRejectInvalidFieldValues(t *testing.T) {
+	testCases := map[string]v.CustomFieldRequest{
+		"boolean":      {Name: "Enabled", Type: v.FieldBoolean, Value: "yes"},
+		"relative url": {Name: "Console", Type: v.FieldUrl, Value: "/admin"},
+		"unknown type": {Name: "Colour", Type: "COLOUR", Value: "red"},
+	}
+
+	for name, field := range testCases {
+		csr := v.CreateSecretRequest{
+			Name:     "Router admin",
+			Type:     v.TypeCredential,
+			Username: "admin",
+			Password: "test",
+			Fields:   []v.CustomFieldRequest{field},
+		}
+		ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/secrets", mockVault.Id), "POST", csr)
+		ctx.Set("user_id", mockUser1.Id)
+		ctx.AddParam("id", mockVault.Id)
+
+		msr := vm.SecretRepository{}
+		mvr, mur := ownedVaultMocks()
+		h := v.SecretHandler{Repo: &msr, VaultRepo: mvr, UserRepo: mur}
+
+		h.CreateSecret(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+		msr.AssertNotCalled(t, "CreateCredential", mock.Anything, mock.Anything)
+	}
+}
+
+func TestSecretHandler_FetchFields_ShouldRevealHiddenValues(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/secrets/mock_secret/fields?reveal=true", mockVault.Id), "GET", nil)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+	ctx.AddParam("secretId", "mock_secret")
+
+	msr := vm.SecretRepository{}
+	msr.On("FindSecretType", "mock_secret", mockVault.Id).Return(v.TypeCredential, nil)
+	mvr, _ := ownedVaultMocks()
+	mfr := vm.CustomFieldRepository{}
+	mfr.On("FindFields", "mock_secret", mock.AnythingOfType("*[]vaults.CustomField")).Run(func(args mock.Arguments) {
+		fields := args.Get(1).(*[]v.CustomField)
+		*fields = []v.CustomField{
+			{Id: "f1", Name: "Enabled", Type: v.FieldBoolean, Value: []byte("true")},
+			{Id: "f2", Name: "Recovery PIN", Type: v.FieldHidden, Value: []byte("ENCRYPTED_PIN")},
+		}
+	}).Return(nil)
+
+	ep := utils_mocks.NewEncryptionProvider(t)
+	ep.On("Decrypt", "ENCRYPTED_PIN").Return("4321", nil)
+
+	h := v.SecretHandler{Ep: ep, Repo: &msr, VaultRepo: mvr, Fields: &mfr}
+
+	h.FetchFields(ctx)
+
+	var resp v.CustomFieldListResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", resp.Fields[0].Value)
+	assert.Equal(t, "4321", resp.Fields[1].Value)
+}
+
+func TestSecretHandler_FetchFields_ShouldFailForUnknownSecret(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/secrets/missing/fields", mockVault.Id), "GET", nil)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+	ctx.AddParam("secretId", "missing")
+
+	msr := vm.SecretRepository{}
+	msr.On("FindSecretType", "missing", mockVault.Id).Return(v.SecretType(""), gorm.ErrRecordNotFound)
+	mvr, _ := ownedVaultMocks()
+	mfr := vm.CustomFieldRepository{}
+
+	h := v.SecretHandler{Repo: &msr, VaultRepo: mvr, Fields: &mfr}
+
+	h.FetchFields(ctx)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mfr.AssertNotCalled(t, "FindFields", mock.Anything, mock.Anything)
+}
+
+func TestSecretHandler_UpdateFields_ShouldKeepUnchangedHiddenValues(t *testing.T) {
+	ufr := v.UpdateFieldsRequest{Fields: []v.CustomFieldRequest{
+		{Name: "Notes", Type: v.FieldText, Value: "rack 4"},
+		{Id: "f2", Name: "Recovery PIN", Type: v.FieldHidden},
+	}}
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/secrets/mock_secret/fields", mockVault.Id), "PUT", ufr)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+	ctx.AddParam("secretId", "mock_secret")
+
+	msr := vm.SecretRepository{}
+	msr.On("FindSecretType", "mock_secret", mockVault.Id).Return(v.TypeCredential, nil)
+	mvr, _ := ownedVaultMocks()
+	mfr := vm.CustomFieldRepository{}
+	mfr.On("FindFields", "mock_secret", mock.AnythingOfType("*[]vaults.CustomField")).Run(func(args mock.Arguments) {
+		fields := args.Get(1).(*[]v.CustomField)
+		*fields = []v.CustomField{{Id: "f2", Name: "PIN", Type: v.FieldHidden, Value: []byte("ENCRYPTED_PIN")}}
+	}).Return(nil)
+	mfr.On("ReplaceFields", "mock_secret", mock.MatchedBy(func(fields []v.CustomField) bool {
+		return len(fields) == 2 && fields[0].Name == "Notes" &&
+			fields[1].Id == "f2" && fields[1].Name == "Recovery PIN" && string(fields[1].Value) == "ENCRYPTED_PIN"
+	})).Return(nil)
+
+	h := v.SecretHandler{Repo: &msr, VaultRepo: mvr, Fields: &mfr}
+
+	h.UpdateFields(ctx)
+
+	var resp v.CustomFieldListResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "********", resp.Fields[1].Value)
+	mfr.AssertCalled(t, "ReplaceFields", "mock_secret", mock.Anything)
+}
+
+func TestSecretHandler_UpdateFields_ShouldRejectDuplicateFieldIds(t *testing.T) {
+	ufr := v.UpdateFieldsRequest{Fields: []v.CustomFieldRequest{
+		{Id: "f2", Name: "PIN", Type: v.FieldHidden},
+		{Id: "f2", Name: "Recovery PIN", Type: v.FieldHidden},
+	}}
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/secrets/mock_secret/fields", mockVault.Id), "PUT", ufr)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+	ctx.AddParam("secretId", "mock_secret")
+
+	mfr := vm.CustomFieldRepository{}
+	h := v.SecretHandler{Repo: &vm.SecretRepository{}, VaultRepo: &vm.VaultRepository{}, Fields: &mfr}
+
+	h.UpdateFields(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	mfr.AssertNotCalled(t, "ReplaceFields", mock.Anything, mock.Anything)
+}
+
+func TestSecretHandler_CreateSecret_ShouldNotKeepTheSecretWhenItsFieldsFail(t *testing.T) {
+	sh, db := prepareBatch(t)
+	sh.UserRepo = &users.UserRepositoryImpl{Db: db}
+	assert.NoError(t, db.Migrator().DropTable(&v.CustomField{}))
+
+	csr := v.CreateSecretRequest{
+		Name:     "Router admin",
+		Type:     v.TypeCredential,
+		Username: "admin",
+		Password: "test",
+		Fields:   []v.CustomFieldRequest{{Name: "Notes", Type: v.FieldText, Value: "rack 4"}},
+	}
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/vaults/work/secrets", "POST", csr)
+	ctx.Set("user_id", "user_1")
+	ctx.AddParam("id", "work")
+
+	sh.CreateSecret(ctx)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var count int64
+	assert.NoError(t, db.Model(&v.Credential{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
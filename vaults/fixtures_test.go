@@ -0,0 +1,41 @@
+package vaults_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/users"
+	utils_mocks "github.com/adarsh-a-tw/passwordly/utils/mocks"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// prepareDb opens an in-memory database with every table of the server and
+// two users, user_1 and user_2. Tests seed the vaults and secrets they need.
+func prepareDb(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	for _, model := range vaults.Models {
+		assert.NoError(t, db.AutoMigrate(model))
+	}
+
+	for _, u := range []users.User{
+		{Id: "user_1", Username: "user_one", Email: "one@example.com", Password: "hash"},
+		{Id: "user_2", Username: "user_two", Email: "two@example.com", Password: "hash"},
+	} {
+		assert.NoError(t, db.Create(&u).Error)
+	}
+	return db
+}
+
+// sealingProvider encrypts a value as "sealed:" followed by the plaintext
+// and decrypts it back, so that tests can read what was stored.
+func sealingProvider() *utils_mocks.EncryptionProvider {
+	ep := &utils_mocks.EncryptionProvider{}
+	ep.On("Encrypt", mock.AnythingOfType("string")).Return(func(s string) string { return "sealed:" + s }, nil)
+	ep.On("Decrypt", mock.AnythingOfType("string")).Return(func(s string) string { return strings.TrimPrefix(s, "sealed:") }, nil)
+	return ep
+}
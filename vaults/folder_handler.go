@@ -0,0 +1,280 @@
+package vaults
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FolderHandler organizes the secrets of a vault into nested folders and
+// tags them.
+type FolderHandler struct {
+	Repo       FolderRepository
+	SecretRepo SecretRepository
+	VaultRepo  VaultRepository
+	Audit      audit.Recorder
+	Index      SearchIndexer
+}
+
+func (fh *FolderHandler) CreateFolder(ctx *gin.Context) {
+	var cfr CreateFolderRequest
+	if err := ctx.ShouldBindJSON(&cfr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	vaultId, ok := requireVaultOwner(ctx, fh.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var folders []Folder
+	if err := fh.Repo.FindFolders(vaultId, &folders); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	tree := newFolderTree(folders)
+	if _, exists := tree[cfr.ParentId]; cfr.ParentId != "" && !exists {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errFolderParent.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(cfr.Name)
+	if name == "" {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errFolderName.Error()})
+		return
+	}
+
+	f := Folder{
+		Id:         uuid.NewString(),
+		Name:       name,
+		ParentId:   optional(cfr.ParentId),
+		VaultRefer: vaultId,
+	}
+	if err := fh.Repo.Create(&f); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	tree[f.Id] = f
+
+	audit.Log(fh.Audit, ctx, audit.Event{
+		Action:     audit.ActionFolderCreate,
+		TargetType: audit.TargetFolder,
+		TargetId:   f.Id,
+		Detail:     tree.path(f.Id),
+	})
+
+	var resp FolderResponse
+	resp.load(f, tree)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (fh *FolderHandler) FetchFolders(ctx *gin.Context) {
+	vaultId, ok := requireVaultOwner(ctx, fh.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var folders []Folder
+	if err := fh.Repo.FindFolders(vaultId, &folders); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	var resp FolderListResponse
+	resp.load(folders)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// UpdateFolder renames a folder and/or moves it under another parent.
+// Moves that would make a folder its own ancestor are refused.
+func (fh *FolderHandler) UpdateFolder(ctx *gin.Context) {
+	var ufr UpdateFolderRequest
+	if err := ctx.ShouldBindJSON(&ufr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	vaultId, ok := requireVaultOwner(ctx, fh.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var folders []Folder
+	if err := fh.Repo.FindFolders(vaultId, &folders); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	tree := newFolderTree(folders)
+	f, exists := tree[ctx.Param("folderId")]
+	if !exists {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if ufr.Name != nil {
+		f.Name = strings.TrimSpace(*ufr.Name)
+		if f.Name == "" {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errFolderName.Error()})
+			return
+		}
+	}
+	if ufr.ParentId != nil {
+		parentId := *ufr.ParentId
+		if _, exists := tree[parentId]; parentId != "" && !exists {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errFolderParent.Error()})
+			return
+		}
+		if parentId != "" && tree.within(parentId, f.Id) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errFolderCycle.Error()})
+			return
+		}
+		f.ParentId = optional(parentId)
+	}
+
+	if err := fh.Repo.Update(&f); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	tree[f.Id] = f
+	reindex(fh.Index, vaultId)
+
+	audit.Log(fh.Audit, ctx, audit.Event{
+		Action:     audit.ActionFolderUpdate,
+		TargetType: audit.TargetFolder,
+		TargetId:   f.Id,
+		Detail:     tree.path(f.Id),
+	})
+
+	var resp FolderResponse
+	resp.load(f, tree)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// DeleteFolder removes a folder. Its secrets and subfolders move up to its
+// parent.
+func (fh *FolderHandler) DeleteFolder(ctx *gin.Context) {
+	vaultId, ok := requireVaultOwner(ctx, fh.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var f Folder
+	if err := fh.Repo.FindFolderById(ctx.Param("folderId"), vaultId, &f); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+	if err := fh.Repo.Delete(&f); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	reindex(fh.Index, vaultId)
+
+	audit.Log(fh.Audit, ctx, audit.Event{
+		Action:     audit.ActionFolderDelete,
+		TargetType: audit.TargetFolder,
+		TargetId:   f.Id,
+		Detail:     f.Name,
+	})
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (fh *FolderHandler) MoveSecret(ctx *gin.Context) {
+	var msr MoveSecretRequest
+	if err := ctx.ShouldBindJSON(&msr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	vaultId, secretType, ok := fh.findSecret(ctx)
+	if !ok {
+		return
+	}
+
+	if msr.FolderId != "" {
+		var f Folder
+		if err := fh.Repo.FindFolderById(msr.FolderId, vaultId, &f); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errFolderMissing.Error()})
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+	}
+
+	secretId := ctx.Param("secretId")
+	if err := fh.SecretRepo.MoveSecret(secretType, secretId, vaultId, optional(msr.FolderId)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	reindex(fh.Index, vaultId)
+
+	audit.Log(fh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretWrite,
+		TargetType: audit.TargetSecret,
+		TargetId:   secretId,
+		Detail:     fmt.Sprintf("moved to folder %q", msr.FolderId),
+	})
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// TagSecret replaces the tags of a secret.
+func (fh *FolderHandler) TagSecret(ctx *gin.Context) {
+	var tsr TagSecretRequest
+	if err := ctx.ShouldBindJSON(&tsr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+	tags, err := normalizeTags(tsr.Tags)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	vaultId, secretType, ok := fh.findSecret(ctx)
+	if !ok {
+		return
+	}
+
+	secretId := ctx.Param("secretId")
+	if err := fh.SecretRepo.TagSecret(secretType, secretId, vaultId, tags); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	reindex(fh.Index, vaultId)
+
+	audit.Log(fh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretWrite,
+		TargetType: audit.TargetSecret,
+		TargetId:   secretId,
+		Detail:     fmt.Sprintf("tags=%s", strings.Join(tags, ",")),
+	})
+
+	ctx.JSON(http.StatusOK, TagSecretRequest{Tags: tags})
+}
+
+// private methods
+
+func (fh *FolderHandler) findSecret(ctx *gin.Context) (string, SecretType, bool) {
+	vaultId, ok := requireVaultOwner(ctx, fh.VaultRepo)
+	if !ok {
+		return "", "", false
+	}
+
+	secretType, err := fh.SecretRepo.FindSecretType(ctx.Param("secretId"), vaultId)
+	if err != nil {
+		handleGormError(ctx, err)
+		return "", "", false
+	}
+	return vaultId, secretType, true
+}
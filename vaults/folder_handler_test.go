@@ -0,0 +1,120 @@
+package vaults_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	v "github.com/adarsh-a-tw/passwordly/vaults"
+	vm "github.com/adarsh-a-tw/passwordly/vaults/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestFolderHandler_CreateFolder_ShouldNestUnderParent(t *testing.T) {
+	h := prepareFolderHandler(mockFolders())
+	h.Repo.(*vm.FolderRepository).On("Create", mock.AnythingOfType("*vaults.Folder")).Return(nil)
+
+	ctx, rec := prepareFolderRequest(t, "POST", "/folders", v.CreateFolderRequest{Name: " Staging ", ParentId: "servers"})
+	h.CreateFolder(ctx)
+
+	var resp v.FolderResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "Staging", resp.Name)
+	assert.Equal(t, "servers", resp.ParentId)
+	assert.Equal(t, "Work/Servers/Staging", resp.Path)
+}
+
+func TestFolderHandler_UpdateFolder_ShouldRejectCycles(t *testing.T) {
+	h := prepareFolderHandler(mockFolders())
+	servers := "servers"
+
+	ctx, rec := prepareFolderRequest(t, "PATCH", "/folders/work", v.UpdateFolderRequest{ParentId: &servers})
+	ctx.AddParam("folderId", "work")
+	h.UpdateFolder(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "subfolders")
+	h.Repo.(*vm.FolderRepository).AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestFolderHandler_MoveSecret_ShouldRejectFoldersOfOtherVaults(t *testing.T) {
+	h := prepareFolderHandler(nil)
+	h.SecretRepo.(*vm.SecretRepository).On("FindSecretType", "mock_secret", mockVault.Id).Return(v.TypeCredential, nil)
+	h.Repo.(*vm.FolderRepository).On("FindFolderById", "elsewhere", mockVault.Id, mock.AnythingOfType("*vaults.Folder")).Return(gorm.ErrRecordNotFound)
+
+	ctx, rec := prepareFolderRequest(t, "PUT", "/secrets/mock_secret/folder", v.MoveSecretRequest{FolderId: "elsewhere"})
+	ctx.AddParam("secretId", "mock_secret")
+	h.MoveSecret(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	h.SecretRepo.(*vm.SecretRepository).AssertNotCalled(t, "MoveSecret", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestFolderHandler_TagSecret_ShouldNormalizeTags(t *testing.T) {
+	h := prepareFolderHandler(nil)
+	msr := h.SecretRepo.(*vm.SecretRepository)
+	msr.On("FindSecretType", "mock_secret", mockVault.Id).Return(v.TypeCredential, nil)
+	msr.On("TagSecret", v.TypeCredential, "mock_secret", mockVault.Id, []string{"prod", "AWS"}).Return(nil)
+	index := vm.SearchIndexer{}
+	index.On("ReindexVault", mockVault.Id).Return(nil)
+	h.Index = &index
+
+	ctx, rec := prepareFolderRequest(t, "PUT", "/secrets/mock_secret/tags", v.TagSecretRequest{Tags: []string{" prod", "AWS", "Prod "}})
+	ctx.AddParam("secretId", "mock_secret")
+	h.TagSecret(ctx)
+
+	var resp v.TagSecretRequest
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"prod", "AWS"}, resp.Tags)
+	index.AssertExpectations(t)
+}
+
+func TestFolderHandler_TagSecret_ShouldRejectCommas(t *testing.T) {
+	h := prepareFolderHandler(nil)
+
+	ctx, rec := prepareFolderRequest(t, "PUT", "/secrets/mock_secret/tags", v.TagSecretRequest{Tags: []string{"prod,aws"}})
+	ctx.AddParam("secretId", "mock_secret")
+	h.TagSecret(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	h.SecretRepo.(*vm.SecretRepository).AssertNotCalled(t, "TagSecret", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// mockFolders returns "Work" with a "Servers" subfolder.
+func mockFolders() []v.Folder {
+	work := "work"
+	return []v.Folder{
+		{Id: "work", Name: "Work", VaultRefer: mockVault.Id},
+		{Id: "servers", Name: "Servers", ParentId: &work, VaultRefer: mockVault.Id},
+	}
+}
+
+func prepareFolderHandler(folders []v.Folder) *v.FolderHandler {
+	mfr := vm.FolderRepository{}
+	mfr.On("FindFolders", mockVault.Id, mock.AnythingOfType("*[]vaults.Folder")).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]v.Folder) = folders
+	}).Return(nil)
+	mvr, _ := ownedVaultMocks()
+
+	return &v.FolderHandler{
+		Repo:       &mfr,
+		SecretRepo: &vm.SecretRepository{},
+		VaultRepo:  mvr,
+	}
+}
+
+func prepareFolderRequest(t *testing.T, method string, path string, body any) (*gin.Context, *httptest.ResponseRecorder) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s%s", mockVault.Id, path), method, body)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+	return ctx, rec
+}
@@ -0,0 +1,47 @@
+package vaults
+
+import "gorm.io/gorm"
+
+type FolderRepository interface {
+	Create(f *Folder) error
+	FindFolders(vaultId string, folders *[]Folder) error
+	FindFolderById(id string, vaultId string, f *Folder) error
+	Update(f *Folder) error
+	Delete(f *Folder) error
+}
+
+type FolderRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func (fr *FolderRepositoryImpl) Create(f *Folder) error {
+	return fr.Db.Create(f).Error
+}
+
+func (fr *FolderRepositoryImpl) FindFolders(vaultId string, folders *[]Folder) error {
+	return fr.Db.Where("vault_refer = ?", vaultId).Order("name ASC, id ASC").Find(folders).Error
+}
+
+func (fr *FolderRepositoryImpl) FindFolderById(id string, vaultId string, f *Folder) error {
+	return fr.Db.Where("id = ? AND vault_refer = ?", id, vaultId).First(f).Error
+}
+
+func (fr *FolderRepositoryImpl) Update(f *Folder) error {
+	return fr.Db.Save(f).Error
+}
+
+// Delete removes a folder and hands its subfolders and secrets to its
+// parent, so nothing inside is lost.
+func (fr *FolderRepositoryImpl) Delete(f *Folder) error {
+	return fr.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Folder{}).Where("parent_id = ?", f.Id).Update("parent_id", f.ParentId).Error; err != nil {
+			return err
+		}
+		for _, st := range secretTables {
+			if err := tx.Table(st.table).Where("folder_id = ?", f.Id).Update("folder_id", f.ParentId).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(f).Error
+	})
+}
@@ -0,0 +1,105 @@
+package vaults
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	maxTags      = 20
+	maxTagLength = 50
+)
+
+var (
+	errInvalidTag    = errors.New("Tags must be 1 to 50 characters without commas")
+	errTooManyTags   = errors.New("A secret can have at most 20 tags")
+	errFolderCycle   = errors.New("A folder cannot be moved into itself or its subfolders")
+	errFolderParent  = errors.New("Parent folder does not exist in this vault")
+	errFolderMissing = errors.New("Folder does not exist in this vault")
+	errFolderName    = errors.New("Folder name cannot be blank")
+)
+
+// normalizeTags trims tags and drops case-insensitive duplicates, keeping
+// the first spelling. Commas are refused as they separate stored tags.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || len(tag) > maxTagLength || strings.Contains(tag, ",") {
+			return nil, errInvalidTag
+		}
+		if seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, errTooManyTags
+	}
+	return normalized, nil
+}
+
+// folderTree indexes the folders of one vault.
+type folderTree map[string]Folder
+
+func newFolderTree(folders []Folder) folderTree {
+	tree := make(folderTree, len(folders))
+	for _, f := range folders {
+		tree[f.Id] = f
+	}
+	return tree
+}
+
+// path joins the names from the top level down to the folder with "/".
+func (ft folderTree) path(id string) string {
+	var names []string
+	seen := make(map[string]bool)
+	for f, ok := ft[id]; ok && !seen[f.Id]; f, ok = ft[deref(f.ParentId)] {
+		seen[f.Id] = true
+		names = append([]string{f.Name}, names...)
+	}
+	return strings.Join(names, "/")
+}
+
+// within reports whether folder id is ancestor or one of its subfolders.
+func (ft folderTree) within(id string, ancestor string) bool {
+	seen := make(map[string]bool)
+	for current := id; current != "" && !seen[current]; current = deref(ft[current].ParentId) {
+		if current == ancestor {
+			return true
+		}
+		seen[current] = true
+	}
+	return false
+}
+
+// subtree returns the id of a folder, along with the ids of all of its
+// subfolders when recursive.
+func (ft folderTree) subtree(id string, recursive bool) []string {
+	ids := []string{id}
+	if recursive {
+		for other := range ft {
+			if other != id && ft.within(other, id) {
+				ids = append(ids, other)
+			}
+		}
+	}
+	return ids
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// optional turns an empty id into nil, the top level of a vault.
+func optional(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
+}
@@ -2,9 +2,13 @@ package vaults
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/adarsh-a-tw/passwordly/audit"
 	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/passwords"
 	"github.com/adarsh-a-tw/passwordly/users"
 	"github.com/adarsh-a-tw/passwordly/utils"
 	"github.com/gin-gonic/gin"
@@ -17,6 +21,11 @@ type VaultHandler struct {
 	Repo       VaultRepository
 	UserRepo   users.UserRepository
 	SecretRepo SecretRepository
+	Fields     CustomFieldRepository
+	Folders    FolderRepository
+	Audit      audit.Recorder
+	Breaches   passwords.BreachChecker
+	Hasher     utils.PasswordHasher
 }
 
 func (vh *VaultHandler) CreateVault(ctx *gin.Context) {
@@ -45,6 +54,12 @@ func (vh *VaultHandler) CreateVault(ctx *gin.Context) {
 		return
 	}
 
+	audit.Log(vh.Audit, ctx, audit.Event{
+		Action:     audit.ActionVaultCreate,
+		TargetType: audit.TargetVault,
+		TargetId:   v.Id,
+	})
+
 	ctx.JSON(http.StatusCreated, VaultResponse{
 		Id:        v.Id,
 		Name:      v.Name,
@@ -55,6 +70,17 @@ func (vh *VaultHandler) CreateVault(ctx *gin.Context) {
 }
 
 func (vh *VaultHandler) FetchVaults(ctx *gin.Context) {
+	var lr ListRequest
+	if err := ctx.ShouldBindQuery(&lr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid query parameters"})
+		return
+	}
+	pq, err := newPageQuery(lr)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+		return
+	}
+
 	id := ctx.GetString("user_id")
 	var u users.User
 
@@ -64,18 +90,38 @@ func (vh *VaultHandler) FetchVaults(ctx *gin.Context) {
 	}
 
 	var vaults []Vault
-	if err := vh.Repo.FetchByUserId(u.Id, &vaults); err != nil {
+	if err := vh.Repo.FetchPageByUserId(u.Id, pq, &vaults); err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
 		return
 	}
 
 	response := VaultListResponse{}
+	if len(vaults) > pq.Limit {
+		vaults = vaults[:pq.Limit]
+		last := vaults[len(vaults)-1]
+		response.NextCursor = pq.cursorAt(last.Id, last.Name, last.CreatedAt, last.UpdatedAt).Encode()
+	}
 	response.load(vaults)
 
+	setNextLink(ctx, response.NextCursor)
 	ctx.JSON(http.StatusOK, response)
 }
 
+// FetchVaultDetails returns a page of the secrets of a vault, optionally
+// limited to a type, a folder (and its subfolders when recursive), a tag or
+// a name prefix.
 func (vh *VaultHandler) FetchVaultDetails(ctx *gin.Context) {
+	var vdr VaultDetailsRequest
+	if err := ctx.ShouldBindQuery(&vdr); err != nil || (vdr.Type != "" && !vdr.Type.IsValid()) {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid query parameters"})
+		return
+	}
+	pq, err := newPageQuery(vdr.ListRequest)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+		return
+	}
+
 	userId := ctx.GetString("user_id")
 	var u users.User
 
@@ -103,19 +149,65 @@ func (vh *VaultHandler) FetchVaultDetails(ctx *gin.Context) {
 		return
 	}
 
-	var credentials []Credential
-	if err = vh.SecretRepo.FindCredentials(&credentials, vaultId); err != nil {
-		handleGormError(ctx, err)
+	var folders []Folder
+	if err = vh.Folders.FindFolders(vaultId, &folders); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
 		return
 	}
-	if err = vh.decryptCredentials(credentials); err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+	tree := newFolderTree(folders)
+	if _, exists := tree[vdr.Folder]; vdr.Folder != "" && !exists {
+		ctx.AbortWithStatus(http.StatusNotFound)
 		return
 	}
 
+	filter := SecretFilter{Type: vdr.Type, Tag: vdr.Tag}
+	if vdr.Folder != "" {
+		filter.FolderIds = tree.subtree(vdr.Folder, vdr.Recursive)
+	}
+
+	var summaries []SecretSummary
+	if err = vh.SecretRepo.FindPage(vaultId, filter, pq, &summaries); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
 	vr := VaultResponse{}
-	vr.load(vault, credentials)
+	if len(summaries) > pq.Limit {
+		summaries = summaries[:pq.Limit]
+		last := summaries[len(summaries)-1]
+		vr.NextCursor = pq.cursorAt(last.Id, last.Name, last.CreatedAt, last.UpdatedAt).Encode()
+	}
+
+	var secrets []Securable
+	if err = vh.SecretRepo.FindByRefs(summaries, &secrets); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	if err = vh.decryptSecrets(secrets); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	var fields []CustomField
+	if err = vh.Fields.FindFieldsByVault(vaultId, &fields); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	fieldsBySecret := make(map[string][]CustomField)
+	for _, f := range fields {
+		fieldsBySecret[f.SecretId] = append(fieldsBySecret[f.SecretId], f)
+	}
+
+	audit.Log(vh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretRead,
+		TargetType: audit.TargetVault,
+		TargetId:   vaultId,
+		Detail:     fmt.Sprintf("%d secrets", len(secrets)),
+	})
+
+	vr.load(vault, secrets, fieldsBySecret)
+	vr.loadFolders(folders)
 
+	setNextLink(ctx, vr.NextCursor)
 	ctx.JSON(http.StatusOK, vr)
 }
 
@@ -156,9 +248,16 @@ func (vh *VaultHandler) UpdateVault(ctx *gin.Context) {
 		return
 	}
 
+	audit.Log(vh.Audit, ctx, audit.Event{
+		Action:     audit.ActionVaultUpdate,
+		TargetType: audit.TargetVault,
+		TargetId:   vault.Id,
+	})
+
 	ctx.JSON(http.StatusOK, gin.H{"message": "Vault updated successfully"})
 }
 
+// DeleteVault moves a vault and its secrets to the trash.
 func (vh *VaultHandler) DeleteVault(ctx *gin.Context) {
 	userId := ctx.GetString("user_id")
 	vaultId := ctx.Param("id")
@@ -187,7 +286,206 @@ func (vh *VaultHandler) DeleteVault(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Vault deleted successfully"})
+	audit.Log(vh.Audit, ctx, audit.Event{
+		Action:     audit.ActionVaultDelete,
+		TargetType: audit.TargetVault,
+		TargetId:   vault.Id,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Vault moved to trash"})
+}
+
+// FetchBreachedSecrets reports credentials whose passwords appear in the
+// breached password dataset. Passwords themselves are never returned.
+func (vh *VaultHandler) FetchBreachedSecrets(ctx *gin.Context) {
+	userId := ctx.GetString("user_id")
+	vaultId := ctx.Param("id")
+
+	valid, err := ValidateVaultOwner(vh.Repo, vaultId, userId)
+
+	if err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+
+	if !valid {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse{Message: "Unauthorized access"})
+		return
+	}
+
+	var credentials []Credential
+	if err = vh.SecretRepo.FindCredentials(&credentials, vaultId); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+	if err = vh.decryptCredentials(credentials); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	response := BreachReportResponse{VaultId: vaultId, Checked: len(credentials), Breached: []BreachedSecretResponse{}}
+	for _, cred := range credentials {
+		count, err := vh.Breaches.Lookup(string(cred.Password))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		if count > 0 {
+			response.Breached = append(response.Breached, BreachedSecretResponse{
+				Id:          cred.Id,
+				Name:        cred.Name,
+				Username:    cred.Username,
+				Occurrences: count,
+			})
+		}
+	}
+
+	audit.Log(vh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretRead,
+		TargetType: audit.TargetVault,
+		TargetId:   vaultId,
+		Detail:     fmt.Sprintf("breach report over %d secrets", len(credentials)),
+	})
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// FetchVaultHealth reports weak, reused, stale and account-duplicate
+// passwords in one vault.
+func (vh *VaultHandler) FetchVaultHealth(ctx *gin.Context) {
+	var hrr HealthReportRequest
+	if err := ctx.ShouldBindQuery(&hrr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid query parameters"})
+		return
+	}
+
+	userId := ctx.GetString("user_id")
+	var u users.User
+
+	if err := vh.UserRepo.FindById(userId, &u); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	vaultId := ctx.Param("id")
+	valid, err := ValidateVaultOwner(vh.Repo, vaultId, userId)
+
+	if err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+
+	if !valid {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse{Message: "Unauthorized access"})
+		return
+	}
+
+	var credentials []Credential
+	if err = vh.SecretRepo.FindCredentials(&credentials, vaultId); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+
+	vh.respondWithHealthReport(ctx, u, credentials, vaultId, hrr)
+}
+
+// FetchHealth reports over every vault of the user, so reuse is detected
+// across vaults as well.
+func (vh *VaultHandler) FetchHealth(ctx *gin.Context) {
+	var hrr HealthReportRequest
+	if err := ctx.ShouldBindQuery(&hrr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid query parameters"})
+		return
+	}
+
+	userId := ctx.GetString("user_id")
+	var u users.User
+
+	if err := vh.UserRepo.FindById(userId, &u); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	var vaults []Vault
+	if err := vh.Repo.FetchByUserId(u.Id, &vaults); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	var credentials []Credential
+	for _, vault := range vaults {
+		var vaultCredentials []Credential
+		if err := vh.SecretRepo.FindCredentials(&vaultCredentials, vault.Id); err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		credentials = append(credentials, vaultCredentials...)
+	}
+
+	vh.respondWithHealthReport(ctx, u, credentials, "", hrr)
+}
+
+func (vh *VaultHandler) respondWithHealthReport(ctx *gin.Context, u users.User, credentials []Credential, vaultId string, hrr HealthReportRequest) {
+	if err := vh.decryptCredentials(credentials); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	maxAgeDays := hrr.MaxAgeDays
+	if maxAgeDays == 0 {
+		maxAgeDays = common.Cfg.PasswordRotationDays
+	}
+
+	ha := healthAnalyzer{
+		hasher:      vh.Hasher,
+		accountHash: u.Password,
+		maxAge:      time.Duration(maxAgeDays) * 24 * time.Hour,
+		now:         time.Now(),
+	}
+	report := ha.analyze(credentials)
+	report.VaultId = vaultId
+
+	targetType, targetId := audit.TargetUser, u.Id
+	if vaultId != "" {
+		targetType, targetId = audit.TargetVault, vaultId
+	}
+	audit.Log(vh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretRead,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Detail:     fmt.Sprintf("health report over %d secrets", len(credentials)),
+	})
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// FetchExpirations lists expiries and rotations falling due within the
+// requested number of days across the caller's vaults, overdue ones first.
+func (vh *VaultHandler) FetchExpirations(ctx *gin.Context) {
+	var er ExpirationsRequest
+	if err := ctx.ShouldBindQuery(&er); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid query parameters"})
+		return
+	}
+	if er.WithinDays == 0 {
+		er.WithinDays = common.Cfg.ReminderWindowDays
+	}
+
+	var secrets []ScheduledSecret
+	if err := vh.SecretRepo.FindScheduled(ctx.GetString("user_id"), &secrets); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	now := time.Now()
+	response := ExpirationListResponse{Expirations: []ExpirationResponse{}}
+	for _, r := range upcomingReminders(secrets, now, time.Duration(er.WithinDays)*24*time.Hour) {
+		exp := ExpirationResponse{}
+		exp.load(r, now)
+		response.Expirations = append(response.Expirations, exp)
+	}
+
+	ctx.JSON(http.StatusOK, response)
 }
 
 func handleGormError(ctx *gin.Context, err error) {
@@ -198,6 +496,34 @@ func handleGormError(ctx *gin.Context, err error) {
 	}
 }
 
+// decryptSecrets decrypts the passwords of the credentials and the values
+// of the keys and documents among secrets.
+func (vh *VaultHandler) decryptSecrets(secrets []Securable) error {
+	for i, s := range secrets {
+		var err error
+		switch secret := s.(type) {
+		case Credential:
+			secret.Password, err = vh.decrypt(secret.Password)
+			secrets[i] = secret
+		case Key:
+			secret.Value, err = vh.decrypt(secret.Value)
+			secrets[i] = secret
+		case Document:
+			secret.Content, err = vh.decrypt(secret.Content)
+			secrets[i] = secret
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (vh *VaultHandler) decrypt(value []byte) ([]byte, error) {
+	plain, err := vh.Ep.Decrypt(string(value))
+	return []byte(plain), err
+}
+
 func (vh *VaultHandler) decryptCredentials(creds []Credential) error {
 	for i := range creds {
 		pwd, err := vh.Ep.Decrypt(string(creds[i].Password))
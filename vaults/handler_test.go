@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/adarsh-a-tw/passwordly/common"
+	passwords_mocks "github.com/adarsh-a-tw/passwordly/passwords/mocks"
 	"github.com/adarsh-a-tw/passwordly/users"
 	user_mocks "github.com/adarsh-a-tw/passwordly/users/mocks"
 	utils_mocks "github.com/adarsh-a-tw/passwordly/utils/mocks"
@@ -183,8 +184,8 @@ func TestVaultHandler_FetchVaults_ShouldFetchVaultsSuccessfully(t *testing.T) {
 		arg.Email = mu.Email
 		arg.Password = mu.Password
 	})
-	repo.On("FetchByUserId", "mock_user_id", mock.AnythingOfType("*[]vaults.Vault")).Return(nil).Run(func(args mock.Arguments) {
-		arg := args.Get(1).(*[]vaults.Vault)
+	repo.On("FetchPageByUserId", "mock_user_id", mock.AnythingOfType("vaults.PageQuery"), mock.AnythingOfType("*[]vaults.Vault")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(2).(*[]vaults.Vault)
 		(*arg) = append((*arg), mockVault1)
 		(*arg) = append((*arg), mockVault2)
 	})
@@ -202,7 +203,7 @@ func TestVaultHandler_FetchVaults_ShouldFetchVaultsSuccessfully(t *testing.T) {
 	common.DecodeJSONResponse(t, rec, &actualResponse)
 
 	userRepo.AssertCalled(t, "FindById", "mock_user_id", mock.AnythingOfType("*users.User"))
-	repo.AssertCalled(t, "FetchByUserId", "mock_user_id", mock.AnythingOfType("*[]vaults.Vault"))
+	repo.AssertCalled(t, "FetchPageByUserId", "mock_user_id", mock.AnythingOfType("vaults.PageQuery"), mock.AnythingOfType("*[]vaults.Vault"))
 
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Equal(t, expectedResponse, actualResponse)
@@ -234,7 +235,7 @@ func TestVaultHandler_FetchVaults_ShouldThrowInternalServerErrorIfFindByIdMethod
 	assert.Equal(t, expectedResponse, actualResponse)
 }
 
-func TestVaultHandler_FetchVaults_ShouldThrowInternalServerErrorIfFetchByUserIdMethodFails(t *testing.T) {
+func TestVaultHandler_FetchVaults_ShouldThrowInternalServerErrorIfFetchPageByUserIdMethodFails(t *testing.T) {
 	expectedResponse := common.ErrorResponse{Message: "Something went wrong. Try again."}
 
 	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/vaults", "GET", nil)
@@ -250,7 +251,7 @@ func TestVaultHandler_FetchVaults_ShouldThrowInternalServerErrorIfFetchByUserIdM
 		arg.Email = mu.Email
 		arg.Password = mu.Password
 	})
-	repo.On("FetchByUserId", "mock_user_id", mock.AnythingOfType("*[]vaults.Vault")).Return(errors.New("MOCK_ERROR"))
+	repo.On("FetchPageByUserId", "mock_user_id", mock.AnythingOfType("vaults.PageQuery"), mock.AnythingOfType("*[]vaults.Vault")).Return(errors.New("MOCK_ERROR"))
 
 	vh := vaults.VaultHandler{
 		Repo:     repo,
@@ -265,7 +266,7 @@ func TestVaultHandler_FetchVaults_ShouldThrowInternalServerErrorIfFetchByUserIdM
 	common.DecodeJSONResponse(t, rec, &actualResponse)
 
 	userRepo.AssertCalled(t, "FindById", "mock_user_id", mock.AnythingOfType("*users.User"))
-	repo.AssertCalled(t, "FetchByUserId", "mock_user_id", mock.AnythingOfType("*[]vaults.Vault"))
+	repo.AssertCalled(t, "FetchPageByUserId", "mock_user_id", mock.AnythingOfType("vaults.PageQuery"), mock.AnythingOfType("*[]vaults.Vault"))
 
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	assert.Equal(t, expectedResponse, actualResponse)
@@ -443,7 +444,7 @@ func TestVaultHandler_DeleteVault_ShouldDeleteVaultSuccessfully(t *testing.T) {
 	repo.AssertNumberOfCalls(t, "Delete", 1)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
-	assert.Equal(t, `{"message":"Vault deleted successfully"}`, rec.Body.String())
+	assert.Equal(t, `{"message":"Vault moved to trash"}`, rec.Body.String())
 }
 
 func TestVaultHandler_DeleteVault_ShouldNotDeleteSuccessfullyWhenVaultOwnerIsNotRequester(t *testing.T) {
@@ -566,12 +567,28 @@ func TestVaultHandler_FetchVaultDetails_ShouldSuccessfullyFetchVaultDetails(t *t
 		arg.UserRefer = existingVault.UserRefer
 	})
 
-	secretRepo.On("FindCredentials", mock.AnythingOfType("*[]vaults.Credential"), existingVault.Id).Return(nil).Run(func(args mock.Arguments) {
-		arg := args.Get(0).(*[]vaults.Credential)
-		mc := mockCredential()
-		*(arg) = []vaults.Credential{*mc}
+	refs := []vaults.SecretSummary{{Id: mockCredential().Id, Type: vaults.TypeCredential}, {Id: "mock_totp", Type: vaults.TypeTotp}}
+	secretRepo.On("FindPage", existingVault.Id, vaults.SecretFilter{}, mock.AnythingOfType("vaults.PageQuery"), mock.AnythingOfType("*[]vaults.SecretSummary")).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(3).(*[]vaults.SecretSummary) = refs
+	})
+
+	secretRepo.On("FindByRefs", refs, mock.AnythingOfType("*[]vaults.Securable")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*[]vaults.Securable)
+		*(arg) = []vaults.Securable{
+			*mockCredential(),
+			vaults.Totp{Id: "mock_totp", Name: "Shared 2FA", Issuer: "ACME", Secret: []byte("ENCRYPTED_SEED"), Digits: 6, Period: 30},
+		}
 	})
 
+	fieldRepo := &vaults_mocks.CustomFieldRepository{}
+	fieldRepo.On("FindFieldsByVault", existingVault.Id, mock.AnythingOfType("*[]vaults.CustomField")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*[]vaults.CustomField)
+		*(arg) = []vaults.CustomField{{Id: "mock_field", SecretId: "mock_totp", Name: "Recovery code", Type: vaults.FieldHidden, Value: []byte("ENCRYPTED_CODE")}}
+	})
+
+	folderRepo := &vaults_mocks.FolderRepository{}
+	folderRepo.On("FindFolders", existingVault.Id, mock.AnythingOfType("*[]vaults.Folder")).Return(nil)
+
 	ep.On("Decrypt", string(mockCredential().Password)).Return(string(mockCredential().Password), nil)
 
 	vh := vaults.VaultHandler{
@@ -579,15 +596,224 @@ func TestVaultHandler_FetchVaultDetails_ShouldSuccessfullyFetchVaultDetails(t *t
 		Repo:       repo,
 		UserRepo:   userRepo,
 		SecretRepo: secretRepo,
+		Fields:     fieldRepo,
+		Folders:    folderRepo,
 	}
 
 	vh.FetchVaultDetails(ctx)
 
 	userRepo.AssertNumberOfCalls(t, "FindById", 1)
 	repo.AssertNumberOfCalls(t, "FetchById", 2)
-	secretRepo.AssertNumberOfCalls(t, "FindCredentials", 1)
+	secretRepo.AssertNumberOfCalls(t, "FindByRefs", 1)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "ENCRYPTED_SEED")
+
+	var actualResponse vaults.VaultResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Len(t, actualResponse.Secrets, 2)
+	assert.Equal(t, vaults.TypeTotp, actualResponse.Secrets[1].Type)
+	assert.Equal(t, "ACME", actualResponse.Secrets[1].Issuer)
+	assert.Empty(t, actualResponse.Secrets[0].Fields)
+	assert.Equal(t, []vaults.CustomFieldResponse{{Id: "mock_field", Name: "Recovery code", Type: vaults.FieldHidden, Value: "********"}}, actualResponse.Secrets[1].Fields)
+	assert.NotContains(t, rec.Body.String(), "ENCRYPTED_CODE")
+}
+
+func TestVaultHandler_FetchBreachedSecrets_ShouldReportBreachedCredentials(t *testing.T) {
+	existingVault := (*mockVaults())[0]
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/breached-secrets", existingVault.Id), "GET", nil)
+
+	ep := utils_mocks.NewEncryptionProvider(t)
+	repo := &vaults_mocks.VaultRepository{}
+	secretRepo := &vaults_mocks.SecretRepository{}
+	breaches := passwords_mocks.NewBreachChecker(t)
+
+	ctx.Set("user_id", "mock_user_id")
+	ctx.AddParam("id", existingVault.Id)
+
+	repo.On("FetchById", existingVault.Id, mock.AnythingOfType("*vaults.Vault")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*vaults.Vault)
+		arg.Id = existingVault.Id
+		arg.UserRefer = existingVault.UserRefer
+	})
+
+	safeCredential := *mockCredential()
+	safeCredential.Id = "mock_safe_cred"
+	safeCredential.Password = []byte("Unlisted#Secret9")
+	secretRepo.On("FindCredentials", mock.AnythingOfType("*[]vaults.Credential"), existingVault.Id).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(0).(*[]vaults.Credential)
+		*(arg) = []vaults.Credential{*mockCredential(), safeCredential}
+	})
+
+	ep.On("Decrypt", "password").Return("password", nil)
+	ep.On("Decrypt", "Unlisted#Secret9").Return("Unlisted#Secret9", nil)
+	breaches.On("Lookup", "password").Return(9545824, nil)
+	breaches.On("Lookup", "Unlisted#Secret9").Return(0, nil)
+
+	vh := vaults.VaultHandler{
+		Ep:         ep,
+		Repo:       repo,
+		SecretRepo: secretRepo,
+		Breaches:   breaches,
+	}
+
+	vh.FetchBreachedSecrets(ctx)
+
+	var actualResponse vaults.BreachReportResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, vaults.BreachReportResponse{
+		VaultId: existingVault.Id,
+		Checked: 2,
+		Breached: []vaults.BreachedSecretResponse{
+			{Id: "mock_cred", Name: "Test Cred", Username: "username", Occurrences: 9545824},
+		},
+	}, actualResponse)
+}
+
+func TestVaultHandler_FetchBreachedSecrets_ShouldNotReportForOtherUsersVault(t *testing.T) {
+	existingVault := (*mockVaults())[0]
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/breached-secrets", existingVault.Id), "GET", nil)
+
+	repo := &vaults_mocks.VaultRepository{}
+
+	ctx.Set("user_id", "another_user_id")
+	ctx.AddParam("id", existingVault.Id)
+
+	repo.On("FetchById", existingVault.Id, mock.AnythingOfType("*vaults.Vault")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*vaults.Vault)
+		arg.UserRefer = existingVault.UserRefer
+	})
+
+	vh := vaults.VaultHandler{Repo: repo}
+
+	vh.FetchBreachedSecrets(ctx)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestVaultHandler_FetchVaultHealth_ShouldReportPasswordIssues(t *testing.T) {
+	existingVault := (*mockVaults())[0]
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/health?max_age_days=30", existingVault.Id), "GET", nil)
+
+	ep := utils_mocks.NewEncryptionProvider(t)
+	hasher := utils_mocks.NewPasswordHasher(t)
+	repo := &vaults_mocks.VaultRepository{}
+	userRepo := &user_mocks.UserRepository{}
+	secretRepo := &vaults_mocks.SecretRepository{}
+
+	userRepo.On("FindById", "mock_user_id", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*users.User) = *mockUser()
+	})
+	repo.On("FetchById", existingVault.Id, mock.AnythingOfType("*vaults.Vault")).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*vaults.Vault)
+		arg.Id = existingVault.Id
+		arg.UserRefer = existingVault.UserRefer
+	})
+
+	credentials := []vaults.Credential{
+		{Id: "weak_1", Password: []byte("password"), UpdatedAt: time.Now()},
+		{Id: "weak_2", Password: []byte("password"), UpdatedAt: time.Now()},
+		{Id: "stale", Password: []byte("Quartz-Nebula-Ferry-42"), UpdatedAt: time.Now().AddDate(0, 0, -45)},
+		{Id: "account", Password: []byte("Orbit#Lantern#Velvet9"), UpdatedAt: time.Now()},
+		{Id: "healthy", Password: []byte("Crimson_Tundra_Walrus_7"), UpdatedAt: time.Now()},
+	}
+	secretRepo.On("FindCredentials", mock.AnythingOfType("*[]vaults.Credential"), existingVault.Id).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(0).(*[]vaults.Credential) = credentials
+	})
+
+	ep.On("Decrypt", mock.AnythingOfType("string")).Return(func(s string) string { return s }, nil)
+	hasher.On("ComparePassword", "Orbit#Lantern#Velvet9", "HashedPassword").Return(true)
+	hasher.On("ComparePassword", mock.AnythingOfType("string"), "HashedPassword").Return(false)
+
+	vh := vaults.VaultHandler{
+		Ep:         ep,
+		Repo:       repo,
+		UserRepo:   userRepo,
+		SecretRepo: secretRepo,
+		Hasher:     hasher,
+	}
+
+	ctx.Set("user_id", "mock_user_id")
+	ctx.AddParam("id", existingVault.Id)
+
+	vh.FetchVaultHealth(ctx)
+
+	var actualResponse vaults.HealthReportResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, existingVault.Id, actualResponse.VaultId)
+	assert.Equal(t, 5, actualResponse.Checked)
+	assert.Equal(t, vaults.HealthSummaryResponse{Weak: 2, Reused: 2, Stale: 1, AccountPasswordDuplicates: 1}, actualResponse.Summary)
+
+	findings := map[string]vaults.CredentialHealthResponse{}
+	for _, ch := range actualResponse.Credentials {
+		findings[ch.Id] = ch
+	}
+	assert.Len(t, findings, 4)
+	assert.True(t, findings["weak_1"].Weak && findings["weak_1"].Reused)
+	assert.Equal(t, findings["weak_1"].ReuseGroup, findings["weak_2"].ReuseGroup)
+	assert.True(t, findings["stale"].Stale)
+	assert.Equal(t, 45, findings["stale"].AgeDays)
+	assert.True(t, findings["account"].MatchesAccountPassword)
+	assert.NotContains(t, rec.Body.String(), "Orbit#Lantern#Velvet9")
+	hasher.AssertNumberOfCalls(t, "ComparePassword", 4)
+}
+
+func TestVaultHandler_FetchHealth_ShouldDetectReuseAcrossVaults(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/vaults/health", "GET", nil)
+
+	ep := utils_mocks.NewEncryptionProvider(t)
+	repo := &vaults_mocks.VaultRepository{}
+	userRepo := &user_mocks.UserRepository{}
+	secretRepo := &vaults_mocks.SecretRepository{}
+
+	userRepo.On("FindById", "mock_user_id", mock.AnythingOfType("*users.User")).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*users.User) = *mockUser()
+	})
+	repo.On("FetchByUserId", "mock_user_id", mock.AnythingOfType("*[]vaults.Vault")).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]vaults.Vault) = *mockVaults()
+	})
+	secretRepo.On("FindCredentials", mock.AnythingOfType("*[]vaults.Credential"), "mock_vault_id_1").Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(0).(*[]vaults.Credential) = []vaults.Credential{{Id: "cred_1", VaultRefer: "mock_vault_id_1", Password: []byte("Crimson_Tundra_Walrus_7"), UpdatedAt: time.Now()}}
+	})
+	secretRepo.On("FindCredentials", mock.AnythingOfType("*[]vaults.Credential"), "mock_vault_id_2").Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(0).(*[]vaults.Credential) = []vaults.Credential{{Id: "cred_2", VaultRefer: "mock_vault_id_2", Password: []byte("Crimson_Tundra_Walrus_7"), UpdatedAt: time.Now()}}
+	})
+	ep.On("Decrypt", "Crimson_Tundra_Walrus_7").Return("Crimson_Tundra_Walrus_7", nil)
+
+	vh := vaults.VaultHandler{
+		Ep:         ep,
+		Repo:       repo,
+		UserRepo:   userRepo,
+		SecretRepo: secretRepo,
+	}
+
+	ctx.Set("user_id", "mock_user_id")
+
+	vh.FetchHealth(ctx)
+
+	var actualResponse vaults.HealthReportResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
 
 	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, vaults.HealthSummaryResponse{Reused: 2}, actualResponse.Summary)
+	assert.Equal(t, []string{"mock_vault_id_1", "mock_vault_id_2"}, []string{actualResponse.Credentials[0].VaultId, actualResponse.Credentials[1].VaultId})
+}
+
+func TestVaultHandler_FetchHealth_ShouldRejectInvalidMaxAge(t *testing.T) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/vaults/health?max_age_days=5000", "GET", nil)
+
+	vh := vaults.VaultHandler{}
+
+	ctx.Set("user_id", "mock_user_id")
+
+	vh.FetchHealth(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
 func mockUser() *users.User {
@@ -0,0 +1,85 @@
+package vaults
+
+import (
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/passwords"
+	"github.com/adarsh-a-tw/passwordly/utils"
+)
+
+// weakPasswordScore is the zxcvbn score below which a stored password is
+// reported as weak.
+const weakPasswordScore = 3
+
+// healthAnalyzer inspects decrypted credentials. Identical passwords are
+// estimated and compared against the account hash once, which matters
+// because every account comparison is a full argon2 run.
+type healthAnalyzer struct {
+	hasher      utils.PasswordHasher
+	accountHash string
+	maxAge      time.Duration
+	now         time.Time
+}
+
+func (ha *healthAnalyzer) analyze(creds []Credential) HealthReportResponse {
+	groups := map[string][]int{}
+	var order []string
+	for i, cred := range creds {
+		pwd := string(cred.Password)
+		if _, ok := groups[pwd]; !ok {
+			order = append(order, pwd)
+		}
+		groups[pwd] = append(groups[pwd], i)
+	}
+
+	report := HealthReportResponse{Checked: len(creds), Credentials: []CredentialHealthResponse{}}
+	results := make([]CredentialHealthResponse, len(creds))
+	reuseGroup := 0
+	for _, pwd := range order {
+		indexes := groups[pwd]
+		estimate := passwords.EstimateStrength(pwd)
+		matchesAccount := ha.hasher != nil && ha.accountHash != "" && ha.hasher.ComparePassword(pwd, ha.accountHash)
+		if len(indexes) > 1 {
+			reuseGroup++
+		}
+
+		for _, i := range indexes {
+			ch := &results[i]
+			ch.Score = estimate.Score
+			ch.Weak = estimate.Score < weakPasswordScore
+			ch.MatchesAccountPassword = matchesAccount
+			if len(indexes) > 1 {
+				ch.Reused = true
+				ch.ReuseGroup = reuseGroup
+			}
+		}
+	}
+
+	for i, cred := range creds {
+		ch := results[i]
+		ch.Id = cred.Id
+		ch.Name = cred.Name
+		ch.Username = cred.Username
+		ch.VaultId = cred.VaultRefer
+		ch.AgeDays = int(ha.now.Sub(cred.UpdatedAt).Hours() / 24)
+		ch.Stale = ha.maxAge > 0 && ha.now.Sub(cred.UpdatedAt) > ha.maxAge
+
+		if ch.Weak {
+			report.Summary.Weak++
+		}
+		if ch.Reused {
+			report.Summary.Reused++
+		}
+		if ch.Stale {
+			report.Summary.Stale++
+		}
+		if ch.MatchesAccountPassword {
+			report.Summary.AccountPasswordDuplicates++
+		}
+		if ch.Weak || ch.Reused || ch.Stale || ch.MatchesAccountPassword {
+			report.Credentials = append(report.Credentials, ch)
+		}
+	}
+
+	return report
+}
@@ -1,5 +1,11 @@
 package vaults
 
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
 func ValidateVaultOwner(vr VaultRepository, vaultId string, userId string) (bool, error) {
 	var vault Vault
 	if err := vr.FetchById(vaultId, &vault); err != nil {
@@ -12,3 +18,88 @@ func ValidateVaultOwner(vr VaultRepository, vaultId string, userId string) (bool
 
 	return true, nil
 }
+
+// requireVaultOwner checks that the :id vault belongs to the current user,
+// responding with 404 otherwise, and returns the vault id.
+func requireVaultOwner(ctx *gin.Context, vr VaultRepository) (string, bool) {
+	vaultId := ctx.Param("id")
+
+	valid, err := ValidateVaultOwner(vr, vaultId, ctx.GetString("user_id"))
+
+	if err != nil {
+		handleGormError(ctx, err)
+		return "", false
+	}
+
+	if !valid {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return "", false
+	}
+
+	return vaultId, true
+}
+
+// requireSecret checks that :secretId is a secret of any type in the :id
+// vault of the current user, responding with 404 otherwise.
+func requireSecret(ctx *gin.Context, vr VaultRepository, sr SecretRepository) (vaultId string, secretId string, ok bool) {
+	vaultId, ok = requireVaultOwner(ctx, vr)
+	if !ok {
+		return "", "", false
+	}
+
+	secretId = ctx.Param("secretId")
+	if _, err := sr.FindSecretType(secretId, vaultId); err != nil {
+		handleGormError(ctx, err)
+		return "", "", false
+	}
+	return vaultId, secretId, true
+}
+
+// findSecrets loads every secret of a vault without decrypting anything.
+func findSecrets(sr SecretRepository, vaultId string) ([]Securable, error) {
+	var credentials []Credential
+	if err := sr.FindCredentials(&credentials, vaultId); err != nil {
+		return nil, err
+	}
+	var totps []Totp
+	if err := sr.FindTotps(&totps, vaultId); err != nil {
+		return nil, err
+	}
+	var sshKeys []SshKey
+	if err := sr.FindSshKeys(&sshKeys, vaultId); err != nil {
+		return nil, err
+	}
+	var cas []CertificateAuthority
+	if err := sr.FindCertificateAuthorities(&cas, vaultId); err != nil {
+		return nil, err
+	}
+	var cards []Card
+	if err := sr.FindCards(&cards, vaultId); err != nil {
+		return nil, err
+	}
+	var identities []Identity
+	if err := sr.FindIdentities(&identities, vaultId); err != nil {
+		return nil, err
+	}
+
+	secrets := make([]Securable, 0, len(credentials)+len(totps)+len(sshKeys)+len(cas)+len(cards)+len(identities))
+	for _, c := range credentials {
+		secrets = append(secrets, c)
+	}
+	for _, t := range totps {
+		secrets = append(secrets, t)
+	}
+	for _, k := range sshKeys {
+		secrets = append(secrets, k)
+	}
+	for _, ca := range cas {
+		secrets = append(secrets, ca)
+	}
+	for _, c := range cards {
+		secrets = append(secrets, c)
+	}
+	for _, i := range identities {
+		secrets = append(secrets, i)
+	}
+	return secrets, nil
+}
@@ -0,0 +1,393 @@
+package vaults
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/imports"
+	"github.com/adarsh-a-tw/passwordly/users"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	importStatusNew       = "new"
+	importStatusDuplicate = "duplicate"
+	importStatusError     = "error"
+
+	maxImportFields = 50
+)
+
+// ImportHandler brings the entries of another password manager's export
+// into a vault. Logins become credentials, notes documents and keys keys;
+// folders are matched by path and created when missing. It also restores
+// the encrypted backups written by ExportHandler.
+type ImportHandler struct {
+	Ep        utils.EncryptionProvider
+	Repo      SecretRepository
+	VaultRepo VaultRepository
+	UserRepo  users.UserRepository
+	Folders   FolderRepository
+	Audit     audit.Recorder
+	Index     SearchIndexer
+	MaxSize   int64
+}
+
+// importPlan is what an import writes and the report of every entry.
+type importPlan struct {
+	folders []Folder
+	writes  []SecretWrite
+	resp    ImportResponse
+}
+
+// ImportSecrets reads an uploaded export and imports it, or with dry_run
+// only reports what the import would do.
+func (ih *ImportHandler) ImportSecrets(ctx *gin.Context) {
+	fh, ok := ih.formFile(ctx)
+	if !ok {
+		return
+	}
+	var ir ImportRequest
+	if err := ctx.ShouldBind(&ir); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+	if !ir.Format.IsValid() {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: imports.ErrUnknownFormat.Error()})
+		return
+	}
+	options := imports.Options{Password: ir.Password, MaxSize: ih.MaxSize}
+	if ir.Mapping != "" {
+		if err := json.Unmarshal([]byte(ir.Mapping), &options.Mapping); err != nil {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid column mapping"})
+			return
+		}
+	}
+
+	vaultId, ok := requireVaultOwner(ctx, ih.VaultRepo)
+	if !ok {
+		return
+	}
+	var v Vault
+	if err := ih.VaultRepo.FetchById(vaultId, &v); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	data, err := readFormFile(fh)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	if kf, err := ctx.FormFile("keyfile"); err == nil {
+		if options.KeyFile, err = readFormFile(kf); err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+	}
+
+	result, err := imports.Parse(ir.Format, data, options)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+		return
+	}
+	plan, err := ih.plan(&v, result, ir.ImportDuplicates)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	plan.resp.DryRun = ir.DryRun
+	if ir.DryRun {
+		ctx.JSON(http.StatusOK, plan.resp)
+		return
+	}
+	if len(plan.writes) > 0 {
+		if err := ih.Repo.ImportSecrets(vaultId, plan.folders, plan.writes); err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		ids := make([]string, len(plan.writes))
+		for i, w := range plan.writes {
+			ids[i] = w.Id
+		}
+		indexSecrets(ih.Index, vaultId, ids...)
+	}
+
+	audit.Log(ih.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretImport,
+		TargetType: audit.TargetVault,
+		TargetId:   vaultId,
+		Detail:     fmt.Sprintf("%d secrets from %s, %d duplicates skipped, %d failed", plan.resp.Imported, ir.Format, plan.resp.Duplicates, plan.resp.Failed),
+	})
+
+	ctx.JSON(http.StatusCreated, plan.resp)
+}
+
+// plan turns parsed entries into the folders and secrets to create,
+// skipping duplicates of existing secrets and of earlier entries unless
+// importDuplicates is set.
+func (ih *ImportHandler) plan(v *Vault, result imports.Result, importDuplicates bool) (importPlan, error) {
+	existing, err := ih.existingSecrets(v.Id)
+	if err != nil {
+		return importPlan{}, err
+	}
+	var folders []Folder
+	if err := ih.Folders.FindFolders(v.Id, &folders); err != nil {
+		return importPlan{}, err
+	}
+	tree := newFolderTree(folders)
+	folderIds := make(map[string]string, len(folders))
+	for _, f := range folders {
+		folderIds[strings.ToLower(tree.path(f.Id))] = f.Id
+	}
+
+	p := importPlan{resp: ImportResponse{FoldersCreated: []string{}, Entries: []ImportEntryResponse{}}}
+	for _, re := range result.Errors {
+		p.resp.Entries = append(p.resp.Entries, ImportEntryResponse{Row: re.Row, Name: re.Name, Status: importStatusError, Message: re.Message})
+	}
+	seen := make(map[string]int)
+	for _, e := range result.Entries {
+		er := ImportEntryResponse{Row: e.Row, Name: e.Name, Type: importType(e.Kind), Folder: strings.Join(e.Folder, "/"), Status: importStatusNew}
+
+		key := duplicateKey(er.Type, e.Name, e.Username)
+		if id, ok := existing[key]; ok && !importDuplicates {
+			er.Status, er.DuplicateOf = importStatusDuplicate, id
+		} else if row, ok := seen[key]; ok && !importDuplicates {
+			er.Status, er.Message = importStatusDuplicate, fmt.Sprintf("Same as row %d", row)
+		} else if w, err := ih.convert(e, v, func() *string { return p.folderId(v.Id, e.Folder, folderIds) }); err != nil {
+			var re requestError
+			if !errors.As(err, &re) {
+				return importPlan{}, err
+			}
+			er.Status, er.Message = importStatusError, re.message
+		} else {
+			er.Id = w.Id
+			p.writes = append(p.writes, w)
+		}
+		if _, ok := seen[key]; !ok {
+			seen[key] = e.Row
+		}
+		p.resp.Entries = append(p.resp.Entries, er)
+	}
+
+	tree = newFolderTree(append(folders, p.folders...))
+	for _, f := range p.folders {
+		p.resp.FoldersCreated = append(p.resp.FoldersCreated, tree.path(f.Id))
+	}
+	sort.SliceStable(p.resp.Entries, func(i, j int) bool { return p.resp.Entries[i].Row < p.resp.Entries[j].Row })
+	for _, er := range p.resp.Entries {
+		switch er.Status {
+		case importStatusNew:
+			p.resp.Imported++
+		case importStatusDuplicate:
+			p.resp.Duplicates++
+		case importStatusError:
+			p.resp.Failed++
+		}
+	}
+	return p, nil
+}
+
+// folderId finds the folder at path, planning any folders on the way that
+// do not exist yet. Paths are matched without regard to case.
+func (p *importPlan) folderId(vaultId string, path []string, folderIds map[string]string) *string {
+	var parentId *string
+	for i := range path {
+		key := strings.ToLower(strings.Join(path[:i+1], "/"))
+		id, ok := folderIds[key]
+		if !ok {
+			id = uuid.NewString()
+			p.folders = append(p.folders, Folder{Id: id, Name: path[i], ParentId: parentId, VaultRefer: vaultId})
+			folderIds[key] = id
+		}
+		parentId = optional(id)
+	}
+	return parentId
+}
+
+// existingSecrets indexes the credentials, keys and documents of a vault by
+// duplicateKey.
+func (ih *ImportHandler) existingSecrets(vaultId string) (map[string]string, error) {
+	existing := make(map[string]string)
+	var credentials []Credential
+	if err := ih.Repo.FindCredentials(&credentials, vaultId); err != nil {
+		return nil, err
+	}
+	for _, c := range credentials {
+		existing[duplicateKey(TypeCredential, c.Name, c.Username)] = c.Id
+	}
+	var keys []Key
+	if err := ih.Repo.FindKeys(&keys, vaultId); err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		existing[duplicateKey(TypeKey, k.Name, "")] = k.Id
+	}
+	var documents []Document
+	if err := ih.Repo.FindDocuments(&documents, vaultId); err != nil {
+		return nil, err
+	}
+	for _, d := range documents {
+		existing[duplicateKey(TypeDocument, d.Name, "")] = d.Id
+	}
+	return existing, nil
+}
+
+// duplicateKey identifies a secret for duplicate detection: credentials by
+// name and username, other secrets by name.
+func duplicateKey(secretType SecretType, name string, username string) string {
+	if secretType != TypeCredential {
+		username = ""
+	}
+	return strings.ToLower(fmt.Sprintf("%s\x00%s\x00%s", secretType, strings.TrimSpace(name), strings.TrimSpace(username)))
+}
+
+func importType(kind imports.Kind) SecretType {
+	switch kind {
+	case imports.KindKey:
+		return TypeKey
+	case imports.KindNote:
+		return TypeDocument
+	}
+	return TypeCredential
+}
+
+// convert builds the secret and fields of an entry, placing it in the
+// folder returned by folder once the entry is known to be valid. Problems
+// with the entry itself are returned as a requestError.
+func (ih *ImportHandler) convert(e imports.Entry, v *Vault, folder func() *string) (SecretWrite, error) {
+	tags, err := normalizeTags(e.Tags)
+	if err != nil {
+		return SecretWrite{}, requestError{err.Error()}
+	}
+	w := SecretWrite{Op: BatchCreate, Type: importType(e.Kind), Id: uuid.NewString()}
+	if w.Fields, err = ih.buildFields(e, w.Id, v.Id); err != nil {
+		return SecretWrite{}, err
+	}
+
+	var value string
+	switch w.Type {
+	case TypeCredential:
+		value = e.Password
+	case TypeKey:
+		value = e.Key
+	case TypeDocument:
+		value = e.Notes
+	}
+	encrypted, err := ih.Ep.Encrypt(value)
+	if err != nil {
+		return SecretWrite{}, err
+	}
+
+	placement := Placement{FolderId: folder(), Tags: strings.Join(tags, ",")}
+	switch w.Type {
+	case TypeCredential:
+		w.Secret = Credential{Id: w.Id, Name: e.Name, Username: e.Username, Password: []byte(encrypted), Vault: *v, Placement: placement}
+	case TypeKey:
+		w.Secret = Key{Id: w.Id, Name: e.Name, Value: []byte(encrypted), Vault: *v, Placement: placement}
+	case TypeDocument:
+		w.Secret = Document{Id: w.Id, Name: e.Name, Content: []byte(encrypted), Vault: *v, Placement: placement}
+	}
+	return w, nil
+}
+
+// buildFields keeps what an entry holds beyond the secret's own value as
+// custom fields: its website, one-time password, notes and extra fields.
+func (ih *ImportHandler) buildFields(e imports.Entry, secretId string, vaultId string) ([]CustomField, error) {
+	var fields []CustomField
+	add := func(name string, fieldType FieldType, value string) {
+		if strings.TrimSpace(name) == "" {
+			name = "Field"
+		}
+		fields = append(fields, CustomField{Name: name, Type: fieldType, Value: []byte(value)})
+	}
+
+	if e.Url != "" {
+		if u, err := url.Parse(e.Url); err == nil && u.Scheme != "" && u.Host != "" {
+			add("Website", FieldUrl, e.Url)
+		} else {
+			add("Website", FieldText, e.Url)
+		}
+	}
+	if e.Kind == imports.KindKey && e.Username != "" {
+		add("Username", FieldText, e.Username)
+	}
+	if e.Totp != "" {
+		add("One-time password", FieldHidden, e.Totp)
+	}
+	if e.Kind != imports.KindNote && e.Notes != "" {
+		add("Notes", FieldText, e.Notes)
+	}
+	for _, f := range e.Fields {
+		if f.Hidden {
+			add(f.Name, FieldHidden, f.Value)
+		} else {
+			add(f.Name, FieldText, f.Value)
+		}
+	}
+	if len(fields) > maxImportFields {
+		return nil, requestError{fmt.Sprintf("Entry has more than %d fields", maxImportFields)}
+	}
+
+	for i := range fields {
+		fields[i].Id = uuid.NewString()
+		fields[i].SecretId = secretId
+		fields[i].Position = i
+		fields[i].VaultRefer = vaultId
+		if fields[i].Type == FieldHidden {
+			encrypted, err := ih.Ep.Encrypt(string(fields[i].Value))
+			if err != nil {
+				return nil, err
+			}
+			fields[i].Value = []byte(encrypted)
+		}
+	}
+	return fields, nil
+}
+
+// formFile limits the request to MaxSize and returns the uploaded file,
+// answering the request when there is none or it is too large.
+func (ih *ImportHandler) formFile(ctx *gin.Context) (*multipart.FileHeader, bool) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, ih.MaxSize+multipartOverhead)
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ih.respondTooLarge(ctx)
+			return nil, false
+		}
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return nil, false
+	}
+	if fh.Size > ih.MaxSize {
+		ih.respondTooLarge(ctx)
+		return nil, false
+	}
+	return fh, true
+}
+
+func readFormFile(fh *multipart.FileHeader) ([]byte, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func (ih *ImportHandler) respondTooLarge(ctx *gin.Context) {
+	ctx.JSON(http.StatusRequestEntityTooLarge, common.ErrorResponse{
+		Message: fmt.Sprintf("Imports are limited to %d bytes", ih.MaxSize),
+	})
+}
@@ -0,0 +1,80 @@
+package vaults
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"gorm.io/gorm"
+)
+
+// EncryptPlaintextSecrets encrypts the values of keys and documents left
+// from when they were stored as plain text, turning their columns into
+// bytea. It has to run before the tables are auto migrated, which cannot
+// convert the columns on Postgres, and does nothing once they are
+// converted.
+func EncryptPlaintextSecrets(db *gorm.DB, ep utils.EncryptionProvider) error {
+	for _, c := range []struct {
+		model  any
+		table  string
+		field  string
+		column string
+	}{
+		{&Key{}, "keys", "Value", "value"},
+		{&Document{}, "documents", "Content", "content"},
+	} {
+		if err := encryptPlaintextColumn(db, ep, c.model, c.table, c.field, c.column); err != nil {
+			return fmt.Errorf("%s: %w", c.table, err)
+		}
+	}
+	return nil
+}
+
+func encryptPlaintextColumn(db *gorm.DB, ep utils.EncryptionProvider, model any, table string, field string, column string) error {
+	if !db.Migrator().HasTable(model) {
+		return nil
+	}
+	columnTypes, err := db.Migrator().ColumnTypes(model)
+	if err != nil {
+		return err
+	}
+	plaintext := false
+	for _, ct := range columnTypes {
+		if ct.Name() == column && strings.EqualFold(ct.DatabaseTypeName(), "text") {
+			plaintext = true
+		}
+	}
+	if !plaintext {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			Id    string
+			Value string
+		}
+		if err := tx.Table(table).Select(fmt.Sprintf("id, %s AS value", column)).Find(&rows).Error; err != nil {
+			return err
+		}
+
+		if tx.Dialector.Name() == "postgres" {
+			alter := fmt.Sprintf("ALTER TABLE %[1]s ALTER COLUMN %[2]s TYPE bytea USING convert_to(%[2]s, 'UTF8')", table, column)
+			if err := tx.Exec(alter).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Migrator().AlterColumn(model, field); err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			encrypted, err := ep.Encrypt(row.Value)
+			if err != nil {
+				return err
+			}
+			if err := tx.Table(table).Where("id = ?", row.Id).UpdateColumn(column, []byte(encrypted)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
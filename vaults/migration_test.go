@@ -0,0 +1,43 @@
+package vaults_test
+
+import (
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/users"
+	utils_mocks "github.com/adarsh-a-tw/passwordly/utils/mocks"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// plaintextKey is a key as it was stored before values were encrypted.
+type plaintextKey struct {
+	Id         string `gorm:"primaryKey"`
+	Name       string `gorm:"notNull"`
+	Value      string `gorm:"notNull"`
+	VaultRefer string
+}
+
+func (plaintextKey) TableName() string {
+	return "keys"
+}
+
+func TestEncryptPlaintextSecrets_ShouldEncryptExistingValuesOnce(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&users.User{}, &vaults.Vault{}, &plaintextKey{}))
+	assert.NoError(t, db.Create(&plaintextKey{Id: "stripe", Name: "Stripe", Value: "sk_live_1", VaultRefer: "work"}).Error)
+
+	ep := utils_mocks.NewEncryptionProvider(t)
+	ep.On("Encrypt", "sk_live_1").Return("ENCRYPTED_KEY", nil).Once()
+
+	assert.NoError(t, vaults.EncryptPlaintextSecrets(db, ep))
+	assert.NoError(t, db.AutoMigrate(&vaults.Key{}, &vaults.Document{}))
+	assert.NoError(t, vaults.EncryptPlaintextSecrets(db, ep))
+
+	var k vaults.Key
+	assert.NoError(t, db.First(&k, "id = ?", "stripe").Error)
+	assert.Equal(t, []byte("ENCRYPTED_KEY"), k.Value)
+	assert.Equal(t, "Stripe", k.Name)
+}
@@ -5,6 +5,7 @@ package mocks
 import (
 	vaults "github.com/adarsh-a-tw/passwordly/vaults"
 	mock "github.com/stretchr/testify/mock"
+	time "time"
 )
 
 // VaultRepository is an autogenerated mock type for the VaultRepository type
@@ -68,6 +69,76 @@ func (_m *VaultRepository) FetchByUserId(userId string, _a1 *[]vaults.Vault) err
 	return r0
 }
 
+// FetchPageByUserId provides a mock function with given fields: userId, pq, _a2
+func (_m *VaultRepository) FetchPageByUserId(userId string, pq vaults.PageQuery, _a2 *[]vaults.Vault) error {
+	ret := _m.Called(userId, pq, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, vaults.PageQuery, *[]vaults.Vault) error); ok {
+		r0 = rf(userId, pq, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FetchTrash provides a mock function with given fields: userId, deletedBefore, _a2
+func (_m *VaultRepository) FetchTrash(userId string, deletedBefore time.Time, _a2 *[]vaults.Vault) error {
+	ret := _m.Called(userId, deletedBefore, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, *[]vaults.Vault) error); ok {
+		r0 = rf(userId, deletedBefore, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FetchTrashedById provides a mock function with given fields: id, v
+func (_m *VaultRepository) FetchTrashedById(id string, v *vaults.Vault) error {
+	ret := _m.Called(id, v)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *vaults.Vault) error); ok {
+		r0 = rf(id, v)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Purge provides a mock function with given fields: v
+func (_m *VaultRepository) Purge(v *vaults.Vault) error {
+	ret := _m.Called(v)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*vaults.Vault) error); ok {
+		r0 = rf(v)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Restore provides a mock function with given fields: v
+func (_m *VaultRepository) Restore(v *vaults.Vault) error {
+	ret := _m.Called(v)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*vaults.Vault) error); ok {
+		r0 = rf(v)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Update provides a mock function with given fields: v
 func (_m *VaultRepository) Update(v *vaults.Vault) error {
 	ret := _m.Called(v)
@@ -5,6 +5,7 @@ package mocks
 import (
 	vaults "github.com/adarsh-a-tw/passwordly/vaults"
 	mock "github.com/stretchr/testify/mock"
+	time "time"
 )
 
 // SecretRepository is an autogenerated mock type for the SecretRepository type
@@ -12,13 +13,207 @@ type SecretRepository struct {
 	mock.Mock
 }
 
-// CreateCredential provides a mock function with given fields: credential
-func (_m *SecretRepository) CreateCredential(credential *vaults.Credential) error {
-	ret := _m.Called(credential)
+// ApplyBatch provides a mock function with given fields: vaultId, writes, atomic
+func (_m *SecretRepository) ApplyBatch(vaultId string, writes []vaults.SecretWrite, atomic bool) ([]error, error) {
+	ret := _m.Called(vaultId, writes, atomic)
+
+	var r0 []error
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, []vaults.SecretWrite, bool) ([]error, error)); ok {
+		return rf(vaultId, writes, atomic)
+	}
+	if rf, ok := ret.Get(0).(func(string, []vaults.SecretWrite, bool) []error); ok {
+		r0 = rf(vaultId, writes, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]error)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, []vaults.SecretWrite, bool) error); ok {
+		r1 = rf(vaultId, writes, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CopySecrets provides a mock function with given fields: copies, vaultId, folderId, attachments
+func (_m *SecretRepository) CopySecrets(copies []vaults.SecretCopy, vaultId string, folderId *string, attachments []vaults.Attachment) error {
+	ret := _m.Called(copies, vaultId, folderId, attachments)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]vaults.SecretCopy, string, *string, []vaults.Attachment) error); ok {
+		r0 = rf(copies, vaultId, folderId, attachments)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateCard provides a mock function with given fields: c, fields
+func (_m *SecretRepository) CreateCard(c *vaults.Card, fields []vaults.CustomField) error {
+	ret := _m.Called(c, fields)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*vaults.Card, []vaults.CustomField) error); ok {
+		r0 = rf(c, fields)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateCertificateAuthority provides a mock function with given fields: ca, fields
+func (_m *SecretRepository) CreateCertificateAuthority(ca *vaults.CertificateAuthority, fields []vaults.CustomField) error {
+	ret := _m.Called(ca, fields)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*vaults.CertificateAuthority, []vaults.CustomField) error); ok {
+		r0 = rf(ca, fields)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateCredential provides a mock function with given fields: credential, fields
+func (_m *SecretRepository) CreateCredential(credential *vaults.Credential, fields []vaults.CustomField) error {
+	ret := _m.Called(credential, fields)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*vaults.Credential, []vaults.CustomField) error); ok {
+		r0 = rf(credential, fields)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateIdentity provides a mock function with given fields: i, fields
+func (_m *SecretRepository) CreateIdentity(i *vaults.Identity, fields []vaults.CustomField) error {
+	ret := _m.Called(i, fields)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*vaults.Identity, []vaults.CustomField) error); ok {
+		r0 = rf(i, fields)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateSshKey provides a mock function with given fields: k, fields
+func (_m *SecretRepository) CreateSshKey(k *vaults.SshKey, fields []vaults.CustomField) error {
+	ret := _m.Called(k, fields)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*vaults.SshKey, []vaults.CustomField) error); ok {
+		r0 = rf(k, fields)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateTotp provides a mock function with given fields: t, fields
+func (_m *SecretRepository) CreateTotp(t *vaults.Totp, fields []vaults.CustomField) error {
+	ret := _m.Called(t, fields)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*vaults.Totp, []vaults.CustomField) error); ok {
+		r0 = rf(t, fields)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteSecret provides a mock function with given fields: secretType, id, vaultId
+func (_m *SecretRepository) DeleteSecret(secretType vaults.SecretType, id string, vaultId string) error {
+	ret := _m.Called(secretType, id, vaultId)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*vaults.Credential) error); ok {
-		r0 = rf(credential)
+	if rf, ok := ret.Get(0).(func(vaults.SecretType, string, string) error); ok {
+		r0 = rf(secretType, id, vaultId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindByRefs provides a mock function with given fields: refs, secrets
+func (_m *SecretRepository) FindByRefs(refs []vaults.SecretSummary, secrets *[]vaults.Securable) error {
+	ret := _m.Called(refs, secrets)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]vaults.SecretSummary, *[]vaults.Securable) error); ok {
+		r0 = rf(refs, secrets)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindCardById provides a mock function with given fields: id, vaultId, c
+func (_m *SecretRepository) FindCardById(id string, vaultId string, c *vaults.Card) error {
+	ret := _m.Called(id, vaultId, c)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, *vaults.Card) error); ok {
+		r0 = rf(id, vaultId, c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindCards provides a mock function with given fields: cards, vaultId
+func (_m *SecretRepository) FindCards(cards *[]vaults.Card, vaultId string) error {
+	ret := _m.Called(cards, vaultId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*[]vaults.Card, string) error); ok {
+		r0 = rf(cards, vaultId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindCertificateAuthorities provides a mock function with given fields: cas, vaultId
+func (_m *SecretRepository) FindCertificateAuthorities(cas *[]vaults.CertificateAuthority, vaultId string) error {
+	ret := _m.Called(cas, vaultId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*[]vaults.CertificateAuthority, string) error); ok {
+		r0 = rf(cas, vaultId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindCertificateAuthorityById provides a mock function with given fields: id, vaultId, ca
+func (_m *SecretRepository) FindCertificateAuthorityById(id string, vaultId string, ca *vaults.CertificateAuthority) error {
+	ret := _m.Called(id, vaultId, ca)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, *vaults.CertificateAuthority) error); ok {
+		r0 = rf(id, vaultId, ca)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -40,6 +235,324 @@ func (_m *SecretRepository) FindCredentials(credentials *[]vaults.Credential, va
 	return r0
 }
 
+// FindDocuments provides a mock function with given fields: documents, vaultId
+func (_m *SecretRepository) FindDocuments(documents *[]vaults.Document, vaultId string) error {
+	ret := _m.Called(documents, vaultId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*[]vaults.Document, string) error); ok {
+		r0 = rf(documents, vaultId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindIdentities provides a mock function with given fields: identities, vaultId
+func (_m *SecretRepository) FindIdentities(identities *[]vaults.Identity, vaultId string) error {
+	ret := _m.Called(identities, vaultId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*[]vaults.Identity, string) error); ok {
+		r0 = rf(identities, vaultId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindIdentityById provides a mock function with given fields: id, vaultId, i
+func (_m *SecretRepository) FindIdentityById(id string, vaultId string, i *vaults.Identity) error {
+	ret := _m.Called(id, vaultId, i)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, *vaults.Identity) error); ok {
+		r0 = rf(id, vaultId, i)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindKeys provides a mock function with given fields: keys, vaultId
+func (_m *SecretRepository) FindKeys(keys *[]vaults.Key, vaultId string) error {
+	ret := _m.Called(keys, vaultId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*[]vaults.Key, string) error); ok {
+		r0 = rf(keys, vaultId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindPage provides a mock function with given fields: vaultId, filter, pq, summaries
+func (_m *SecretRepository) FindPage(vaultId string, filter vaults.SecretFilter, pq vaults.PageQuery, summaries *[]vaults.SecretSummary) error {
+	ret := _m.Called(vaultId, filter, pq, summaries)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, vaults.SecretFilter, vaults.PageQuery, *[]vaults.SecretSummary) error); ok {
+		r0 = rf(vaultId, filter, pq, summaries)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindScheduled provides a mock function with given fields: userId, secrets
+func (_m *SecretRepository) FindScheduled(userId string, secrets *[]vaults.ScheduledSecret) error {
+	ret := _m.Called(userId, secrets)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *[]vaults.ScheduledSecret) error); ok {
+		r0 = rf(userId, secrets)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindSecretType provides a mock function with given fields: id, vaultId
+func (_m *SecretRepository) FindSecretType(id string, vaultId string) (vaults.SecretType, error) {
+	ret := _m.Called(id, vaultId)
+
+	var r0 vaults.SecretType
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (vaults.SecretType, error)); ok {
+		return rf(id, vaultId)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) vaults.SecretType); ok {
+		r0 = rf(id, vaultId)
+	} else {
+		r0 = ret.Get(0).(vaults.SecretType)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(id, vaultId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindSshKeyById provides a mock function with given fields: id, vaultId, k
+func (_m *SecretRepository) FindSshKeyById(id string, vaultId string, k *vaults.SshKey) error {
+	ret := _m.Called(id, vaultId, k)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, *vaults.SshKey) error); ok {
+		r0 = rf(id, vaultId, k)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindSshKeys provides a mock function with given fields: keys, vaultId
+func (_m *SecretRepository) FindSshKeys(keys *[]vaults.SshKey, vaultId string) error {
+	ret := _m.Called(keys, vaultId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*[]vaults.SshKey, string) error); ok {
+		r0 = rf(keys, vaultId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindSummaries provides a mock function with given fields: ids, summaries
+func (_m *SecretRepository) FindSummaries(ids []string, summaries *[]vaults.SecretSummary) error {
+	ret := _m.Called(ids, summaries)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]string, *[]vaults.SecretSummary) error); ok {
+		r0 = rf(ids, summaries)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindTotpById provides a mock function with given fields: id, vaultId, t
+func (_m *SecretRepository) FindTotpById(id string, vaultId string, t *vaults.Totp) error {
+	ret := _m.Called(id, vaultId, t)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, *vaults.Totp) error); ok {
+		r0 = rf(id, vaultId, t)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindTotps provides a mock function with given fields: totps, vaultId
+func (_m *SecretRepository) FindTotps(totps *[]vaults.Totp, vaultId string) error {
+	ret := _m.Called(totps, vaultId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*[]vaults.Totp, string) error); ok {
+		r0 = rf(totps, vaultId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindTrash provides a mock function with given fields: userId, deletedBefore, secrets
+func (_m *SecretRepository) FindTrash(userId string, deletedBefore time.Time, secrets *[]vaults.TrashedSecret) error {
+	ret := _m.Called(userId, deletedBefore, secrets)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, time.Time, *[]vaults.TrashedSecret) error); ok {
+		r0 = rf(userId, deletedBefore, secrets)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindTrashedSecret provides a mock function with given fields: id, userId, s
+func (_m *SecretRepository) FindTrashedSecret(id string, userId string, s *vaults.TrashedSecret) error {
+	ret := _m.Called(id, userId, s)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, *vaults.TrashedSecret) error); ok {
+		r0 = rf(id, userId, s)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ImportSecrets provides a mock function with given fields: vaultId, folders, writes
+func (_m *SecretRepository) ImportSecrets(vaultId string, folders []vaults.Folder, writes []vaults.SecretWrite) error {
+	ret := _m.Called(vaultId, folders, writes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []vaults.Folder, []vaults.SecretWrite) error); ok {
+		r0 = rf(vaultId, folders, writes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkReminded provides a mock function with given fields: secretType, ids, at
+func (_m *SecretRepository) MarkReminded(secretType vaults.SecretType, ids []string, at time.Time) error {
+	ret := _m.Called(secretType, ids, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(vaults.SecretType, []string, time.Time) error); ok {
+		r0 = rf(secretType, ids, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MoveSecret provides a mock function with given fields: secretType, id, vaultId, folderId
+func (_m *SecretRepository) MoveSecret(secretType vaults.SecretType, id string, vaultId string, folderId *string) error {
+	ret := _m.Called(secretType, id, vaultId, folderId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(vaults.SecretType, string, string, *string) error); ok {
+		r0 = rf(secretType, id, vaultId, folderId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MoveSecrets provides a mock function with given fields: refs, vaultId, folderId
+func (_m *SecretRepository) MoveSecrets(refs []vaults.SecretSummary, vaultId string, folderId *string) error {
+	ret := _m.Called(refs, vaultId, folderId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]vaults.SecretSummary, string, *string) error); ok {
+		r0 = rf(refs, vaultId, folderId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeSecret provides a mock function with given fields: secretType, id
+func (_m *SecretRepository) PurgeSecret(secretType vaults.SecretType, id string) error {
+	ret := _m.Called(secretType, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(vaults.SecretType, string) error); ok {
+		r0 = rf(secretType, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreSecret provides a mock function with given fields: secretType, id
+func (_m *SecretRepository) RestoreSecret(secretType vaults.SecretType, id string) error {
+	ret := _m.Called(secretType, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(vaults.SecretType, string) error); ok {
+		r0 = rf(secretType, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreVaults provides a mock function with given fields: restores
+func (_m *SecretRepository) RestoreVaults(restores []vaults.VaultRestore) error {
+	ret := _m.Called(restores)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]vaults.VaultRestore) error); ok {
+		r0 = rf(restores)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TagSecret provides a mock function with given fields: secretType, id, vaultId, tags
+func (_m *SecretRepository) TagSecret(secretType vaults.SecretType, id string, vaultId string, tags []string) error {
+	ret := _m.Called(secretType, id, vaultId, tags)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(vaults.SecretType, string, string, []string) error); ok {
+		r0 = rf(secretType, id, vaultId, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewSecretRepository interface {
 	mock.TestingT
 	Cleanup(func())
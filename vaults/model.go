@@ -1,11 +1,17 @@
 package vaults
 
 import (
+	"strings"
 	"time"
 
+	"github.com/adarsh-a-tw/passwordly/pki"
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
 	"github.com/adarsh-a-tw/passwordly/users"
+	"gorm.io/gorm"
 )
 
+// Vault holds a user's secrets. Deleting a vault or secret only sets
+// DeletedAt, which moves it to the trash until it is restored or purged.
 type Vault struct {
 	Id        string `gorm:"primaryKey"`
 	Name      string `gorm:"notNull"`
@@ -13,6 +19,7 @@ type Vault struct {
 	User      users.User `gorm:"foreignKey:UserRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 type Credential struct {
@@ -21,43 +28,370 @@ type Credential struct {
 	Username   string `json:"username" gorm:"notNull"`
 	Password   []byte `json:"password" gorm:"notNull,type:bytea"`
 	VaultRefer string
-	Vault      Vault     `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Vault      Vault          `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+	Expiry
+	Placement
 }
 
 type Key struct {
 	Id         string `json:"id" gorm:"primaryKey"`
 	Name       string `gorm:"notNull"`
-	Value      string `json:"value" gorm:"notNull"`
+	Value      []byte `json:"value" gorm:"notNull;type:bytea"`
 	VaultRefer string
-	Vault      Vault     `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	Vault      Vault          `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+	Expiry
+	Placement
 }
 
 type Document struct {
 	Id         string `json:"id" gorm:"primaryKey"`
 	Name       string `gorm:"notNull"`
-	Content    string `json:"content" gorm:"notNull"`
+	Content    []byte `json:"content" gorm:"notNull;type:bytea"`
 	VaultRefer string
+	Vault      Vault          `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+	Expiry
+	Placement
+}
+
+// Totp holds a shared 2FA seed. Secret is the encrypted base32 seed; the
+// remaining fields are the parameters needed to derive codes from it.
+type Totp struct {
+	Id          string `gorm:"primaryKey"`
+	Name        string `gorm:"notNull"`
+	Issuer      string
+	AccountName string
+	Secret      []byte `gorm:"notNull,type:bytea"`
+	Algorithm   string `gorm:"notNull"`
+	Digits      int    `gorm:"notNull"`
+	Period      int    `gorm:"notNull"`
+	VaultRefer  string
+	Vault       Vault `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	Expiry
+	Placement
+}
+
+// SshKey stores an encrypted OpenSSH private key. The public key and
+// fingerprint are kept in plaintext so they can be listed without
+// decrypting anything.
+type SshKey struct {
+	Id          string `gorm:"primaryKey"`
+	Name        string `gorm:"notNull"`
+	KeyType     string `gorm:"notNull"`
+	Bits        int
+	PrivateKey  []byte `gorm:"notNull,type:bytea"`
+	PublicKey   string `gorm:"notNull"`
+	Fingerprint string `gorm:"notNull"`
+	Comment     string
+	VaultRefer  string
+	Vault       Vault `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	Expiry
+	Placement
+}
+
+// Card is a payment card. The number and CVV are encrypted; the last four
+// digits are kept in plaintext for masked display.
+type Card struct {
+	Id             string `gorm:"primaryKey"`
+	Name           string `gorm:"notNull"`
+	CardholderName string
+	Number         []byte `gorm:"notNull,type:bytea"`
+	Last4          string `gorm:"notNull"`
+	Brand          string
+	ExpiryMonth    int    `gorm:"notNull"`
+	ExpiryYear     int    `gorm:"notNull"`
+	Cvv            []byte `gorm:"type:bytea"`
+	VaultRefer     string
+	Vault          Vault `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	Expiry
+	Placement
+}
+
+// Identity holds personal details. Address and document numbers are
+// encrypted, with the last four characters of each number kept for masked
+// display.
+type Identity struct {
+	Id             string `gorm:"primaryKey"`
+	Name           string `gorm:"notNull"`
+	FullName       string `gorm:"notNull"`
+	Email          string
+	Phone          string
+	Address        []byte `gorm:"type:bytea"`
+	PassportNumber []byte `gorm:"type:bytea"`
+	PassportLast4  string
+	LicenceNumber  []byte `gorm:"type:bytea"`
+	LicenceLast4   string
+	VaultRefer     string
+	Vault          Vault `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	Expiry
+	Placement
+}
+
+// CertificateAuthority is an internal X.509 CA. The certificate is public
+// and kept in plaintext; the PKCS#8 private key is encrypted. The remaining
+// fields constrain the leaf certificates it issues.
+type CertificateAuthority struct {
+	Id              string `gorm:"primaryKey"`
+	Name            string `gorm:"notNull"`
+	Subject         string `gorm:"notNull"`
+	Certificate     string `gorm:"notNull"`
+	PrivateKey      []byte `gorm:"notNull,type:bytea"`
+	AllowedDomains  string
+	AllowSubdomains bool
+	AllowIpSans     bool
+	MaxTtlSeconds   int `gorm:"notNull"`
+	VaultRefer      string
+	Vault           Vault `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+	Expiry
+	Placement
+}
+
+func (ca CertificateAuthority) Constraints() pki.Constraints {
+	return pki.Constraints{
+		AllowedDomains:  splitList(ca.AllowedDomains),
+		AllowSubdomains: ca.AllowSubdomains,
+		AllowIPs:        ca.AllowIpSans,
+		MaxTTL:          time.Duration(ca.MaxTtlSeconds) * time.Second,
+	}
+}
+
+// IssuedCertificate records every leaf certificate a CA signs so that it can
+// be listed and revoked. Private keys generated for a leaf are never stored.
+type IssuedCertificate struct {
+	Id               string `gorm:"primaryKey"`
+	SerialNumber     string `gorm:"notNull;index"`
+	CommonName       string
+	DnsNames         string
+	IpAddresses      string
+	Certificate      string    `gorm:"notNull"`
+	NotBefore        time.Time `gorm:"notNull"`
+	NotAfter         time.Time `gorm:"notNull"`
+	RevokedAt        *time.Time
+	RevocationReason int
+	CaRefer          string               `gorm:"index"`
+	Ca               CertificateAuthority `gorm:"foreignKey:CaRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt        time.Time
+}
+
+// SshCaRole is a signing policy of a vault's SSH certificate authority. The
+// CA is an SshKey stored in the same vault; list fields are comma separated.
+type SshCaRole struct {
+	Id                     string `gorm:"primaryKey"`
+	Name                   string `gorm:"notNull"`
+	CertType               string `gorm:"notNull"`
+	AllowedPrincipals      string `gorm:"notNull"`
+	DefaultPrincipals      string
+	MaxTtlSeconds          int `gorm:"notNull"`
+	DefaultTtlSeconds      int `gorm:"notNull"`
+	AllowedExtensions      string
+	DefaultExtensions      string
+	AllowedCriticalOptions string
+	CaKeyRefer             string
+	CaKey                  SshKey `gorm:"foreignKey:CaKeyRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	VaultRefer             string
+	Vault                  Vault `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+}
+
+func (r SshCaRole) Policy() sshkeys.Policy {
+	return sshkeys.Policy{
+		CertType:               sshkeys.CertType(r.CertType),
+		AllowedPrincipals:      splitList(r.AllowedPrincipals),
+		DefaultPrincipals:      splitList(r.DefaultPrincipals),
+		MaxTTL:                 time.Duration(r.MaxTtlSeconds) * time.Second,
+		DefaultTTL:             time.Duration(r.DefaultTtlSeconds) * time.Second,
+		AllowedExtensions:      splitList(r.AllowedExtensions),
+		DefaultExtensions:      splitList(r.DefaultExtensions),
+		AllowedCriticalOptions: splitList(r.AllowedCriticalOptions),
+	}
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// CustomField is an extra named attribute of a secret of any type. Hidden
+// values are encrypted; the others are stored as entered.
+type CustomField struct {
+	Id         string    `gorm:"primaryKey"`
+	SecretId   string    `gorm:"notNull;index"`
+	Name       string    `gorm:"notNull"`
+	Type       FieldType `gorm:"notNull"`
+	Value      []byte    `gorm:"type:bytea"`
+	Position   int       `gorm:"notNull"`
+	VaultRefer string    `gorm:"index"`
 	Vault      Vault     `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Folder groups secrets within a vault. Folders nest through ParentId; a
+// nil ParentId is the vault's top level.
+type Folder struct {
+	Id         string  `gorm:"primaryKey"`
+	Name       string  `gorm:"notNull"`
+	ParentId   *string `gorm:"index"`
+	VaultRefer string  `gorm:"index"`
+	Vault      Vault   `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Attachment is a file stored encrypted in the blob store under StorageKey.
+// Each file has its own data key, kept here encrypted.
+type Attachment struct {
+	Id          string `gorm:"primaryKey"`
+	SecretId    string `gorm:"notNull;index"`
+	Name        string `gorm:"notNull"`
+	ContentType string
+	Size        int64  `gorm:"notNull"`
+	StorageKey  string `gorm:"notNull"`
+	DataKey     []byte `gorm:"type:bytea;notNull"`
+	VaultRefer  string `gorm:"index"`
+	Vault       Vault  `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt   time.Time
+}
+
+// SearchEntry records that a secret contains a search term. Token is the
+// blind index of the term, so the index never holds searchable plaintext.
+// Weight ranks where the term was found, such as the name over a tag.
+type SearchEntry struct {
+	SecretId   string     `gorm:"primaryKey"`
+	Token      string     `gorm:"primaryKey;index:idx_search_user_token,priority:2"`
+	SecretType SecretType `gorm:"notNull"`
+	Weight     int        `gorm:"notNull"`
+	UserRefer  string     `gorm:"notNull;index:idx_search_user_token,priority:1"`
+	VaultRefer string     `gorm:"index"`
+	Vault      Vault      `gorm:"foreignKey:VaultRefer;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// Expiry tracks the real-world lifetime of a secret and is embedded by every
+// secret type. Rotation is due RotateEveryDays after the secret was last
+// updated.
+type Expiry struct {
+	ExpiresAt       *time.Time
+	RotateEveryDays int
+	LastRemindedAt  *time.Time
+}
+
+func (e Expiry) RotationDueAt(updatedAt time.Time) *time.Time {
+	if e.RotateEveryDays <= 0 {
+		return nil
+	}
+	due := updatedAt.AddDate(0, 0, e.RotateEveryDays)
+	return &due
+}
+
+// Placement is where a secret sits in its vault: an optional folder and
+// free-form tags, stored comma-separated. It is embedded by every secret
+// type.
+type Placement struct {
+	FolderId *string `gorm:"index"`
+	Tags     string
+}
+
+// SecretMetadata holds the fields every secret type shares.
+type SecretMetadata struct {
+	Id        string
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Expiry    Expiry
+	Placement Placement
 }
 
 type Securable interface {
 	Type() SecretType
+	Metadata() SecretMetadata
 }
 
 func (Credential) Type() SecretType {
 	return TypeCredential
 }
 
+func (c Credential) Metadata() SecretMetadata {
+	return SecretMetadata{Id: c.Id, Name: c.Name, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt, Expiry: c.Expiry, Placement: c.Placement}
+}
+
 func (Key) Type() SecretType {
 	return TypeKey
 }
 
+func (k Key) Metadata() SecretMetadata {
+	return SecretMetadata{Id: k.Id, Name: k.Name, CreatedAt: k.CreatedAt, UpdatedAt: k.UpdatedAt, Expiry: k.Expiry, Placement: k.Placement}
+}
+
 func (Document) Type() SecretType {
 	return TypeDocument
 }
+
+func (d Document) Metadata() SecretMetadata {
+	return SecretMetadata{Id: d.Id, Name: d.Name, CreatedAt: d.CreatedAt, UpdatedAt: d.UpdatedAt, Expiry: d.Expiry, Placement: d.Placement}
+}
+
+func (Totp) Type() SecretType {
+	return TypeTotp
+}
+
+func (t Totp) Metadata() SecretMetadata {
+	return SecretMetadata{Id: t.Id, Name: t.Name, CreatedAt: t.CreatedAt, UpdatedAt: t.UpdatedAt, Expiry: t.Expiry, Placement: t.Placement}
+}
+
+func (SshKey) Type() SecretType {
+	return TypeSshKey
+}
+
+func (k SshKey) Metadata() SecretMetadata {
+	return SecretMetadata{Id: k.Id, Name: k.Name, CreatedAt: k.CreatedAt, UpdatedAt: k.UpdatedAt, Expiry: k.Expiry, Placement: k.Placement}
+}
+
+func (CertificateAuthority) Type() SecretType {
+	return TypeCertificateAuthority
+}
+
+func (ca CertificateAuthority) Metadata() SecretMetadata {
+	return SecretMetadata{Id: ca.Id, Name: ca.Name, CreatedAt: ca.CreatedAt, UpdatedAt: ca.UpdatedAt, Expiry: ca.Expiry, Placement: ca.Placement}
+}
+
+func (Card) Type() SecretType {
+	return TypeCard
+}
+
+func (c Card) Metadata() SecretMetadata {
+	return SecretMetadata{Id: c.Id, Name: c.Name, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt, Expiry: c.Expiry, Placement: c.Placement}
+}
+
+func (Identity) Type() SecretType {
+	return TypeIdentity
+}
+
+func (i Identity) Metadata() SecretMetadata {
+	return SecretMetadata{Id: i.Id, Name: i.Name, CreatedAt: i.CreatedAt, UpdatedAt: i.UpdatedAt, Expiry: i.Expiry, Placement: i.Placement}
+}
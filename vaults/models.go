@@ -0,0 +1,29 @@
+package vaults
+
+import (
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/users"
+)
+
+// Models are the tables of the server, each after those it refers to.
+var Models = []any{
+	&users.User{},
+	&users.PasswordHistory{},
+	&Vault{},
+	&Credential{},
+	&Key{},
+	&Document{},
+	&Totp{},
+	&SshKey{},
+	&SshCaRole{},
+	&CertificateAuthority{},
+	&IssuedCertificate{},
+	&Card{},
+	&Identity{},
+	&CustomField{},
+	&Attachment{},
+	&Folder{},
+	&SearchEntry{},
+	&audit.Event{},
+	&audit.Head{},
+}
@@ -0,0 +1,165 @@
+package vaults
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Page sizes used when PAGE_SIZE_DEFAULT and PAGE_SIZE_MAX are not set.
+const (
+	fallbackPageSize    = 50
+	fallbackMaxPageSize = 200
+)
+
+const (
+	SortName    = "name"
+	SortCreated = "created"
+	SortUpdated = "updated"
+)
+
+var sortColumns = map[string]string{
+	SortName:    "name",
+	SortCreated: "created_at",
+	SortUpdated: "updated_at",
+}
+
+var errInvalidCursor = errors.New("Cursor is invalid or does not match the requested sort order")
+
+// PageQuery selects one page of a listing ordered by a sort column with id
+// as the tie-breaker. Pages are found by keyset rather than offset: the
+// cursor holds the sort key and id of the last row already returned.
+type PageQuery struct {
+	Sort       string
+	Desc       bool
+	After      *Cursor
+	Limit      int
+	NamePrefix string
+}
+
+// SecretFilter narrows a listing of secrets. A non-nil FolderIds limits it
+// to those folders; an empty Type allows every type.
+type SecretFilter struct {
+	Type      SecretType
+	FolderIds []string
+	Tag       string
+}
+
+// Cursor marks the last row of a page. Sort and Desc are kept so that a
+// cursor cannot be replayed against a different ordering.
+type Cursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d,omitempty"`
+	Key  string `json:"k"`
+	Id   string `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Id == "" {
+		return nil, errInvalidCursor
+	}
+	return &c, nil
+}
+
+// newPageQuery validates the listing parameters of a request. Dates sort
+// newest first and names alphabetically unless an order is given.
+func newPageQuery(lr ListRequest) (PageQuery, error) {
+	pageSize, maxPageSize := common.Cfg.PageSizeDefault, common.Cfg.PageSizeMax
+	if maxPageSize == 0 {
+		pageSize, maxPageSize = fallbackPageSize, fallbackMaxPageSize
+	}
+	if lr.PageSize > maxPageSize {
+		return PageQuery{}, fmt.Errorf("page_size cannot be more than %d", maxPageSize)
+	}
+	if lr.PageSize != 0 {
+		pageSize = lr.PageSize
+	}
+
+	pq := PageQuery{Sort: lr.Sort, Limit: pageSize, NamePrefix: lr.Name}
+	if pq.Sort == "" {
+		pq.Sort = SortUpdated
+	}
+	pq.Desc = lr.Order == "desc" || (lr.Order == "" && pq.Sort != SortName)
+
+	if lr.Cursor != "" {
+		c, err := decodeCursor(lr.Cursor)
+		if err != nil || c.Sort != pq.Sort || c.Desc != pq.Desc {
+			return PageQuery{}, errInvalidCursor
+		}
+		if pq.Sort != SortName {
+			if _, err := time.Parse(time.RFC3339Nano, c.Key); err != nil {
+				return PageQuery{}, errInvalidCursor
+			}
+		}
+		pq.After = c
+	}
+	return pq, nil
+}
+
+// cursorAt builds the cursor continuing after a row with the given values.
+func (pq PageQuery) cursorAt(id string, name string, createdAt time.Time, updatedAt time.Time) Cursor {
+	c := Cursor{Sort: pq.Sort, Desc: pq.Desc, Id: id, Key: name}
+	switch pq.Sort {
+	case SortCreated:
+		c.Key = createdAt.Format(time.RFC3339Nano)
+	case SortUpdated:
+		c.Key = updatedAt.Format(time.RFC3339Nano)
+	}
+	return c
+}
+
+// scope filters by name prefix, skips to the cursor and orders the rows.
+// One row more than the limit is fetched to tell whether a next page exists.
+func (pq PageQuery) scope(db *gorm.DB) *gorm.DB {
+	column := sortColumns[pq.Sort]
+	direction, comparison := "ASC", ">"
+	if pq.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if pq.NamePrefix != "" {
+		db = db.Where(`LOWER(name) LIKE ? ESCAPE '\'`, escapeLike(strings.ToLower(pq.NamePrefix))+"%")
+	}
+	if pq.After != nil {
+		var key any = pq.After.Key
+		if pq.Sort != SortName {
+			key, _ = time.Parse(time.RFC3339Nano, pq.After.Key)
+		}
+		db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, comparison), key, key, pq.After.Id)
+	}
+	return db.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).Limit(pq.Limit + 1)
+}
+
+// setNextLink advertises the next page in a Link header, keeping the other
+// query parameters of the request.
+func setNextLink(ctx *gin.Context, cursor string) {
+	if cursor == "" {
+		return
+	}
+	query := ctx.Request.URL.Query()
+	query.Set("cursor", cursor)
+	next := url.URL{Path: ctx.Request.URL.Path, RawQuery: query.Encode()}
+	ctx.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,169 @@
+package vaults_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/users"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultHandler_FetchVaults_ShouldPageThroughVaultsByCursor(t *testing.T) {
+	vh := prepareListing(t)
+
+	var ids []string
+	query := "page_size=2"
+	for page := 0; page < 5; page++ {
+		resp, code := fetchVaultsPage(t, vh, query)
+		assert.Equal(t, http.StatusOK, code)
+		for _, v := range resp.Vaults {
+			ids = append(ids, v.Id)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		query = "page_size=2&cursor=" + resp.NextCursor
+	}
+
+	assert.Equal(t, []string{"vault_5", "vault_4", "vault_3", "vault_2", "vault_1"}, ids)
+}
+
+func TestVaultHandler_FetchVaults_ShouldSortAndFilterByName(t *testing.T) {
+	vh := prepareListing(t)
+
+	resp, _ := fetchVaultsPage(t, vh, "sort=name&name=team")
+
+	assert.Len(t, resp.Vaults, 2)
+	assert.Equal(t, "Team Alpha", resp.Vaults[0].Name)
+	assert.Equal(t, "Team Beta", resp.Vaults[1].Name)
+	assert.Empty(t, resp.NextCursor)
+}
+
+func TestVaultHandler_FetchVaults_ShouldRejectInvalidPaging(t *testing.T) {
+	vh := prepareListing(t)
+
+	first, _ := fetchVaultsPage(t, vh, "page_size=1&sort=name")
+	for _, query := range []string{
+		"page_size=500",
+		"cursor=not-a-cursor",
+		"page_size=1&cursor=" + first.NextCursor,
+		"sort=size",
+	} {
+		_, code := fetchVaultsPage(t, vh, query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestVaultHandler_FetchVaultDetails_ShouldPageAcrossSecretTypes(t *testing.T) {
+	vh := prepareListing(t)
+
+	ctx, rec := prepareDetailsRequest(t, "page_size=2")
+	vh.FetchVaultDetails(ctx)
+
+	var resp vaults.VaultResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+	assert.Equal(t, []string{"card", "db"}, secretIds(resp))
+	assert.Equal(t, "password", resp.Secrets[1].Password)
+	assert.Contains(t, rec.Header().Get("Link"), "cursor="+resp.NextCursor)
+
+	ctx, rec = prepareDetailsRequest(t, "page_size=2&cursor="+resp.NextCursor)
+	vh.FetchVaultDetails(ctx)
+
+	resp = vaults.VaultResponse{}
+	common.DecodeJSONResponse(t, rec, &resp)
+	assert.Equal(t, []string{"totp", "vpn"}, secretIds(resp))
+	assert.NotEmpty(t, resp.NextCursor)
+}
+
+func TestVaultHandler_FetchVaultDetails_ShouldFilterByTypeFolderAndTag(t *testing.T) {
+	vh := prepareListing(t)
+
+	for query, expected := range map[string][]string{
+		"type=CREDENTIAL&sort=name":                     {"db", "mail", "staging", "vpn"},
+		"folder=work&sort=name":                         {"vpn"},
+		"folder=work&recursive=true&sort=name":          {"db", "staging", "vpn"},
+		"folder=work&recursive=true&tag=PROD&sort=name": {"db", "vpn"},
+		"tag=aws": {"db"},
+	} {
+		ctx, rec := prepareDetailsRequest(t, query)
+		vh.FetchVaultDetails(ctx)
+
+		var resp vaults.VaultResponse
+		common.DecodeJSONResponse(t, rec, &resp)
+		assert.Equal(t, http.StatusOK, rec.Code, query)
+		assert.Equal(t, expected, secretIds(resp), query)
+	}
+}
+
+// prepareListing stores five vaults for user_1, the first holding secrets
+// of several types, some of them in folders and tagged.
+func prepareListing(t *testing.T) *vaults.VaultHandler {
+	db := prepareDb(t)
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	work, servers := "work", "servers"
+	records := []any{
+		&vaults.Folder{Id: work, Name: "Work", VaultRefer: "vault_1"},
+		&vaults.Folder{Id: servers, Name: "Servers", ParentId: &work, VaultRefer: "vault_1"},
+		&vaults.Credential{Id: "db", Name: "Database", Username: "admin", Password: []byte("sealed:password"), VaultRefer: "vault_1", UpdatedAt: at(50),
+			Placement: vaults.Placement{FolderId: &servers, Tags: "prod,aws"}},
+		&vaults.Credential{Id: "vpn", Name: "VPN", Username: "me", Password: []byte("sealed:password"), VaultRefer: "vault_1", UpdatedAt: at(30),
+			Placement: vaults.Placement{FolderId: &work, Tags: "prod"}},
+		&vaults.Credential{Id: "mail", Name: "Mail", Username: "me", Password: []byte("sealed:password"), VaultRefer: "vault_1", UpdatedAt: at(10),
+			Placement: vaults.Placement{Tags: "prod"}},
+		&vaults.Credential{Id: "staging", Name: "Staging DB", Username: "admin", Password: []byte("sealed:password"), VaultRefer: "vault_1", UpdatedAt: at(20),
+			Placement: vaults.Placement{FolderId: &servers}},
+		&vaults.Totp{Id: "totp", Name: "Shared 2FA", Secret: []byte("x"), Algorithm: "SHA1", Digits: 6, Period: 30, VaultRefer: "vault_1", UpdatedAt: at(40)},
+		&vaults.Card{Id: "card", Name: "Company card", Number: []byte("x"), Last4: "4242", ExpiryMonth: 1, ExpiryYear: 2030, VaultRefer: "vault_1", UpdatedAt: at(60)},
+	}
+	for i, name := range []string{"Personal", "Team Beta", "Family", "Team Alpha", "Archive"} {
+		records = append(records, &vaults.Vault{Id: fmt.Sprintf("vault_%d", i+1), Name: name, UserRefer: "user_1", UpdatedAt: at(i)})
+	}
+	for _, record := range records {
+		assert.NoError(t, db.Create(record).Error)
+	}
+
+	return &vaults.VaultHandler{
+		Ep:         sealingProvider(),
+		Repo:       &vaults.VaultRepositoryImpl{Db: db},
+		UserRepo:   &users.UserRepositoryImpl{Db: db},
+		SecretRepo: &vaults.SecretRepositoryImpl{Db: db},
+		Fields:     &vaults.CustomFieldRepositoryImpl{Db: db},
+		Folders:    &vaults.FolderRepositoryImpl{Db: db},
+	}
+}
+
+func fetchVaultsPage(t *testing.T, vh *vaults.VaultHandler, query string) (vaults.VaultListResponse, int) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/vaults?"+query, "GET", nil)
+	ctx.Set("user_id", "user_1")
+	vh.FetchVaults(ctx)
+
+	var resp vaults.VaultListResponse
+	if rec.Code == http.StatusOK {
+		common.DecodeJSONResponse(t, rec, &resp)
+	}
+	return resp, rec.Code
+}
+
+func prepareDetailsRequest(t *testing.T, query string) (*gin.Context, *httptest.ResponseRecorder) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/vaults/vault_1?"+query, "GET", nil)
+	ctx.Set("user_id", "user_1")
+	ctx.AddParam("id", "vault_1")
+	return ctx, rec
+}
+
+func secretIds(resp vaults.VaultResponse) []string {
+	ids := make([]string, len(resp.Secrets))
+	for i, s := range resp.Secrets {
+		ids[i] = s.Id
+	}
+	return ids
+}
@@ -0,0 +1,255 @@
+package vaults
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/pki"
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PkiHandler issues and revokes leaf certificates from the
+// CertificateAuthority secrets of a vault.
+type PkiHandler struct {
+	Ep         utils.EncryptionProvider
+	Repo       PkiRepository
+	SecretRepo SecretRepository
+	VaultRepo  VaultRepository
+	Audit      audit.Recorder
+}
+
+func (ph *PkiHandler) IssueCertificate(ctx *gin.Context) {
+	var icr IssueCertificateRequest
+	if err := ctx.ShouldBindJSON(&icr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	record, ca, ok := ph.loadCA(ctx)
+	if !ok {
+		return
+	}
+
+	var req pki.LeafRequest
+	var privateKey []byte
+	if icr.Csr != "" {
+		var err error
+		if req, err = pki.ParseCSR([]byte(icr.Csr)); err != nil {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+			return
+		}
+	} else {
+		keyType := sshkeys.KeyType(strings.ToUpper(icr.KeyType))
+		if keyType == "" {
+			keyType = sshkeys.KeyTypeECDSA
+		}
+		key, err := sshkeys.Generate(keyType, icr.Bits)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+			return
+		}
+		if privateKey, err = pki.EncodePrivateKey(key); err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		req.PublicKey = key.Public()
+	}
+	if icr.hasNames() {
+		req.CommonName = icr.CommonName
+		req.DNSNames = icr.DnsNames
+		req.IPAddresses = icr.ips()
+	}
+	req.TTL = time.Duration(icr.TtlSeconds) * time.Second
+
+	cert, err := ca.Issue(req, record.Constraints(), time.Now())
+	if err != nil {
+		if errors.Is(err, pki.ErrNotPermitted) || errors.Is(err, pki.ErrInvalidCSR) {
+			audit.Log(ph.Audit, ctx, audit.Event{
+				Action:     audit.ActionCertIssue,
+				TargetType: audit.TargetSecret,
+				TargetId:   record.Id,
+				Detail:     err.Error(),
+				Outcome:    audit.OutcomeFailure,
+			})
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	ips := make([]string, len(cert.IPAddresses))
+	for i, ip := range cert.IPAddresses {
+		ips[i] = ip.String()
+	}
+	issued := IssuedCertificate{
+		Id:           uuid.NewString(),
+		SerialNumber: cert.SerialNumber.Text(16),
+		CommonName:   cert.Subject.CommonName,
+		DnsNames:     strings.Join(cert.DNSNames, ","),
+		IpAddresses:  strings.Join(ips, ","),
+		Certificate:  string(pki.EncodeCertificate(cert)),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		CaRefer:      record.Id,
+	}
+	if err := ph.Repo.CreateIssued(&issued); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	names := append(append([]string{}, cert.DNSNames...), ips...)
+	audit.Log(ph.Audit, ctx, audit.Event{
+		Action:     audit.ActionCertIssue,
+		TargetType: audit.TargetCertificate,
+		TargetId:   issued.Id,
+		Detail: fmt.Sprintf(
+			"ca=%s serial=%s names=%s not_after=%s",
+			record.Id, issued.SerialNumber, strings.Join(names, ","), cert.NotAfter.UTC().Format(time.RFC3339),
+		),
+	})
+
+	var resp IssuedCertificateResponse
+	resp.load(issued, time.Now())
+	resp.IssuingCa = record.Certificate
+	resp.PrivateKey = string(privateKey)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+func (ph *PkiHandler) FetchCertificates(ctx *gin.Context) {
+	record, ok := ph.findCA(ctx)
+	if !ok {
+		return
+	}
+
+	var certs []IssuedCertificate
+	if err := ph.Repo.FindIssued(record.Id, &certs); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	now := time.Now()
+	resp := IssuedCertificateListResponse{Certificates: make([]IssuedCertificateResponse, len(certs))}
+	for i, c := range certs {
+		resp.Certificates[i].load(c, now)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (ph *PkiHandler) RevokeCertificate(ctx *gin.Context) {
+	var rcr RevokeCertificateRequest
+	if err := ctx.ShouldBindJSON(&rcr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	record, ok := ph.findCA(ctx)
+	if !ok {
+		return
+	}
+
+	var c IssuedCertificate
+	if err := ph.Repo.FindIssuedById(ctx.Param("certId"), record.Id, &c); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+	if c.RevokedAt != nil {
+		ctx.JSON(http.StatusConflict, common.ErrorResponse{Message: "Certificate is already revoked"})
+		return
+	}
+
+	now := time.Now().UTC()
+	c.RevokedAt = &now
+	c.RevocationReason = rcr.Reason
+	if err := ph.Repo.Revoke(&c); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(ph.Audit, ctx, audit.Event{
+		Action:     audit.ActionCertRevoke,
+		TargetType: audit.TargetCertificate,
+		TargetId:   c.Id,
+		Detail:     fmt.Sprintf("ca=%s serial=%s reason=%d", record.Id, c.SerialNumber, c.RevocationReason),
+	})
+
+	var resp IssuedCertificateResponse
+	resp.load(c, now)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// FetchCRL returns a freshly signed PEM revocation list for the CA.
+func (ph *PkiHandler) FetchCRL(ctx *gin.Context) {
+	record, ca, ok := ph.loadCA(ctx)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	var certs []IssuedCertificate
+	if err := ph.Repo.FindRevoked(record.Id, now, &certs); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	revoked := make([]pki.Revocation, 0, len(certs))
+	for _, c := range certs {
+		serial, ok := new(big.Int).SetString(c.SerialNumber, 16)
+		if !ok {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		revoked = append(revoked, pki.Revocation{Serial: serial, RevokedAt: *c.RevokedAt, Reason: c.RevocationReason})
+	}
+
+	crl, err := ca.CRL(revoked, now)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	ctx.Data(http.StatusOK, "application/x-pem-file", crl)
+}
+
+// private methods
+
+func (ph *PkiHandler) findCA(ctx *gin.Context) (*CertificateAuthority, bool) {
+	vaultId, ok := requireVaultOwner(ctx, ph.VaultRepo)
+	if !ok {
+		return nil, false
+	}
+
+	var record CertificateAuthority
+	if err := ph.SecretRepo.FindCertificateAuthorityById(ctx.Param("secretId"), vaultId, &record); err != nil {
+		handleGormError(ctx, err)
+		return nil, false
+	}
+	return &record, true
+}
+
+func (ph *PkiHandler) loadCA(ctx *gin.Context) (*CertificateAuthority, *pki.CA, bool) {
+	record, ok := ph.findCA(ctx)
+	if !ok {
+		return nil, nil, false
+	}
+
+	keyPEM, err := ph.Ep.Decrypt(string(record.PrivateKey))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return nil, nil, false
+	}
+	ca, err := pki.LoadCA([]byte(record.Certificate), []byte(keyPEM))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return nil, nil, false
+	}
+	return record, ca, true
+}
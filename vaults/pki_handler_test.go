@@ -0,0 +1,190 @@
+package vaults_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	audit_mocks "github.com/adarsh-a-tw/passwordly/audit/mocks"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/pki"
+	utils_mocks "github.com/adarsh-a-tw/passwordly/utils/mocks"
+	v "github.com/adarsh-a-tw/passwordly/vaults"
+	vm "github.com/adarsh-a-tw/passwordly/vaults/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSecretHandler_CreateSecret_ShouldCreateCertificateAuthority(t *testing.T) {
+	csr := v.CreateSecretRequest{
+		Name:           "Internal CA",
+		Type:           v.TypeCertificateAuthority,
+		CommonName:     "ACME Internal Root",
+		Organization:   "ACME",
+		TtlDays:        365,
+		AllowedDomains: []string{"internal.acme.com"},
+	}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/secrets", mockVault.Id), "POST", csr)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+
+	msr := vm.SecretRepository{}
+	msr.On("CreateCertificateAuthority", mock.MatchedBy(func(ca *v.CertificateAuthority) bool {
+		return string(ca.PrivateKey) == "ENCRYPTED_CA" && ca.AllowedDomains == "internal.acme.com" &&
+			ca.MaxTtlSeconds == int(pki.DefaultMaxTTL/time.Second) && ca.ExpiresAt != nil
+	}), mock.AnythingOfType("[]vaults.CustomField")).Return(nil)
+	mvr, mur := ownedVaultMocks()
+
+	ep := utils_mocks.NewEncryptionProvider(t)
+	ep.On("Encrypt", mock.AnythingOfType("string")).Return("ENCRYPTED_CA", nil)
+
+	h := v.SecretHandler{
+		Ep:        ep,
+		Repo:      &msr,
+		VaultRepo: mvr,
+		UserRepo:  mur,
+	}
+
+	h.CreateSecret(ctx)
+
+	var resp v.SecretResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "CN=ACME Internal Root,O=ACME", resp.Subject)
+	assert.InDelta(t, time.Now().AddDate(0, 0, 365).Unix(), resp.ExpiresAt, 60)
+	assert.NotContains(t, rec.Body.String(), "PRIVATE KEY")
+
+	cert, err := pki.ParseCertificate([]byte(resp.Certificate))
+	assert.NoError(t, err)
+	assert.True(t, cert.IsCA)
+}
+
+func TestPkiHandler_IssueCertificate_ShouldIssueWithGeneratedKey(t *testing.T) {
+	h, ctx, rec, ca := preparePkiRequest(t, "POST", "/certificates", v.IssueCertificateRequest{
+		CommonName: "api.internal.acme.com",
+		TtlSeconds: 3600,
+	})
+	h.Repo.(*vm.PkiRepository).On("CreateIssued", mock.AnythingOfType("*vaults.IssuedCertificate")).Return(nil)
+
+	h.IssueCertificate(ctx)
+
+	var resp v.IssuedCertificateResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, []string{"api.internal.acme.com"}, resp.DnsNames)
+	assert.Equal(t, 1, resp.DaysRemaining)
+
+	leaf, err := pki.ParseCertificate([]byte(resp.Certificate))
+	assert.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate)
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: "api.internal.acme.com", Roots: roots})
+	assert.NoError(t, err)
+
+	key, err := pki.ParsePrivateKey([]byte(resp.PrivateKey))
+	assert.NoError(t, err)
+	assert.True(t, key.Public().(*ecdsa.PublicKey).Equal(leaf.PublicKey))
+	assert.Equal(t, leaf.SerialNumber.Text(16), resp.SerialNumber)
+}
+
+func TestPkiHandler_IssueCertificate_ShouldRejectNamesOutsideAllowedDomains(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: []string{"evil.example.com"}}, key)
+	csr := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+
+	h, ctx, rec, _ := preparePkiRequest(t, "POST", "/certificates", v.IssueCertificateRequest{Csr: string(csr)})
+
+	h.IssueCertificate(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	h.Repo.(*vm.PkiRepository).AssertNotCalled(t, "CreateIssued", mock.Anything)
+	h.Audit.(*audit_mocks.EventRepository).AssertCalled(t, "Record", mock.MatchedBy(func(e *audit.Event) bool {
+		return e.Action == audit.ActionCertIssue && e.Outcome == audit.OutcomeFailure
+	}))
+}
+
+func TestPkiHandler_FetchCRL_ShouldListRevokedCertificates(t *testing.T) {
+	h, ctx, rec, ca := preparePkiRequest(t, "GET", "/crl", nil)
+	revokedAt := time.Now().Add(-time.Hour)
+	h.Repo.(*vm.PkiRepository).On("FindRevoked", "mock-ca", mock.AnythingOfType("time.Time"), mock.AnythingOfType("*[]vaults.IssuedCertificate")).Run(func(args mock.Arguments) {
+		certs := args.Get(2).(*[]v.IssuedCertificate)
+		*certs = []v.IssuedCertificate{{Id: "revoked", SerialNumber: "2a", RevokedAt: &revokedAt}}
+	}).Return(nil)
+
+	h.FetchCRL(ctx)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	block, _ := pem.Decode(rec.Body.Bytes())
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	assert.NoError(t, err)
+	assert.NoError(t, crl.CheckSignatureFrom(ca.Certificate))
+	assert.Len(t, crl.RevokedCertificates, 1)
+	assert.Equal(t, int64(42), crl.RevokedCertificates[0].SerialNumber.Int64())
+}
+
+func TestPkiHandler_RevokeCertificate_ShouldRefuseRevokingTwice(t *testing.T) {
+	h, ctx, rec, _ := preparePkiRequest(t, "POST", "/certificates/issued/revoke", v.RevokeCertificateRequest{Reason: 1})
+	ctx.AddParam("certId", "issued")
+	revokedAt := time.Now()
+	h.Repo.(*vm.PkiRepository).On("FindIssuedById", "issued", "mock-ca", mock.AnythingOfType("*vaults.IssuedCertificate")).Run(func(args mock.Arguments) {
+		c := args.Get(2).(*v.IssuedCertificate)
+		c.Id = "issued"
+		c.RevokedAt = &revokedAt
+	}).Return(nil)
+
+	h.RevokeCertificate(ctx)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	h.Repo.(*vm.PkiRepository).AssertNotCalled(t, "Revoke", mock.Anything)
+}
+
+// preparePkiRequest wires a handler around a freshly generated CA stored as
+// the "mock-ca" secret, restricted to subdomains of internal.acme.com.
+func preparePkiRequest(t *testing.T, method string, path string, body any) (*v.PkiHandler, *gin.Context, *httptest.ResponseRecorder, *pki.CA) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca, _ := pki.NewCA(key, pki.Subject{CommonName: "ACME Internal Root"}, 0, time.Now())
+	keyPEM, _ := pki.EncodePrivateKey(key)
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/secrets/mock-ca%s", mockVault.Id, path), method, body)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+	ctx.AddParam("secretId", "mock-ca")
+
+	msr := vm.SecretRepository{}
+	msr.On("FindCertificateAuthorityById", "mock-ca", mockVault.Id, mock.AnythingOfType("*vaults.CertificateAuthority")).Run(func(args mock.Arguments) {
+		record := args.Get(2).(*v.CertificateAuthority)
+		record.Id = "mock-ca"
+		record.Certificate = string(pki.EncodeCertificate(ca.Certificate))
+		record.PrivateKey = []byte("ENCRYPTED_CA")
+		record.AllowedDomains = "internal.acme.com"
+		record.AllowSubdomains = true
+		record.MaxTtlSeconds = 86400
+	}).Return(nil)
+	mvr, _ := ownedVaultMocks()
+
+	ep := &utils_mocks.EncryptionProvider{}
+	ep.On("Decrypt", "ENCRYPTED_CA").Return(string(keyPEM), nil)
+	recorder := &audit_mocks.EventRepository{}
+	recorder.On("Record", mock.AnythingOfType("*audit.Event")).Return(nil)
+
+	h := &v.PkiHandler{
+		Ep:         ep,
+		Repo:       &vm.PkiRepository{},
+		SecretRepo: &msr,
+		VaultRepo:  mvr,
+		Audit:      recorder,
+	}
+	return h, ctx, rec, ca
+}
@@ -0,0 +1,41 @@
+package vaults
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PkiRepository interface {
+	CreateIssued(c *IssuedCertificate) error
+	FindIssued(caId string, certs *[]IssuedCertificate) error
+	FindIssuedById(id string, caId string, c *IssuedCertificate) error
+	FindRevoked(caId string, now time.Time, certs *[]IssuedCertificate) error
+	Revoke(c *IssuedCertificate) error
+}
+
+type PkiRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func (pr *PkiRepositoryImpl) CreateIssued(c *IssuedCertificate) error {
+	return pr.Db.Create(c).Error
+}
+
+func (pr *PkiRepositoryImpl) FindIssued(caId string, certs *[]IssuedCertificate) error {
+	return pr.Db.Where("ca_refer = ?", caId).Order("not_after ASC, id ASC").Find(certs).Error
+}
+
+func (pr *PkiRepositoryImpl) FindIssuedById(id string, caId string, c *IssuedCertificate) error {
+	return pr.Db.Where("id = ? AND ca_refer = ?", id, caId).First(c).Error
+}
+
+// FindRevoked returns the revoked certificates that have not expired yet;
+// expired ones no longer need to appear on the revocation list.
+func (pr *PkiRepositoryImpl) FindRevoked(caId string, now time.Time, certs *[]IssuedCertificate) error {
+	return pr.Db.Where("ca_refer = ? AND revoked_at IS NOT NULL AND not_after > ?", caId, now).Order("revoked_at ASC").Find(certs).Error
+}
+
+func (pr *PkiRepositoryImpl) Revoke(c *IssuedCertificate) error {
+	return pr.Db.Model(c).Updates(map[string]any{"revoked_at": c.RevokedAt, "revocation_reason": c.RevocationReason}).Error
+}
@@ -0,0 +1,159 @@
+package vaults
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/notify"
+)
+
+// ScheduledSecret is a secret of any type that has an expiry or rotation
+// period, together with where it lives and who owns it.
+type ScheduledSecret struct {
+	Id        string
+	Name      string
+	Type      SecretType
+	VaultId   string
+	VaultName string
+	UserId    string
+	UserEmail string
+	UpdatedAt time.Time
+	Expiry
+}
+
+type ReminderKind string
+
+const (
+	ReminderExpiry   ReminderKind = "EXPIRY"
+	ReminderRotation ReminderKind = "ROTATION"
+)
+
+type Reminder struct {
+	Secret  ScheduledSecret
+	Kind    ReminderKind
+	DueAt   time.Time
+	Overdue bool
+}
+
+// upcomingReminders returns the expiries and rotations due before
+// now+within, overdue ones included, soonest first.
+func upcomingReminders(secrets []ScheduledSecret, now time.Time, within time.Duration) []Reminder {
+	reminders := []Reminder{}
+	add := func(s ScheduledSecret, kind ReminderKind, dueAt *time.Time) {
+		if dueAt != nil && dueAt.Before(now.Add(within)) {
+			reminders = append(reminders, Reminder{Secret: s, Kind: kind, DueAt: *dueAt, Overdue: !dueAt.After(now)})
+		}
+	}
+	for _, s := range secrets {
+		add(s, ReminderExpiry, s.ExpiresAt)
+		add(s, ReminderRotation, s.RotationDueAt(s.UpdatedAt))
+	}
+	sort.SliceStable(reminders, func(i, j int) bool {
+		return reminders[i].DueAt.Before(reminders[j].DueAt)
+	})
+	return reminders
+}
+
+// remindEvery stops the scheduler from repeating a reminder on every tick.
+const remindEvery = 24 * time.Hour
+
+// ReminderScheduler periodically notifies owners of secrets that are about
+// to expire or are due for rotation, one notification per user per run.
+type ReminderScheduler struct {
+	Repo     SecretRepository
+	Notifier notify.Notifier
+	Interval time.Duration
+	Window   time.Duration
+}
+
+func (rs *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(rs.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := rs.RunOnce(time.Now()); err != nil {
+			log.Println("Secret reminders failed:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rs *ReminderScheduler) RunOnce(now time.Time) error {
+	var secrets []ScheduledSecret
+	if err := rs.Repo.FindScheduled("", &secrets); err != nil {
+		return err
+	}
+
+	byUser := map[string][]Reminder{}
+	var userIds []string
+	for _, r := range upcomingReminders(secrets, now, rs.Window) {
+		if last := r.Secret.LastRemindedAt; last != nil && now.Sub(*last) < remindEvery {
+			continue
+		}
+		if _, ok := byUser[r.Secret.UserId]; !ok {
+			userIds = append(userIds, r.Secret.UserId)
+		}
+		byUser[r.Secret.UserId] = append(byUser[r.Secret.UserId], r)
+	}
+
+	for _, userId := range userIds {
+		reminders := byUser[userId]
+		if err := rs.Notifier.Notify(reminderNotification(reminders, now)); err != nil {
+			// Leave the secrets unmarked so the next run retries.
+			log.Printf("Could not notify user %s: %v", userId, err)
+			continue
+		}
+
+		ids := map[SecretType][]string{}
+		for _, r := range reminders {
+			ids[r.Secret.Type] = append(ids[r.Secret.Type], r.Secret.Id)
+		}
+		for secretType, typeIds := range ids {
+			if err := rs.Repo.MarkReminded(secretType, typeIds, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func reminderNotification(reminders []Reminder, now time.Time) notify.Notification {
+	var body strings.Builder
+	body.WriteString("The following secrets need your attention:\n\n")
+	data := make([]ExpirationResponse, 0, len(reminders))
+	for _, r := range reminders {
+		er := ExpirationResponse{}
+		er.load(r, now)
+		data = append(data, er)
+
+		var status string
+		switch {
+		case r.Kind == ReminderExpiry && r.Overdue:
+			status = "expired on"
+		case r.Kind == ReminderExpiry:
+			status = "expires on"
+		case r.Overdue:
+			status = "rotation overdue since"
+		default:
+			status = "rotation due on"
+		}
+		fmt.Fprintf(&body, "- %s (%s) in vault %s: %s %s\n",
+			r.Secret.Name, strings.ToLower(string(r.Secret.Type)), r.Secret.VaultName, status, r.DueAt.UTC().Format("2006-01-02"))
+	}
+
+	return notify.Notification{
+		UserId:    reminders[0].Secret.UserId,
+		Recipient: reminders[0].Secret.UserEmail,
+		Subject:   fmt.Sprintf("%d secrets are expiring or due for rotation", len(reminders)),
+		Body:      body.String(),
+		Data:      data,
+	}
+}
@@ -0,0 +1,154 @@
+package vaults_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/notify"
+	notify_mocks "github.com/adarsh-a-tw/passwordly/notify/mocks"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	vaults_mocks "github.com/adarsh-a-tw/passwordly/vaults/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func daysFrom(now time.Time, days int) *time.Time {
+	t := now.AddDate(0, 0, days)
+	return &t
+}
+
+// prepareScheduledSecrets stores, for two users, secrets that are expiring,
+// due for rotation, comfortably in date and unscheduled.
+func prepareScheduledSecrets(t *testing.T, now time.Time) *gorm.DB {
+	db := prepareDb(t)
+	assert.NoError(t, db.Create(&vaults.Vault{Id: "vault_1", Name: "Team", UserRefer: "user_1"}).Error)
+	assert.NoError(t, db.Create(&vaults.Vault{Id: "vault_2", Name: "Personal", UserRefer: "user_2"}).Error)
+
+	assert.NoError(t, db.Create(&vaults.Credential{
+		Id: "expiring_cred", Name: "Build bot", Username: "bot", Password: []byte("x"), VaultRefer: "vault_1",
+		Expiry: vaults.Expiry{ExpiresAt: daysFrom(now, 3)},
+	}).Error)
+	assert.NoError(t, db.Create(&vaults.Credential{
+		Id: "unscheduled_cred", Name: "Forum", Username: "me", Password: []byte("x"), VaultRefer: "vault_1",
+	}).Error)
+	assert.NoError(t, db.Create(&vaults.Key{
+		Id: "rotation_key", Name: "Payments API", Value: []byte("x"), VaultRefer: "vault_1",
+		CreatedAt: now.AddDate(0, 0, -40), UpdatedAt: now.AddDate(0, 0, -40),
+		Expiry: vaults.Expiry{RotateEveryDays: 30},
+	}).Error)
+	assert.NoError(t, db.Create(&vaults.Document{
+		Id: "distant_doc", Name: "Certificate", Content: []byte("x"), VaultRefer: "vault_2",
+		Expiry: vaults.Expiry{ExpiresAt: daysFrom(now, 200)},
+	}).Error)
+	assert.NoError(t, db.Create(&vaults.Key{
+		Id: "expiring_key", Name: "Cloud token", Value: []byte("x"), VaultRefer: "vault_2",
+		Expiry: vaults.Expiry{ExpiresAt: daysFrom(now, 10)},
+	}).Error)
+	return db
+}
+
+func TestSecretRepositoryImpl_FindScheduled_ShouldSpanSecretTypes(t *testing.T) {
+	now := time.Now()
+	repo := &vaults.SecretRepositoryImpl{Db: prepareScheduledSecrets(t, now)}
+
+	var secrets []vaults.ScheduledSecret
+	assert.NoError(t, repo.FindScheduled("user_1", &secrets))
+
+	assert.Len(t, secrets, 2)
+	assert.Equal(t, "expiring_cred", secrets[0].Id)
+	assert.Equal(t, vaults.SecretType(vaults.TypeCredential), secrets[0].Type)
+	assert.Equal(t, "Team", secrets[0].VaultName)
+	assert.Equal(t, "one@example.com", secrets[0].UserEmail)
+	assert.WithinDuration(t, *daysFrom(now, 3), *secrets[0].ExpiresAt, time.Second)
+	assert.Equal(t, "rotation_key", secrets[1].Id)
+	assert.Equal(t, 30, secrets[1].RotateEveryDays)
+
+	assert.NoError(t, repo.FindScheduled("", &secrets))
+	assert.Len(t, secrets, 4)
+}
+
+func TestReminderScheduler_RunOnce_ShouldNotifyEachUserOnce(t *testing.T) {
+	now := time.Now()
+	repo := &vaults.SecretRepositoryImpl{Db: prepareScheduledSecrets(t, now)}
+
+	var sent []notify.Notification
+	notifier := notify_mocks.NewNotifier(t)
+	notifier.On("Notify", mock.AnythingOfType("notify.Notification")).Return(nil).Run(func(args mock.Arguments) {
+		sent = append(sent, args.Get(0).(notify.Notification))
+	})
+
+	rs := vaults.ReminderScheduler{Repo: repo, Notifier: notifier, Window: 14 * 24 * time.Hour}
+
+	assert.NoError(t, rs.RunOnce(now))
+
+	assert.Len(t, sent, 2)
+	assert.Equal(t, "one@example.com", sent[0].Recipient)
+	assert.Contains(t, sent[0].Body, "Payments API (key) in vault Team: rotation overdue since")
+	assert.Contains(t, sent[0].Body, "Build bot (credential) in vault Team: expires on")
+	assert.Equal(t, "two@example.com", sent[1].Recipient)
+	assert.NotContains(t, sent[1].Body, "Certificate")
+
+	// Reminders are not repeated within a day, and sending them does not
+	// reset the rotation schedule.
+	assert.NoError(t, rs.RunOnce(now.Add(time.Hour)))
+	assert.Len(t, sent, 2)
+
+	var secrets []vaults.ScheduledSecret
+	assert.NoError(t, repo.FindScheduled("user_1", &secrets))
+	assert.WithinDuration(t, now.AddDate(0, 0, -40), secrets[1].UpdatedAt, time.Second)
+
+	assert.NoError(t, rs.RunOnce(now.Add(25*time.Hour)))
+	assert.Len(t, sent, 4)
+}
+
+func TestReminderScheduler_RunOnce_ShouldRetryAfterFailedNotification(t *testing.T) {
+	now := time.Now()
+	repo := &vaults.SecretRepositoryImpl{Db: prepareScheduledSecrets(t, now)}
+
+	notifier := notify_mocks.NewNotifier(t)
+	notifier.On("Notify", mock.AnythingOfType("notify.Notification")).Return(errors.New("MOCK_ERROR")).Twice()
+	notifier.On("Notify", mock.AnythingOfType("notify.Notification")).Return(nil).Twice()
+
+	rs := vaults.ReminderScheduler{Repo: repo, Notifier: notifier, Window: 14 * 24 * time.Hour}
+
+	assert.NoError(t, rs.RunOnce(now))
+	assert.NoError(t, rs.RunOnce(now.Add(time.Minute)))
+
+	notifier.AssertNumberOfCalls(t, "Notify", 4)
+}
+
+func TestVaultHandler_FetchExpirations_ShouldListUpcomingExpirations(t *testing.T) {
+	now := time.Now()
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/vaults/expirations?within_days=30", "GET", nil)
+
+	secretRepo := vaults_mocks.NewSecretRepository(t)
+	secretRepo.On("FindScheduled", "mock_user_id", mock.AnythingOfType("*[]vaults.ScheduledSecret")).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]vaults.ScheduledSecret) = []vaults.ScheduledSecret{
+			{Id: "later", Type: vaults.TypeKey, UpdatedAt: now, Expiry: vaults.Expiry{ExpiresAt: daysFrom(now, 20)}},
+			{Id: "outside", Type: vaults.TypeKey, UpdatedAt: now, Expiry: vaults.Expiry{ExpiresAt: daysFrom(now, 60)}},
+			{Id: "overdue", Type: vaults.TypeCredential, UpdatedAt: now.AddDate(0, 0, -100), Expiry: vaults.Expiry{RotateEveryDays: 90}},
+		}
+	})
+
+	vh := vaults.VaultHandler{SecretRepo: secretRepo}
+
+	ctx.Set("user_id", "mock_user_id")
+
+	vh.FetchExpirations(ctx)
+
+	var actualResponse vaults.ExpirationListResponse
+	common.DecodeJSONResponse(t, rec, &actualResponse)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, actualResponse.Expirations, 2)
+	assert.Equal(t, "overdue", actualResponse.Expirations[0].Id)
+	assert.Equal(t, vaults.ReminderRotation, actualResponse.Expirations[0].Kind)
+	assert.True(t, actualResponse.Expirations[0].Overdue)
+	assert.Equal(t, "later", actualResponse.Expirations[1].Id)
+	assert.Equal(t, vaults.ReminderExpiry, actualResponse.Expirations[1].Kind)
+	assert.Equal(t, 20, actualResponse.Expirations[1].DaysRemaining)
+}
@@ -1,13 +1,23 @@
 package vaults
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type VaultRepository interface {
 	Create(v *Vault) error
 	FetchByUserId(userId string, vaults *[]Vault) error
+	FetchPageByUserId(userId string, pq PageQuery, vaults *[]Vault) error
 	FetchById(id string, v *Vault) error
 	Update(v *Vault) error
 	Delete(v *Vault) error
+	FetchTrash(userId string, deletedBefore time.Time, vaults *[]Vault) error
+	FetchTrashedById(id string, v *Vault) error
+	Restore(v *Vault) error
+	Purge(v *Vault) error
 }
 
 type VaultRepositoryImpl struct {
@@ -22,6 +32,12 @@ func (vr *VaultRepositoryImpl) FetchByUserId(userId string, vaults *[]Vault) err
 	return vr.Db.Where("user_refer = ?", userId).Order("updated_at DESC, id DESC").Find(vaults).Error
 }
 
+// FetchPageByUserId returns up to pq.Limit+1 vaults, the extra one only
+// signalling that another page follows.
+func (vr *VaultRepositoryImpl) FetchPageByUserId(userId string, pq PageQuery, vaults *[]Vault) error {
+	return vr.Db.Where("user_refer = ?", userId).Scopes(pq.scope).Find(vaults).Error
+}
+
 func (vr *VaultRepositoryImpl) FetchById(id string, v *Vault) error {
 	return vr.Db.Where("Id = ?", id).First(v).Error
 }
@@ -30,8 +46,39 @@ func (vr *VaultRepositoryImpl) Update(v *Vault) error {
 	return vr.Db.Save(v).Error
 }
 
+// Delete moves a vault to the trash. Its secrets stay as they are and are
+// hidden along with it; its search entries are dropped and rebuilt on
+// restore.
 func (vr *VaultRepositoryImpl) Delete(v *Vault) error {
-	tx := vr.Db.Begin()
+	return vr.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("vault_refer = ?", v.Id).Delete(&SearchEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(v).Error
+	})
+}
+
+// FetchTrash returns the trashed vaults deleted before deletedBefore, most
+// recently deleted first. An empty userId searches every user's trash.
+func (vr *VaultRepositoryImpl) FetchTrash(userId string, deletedBefore time.Time, vaults *[]Vault) error {
+	query := vr.Db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore)
+	if userId != "" {
+		query = query.Where("user_refer = ?", userId)
+	}
+	return query.Order("deleted_at DESC, id DESC").Find(vaults).Error
+}
+
+func (vr *VaultRepositoryImpl) FetchTrashedById(id string, v *Vault) error {
+	return vr.Db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(v).Error
+}
+
+func (vr *VaultRepositoryImpl) Restore(v *Vault) error {
+	return vr.Db.Unscoped().Model(v).UpdateColumn("deleted_at", nil).Error
+}
+
+// Purge permanently removes a trashed vault and everything in it.
+func (vr *VaultRepositoryImpl) Purge(v *Vault) error {
+	tx := vr.Db.Unscoped().Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -42,7 +89,39 @@ func (vr *VaultRepositoryImpl) Delete(v *Vault) error {
 		return err
 	}
 
-	if err := tx.Where("vault_refer = ?", v.Id).Delete(&Credential{}).Error; err != nil {
+	if err := tx.Where("vault_refer = ?", v.Id).Delete(&CustomField{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("vault_refer = ?", v.Id).Delete(&Attachment{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("vault_refer = ?", v.Id).Delete(&SearchEntry{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("ca_refer IN (?)", tx.Model(&CertificateAuthority{}).Select("id").Where("vault_refer = ?", v.Id)).Delete(&IssuedCertificate{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Where("vault_refer = ?", v.Id).Delete(&SshCaRole{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, st := range secretTables {
+		if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE vault_refer = ?", st.table), v.Id).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Where("vault_refer = ?", v.Id).Delete(&Folder{}).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -0,0 +1,455 @@
+package vaults
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/backup"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/pki"
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
+	"github.com/adarsh-a-tw/passwordly/totp"
+	"github.com/adarsh-a-tw/passwordly/users"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RestoreBackup restores every vault of an uploaded backup as a new vault
+// of the current user.
+func (ih *ImportHandler) RestoreBackup(ctx *gin.Context) {
+	fh, ok := ih.formFile(ctx)
+	if !ok {
+		return
+	}
+	var rr RestoreRequest
+	if err := ctx.ShouldBind(&rr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+	content, ok := ih.openBackup(ctx, fh, rr)
+	if !ok {
+		return
+	}
+	if len(content.Vaults) == 0 {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "The backup holds no vaults"})
+		return
+	}
+
+	var u users.User
+	if err := ih.UserRepo.FindById(ctx.GetString("user_id"), &u); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	restores := make([]VaultRestore, 0, len(content.Vaults))
+	for _, bv := range content.Vaults {
+		if strings.TrimSpace(bv.Name) == "" {
+			respondSecretError(ctx, requestError{"A vault of the backup has no name"})
+			return
+		}
+		v := Vault{Id: uuid.NewString(), Name: bv.Name, User: u}
+		p, err := ih.restorePlan(&v, bv, nil)
+		if err != nil {
+			respondSecretError(ctx, err)
+			return
+		}
+		restores = append(restores, VaultRestore{Vault: v, Folders: p.folders, Writes: p.writes})
+	}
+	if err := ih.Repo.RestoreVaults(restores); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	resp := RestoreResponse{Vaults: []RestoredVaultResponse{}}
+	for _, r := range restores {
+		reindex(ih.Index, r.Vault.Id)
+		audit.Log(ih.Audit, ctx, audit.Event{
+			Action:     audit.ActionVaultCreate,
+			TargetType: audit.TargetVault,
+			TargetId:   r.Vault.Id,
+			Detail:     "restored from " + backup.FormatVaults,
+		})
+		ih.auditRestore(ctx, r.Vault.Id, len(r.Writes))
+		resp.Vaults = append(resp.Vaults, RestoredVaultResponse{Id: r.Vault.Id, Name: r.Vault.Name, Secrets: len(r.Writes), Folders: len(r.Folders)})
+	}
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// RestoreVaultBackup restores one vault of an uploaded backup into an
+// existing vault. Folders are matched by path and created when missing;
+// the secrets are added alongside those already in the vault.
+func (ih *ImportHandler) RestoreVaultBackup(ctx *gin.Context) {
+	fh, ok := ih.formFile(ctx)
+	if !ok {
+		return
+	}
+	var rr RestoreRequest
+	if err := ctx.ShouldBind(&rr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	vaultId, ok := requireVaultOwner(ctx, ih.VaultRepo)
+	if !ok {
+		return
+	}
+	var v Vault
+	if err := ih.VaultRepo.FetchById(vaultId, &v); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	content, ok := ih.openBackup(ctx, fh, rr)
+	if !ok {
+		return
+	}
+	bv, err := backupVault(content, rr.Vault)
+	if err != nil {
+		respondSecretError(ctx, err)
+		return
+	}
+	var folders []Folder
+	if err := ih.Folders.FindFolders(vaultId, &folders); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	p, err := ih.restorePlan(&v, bv, folders)
+	if err != nil {
+		respondSecretError(ctx, err)
+		return
+	}
+
+	if len(p.folders) > 0 || len(p.writes) > 0 {
+		if err := ih.Repo.ImportSecrets(vaultId, p.folders, p.writes); err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		reindex(ih.Index, vaultId)
+	}
+	ih.auditRestore(ctx, vaultId, len(p.writes))
+
+	ctx.JSON(http.StatusCreated, RestoreResponse{Vaults: []RestoredVaultResponse{
+		{Id: v.Id, Name: v.Name, Secrets: len(p.writes), Folders: len(p.folders)},
+	}})
+}
+
+func (ih *ImportHandler) auditRestore(ctx *gin.Context, vaultId string, count int) {
+	audit.Log(ih.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretImport,
+		TargetType: audit.TargetVault,
+		TargetId:   vaultId,
+		Detail:     fmt.Sprintf("%d secrets from %s", count, backup.FormatVaults),
+	})
+}
+
+// openBackup decrypts an uploaded backup and verifies it, answering the
+// request when it cannot be opened.
+func (ih *ImportHandler) openBackup(ctx *gin.Context, fh *multipart.FileHeader, rr RestoreRequest) (backup.Vaults, bool) {
+	data, err := readFormFile(fh)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return backup.Vaults{}, false
+	}
+
+	var content backup.Vaults
+	_, err = backup.Open(data, backup.FormatVaults, backup.Protection{
+		Passphrase: rr.Passphrase,
+		Identity:   strings.TrimSpace(rr.Identity),
+	}, &content)
+	for _, known := range []error{
+		backup.ErrNotBackup, backup.ErrWrongFormat, backup.ErrUnsupportedVersion, backup.ErrWrongKey,
+		backup.ErrCorrupt, backup.ErrChecksum, backup.ErrProtection, backup.ErrInvalidAgeKey,
+	} {
+		if errors.Is(err, known) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: known.Error()})
+			return backup.Vaults{}, false
+		}
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return backup.Vaults{}, false
+	}
+	return content, true
+}
+
+// backupVault picks the vault with the given id from a backup, or its only
+// vault when id is empty.
+func backupVault(content backup.Vaults, id string) (backup.Vault, error) {
+	if id == "" {
+		if len(content.Vaults) != 1 {
+			return backup.Vault{}, requestError{fmt.Sprintf("The backup holds %d vaults; choose one with vault", len(content.Vaults))}
+		}
+		return content.Vaults[0], nil
+	}
+	for _, bv := range content.Vaults {
+		if bv.Id == id {
+			return bv, nil
+		}
+	}
+	return backup.Vault{}, requestError{fmt.Sprintf("The backup holds no vault %s", id)}
+}
+
+// restorePlan turns a backed up vault into the folders and secrets to
+// create in v, next to the folders v already has. Every backed up folder
+// is kept, even when empty. Anything invalid in the backup is returned as
+// a requestError.
+func (ih *ImportHandler) restorePlan(v *Vault, bv backup.Vault, folders []Folder) (importPlan, error) {
+	paths, err := backupFolderPaths(bv.Folders)
+	if err != nil {
+		return importPlan{}, err
+	}
+	tree := newFolderTree(folders)
+	folderIds := make(map[string]string, len(folders))
+	for _, f := range folders {
+		folderIds[strings.ToLower(tree.path(f.Id))] = f.Id
+	}
+
+	var p importPlan
+	placed := make(map[string]*string, len(bv.Folders))
+	for _, f := range bv.Folders {
+		placed[f.Id] = p.folderId(v.Id, paths[f.Id], folderIds)
+	}
+	for _, bs := range bv.Secrets {
+		folderId, ok := placed[bs.FolderId]
+		if bs.FolderId != "" && !ok {
+			return importPlan{}, requestError{fmt.Sprintf("Secret %q is in a folder the backup does not hold", bs.Name)}
+		}
+		w, err := ih.restoreSecret(bs, v, folderId)
+		if err != nil {
+			return importPlan{}, err
+		}
+		p.writes = append(p.writes, w)
+	}
+	return p, nil
+}
+
+// backupFolderPaths returns the names from the top level down to every
+// folder of a backup, rejecting folders that are unnamed, repeated or
+// nested in a loop or under a missing parent.
+func backupFolderPaths(folders []backup.Folder) (map[string][]string, error) {
+	byId := make(map[string]backup.Folder, len(folders))
+	for _, f := range folders {
+		if _, ok := byId[f.Id]; ok || f.Id == "" || strings.TrimSpace(f.Name) == "" {
+			return nil, requestError{"The backup holds an invalid folder"}
+		}
+		byId[f.Id] = f
+	}
+
+	paths := make(map[string][]string, len(folders))
+	for _, f := range folders {
+		var path []string
+		seen := make(map[string]bool)
+		for current := f; ; {
+			if seen[current.Id] {
+				return nil, requestError{fmt.Sprintf("Folder %q is nested in itself", f.Name)}
+			}
+			seen[current.Id] = true
+			path = append([]string{current.Name}, path...)
+			if current.ParentId == "" {
+				break
+			}
+			parent, ok := byId[current.ParentId]
+			if !ok {
+				return nil, requestError{fmt.Sprintf("Folder %q is in a folder the backup does not hold", f.Name)}
+			}
+			current = parent
+		}
+		paths[f.Id] = path
+	}
+	return paths, nil
+}
+
+// restoreSecret checks a backed up secret and builds it under a new id,
+// encrypted and ready to be stored. Derived values such as the public key
+// of an SSH key or the last digits of a card are worked out again rather
+// than trusted.
+func (ih *ImportHandler) restoreSecret(bs backup.Secret, v *Vault, folderId *string) (SecretWrite, error) {
+	invalid := func(message string) error {
+		return requestError{fmt.Sprintf("Secret %q: %s", bs.Name, message)}
+	}
+	if strings.TrimSpace(bs.Name) == "" {
+		return SecretWrite{}, requestError{"A secret of the backup has no name"}
+	}
+	tags, err := normalizeTags(bs.Tags)
+	if err != nil {
+		return SecretWrite{}, invalid(err.Error())
+	}
+
+	w := SecretWrite{Op: BatchCreate, Type: SecretType(bs.Type), Id: uuid.NewString()}
+	if w.Fields, err = ih.restoreFields(bs, w.Id, v.Id); err != nil {
+		return SecretWrite{}, err
+	}
+
+	expiry := Expiry{ExpiresAt: bs.ExpiresAt, RotateEveryDays: bs.RotateEveryDays}
+	placement := Placement{FolderId: folderId, Tags: strings.Join(tags, ",")}
+	switch {
+	case w.Type == TypeCredential && bs.Credential != nil:
+		password, err := ih.Ep.Encrypt(bs.Credential.Password)
+		if err != nil {
+			return SecretWrite{}, err
+		}
+		w.Secret = Credential{Id: w.Id, Name: bs.Name, Username: bs.Credential.Username, Password: []byte(password),
+			Vault: *v, CreatedAt: bs.CreatedAt, UpdatedAt: bs.UpdatedAt, Expiry: expiry, Placement: placement}
+
+	case w.Type == TypeKey && bs.Key != nil:
+		value, err := ih.Ep.Encrypt(bs.Key.Value)
+		if err != nil {
+			return SecretWrite{}, err
+		}
+		w.Secret = Key{Id: w.Id, Name: bs.Name, Value: []byte(value),
+			Vault: *v, CreatedAt: bs.CreatedAt, UpdatedAt: bs.UpdatedAt, Expiry: expiry, Placement: placement}
+
+	case w.Type == TypeDocument && bs.Document != nil:
+		content, err := ih.Ep.Encrypt(bs.Document.Content)
+		if err != nil {
+			return SecretWrite{}, err
+		}
+		w.Secret = Document{Id: w.Id, Name: bs.Name, Content: []byte(content),
+			Vault: *v, CreatedAt: bs.CreatedAt, UpdatedAt: bs.UpdatedAt, Expiry: expiry, Placement: placement}
+
+	case w.Type == TypeTotp && bs.Totp != nil:
+		t := bs.Totp
+		key, err := totp.NewKey(t.Secret, totp.Algorithm(t.Algorithm), t.Digits, t.Period)
+		if err != nil {
+			return SecretWrite{}, invalid(err.Error())
+		}
+		secret, err := ih.Ep.Encrypt(key.Secret)
+		if err != nil {
+			return SecretWrite{}, err
+		}
+		w.Secret = Totp{Id: w.Id, Name: bs.Name, Issuer: t.Issuer, AccountName: t.AccountName, Secret: []byte(secret),
+			Algorithm: string(key.Algorithm), Digits: key.Digits, Period: key.Period,
+			Vault: *v, CreatedAt: bs.CreatedAt, UpdatedAt: bs.UpdatedAt, Expiry: expiry, Placement: placement}
+
+	case w.Type == TypeSshKey && bs.SshKey != nil:
+		k := bs.SshKey
+		signer, err := sshkeys.Parse([]byte(k.PrivateKey), "")
+		if err != nil {
+			return SecretWrite{}, invalid(err.Error())
+		}
+		info, err := sshkeys.Describe(signer, k.Comment)
+		if err != nil {
+			return SecretWrite{}, invalid(err.Error())
+		}
+		privateKey, err := ih.Ep.Encrypt(k.PrivateKey)
+		if err != nil {
+			return SecretWrite{}, err
+		}
+		w.Secret = SshKey{Id: w.Id, Name: bs.Name, KeyType: string(info.Type), Bits: info.Bits, PrivateKey: []byte(privateKey),
+			PublicKey: info.PublicKey, Fingerprint: info.Fingerprint, Comment: k.Comment,
+			Vault: *v, CreatedAt: bs.CreatedAt, UpdatedAt: bs.UpdatedAt, Expiry: expiry, Placement: placement}
+
+	case w.Type == TypeCertificateAuthority && bs.CertificateAuthority != nil:
+		ca := bs.CertificateAuthority
+		loaded, err := pki.LoadCA([]byte(ca.Certificate), []byte(ca.PrivateKey))
+		if err != nil {
+			return SecretWrite{}, invalid(err.Error())
+		}
+		for _, domain := range ca.AllowedDomains {
+			if domain == "" || strings.ContainsAny(domain, ", ") {
+				return SecretWrite{}, invalid("invalid allowed domain")
+			}
+		}
+		if ca.MaxTtlSeconds < 1 {
+			return SecretWrite{}, invalid("invalid maximum TTL")
+		}
+		privateKey, err := ih.Ep.Encrypt(ca.PrivateKey)
+		if err != nil {
+			return SecretWrite{}, err
+		}
+		w.Secret = CertificateAuthority{Id: w.Id, Name: bs.Name, Subject: loaded.Certificate.Subject.String(),
+			Certificate: ca.Certificate, PrivateKey: []byte(privateKey), AllowedDomains: strings.Join(ca.AllowedDomains, ","),
+			AllowSubdomains: ca.AllowSubdomains, AllowIpSans: ca.AllowIpSans, MaxTtlSeconds: ca.MaxTtlSeconds,
+			Vault: *v, CreatedAt: bs.CreatedAt, UpdatedAt: bs.UpdatedAt, Expiry: expiry, Placement: placement}
+
+	case w.Type == TypeCard && bs.Card != nil:
+		c := bs.Card
+		number, ok := normalizeCardNumber(c.Number)
+		if !ok {
+			return SecretWrite{}, invalid("invalid card number")
+		}
+		brand := cardBrand(number)
+		if !validCvv(c.Cvv, brand) {
+			return SecretWrite{}, invalid("invalid CVV")
+		}
+		if c.ExpiryMonth < 1 || c.ExpiryMonth > 12 || c.ExpiryYear < 2000 || c.ExpiryYear > 2100 {
+			return SecretWrite{}, invalid("invalid expiry")
+		}
+		encrypted, err := ih.encryptAll(number, c.Cvv)
+		if err != nil {
+			return SecretWrite{}, err
+		}
+		w.Secret = Card{Id: w.Id, Name: bs.Name, CardholderName: c.CardholderName, Number: encrypted[0], Last4: lastFour(number),
+			Brand: brand, ExpiryMonth: c.ExpiryMonth, ExpiryYear: c.ExpiryYear, Cvv: encrypted[1],
+			Vault: *v, CreatedAt: bs.CreatedAt, UpdatedAt: bs.UpdatedAt, Expiry: expiry, Placement: placement}
+
+	case w.Type == TypeIdentity && bs.Identity != nil:
+		i := bs.Identity
+		if i.FullName == "" {
+			return SecretWrite{}, invalid("full name is required")
+		}
+		encrypted, err := ih.encryptAll(i.Address, i.PassportNumber, i.LicenceNumber)
+		if err != nil {
+			return SecretWrite{}, err
+		}
+		w.Secret = Identity{Id: w.Id, Name: bs.Name, FullName: i.FullName, Email: i.Email, Phone: i.Phone,
+			Address: encrypted[0], PassportNumber: encrypted[1], PassportLast4: lastFour(i.PassportNumber),
+			LicenceNumber: encrypted[2], LicenceLast4: lastFour(i.LicenceNumber),
+			Vault: *v, CreatedAt: bs.CreatedAt, UpdatedAt: bs.UpdatedAt, Expiry: expiry, Placement: placement}
+
+	default:
+		return SecretWrite{}, invalid(fmt.Sprintf("no values for type %q", bs.Type))
+	}
+	return w, nil
+}
+
+// restoreFields checks the custom fields of a backed up secret and builds
+// them, encrypting hidden values.
+func (ih *ImportHandler) restoreFields(bs backup.Secret, secretId string, vaultId string) ([]CustomField, error) {
+	if len(bs.Fields) > maxImportFields {
+		return nil, requestError{fmt.Sprintf("Secret %q has more than %d fields", bs.Name, maxImportFields)}
+	}
+	reqs := make([]CustomFieldRequest, len(bs.Fields))
+	for i, f := range bs.Fields {
+		reqs[i] = CustomFieldRequest{Name: f.Name, Type: FieldType(f.Type), Value: f.Value}
+		if f.Name == "" || !reqs[i].Type.IsValid() {
+			return nil, requestError{fmt.Sprintf("Secret %q has an invalid field", bs.Name)}
+		}
+	}
+	if err := validateFields(reqs); err != nil {
+		return nil, requestError{fmt.Sprintf("Secret %q: %s", bs.Name, err)}
+	}
+
+	fields := make([]CustomField, len(reqs))
+	for i, req := range reqs {
+		fields[i] = CustomField{Id: uuid.NewString(), SecretId: secretId, Name: req.Name, Type: req.Type, Value: []byte(req.Value), Position: i, VaultRefer: vaultId}
+		if req.Type == FieldHidden {
+			encrypted, err := ih.encryptAll(req.Value)
+			if err != nil {
+				return nil, err
+			}
+			fields[i].Value = encrypted[0]
+		}
+	}
+	return fields, nil
+}
+
+// encryptAll encrypts values in order, leaving empty ones empty rather than
+// storing an encrypted empty string.
+func (ih *ImportHandler) encryptAll(values ...string) ([][]byte, error) {
+	encrypted := make([][]byte, len(values))
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		e, err := ih.Ep.Encrypt(value)
+		if err != nil {
+			return nil, err
+		}
+		encrypted[i] = []byte(e)
+	}
+	return encrypted, nil
+}
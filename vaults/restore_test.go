@@ -0,0 +1,227 @@
+package vaults_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/adarsh-a-tw/passwordly/backup"
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
+	"github.com/adarsh-a-tw/passwordly/users"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRestoreBackup_ShouldRestoreExportedVaultsAsNewVaults(t *testing.T) {
+	eh, db := prepareExport(t)
+	key, err := sshkeys.Generate(sshkeys.KeyTypeEd25519, 0)
+	assert.NoError(t, err)
+	info, err := sshkeys.Describe(key, "deploy")
+	assert.NoError(t, err)
+	openssh, err := sshkeys.Export(key, sshkeys.FormatOpenSSH, "", "deploy")
+	assert.NoError(t, err)
+	for _, record := range []any{
+		&vaults.SshKey{Id: "deploy", Name: "Deploy key", KeyType: string(info.Type), Bits: info.Bits, PrivateKey: []byte("sealed:" + string(openssh)),
+			PublicKey: info.PublicKey, Fingerprint: info.Fingerprint, Comment: "deploy", VaultRefer: "work"},
+		&vaults.Identity{Id: "me", Name: "Me", FullName: "Mona Lisa", PassportNumber: []byte("sealed:P1234567"), PassportLast4: "4567", VaultRefer: "work"},
+	} {
+		assert.NoError(t, db.Create(record).Error)
+	}
+
+	rec := exportBackup(eh, "", `{"passphrase": "correct horse"}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "attachment; filename=passwordly-backup-"+time.Now().Format("2006-01-02")+".age", rec.Header().Get("Content-Disposition"))
+	assert.False(t, bytes.Contains(rec.Body.Bytes(), []byte("toor")))
+	var content backup.Vaults
+	_, err = backup.Open(rec.Body.Bytes(), backup.FormatVaults, backup.Protection{Passphrase: "correct horse"}, &content)
+	assert.NoError(t, err)
+	assert.Len(t, content.Vaults, 2)
+
+	ih := restoreHandler(eh, db)
+	resp, code := restoreBackup(t, ih, "", map[string]string{"passphrase": "correct horse"}, rec.Body.Bytes())
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Len(t, resp.Vaults, 2)
+	restored := map[string]vaults.RestoredVaultResponse{}
+	for _, rv := range resp.Vaults {
+		restored[rv.Name] = rv
+	}
+	assert.Equal(t, 7, restored["Work"].Secrets)
+	assert.Equal(t, 2, restored["Work"].Folders)
+	assert.Equal(t, 0, restored["Copy"].Secrets)
+	var count int64
+	db.Model(&vaults.Vault{}).Where("user_refer = ?", "user_1").Count(&count)
+	assert.Equal(t, int64(4), count)
+
+	workId := restored["Work"].Id
+	var c vaults.Credential
+	assert.NoError(t, db.First(&c, "vault_refer = ? AND name = ?", workId, "Build server").Error)
+	assert.NotEqual(t, "build", c.Id)
+	assert.Equal(t, "sealed:toor", string(c.Password))
+	assert.Equal(t, "ci,prod", c.Tags)
+	assert.Equal(t, "Servers", folderName(t, db, c.FolderId))
+	var fields []vaults.CustomField
+	assert.NoError(t, db.Order("position").Find(&fields, "secret_id = ?", c.Id).Error)
+	assert.Len(t, fields, 3)
+	assert.Equal(t, "sealed:1234", string(fields[1].Value))
+	assert.Equal(t, "5678", string(fields[2].Value))
+
+	var totp vaults.Totp
+	assert.NoError(t, db.First(&totp, "vault_refer = ?", workId).Error)
+	assert.Equal(t, "sealed:JBSWY3DPEHPK3PXP", string(totp.Secret))
+	assert.Equal(t, "Two factor", folderName(t, db, totp.FolderId))
+	var card vaults.Card
+	assert.NoError(t, db.First(&card, "vault_refer = ?", workId).Error)
+	assert.Equal(t, "1111", card.Last4)
+	assert.Equal(t, "sealed:123", string(card.Cvv))
+	var sshKey vaults.SshKey
+	assert.NoError(t, db.First(&sshKey, "vault_refer = ?", workId).Error)
+	assert.Equal(t, info.Fingerprint, sshKey.Fingerprint)
+	var identity vaults.Identity
+	assert.NoError(t, db.First(&identity, "vault_refer = ?", workId).Error)
+	assert.Equal(t, "4567", identity.PassportLast4)
+	assert.Empty(t, identity.Address)
+}
+
+func TestRestoreVaultBackup_ShouldMergeIntoAnExistingVault(t *testing.T) {
+	eh, db := prepareExport(t)
+	identity, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(&vaults.Folder{Id: "copy_servers", Name: "SERVERS", VaultRefer: "copy"}).Error)
+
+	rec := exportBackup(eh, "work", `{"recipient": "`+identity.Recipient().String()+`"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "attachment; filename=Work-"+time.Now().Format("2006-01-02")+".age", rec.Header().Get("Content-Disposition"))
+
+	ih := restoreHandler(eh, db)
+	resp, code := restoreBackup(t, ih, "copy", map[string]string{"identity": identity.String()}, rec.Body.Bytes())
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, []vaults.RestoredVaultResponse{{Id: "copy", Name: "Copy", Secrets: 5, Folders: 1}}, resp.Vaults)
+	var c vaults.Credential
+	assert.NoError(t, db.First(&c, "vault_refer = ? AND name = ?", "copy", "Build server").Error)
+	assert.Equal(t, "copy_servers", *c.FolderId)
+	var d vaults.Document
+	assert.NoError(t, db.First(&d, "vault_refer = ? AND name = ?", "copy", "Runbook").Error)
+	assert.Equal(t, "sealed:restart the agent", string(d.Content))
+}
+
+func TestRestore_ShouldRejectInvalidBackups(t *testing.T) {
+	eh, db := prepareExport(t)
+	ih := restoreHandler(eh, db)
+	rec := exportBackup(eh, "", `{"passphrase": "correct horse"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	data := rec.Body.Bytes()
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-3] ^= 1
+
+	invalidCard, err := backup.Seal(backup.FormatVaults, backup.Vaults{Vaults: []backup.Vault{{Id: "v", Name: "Cards", Secrets: []backup.Secret{
+		{Id: "s", Type: "CARD", Name: "Visa", Card: &backup.Card{Number: "4111111111111112", ExpiryMonth: 1, ExpiryYear: 2030}},
+	}}}}, backup.Protection{Passphrase: "correct horse", WorkFactor: 10})
+	assert.NoError(t, err)
+
+	passphrase := map[string]string{"passphrase": "correct horse"}
+	for name, tc := range []struct {
+		vaultId string
+		form    map[string]string
+		file    []byte
+		code    int
+	}{
+		{"", map[string]string{"passphrase": "wrong horse"}, data, http.StatusBadRequest},
+		{"", passphrase, tampered, http.StatusBadRequest},
+		{"", passphrase, []byte("name,password\n"), http.StatusBadRequest},
+		{"", map[string]string{"passphrase": "correct horse", "identity": "AGE-SECRET-KEY-1"}, data, http.StatusBadRequest},
+		{"", passphrase, invalidCard, http.StatusBadRequest},
+		{"copy", passphrase, data, http.StatusBadRequest},
+		{"copy", map[string]string{"passphrase": "correct horse", "vault": "missing"}, data, http.StatusBadRequest},
+		{"other", map[string]string{"passphrase": "correct horse", "vault": "work"}, data, http.StatusNotFound},
+		{"copy", map[string]string{"passphrase": "correct horse", "vault": "work"}, data, http.StatusCreated},
+	} {
+		_, code := restoreBackup(t, ih, tc.vaultId, tc.form, tc.file)
+		assert.Equal(t, tc.code, code, name)
+	}
+	var count int64
+	db.Model(&vaults.Vault{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+
+	assert.Equal(t, http.StatusBadRequest, exportBackup(eh, "", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, exportBackup(eh, "", `{"passphrase": "short"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, exportBackup(eh, "", `{"passphrase": "correct horse", "recipient": "age1x"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, exportBackup(eh, "work", `{"recipient": "age1x"}`).Code)
+	assert.Equal(t, http.StatusNotFound, exportBackup(eh, "other", `{"passphrase": "correct horse"}`).Code)
+}
+
+func restoreHandler(eh *vaults.ExportHandler, db *gorm.DB) *vaults.ImportHandler {
+	eh.WorkFactor = 10
+	return &vaults.ImportHandler{
+		Ep:        eh.Ep,
+		Repo:      eh.Repo,
+		VaultRepo: eh.VaultRepo,
+		UserRepo:  &users.UserRepositoryImpl{Db: db},
+		Folders:   eh.Folders,
+		MaxSize:   1 << 20,
+	}
+}
+
+// exportBackup exports every vault of user_1, or only vaultId when given.
+func exportBackup(eh *vaults.ExportHandler, vaultId string, body string) *httptest.ResponseRecorder {
+	eh.WorkFactor = 10
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest("POST", "/api/v1/vaults/"+strings.TrimPrefix(vaultId+"/export", "/"), bytes.NewBufferString(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Set("user_id", "user_1")
+	if vaultId == "" {
+		eh.ExportBackup(ctx)
+	} else {
+		ctx.AddParam("id", vaultId)
+		eh.ExportVaultBackup(ctx)
+	}
+	return rec
+}
+
+// restoreBackup restores into new vaults, or into vaultId when given.
+func restoreBackup(t *testing.T, ih *vaults.ImportHandler, vaultId string, form map[string]string, file []byte) (vaults.RestoreResponse, int) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range form {
+		assert.NoError(t, mw.WriteField(name, value))
+	}
+	part, err := mw.CreateFormFile("file", "backup.age")
+	assert.NoError(t, err)
+	part.Write(file)
+	assert.NoError(t, mw.Close())
+
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	ctx.Request = httptest.NewRequest("POST", "/api/v1/vaults/"+strings.TrimPrefix(vaultId+"/restore", "/"), &body)
+	ctx.Request.Header.Set("Content-Type", mw.FormDataContentType())
+	ctx.Set("user_id", "user_1")
+	if vaultId == "" {
+		ih.RestoreBackup(ctx)
+	} else {
+		ctx.AddParam("id", vaultId)
+		ih.RestoreVaultBackup(ctx)
+	}
+
+	var resp vaults.RestoreResponse
+	if rec.Code < http.StatusBadRequest {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	}
+	return resp, rec.Code
+}
+
+func folderName(t *testing.T, db *gorm.DB, id *string) string {
+	var f vaults.Folder
+	assert.NoError(t, db.First(&f, "id = ?", *id).Error)
+	return f.Name
+}
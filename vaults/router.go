@@ -1,33 +1,55 @@
 package vaults
 
 import (
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/backup"
+	"github.com/adarsh-a-tw/passwordly/blobs"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/keepass"
 	"github.com/adarsh-a-tw/passwordly/middleware"
+	"github.com/adarsh-a-tw/passwordly/notify"
+	"github.com/adarsh-a-tw/passwordly/passwords"
 	"github.com/adarsh-a-tw/passwordly/users"
 	"github.com/adarsh-a-tw/passwordly/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(r *gin.Engine, db *gorm.DB) {
-	// Authenticated Routes
-	rg := r.Group("/api/v1/vaults")
-	rg.Use(middleware.TokenAuthMiddleware)
-
+func SetupRoutes(r *gin.Engine, db *gorm.DB, breaches passwords.BreachChecker, blobStore blobs.BlobStore) {
 	vaultsRepo := &VaultRepositoryImpl{
 		Db: db,
 	}
 	userRepo := &users.UserRepositoryImpl{
 		Db: db,
 	}
+
+	// Authenticated Routes
+	rg := r.Group("/api/v1/vaults")
+	rg.Use(middleware.TokenAuthMiddleware(&users.SessionValidatorImpl{Repo: userRepo}))
 	secretRepo := &SecretRepositoryImpl{Db: db}
+	auditRepo := &audit.EventRepositoryImpl{Db: db, Chain: audit.NewChain(common.Cfg.AuditKey)}
 
 	ep, _ := utils.NewEncryptionProvider()
 
+	fieldRepo := &CustomFieldRepositoryImpl{Db: db}
+	attachmentRepo := &AttachmentRepositoryImpl{Db: db}
+	folderRepo := &FolderRepositoryImpl{Db: db}
+	blind := utils.NewBlindIndex(common.Cfg.SearchIndexKey)
+	searchRepo := &SearchRepositoryImpl{Db: db}
+	indexer := NewSearchIndexer(db, blind)
+
 	vh := VaultHandler{
 		Ep:         ep,
 		Repo:       vaultsRepo,
 		UserRepo:   userRepo,
 		SecretRepo: secretRepo,
+		Fields:     fieldRepo,
+		Folders:    folderRepo,
+		Audit:      auditRepo,
+		Breaches:   breaches,
+		Hasher:     utils.NewPasswordHasher(),
 	}
 
 	sh := SecretHandler{
@@ -35,14 +57,193 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		Repo:      secretRepo,
 		UserRepo:  userRepo,
 		VaultRepo: vaultsRepo,
+		Audit:     auditRepo,
+		Generator: &passwords.GeneratorImpl{},
+		Fields:    fieldRepo,
+		Folders:   folderRepo,
+		Index:     indexer,
+	}
+
+	ch := SshCaHandler{
+		Ep:         ep,
+		Repo:       &SshCaRepositoryImpl{Db: db},
+		SecretRepo: secretRepo,
+		VaultRepo:  vaultsRepo,
+		Audit:      auditRepo,
+	}
+
+	ph := PkiHandler{
+		Ep:         ep,
+		Repo:       &PkiRepositoryImpl{Db: db},
+		SecretRepo: secretRepo,
+		VaultRepo:  vaultsRepo,
+		Audit:      auditRepo,
+	}
+
+	ah := AttachmentHandler{
+		Ep:         ep,
+		Repo:       attachmentRepo,
+		SecretRepo: secretRepo,
+		VaultRepo:  vaultsRepo,
+		Blobs:      blobStore,
+		MaxSize:    common.Cfg.AttachmentMaxBytes,
+		Audit:      auditRepo,
+	}
+
+	fh := FolderHandler{
+		Repo:       folderRepo,
+		SecretRepo: secretRepo,
+		VaultRepo:  vaultsRepo,
+		Audit:      auditRepo,
+		Index:      indexer,
+	}
+
+	th := TrashHandler{
+		VaultRepo:  vaultsRepo,
+		SecretRepo: secretRepo,
+		Purger:     NewPurger(db, blobStore),
+		Audit:      auditRepo,
+		Index:      indexer,
+	}
+
+	xh := TransferHandler{
+		Repo:        secretRepo,
+		VaultRepo:   vaultsRepo,
+		Folders:     folderRepo,
+		Attachments: attachmentRepo,
+		Blobs:       blobStore,
+		Audit:       auditRepo,
+		Index:       indexer,
+	}
+
+	ih := ImportHandler{
+		Ep:        ep,
+		Repo:      secretRepo,
+		VaultRepo: vaultsRepo,
+		UserRepo:  userRepo,
+		Folders:   folderRepo,
+		Audit:     auditRepo,
+		Index:     indexer,
+		MaxSize:   common.Cfg.ImportMaxBytes,
+	}
+
+	eh := ExportHandler{
+		Ep:         ep,
+		Repo:       secretRepo,
+		VaultRepo:  vaultsRepo,
+		Folders:    folderRepo,
+		Fields:     fieldRepo,
+		Audit:      auditRepo,
+		KeePass:    keepass.DefaultSettings,
+		WorkFactor: backup.DefaultWorkFactor,
+	}
+
+	srh := SearchHandler{
+		Blind:      blind,
+		Repo:       searchRepo,
+		SecretRepo: secretRepo,
+		Folders:    folderRepo,
 	}
 
 	rg.POST("", vh.CreateVault)
 	rg.GET("", vh.FetchVaults)
+	rg.GET("/health", vh.FetchHealth)
+	rg.GET("/expirations", vh.FetchExpirations)
+	rg.POST("/export", eh.ExportBackup)
+	rg.POST("/restore", ih.RestoreBackup)
 
 	rg.GET("/:id", vh.FetchVaultDetails)
 	rg.PATCH("/:id", vh.UpdateVault)
 	rg.DELETE("/:id", vh.DeleteVault)
+	rg.GET("/:id/breached-secrets", vh.FetchBreachedSecrets)
+	rg.GET("/:id/health", vh.FetchVaultHealth)
+
+	rg.POST("/:id/folders", fh.CreateFolder)
+	rg.GET("/:id/folders", fh.FetchFolders)
+	rg.PATCH("/:id/folders/:folderId", fh.UpdateFolder)
+	rg.DELETE("/:id/folders/:folderId", fh.DeleteFolder)
 
 	rg.POST("/:id/secrets", sh.CreateSecret)
+	rg.DELETE("/:id/secrets/:secretId", sh.DeleteSecret)
+	rg.POST("/:id/secrets/batch", sh.BatchSecrets)
+	rg.POST("/:id/secrets/move", xh.MoveSecrets)
+	rg.POST("/:id/secrets/copy", xh.CopySecrets)
+	rg.POST("/:id/import", ih.ImportSecrets)
+	rg.POST("/:id/export", eh.ExportVaultBackup)
+	rg.POST("/:id/export/keepass", eh.ExportKeePass)
+	rg.POST("/:id/restore", ih.RestoreVaultBackup)
+	rg.GET("/:id/secrets/:secretId/totp", sh.FetchTotpCode)
+	rg.POST("/:id/secrets/:secretId/ssh/export", sh.ExportSshKey)
+	rg.GET("/:id/secrets/:secretId/card", sh.FetchCard)
+	rg.GET("/:id/secrets/:secretId/identity", sh.FetchIdentity)
+	rg.PUT("/:id/secrets/:secretId/folder", fh.MoveSecret)
+	rg.PUT("/:id/secrets/:secretId/tags", fh.TagSecret)
+	rg.GET("/:id/secrets/:secretId/fields", sh.FetchFields)
+	rg.PUT("/:id/secrets/:secretId/fields", sh.UpdateFields)
+	rg.POST("/:id/secrets/:secretId/attachments", ah.UploadAttachment)
+	rg.GET("/:id/secrets/:secretId/attachments", ah.FetchAttachments)
+	rg.GET("/:id/secrets/:secretId/attachments/:attachmentId", ah.DownloadAttachment)
+	rg.DELETE("/:id/secrets/:secretId/attachments/:attachmentId", ah.DeleteAttachment)
+	rg.POST("/:id/secrets/:secretId/certificates", ph.IssueCertificate)
+	rg.GET("/:id/secrets/:secretId/certificates", ph.FetchCertificates)
+	rg.POST("/:id/secrets/:secretId/certificates/:certId/revoke", ph.RevokeCertificate)
+	rg.GET("/:id/secrets/:secretId/crl", ph.FetchCRL)
+
+	rg.POST("/:id/ssh/roles", ch.CreateRole)
+	rg.GET("/:id/ssh/roles", ch.FetchRoles)
+	rg.DELETE("/:id/ssh/roles/:roleId", ch.DeleteRole)
+	rg.POST("/:id/ssh/roles/:roleId/sign", ch.SignCertificate)
+
+	sg := r.Group("/api/v1/secrets")
+	sg.Use(middleware.TokenAuthMiddleware(&users.SessionValidatorImpl{Repo: userRepo}))
+	sg.GET("/search", srh.SearchSecrets)
+
+	tg := r.Group("/api/v1/trash")
+	tg.Use(middleware.TokenAuthMiddleware(&users.SessionValidatorImpl{Repo: userRepo}))
+	tg.GET("", th.FetchTrash)
+	tg.POST("/vaults/:id/restore", th.RestoreVault)
+	tg.DELETE("/vaults/:id", th.PurgeVault)
+	tg.POST("/secrets/:secretId/restore", th.RestoreSecret)
+	tg.DELETE("/secrets/:secretId", th.PurgeSecret)
+}
+
+// NewSearchIndexer wires an indexer for the search-reindex command and the
+// handlers that keep the index up to date.
+func NewSearchIndexer(db *gorm.DB, blind *utils.BlindIndex) *SearchIndexerImpl {
+	return &SearchIndexerImpl{
+		Blind:      blind,
+		Repo:       &SearchRepositoryImpl{Db: db},
+		VaultRepo:  &VaultRepositoryImpl{Db: db},
+		SecretRepo: &SecretRepositoryImpl{Db: db},
+		Fields:     &CustomFieldRepositoryImpl{Db: db},
+		Folders:    &FolderRepositoryImpl{Db: db},
+	}
+}
+
+func NewReminderScheduler(db *gorm.DB, notifier notify.Notifier) *ReminderScheduler {
+	return &ReminderScheduler{
+		Repo:     &SecretRepositoryImpl{Db: db},
+		Notifier: notifier,
+		Interval: time.Duration(common.Cfg.ReminderIntervalMins) * time.Minute,
+		Window:   time.Duration(common.Cfg.ReminderWindowDays) * 24 * time.Hour,
+	}
+}
+
+func NewPurger(db *gorm.DB, blobStore blobs.BlobStore) *PurgerImpl {
+	return &PurgerImpl{
+		VaultRepo:   &VaultRepositoryImpl{Db: db},
+		SecretRepo:  &SecretRepositoryImpl{Db: db},
+		Attachments: &AttachmentRepositoryImpl{Db: db},
+		Blobs:       blobStore,
+	}
+}
+
+func NewPurgeScheduler(db *gorm.DB, blobStore blobs.BlobStore) *PurgeScheduler {
+	return &PurgeScheduler{
+		Purger:     NewPurger(db, blobStore),
+		VaultRepo:  &VaultRepositoryImpl{Db: db},
+		SecretRepo: &SecretRepositoryImpl{Db: db},
+		Interval:   time.Duration(common.Cfg.TrashPurgeIntervalMins) * time.Minute,
+		Retention:  trashRetention(),
+	}
 }
@@ -0,0 +1,273 @@
+package vaults
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/adarsh-a-tw/passwordly/utils"
+)
+
+const (
+	minTermLength = 2
+	maxTermLength = 24
+	maxQueryTerms = 8
+)
+
+// Weights rank where a term was found. A whole word counts twice as much as
+// a prefix of one.
+const (
+	weightName     = 8
+	weightUsername = 4
+	weightUrl      = 4
+	weightTag      = 3
+	weightFolder   = 2
+)
+
+var errSearchQuery = errors.New("Search query needs a word of at least 2 characters")
+
+// SearchHit is a secret matching every term of a query, with its score.
+type SearchHit struct {
+	SecretId   string
+	SecretType SecretType
+	VaultId    string
+	Score      int
+}
+
+// SecretSummary is the plaintext metadata of a secret of any type, enough
+// to list it without loading or decrypting the secret itself.
+type SecretSummary struct {
+	Id        string
+	Name      string
+	Type      SecretType
+	VaultId   string
+	VaultName string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Placement
+}
+
+type SearchIndexer interface {
+	ReindexVault(vaultId string) error
+	IndexSecrets(vaultId string, secretIds []string) error
+}
+
+// SearchIndexerImpl keeps the search index of a vault in step with its
+// secrets. Writes to secrets update only their own entries. Folder changes
+// rebuild the whole vault, which keeps renames and moves consistent without
+// tracking which secrets they affect.
+type SearchIndexerImpl struct {
+	Blind      *utils.BlindIndex
+	Repo       SearchRepository
+	VaultRepo  VaultRepository
+	SecretRepo SecretRepository
+	Fields     CustomFieldRepository
+	Folders    FolderRepository
+}
+
+func (si *SearchIndexerImpl) ReindexVault(vaultId string) error {
+	var vault Vault
+	if err := si.VaultRepo.FetchById(vaultId, &vault); err != nil {
+		return err
+	}
+	secrets, err := findSecrets(si.SecretRepo, vaultId)
+	if err != nil {
+		return err
+	}
+	var fields []CustomField
+	if err := si.Fields.FindFieldsByVault(vaultId, &fields); err != nil {
+		return err
+	}
+	entries, err := si.entries(&vault, secrets, fields)
+	if err != nil {
+		return err
+	}
+	return si.Repo.ReplaceVault(vaultId, entries)
+}
+
+// IndexSecrets replaces the entries of the given secrets of a vault.
+// Secrets that are no longer in the vault, because they were deleted or
+// moved out, are only dropped from the index.
+func (si *SearchIndexerImpl) IndexSecrets(vaultId string, secretIds []string) error {
+	if len(secretIds) == 0 {
+		return nil
+	}
+	var vault Vault
+	if err := si.VaultRepo.FetchById(vaultId, &vault); err != nil {
+		return err
+	}
+	var found []SecretSummary
+	if err := si.SecretRepo.FindSummaries(secretIds, &found); err != nil {
+		return err
+	}
+	refs := found[:0]
+	for _, r := range found {
+		if r.VaultId == vaultId {
+			refs = append(refs, r)
+		}
+	}
+	var secrets []Securable
+	if err := si.SecretRepo.FindByRefs(refs, &secrets); err != nil {
+		return err
+	}
+	var fields []CustomField
+	if err := si.Fields.FindFieldsBySecrets(secretIds, &fields); err != nil {
+		return err
+	}
+	entries, err := si.entries(&vault, secrets, fields)
+	if err != nil {
+		return err
+	}
+	return si.Repo.ReplaceSecrets(secretIds, entries)
+}
+
+// entries builds the index entries of secrets of a vault.
+func (si *SearchIndexerImpl) entries(vault *Vault, secrets []Securable, fields []CustomField) ([]SearchEntry, error) {
+	fieldsBySecret := make(map[string][]CustomField)
+	for _, f := range fields {
+		fieldsBySecret[f.SecretId] = append(fieldsBySecret[f.SecretId], f)
+	}
+	var folders []Folder
+	if err := si.Folders.FindFolders(vault.Id, &folders); err != nil {
+		return nil, err
+	}
+	tree := newFolderTree(folders)
+
+	var entries []SearchEntry
+	for _, s := range secrets {
+		id := s.Metadata().Id
+		for term, weight := range secretSearchTerms(s, fieldsBySecret[id], tree) {
+			entries = append(entries, SearchEntry{
+				SecretId:   id,
+				Token:      si.Blind.Token(term),
+				SecretType: s.Type(),
+				Weight:     weight,
+				UserRefer:  vault.UserRefer,
+				VaultRefer: vault.Id,
+			})
+		}
+	}
+	return entries, nil
+}
+
+// reindex rebuilds the search index of a vault after a write that may
+// affect any of its secrets. The index can always be rebuilt with
+// search-reindex, so failures are only logged rather than failing the
+// write.
+func reindex(si SearchIndexer, vaultId string) {
+	if si == nil {
+		return
+	}
+	if err := si.ReindexVault(vaultId); err != nil {
+		log.Printf("Could not update search index of vault %s: %v", vaultId, err)
+	}
+}
+
+// indexSecrets refreshes the search entries of secrets of a vault after
+// they were written, logging failures as reindex does.
+func indexSecrets(si SearchIndexer, vaultId string, secretIds ...string) {
+	if si == nil || len(secretIds) == 0 {
+		return
+	}
+	if err := si.IndexSecrets(vaultId, secretIds); err != nil {
+		log.Printf("Could not update search index of vault %s: %v", vaultId, err)
+	}
+}
+
+// secretSearchTerms collects the searchable terms of a secret with the
+// weight of the best place each was found: its name, usernames, URL fields,
+// tags and the names of its folders.
+func secretSearchTerms(s Securable, fields []CustomField, tree folderTree) map[string]int {
+	terms := make(map[string]int)
+	m := s.Metadata()
+	addSearchTerms(terms, m.Name, weightName)
+	for _, username := range searchUsernames(s) {
+		addSearchTerms(terms, username, weightUsername)
+	}
+	for _, f := range fields {
+		if f.Type == FieldUrl {
+			addSearchTerms(terms, searchableUrl(string(f.Value)), weightUrl)
+		}
+	}
+	for _, tag := range splitList(m.Placement.Tags) {
+		addSearchTerms(terms, tag, weightTag)
+	}
+	addSearchTerms(terms, tree.path(deref(m.Placement.FolderId)), weightFolder)
+	return terms
+}
+
+// addSearchTerms adds every prefix of every word in text, so that queries
+// match as the user types.
+func addSearchTerms(terms map[string]int, text string, weight int) {
+	for _, word := range searchWords(text) {
+		runes := []rune(word)
+		for n := minTermLength; n <= len(runes) && n <= maxTermLength; n++ {
+			w := weight
+			if n == len(runes) {
+				w *= 2
+			}
+			if term := string(runes[:n]); w > terms[term] {
+				terms[term] = w
+			}
+		}
+	}
+}
+
+// queryTerms splits a search query into the terms to look up, cutting long
+// words to the longest prefix that is indexed.
+func queryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range searchWords(query) {
+		runes := []rune(word)
+		if len(runes) < minTermLength {
+			continue
+		}
+		if len(runes) > maxTermLength {
+			runes = runes[:maxTermLength]
+		}
+		if term := string(runes); !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) > maxQueryTerms {
+		terms = terms[:maxQueryTerms]
+	}
+	return terms
+}
+
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func searchUsernames(s Securable) []string {
+	switch secret := s.(type) {
+	case Credential:
+		return []string{secret.Username}
+	case Totp:
+		return []string{secret.Issuer, secret.AccountName}
+	case SshKey:
+		return []string{secret.Comment}
+	case Card:
+		return []string{secret.CardholderName}
+	case Identity:
+		return []string{secret.FullName, secret.Email}
+	}
+	return nil
+}
+
+// searchableUrl drops the scheme, query and fragment of a URL, which would
+// only add noise such as "https".
+func searchableUrl(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return value
+	}
+	return u.Host + u.Path
+}
@@ -0,0 +1,97 @@
+package vaults
+
+import (
+	"net/http"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultSearchPageSize = 20
+
+// SearchHandler searches the metadata of secrets across all of the caller's
+// vaults. Queries go to the blind search index; nothing is decrypted.
+type SearchHandler struct {
+	Blind      *utils.BlindIndex
+	Repo       SearchRepository
+	SecretRepo SecretRepository
+	Folders    FolderRepository
+}
+
+func (sh *SearchHandler) SearchSecrets(ctx *gin.Context) {
+	var ssr SearchSecretsRequest
+	if err := ctx.ShouldBindQuery(&ssr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid query parameters"})
+		return
+	}
+	if ssr.Page == 0 {
+		ssr.Page = 1
+	}
+	if ssr.PageSize == 0 {
+		ssr.PageSize = defaultSearchPageSize
+	}
+
+	terms := queryTerms(ssr.Query)
+	if len(terms) == 0 {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errSearchQuery.Error()})
+		return
+	}
+	tokens := make([]string, len(terms))
+	for i, term := range terms {
+		tokens[i] = sh.Blind.Token(term)
+	}
+
+	var hits []SearchHit
+	total, err := sh.Repo.Search(ctx.GetString("user_id"), tokens, (ssr.Page-1)*ssr.PageSize, ssr.PageSize, &hits)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	resp := SearchResponse{Results: make([]SearchResultResponse, 0, len(hits)), Page: ssr.Page, PageSize: ssr.PageSize, Total: total}
+	if len(hits) == 0 {
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.SecretId
+	}
+	var summaries []SecretSummary
+	if err := sh.SecretRepo.FindSummaries(ids, &summaries); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	byId := make(map[string]SecretSummary, len(summaries))
+	for _, s := range summaries {
+		byId[s.Id] = s
+	}
+
+	trees := make(map[string]folderTree)
+	for _, h := range hits {
+		s, found := byId[h.SecretId]
+		if !found {
+			// The index is rebuilt after writes; skip anything it still
+			// holds for a secret that has since gone.
+			continue
+		}
+		tree, loaded := trees[s.VaultId]
+		if !loaded {
+			var folders []Folder
+			if err := sh.Folders.FindFolders(s.VaultId, &folders); err != nil {
+				ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+				return
+			}
+			tree = newFolderTree(folders)
+			trees[s.VaultId] = tree
+		}
+
+		var result SearchResultResponse
+		result.load(s, h.Score, tree)
+		resp.Results = append(resp.Results, result)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
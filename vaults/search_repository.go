@@ -0,0 +1,64 @@
+package vaults
+
+import "gorm.io/gorm"
+
+type SearchRepository interface {
+	ReplaceVault(vaultId string, entries []SearchEntry) error
+	ReplaceSecrets(secretIds []string, entries []SearchEntry) error
+	Search(userId string, tokens []string, offset int, limit int, hits *[]SearchHit) (int64, error)
+}
+
+type SearchRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+// ReplaceVault swaps the whole index of a vault in one transaction.
+func (sr *SearchRepositoryImpl) ReplaceVault(vaultId string, entries []SearchEntry) error {
+	return sr.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("vault_refer = ?", vaultId).Delete(&SearchEntry{}).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(entries, 500).Error
+	})
+}
+
+// ReplaceSecrets swaps the index entries of some secrets in one
+// transaction.
+func (sr *SearchRepositoryImpl) ReplaceSecrets(secretIds []string, entries []SearchEntry) error {
+	return sr.Db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(secretIds); start += 500 {
+			end := start + 500
+			if end > len(secretIds) {
+				end = len(secretIds)
+			}
+			if err := tx.Where("secret_id IN ?", secretIds[start:end]).Delete(&SearchEntry{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(entries, 500).Error
+	})
+}
+
+// Search returns the secrets of the user's vaults that carry every token,
+// best scores first, along with the total number of matches.
+func (sr *SearchRepositoryImpl) Search(userId string, tokens []string, offset int, limit int, hits *[]SearchHit) (int64, error) {
+	matches := sr.Db.Model(&SearchEntry{}).
+		Select("secret_id, secret_type, vault_refer AS vault_id, SUM(weight) AS score").
+		Where("user_refer = ? AND token IN ?", userId, tokens).
+		Group("secret_id, secret_type, vault_refer").
+		Having("COUNT(*) = ?", len(tokens))
+
+	var total int64
+	if err := sr.Db.Table("(?) AS matches", matches).Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	err := matches.Order("score DESC, secret_id ASC").Offset(offset).Limit(limit).Scan(hits).Error
+	return total, err
+}
@@ -0,0 +1,157 @@
+package vaults_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchHandler_SearchSecrets_ShouldRankMatchesAcrossVaults(t *testing.T) {
+	sh := prepareSearch(t)
+
+	resp, code := search(t, sh, "user_1", "q=git")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(3), resp.Total)
+	assert.Equal(t, "github", resp.Results[0].Id)
+	assert.Equal(t, "Work", resp.Results[0].VaultName)
+	assert.Equal(t, "Engineering/Source", resp.Results[0].FolderPath)
+	assert.Equal(t, "mirror", resp.Results[1].Id)
+	assert.Equal(t, "Personal", resp.Results[1].VaultName)
+	assert.Equal(t, "deploy_key", resp.Results[2].Id)
+	assert.Equal(t, []string{"git", "ci"}, resp.Results[2].Tags)
+	assert.Greater(t, resp.Results[1].Score, resp.Results[2].Score)
+}
+
+func TestSearchHandler_SearchSecrets_ShouldRequireEveryTerm(t *testing.T) {
+	sh := prepareSearch(t)
+
+	resp, _ := search(t, sh, "user_1", "q=engineering+octocat")
+	assert.Equal(t, int64(1), resp.Total)
+	assert.Equal(t, "github", resp.Results[0].Id)
+
+	resp, _ = search(t, sh, "user_1", "q=source.example")
+	assert.Equal(t, int64(1), resp.Total)
+	assert.Equal(t, "mirror", resp.Results[0].Id)
+}
+
+func TestSearchHandler_SearchSecrets_ShouldPaginate(t *testing.T) {
+	sh := prepareSearch(t)
+
+	resp, _ := search(t, sh, "user_1", "q=git&page=2&page_size=2")
+
+	assert.Equal(t, int64(3), resp.Total)
+	assert.Equal(t, 2, resp.Page)
+	assert.Len(t, resp.Results, 1)
+	assert.Equal(t, "deploy_key", resp.Results[0].Id)
+}
+
+func TestSearchHandler_SearchSecrets_ShouldOnlySearchOwnVaults(t *testing.T) {
+	sh := prepareSearch(t)
+
+	resp, code := search(t, sh, "user_2", "q=git")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(1), resp.Total)
+	assert.Equal(t, "gitlab", resp.Results[0].Id)
+}
+
+func TestSearchHandler_SearchSecrets_ShouldRejectQueriesWithoutTerms(t *testing.T) {
+	sh := prepareSearch(t)
+
+	_, code := search(t, sh, "user_1", "q=a+-")
+
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSearchIndexerImpl_ReindexVault_ShouldOnlyStoreBlindTokens(t *testing.T) {
+	sh := prepareSearch(t)
+	db := sh.Repo.(*vaults.SearchRepositoryImpl).Db
+
+	var tokens []string
+	assert.NoError(t, db.Model(&vaults.SearchEntry{}).Where("secret_id = ?", "github").Pluck("token", &tokens).Error)
+
+	assert.NotEmpty(t, tokens)
+	assert.NotContains(t, tokens, "github")
+	assert.Contains(t, tokens, sh.Blind.Token("github"))
+}
+
+func TestSearchIndexerImpl_IndexSecrets_ShouldOnlyReplaceEntriesOfTheGivenSecrets(t *testing.T) {
+	sh := prepareSearch(t)
+	db := sh.Repo.(*vaults.SearchRepositoryImpl).Db
+	indexer := vaults.NewSearchIndexer(db, sh.Blind)
+	var deployKeyEntries int64
+	assert.NoError(t, db.Model(&vaults.SearchEntry{}).Where("secret_id = ?", "deploy_key").Count(&deployKeyEntries).Error)
+
+	assert.NoError(t, db.Model(&vaults.Credential{}).Where("id = ?", "github").Update("name", "Bitbucket").Error)
+	assert.NoError(t, db.Delete(&vaults.Credential{}, "id = ?", "mirror").Error)
+	assert.NoError(t, indexer.IndexSecrets("work", []string{"github"}))
+	assert.NoError(t, indexer.IndexSecrets("personal", []string{"mirror"}))
+
+	resp, _ := search(t, sh, "user_1", "q=bitbucket")
+	assert.Equal(t, int64(1), resp.Total)
+	assert.Equal(t, "github", resp.Results[0].Id)
+	resp, _ = search(t, sh, "user_1", "q=git")
+	assert.Equal(t, int64(1), resp.Total)
+	assert.Equal(t, "deploy_key", resp.Results[0].Id)
+
+	var count int64
+	assert.NoError(t, db.Model(&vaults.SearchEntry{}).Where("secret_id = ?", "deploy_key").Count(&count).Error)
+	assert.Equal(t, deployKeyEntries, count)
+	assert.NoError(t, db.Model(&vaults.SearchEntry{}).Where("secret_id = ?", "mirror").Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+// prepareSearch indexes two vaults of user_1 and one of user_2.
+func prepareSearch(t *testing.T) *vaults.SearchHandler {
+	db := prepareDb(t)
+
+	engineering := "engineering"
+	source := "source"
+	for _, record := range []any{
+		&vaults.Vault{Id: "work", Name: "Work", UserRefer: "user_1"},
+		&vaults.Vault{Id: "personal", Name: "Personal", UserRefer: "user_1"},
+		&vaults.Vault{Id: "other", Name: "Other", UserRefer: "user_2"},
+		&vaults.Folder{Id: engineering, Name: "Engineering", VaultRefer: "work"},
+		&vaults.Folder{Id: source, Name: "Source", ParentId: &engineering, VaultRefer: "work"},
+		&vaults.Credential{Id: "github", Name: "GitHub", Username: "octocat", Password: []byte("x"), VaultRefer: "work",
+			Placement: vaults.Placement{FolderId: &source}},
+		&vaults.SshKey{Id: "deploy_key", Name: "Deploy key", KeyType: "ed25519", PrivateKey: []byte("x"), PublicKey: "x", Fingerprint: "x",
+			VaultRefer: "work", Placement: vaults.Placement{Tags: "git,ci"}},
+		&vaults.Credential{Id: "mirror", Name: "Mirror", Username: "me", Password: []byte("x"), VaultRefer: "personal"},
+		&vaults.CustomField{Id: "mirror_url", SecretId: "mirror", Name: "Website", Type: vaults.FieldUrl,
+			Value: []byte("https://git.source.example/login?next=%2F"), VaultRefer: "personal"},
+		&vaults.Credential{Id: "gitlab", Name: "GitLab", Username: "someone", Password: []byte("x"), VaultRefer: "other"},
+	} {
+		assert.NoError(t, db.Create(record).Error)
+	}
+
+	blind := utils.NewBlindIndex("search test key")
+	indexer := vaults.NewSearchIndexer(db, blind)
+	for _, id := range []string{"work", "personal", "other"} {
+		assert.NoError(t, indexer.ReindexVault(id))
+	}
+
+	return &vaults.SearchHandler{
+		Blind:      blind,
+		Repo:       &vaults.SearchRepositoryImpl{Db: db},
+		SecretRepo: &vaults.SecretRepositoryImpl{Db: db},
+		Folders:    &vaults.FolderRepositoryImpl{Db: db},
+	}
+}
+
+func search(t *testing.T, sh *vaults.SearchHandler, userId string, query string) (vaults.SearchResponse, int) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/secrets/search?"+query, "GET", nil)
+	ctx.Set("user_id", userId)
+	sh.SearchSecrets(ctx)
+
+	var resp vaults.SearchResponse
+	if rec.Code == http.StatusOK {
+		common.DecodeJSONResponse(t, rec, &resp)
+	}
+	return resp, rec.Code
+}
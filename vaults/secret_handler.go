@@ -1,13 +1,24 @@
 package vaults
 
 import (
+	"crypto"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/adarsh-a-tw/passwordly/audit"
 	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/passwords"
+	"github.com/adarsh-a-tw/passwordly/pki"
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
+	"github.com/adarsh-a-tw/passwordly/totp"
 	"github.com/adarsh-a-tw/passwordly/users"
 	"github.com/adarsh-a-tw/passwordly/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type SecretHandler struct {
@@ -15,6 +26,11 @@ type SecretHandler struct {
 	Repo      SecretRepository
 	VaultRepo VaultRepository
 	UserRepo  users.UserRepository
+	Audit     audit.Recorder
+	Generator passwords.Generator
+	Fields    CustomFieldRepository
+	Folders   FolderRepository
+	Index     SearchIndexer
 }
 
 func (sh *SecretHandler) CreateSecret(ctx *gin.Context) {
@@ -32,61 +48,648 @@ func (sh *SecretHandler) CreateSecret(ctx *gin.Context) {
 		return
 	}
 
-	vaultId := ctx.Param("id")
+	vaultId, ok := requireVaultOwner(ctx, sh.VaultRepo)
+	if !ok {
+		return
+	}
 
-	valid, err := ValidateVaultOwner(sh.VaultRepo, vaultId, userId)
+	var v Vault
+	if err := sh.VaultRepo.FetchById(vaultId, &v); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
 
+	if err := sh.checkSecret(&csr, vaultId); err != nil {
+		respondSecretError(ctx, err)
+		return
+	}
+	s, err := sh.buildSecret(&csr, &v, uuid.NewString())
+	if err != nil {
+		respondSecretError(ctx, err)
+		return
+	}
+	fields, err := sh.buildFields(csr.Fields, s.Metadata().Id, vaultId, nil)
 	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	if s, err = sh.createSecret(s, fields); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	if c, ok := s.(Credential); ok {
+		c.Password = []byte(csr.Password)
+		s = c
+	}
+	sh.created(ctx, s, fields)
+}
+
+// FetchTotpCode returns the current one-time code of a TOTP secret. The
+// seed itself never leaves the server.
+func (sh *SecretHandler) FetchTotpCode(ctx *gin.Context) {
+	vaultId, ok := requireVaultOwner(ctx, sh.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var t Totp
+	if err := sh.Repo.FindTotpById(ctx.Param("secretId"), vaultId, &t); err != nil {
 		handleGormError(ctx, err)
 		return
 	}
 
-	if !valid {
-		ctx.AbortWithStatus(http.StatusNotFound)
+	secret, err := sh.Ep.Decrypt(string(t.Secret))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
 		return
 	}
 
-	var v Vault
-	if err := sh.VaultRepo.FetchById(vaultId, &v); err != nil {
+	key := totp.Key{Secret: secret, Algorithm: totp.Algorithm(t.Algorithm), Digits: t.Digits, Period: t.Period}
+	code, remaining, err := key.Code(time.Now())
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
 		return
 	}
 
-	switch csr.Type {
-	case TypeCredential:
-		sh.handleCreateCredential(ctx, &csr, &v)
-	default:
+	audit.Log(sh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretRead,
+		TargetType: audit.TargetSecret,
+		TargetId:   t.Id,
+		Detail:     string(TypeTotp),
+	})
+
+	ctx.JSON(http.StatusOK, TotpCodeResponse{
+		Code:             code,
+		SecondsRemaining: remaining,
+		Period:           t.Period,
+		Digits:           t.Digits,
+	})
+}
+
+// FetchCard returns a single card with its number and CVV decrypted.
+func (sh *SecretHandler) FetchCard(ctx *gin.Context) {
+	vaultId, ok := requireVaultOwner(ctx, sh.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var c Card
+	if err := sh.Repo.FindCardById(ctx.Param("secretId"), vaultId, &c); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+
+	number, err := sh.decryptOptional(c.Number)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	cvv, err := sh.decryptOptional(c.Cvv)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(sh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretRead,
+		TargetType: audit.TargetSecret,
+		TargetId:   c.Id,
+		Detail:     string(TypeCard),
+	})
+
+	sr := SecretResponse{}
+	sr.load(c)
+	sr.CardNumber = number
+	sr.Cvv = cvv
+	ctx.JSON(http.StatusOK, sr)
+}
+
+// FetchIdentity returns a single identity with its address and document
+// numbers decrypted.
+func (sh *SecretHandler) FetchIdentity(ctx *gin.Context) {
+	vaultId, ok := requireVaultOwner(ctx, sh.VaultRepo)
+	if !ok {
+		return
 	}
+
+	var i Identity
+	if err := sh.Repo.FindIdentityById(ctx.Param("secretId"), vaultId, &i); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+
+	sr := SecretResponse{}
+	sr.load(i)
+	for _, field := range []struct {
+		encrypted []byte
+		plain     *string
+	}{
+		{i.Address, &sr.Address},
+		{i.PassportNumber, &sr.PassportNumber},
+		{i.LicenceNumber, &sr.LicenceNumber},
+	} {
+		value, err := sh.decryptOptional(field.encrypted)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return
+		}
+		*field.plain = value
+	}
+
+	audit.Log(sh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretRead,
+		TargetType: audit.TargetSecret,
+		TargetId:   i.Id,
+		Detail:     string(TypeIdentity),
+	})
+
+	ctx.JSON(http.StatusOK, sr)
 }
 
-// private methods
-func (sh *SecretHandler) handleCreateCredential(ctx *gin.Context, csr *CreateSecretRequest, v *Vault) {
-	if csr.Username == "" || csr.Password == "" {
+// ExportSshKey returns the decrypted private key in the requested format,
+// optionally protected by a passphrase for OpenSSH output.
+func (sh *SecretHandler) ExportSshKey(ctx *gin.Context) {
+	var ser SshKeyExportRequest
+	if err := ctx.ShouldBindJSON(&ser); err != nil {
 		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
 		return
 	}
-	ep, err := sh.Ep.Encrypt(csr.Password)
+
+	vaultId, ok := requireVaultOwner(ctx, sh.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var k SshKey
+	if err := sh.Repo.FindSshKeyById(ctx.Param("secretId"), vaultId, &k); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+
+	privateKey, err := sh.Ep.Decrypt(string(k.PrivateKey))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
 		return
 	}
-	c := Credential{
-		Id:       uuid.NewString(),
-		Name:     csr.Name,
-		Username: csr.Username,
-		Password: []byte(ep),
-		Vault:    *v,
+	signer, err := sshkeys.Parse([]byte(privateKey), "")
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	format := sshkeys.Format(ser.Format)
+	if format == "" {
+		format = sshkeys.FormatOpenSSH
 	}
-	if err := sh.Repo.CreateCredential(&c); err != nil {
+	exported, err := sshkeys.Export(signer, format, ser.Passphrase, k.Comment)
+	if err != nil {
+		if errors.Is(err, sshkeys.ErrPEMPassphrase) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
 		return
 	}
-	c.Password = []byte(csr.Password)
+
+	audit.Log(sh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretRead,
+		TargetType: audit.TargetSecret,
+		TargetId:   k.Id,
+		Detail:     fmt.Sprintf("%s export as %s", TypeSshKey, format),
+	})
+
+	ctx.JSON(http.StatusOK, SshKeyExportResponse{
+		Format:      string(format),
+		PrivateKey:  string(exported),
+		PublicKey:   k.PublicKey,
+		Fingerprint: k.Fingerprint,
+	})
+}
+
+// DeleteSecret moves a secret of any type to the trash, from where it can
+// be restored until it is purged.
+func (sh *SecretHandler) DeleteSecret(ctx *gin.Context) {
+	vaultId, ok := requireVaultOwner(ctx, sh.VaultRepo)
+	if !ok {
+		return
+	}
+
+	secretId := ctx.Param("secretId")
+	secretType, err := sh.Repo.FindSecretType(secretId, vaultId)
+	if err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+
+	if err := sh.Repo.DeleteSecret(secretType, secretId, vaultId); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	indexSecrets(sh.Index, vaultId, secretId)
+
+	audit.Log(sh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretDelete,
+		TargetType: audit.TargetSecret,
+		TargetId:   secretId,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Secret moved to trash"})
+}
+
+// private methods
+
+// requestError is a problem with what the caller sent. It is answered with
+// 400 and its message instead of as an internal error.
+type requestError struct {
+	message string
+}
+
+func (e requestError) Error() string {
+	return e.message
+}
+
+var errInvalidBody = requestError{"Invalid Request body"}
+
+// respondSecretError answers a failure to check or build a secret.
+func respondSecretError(ctx *gin.Context, err error) {
+	var re requestError
+	if errors.As(err, &re) {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: re.message})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+}
+
+// checkSecret validates the parts of a request shared by every secret type
+// and normalizes its tags.
+func (sh *SecretHandler) checkSecret(csr *CreateSecretRequest, vaultId string) error {
+	if err := validateFields(csr.Fields); err != nil {
+		return requestError{err.Error()}
+	}
+
+	var err error
+	if csr.Tags, err = normalizeTags(csr.Tags); err != nil {
+		return requestError{err.Error()}
+	}
+	if csr.FolderId != "" {
+		var f Folder
+		if err := sh.Folders.FindFolderById(csr.FolderId, vaultId, &f); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return requestError{errFolderMissing.Error()}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// buildSecret turns a checked request into an encrypted secret with the
+// given id, ready to be stored. A generated password is written back to
+// csr.Password.
+func (sh *SecretHandler) buildSecret(csr *CreateSecretRequest, v *Vault, id string) (Securable, error) {
+	switch csr.Type {
+	case TypeCredential:
+		return sh.buildCredential(csr, v, id)
+	case TypeTotp:
+		return sh.buildTotp(csr, v, id)
+	case TypeSshKey:
+		return sh.buildSshKey(csr, v, id)
+	case TypeCertificateAuthority:
+		return sh.buildCertificateAuthority(csr, v, id)
+	case TypeCard:
+		return sh.buildCard(csr, v, id)
+	case TypeIdentity:
+		return sh.buildIdentity(csr, v, id)
+	}
+	return nil, errInvalidBody
+}
+
+// createSecret stores a secret built by buildSecret together with its
+// custom fields and returns it with the timestamps it was stored with.
+func (sh *SecretHandler) createSecret(s Securable, fields []CustomField) (Securable, error) {
+	var err error
+	switch secret := s.(type) {
+	case Credential:
+		err = sh.Repo.CreateCredential(&secret, fields)
+		s = secret
+	case Totp:
+		err = sh.Repo.CreateTotp(&secret, fields)
+		s = secret
+	case SshKey:
+		err = sh.Repo.CreateSshKey(&secret, fields)
+		s = secret
+	case CertificateAuthority:
+		err = sh.Repo.CreateCertificateAuthority(&secret, fields)
+		s = secret
+	case Card:
+		err = sh.Repo.CreateCard(&secret, fields)
+		s = secret
+	case Identity:
+		err = sh.Repo.CreateIdentity(&secret, fields)
+		s = secret
+	default:
+		err = fmt.Errorf("Unknown secret type %s", s.Type())
+	}
+	return s, err
+}
+
+func (sh *SecretHandler) buildCredential(csr *CreateSecretRequest, v *Vault, id string) (Securable, error) {
+	if csr.Generate {
+		if csr.Password != "" {
+			return nil, requestError{"Provide either a password or generate, not both"}
+		}
+		var opts passwords.GeneratorOptions
+		if csr.GenerateOptions != nil {
+			opts = csr.GenerateOptions.Options()
+		}
+		generated, err := sh.Generator.Generate(opts)
+		if err != nil {
+			if errors.Is(err, passwords.ErrInvalidGeneratorOptions) {
+				return nil, requestError{err.Error()}
+			}
+			return nil, err
+		}
+		csr.Password = generated.Password
+	}
+	if csr.Username == "" || csr.Password == "" {
+		return nil, errInvalidBody
+	}
+	ep, err := sh.Ep.Encrypt(csr.Password)
+	if err != nil {
+		return nil, err
+	}
+	return Credential{
+		Id:        id,
+		Name:      csr.Name,
+		Username:  csr.Username,
+		Password:  []byte(ep),
+		Vault:     *v,
+		Expiry:    csr.expiry(),
+		Placement: csr.placement(),
+	}, nil
+}
+
+func (sh *SecretHandler) buildTotp(csr *CreateSecretRequest, v *Vault, id string) (Securable, error) {
+	var key totp.Key
+	var err error
+	if strings.HasPrefix(csr.Totp, "otpauth://") {
+		key, err = totp.ParseURI(csr.Totp)
+	} else {
+		key, err = totp.NewKey(csr.Totp, totp.Algorithm(strings.ToUpper(csr.Algorithm)), csr.Digits, csr.Period)
+		key.Issuer = csr.Issuer
+		key.AccountName = csr.Username
+	}
+	if csr.Totp == "" || err != nil {
+		return nil, errInvalidBody
+	}
+
+	es, err := sh.Ep.Encrypt(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return Totp{
+		Id:          id,
+		Name:        csr.Name,
+		Issuer:      key.Issuer,
+		AccountName: key.AccountName,
+		Secret:      []byte(es),
+		Algorithm:   string(key.Algorithm),
+		Digits:      key.Digits,
+		Period:      key.Period,
+		Vault:       *v,
+		Expiry:      csr.expiry(),
+		Placement:   csr.placement(),
+	}, nil
+}
+
+func (sh *SecretHandler) buildSshKey(csr *CreateSecretRequest, v *Vault, id string) (Securable, error) {
+	var signer crypto.Signer
+	var err error
+	if csr.PrivateKey != "" {
+		signer, err = sshkeys.Parse([]byte(csr.PrivateKey), csr.Passphrase)
+	} else {
+		signer, err = sshkeys.Generate(sshkeys.KeyType(strings.ToUpper(csr.KeyType)), csr.Bits)
+	}
+	if err != nil {
+		if errors.Is(err, sshkeys.ErrPassphraseNeeded) || errors.Is(err, sshkeys.ErrWrongPassphrase) || errors.Is(err, sshkeys.ErrUnsupportedKey) {
+			return nil, requestError{err.Error()}
+		}
+		return nil, errInvalidBody
+	}
+
+	info, err := sshkeys.Describe(signer, csr.Comment)
+	if err != nil {
+		return nil, requestError{err.Error()}
+	}
+	// Keys are stored unprotected in OpenSSH form; the vault encryption
+	// replaces any passphrase they were imported with.
+	openssh, err := sshkeys.Export(signer, sshkeys.FormatOpenSSH, "", csr.Comment)
+	if err != nil {
+		return nil, err
+	}
+	ek, err := sh.Ep.Encrypt(string(openssh))
+	if err != nil {
+		return nil, err
+	}
+
+	return SshKey{
+		Id:          id,
+		Name:        csr.Name,
+		KeyType:     string(info.Type),
+		Bits:        info.Bits,
+		PrivateKey:  []byte(ek),
+		PublicKey:   info.PublicKey,
+		Fingerprint: info.Fingerprint,
+		Comment:     csr.Comment,
+		Vault:       *v,
+		Expiry:      csr.expiry(),
+		Placement:   csr.placement(),
+	}, nil
+}
+
+func (sh *SecretHandler) buildCertificateAuthority(csr *CreateSecretRequest, v *Vault, id string) (Securable, error) {
+	for _, domain := range csr.AllowedDomains {
+		if domain == "" || strings.ContainsAny(domain, ", ") {
+			return nil, errInvalidBody
+		}
+	}
+
+	var ca *pki.CA
+	var err error
+	if csr.Certificate != "" || csr.PrivateKey != "" {
+		ca, err = pki.LoadCA([]byte(csr.Certificate), []byte(csr.PrivateKey))
+	} else if csr.CommonName == "" {
+		return nil, errInvalidBody
+	} else {
+		keyType := sshkeys.KeyType(strings.ToUpper(csr.KeyType))
+		if keyType == "" {
+			keyType = sshkeys.KeyTypeECDSA
+		}
+		var key crypto.Signer
+		if key, err = sshkeys.Generate(keyType, csr.Bits); err == nil {
+			subject := pki.Subject{CommonName: csr.CommonName, Organization: csr.Organization}
+			ca, err = pki.NewCA(key, subject, time.Duration(csr.TtlDays)*24*time.Hour, time.Now())
+		}
+	}
+	if err != nil {
+		if errors.Is(err, pki.ErrInvalidCertificate) || errors.Is(err, pki.ErrInvalidPrivateKey) ||
+			errors.Is(err, pki.ErrKeyMismatch) || errors.Is(err, sshkeys.ErrUnsupportedKey) {
+			return nil, requestError{err.Error()}
+		}
+		return nil, err
+	}
+
+	keyPEM, err := pki.EncodePrivateKey(ca.Key)
+	if err != nil {
+		return nil, err
+	}
+	ek, err := sh.Ep.Encrypt(string(keyPEM))
+	if err != nil {
+		return nil, err
+	}
+
+	// The CA's own expiry feeds the reminder scheduler unless the caller
+	// asked to be reminded earlier.
+	expiry := csr.expiry()
+	if expiry.ExpiresAt == nil {
+		notAfter := ca.Certificate.NotAfter.UTC()
+		expiry.ExpiresAt = &notAfter
+	}
+	maxTtl := csr.MaxTtlSeconds
+	if maxTtl == 0 {
+		maxTtl = int(pki.DefaultMaxTTL / time.Second)
+	}
+
+	return CertificateAuthority{
+		Id:              id,
+		Name:            csr.Name,
+		Subject:         ca.Certificate.Subject.String(),
+		Certificate:     string(pki.EncodeCertificate(ca.Certificate)),
+		PrivateKey:      []byte(ek),
+		AllowedDomains:  strings.Join(csr.AllowedDomains, ","),
+		AllowSubdomains: csr.AllowSubdomains,
+		AllowIpSans:     csr.AllowIpSans,
+		MaxTtlSeconds:   maxTtl,
+		Vault:           *v,
+		Expiry:          expiry,
+		Placement:       csr.placement(),
+	}, nil
+}
+
+func (sh *SecretHandler) buildCard(csr *CreateSecretRequest, v *Vault, id string) (Securable, error) {
+	number, ok := normalizeCardNumber(csr.CardNumber)
+	if !ok {
+		return nil, requestError{"Invalid card number"}
+	}
+	brand := cardBrand(number)
+	if !validCvv(csr.Cvv, brand) {
+		return nil, requestError{"Invalid CVV"}
+	}
+	if csr.ExpiryMonth == 0 || csr.ExpiryYear == 0 {
+		return nil, errInvalidBody
+	}
+
+	en, err := sh.Ep.Encrypt(number)
+	if err != nil {
+		return nil, err
+	}
+	ec, err := sh.encryptOptional(csr.Cvv)
+	if err != nil {
+		return nil, err
+	}
+
+	// A card expires with its expiry month unless an earlier reminder date
+	// was asked for.
+	expiry := csr.expiry()
+	if expiry.ExpiresAt == nil {
+		expiresAt := cardExpiresAt(csr.ExpiryMonth, csr.ExpiryYear)
+		expiry.ExpiresAt = &expiresAt
+	}
+
+	return Card{
+		Id:             id,
+		Name:           csr.Name,
+		CardholderName: csr.CardholderName,
+		Number:         []byte(en),
+		Last4:          lastFour(number),
+		Brand:          brand,
+		ExpiryMonth:    csr.ExpiryMonth,
+		ExpiryYear:     csr.ExpiryYear,
+		Cvv:            ec,
+		Vault:          *v,
+		Expiry:         expiry,
+		Placement:      csr.placement(),
+	}, nil
+}
+
+func (sh *SecretHandler) buildIdentity(csr *CreateSecretRequest, v *Vault, id string) (Securable, error) {
+	if csr.FullName == "" {
+		return nil, errInvalidBody
+	}
+
+	i := Identity{
+		Id:            id,
+		Name:          csr.Name,
+		FullName:      csr.FullName,
+		Email:         csr.Email,
+		Phone:         csr.Phone,
+		PassportLast4: lastFour(csr.PassportNumber),
+		LicenceLast4:  lastFour(csr.LicenceNumber),
+		Vault:         *v,
+		Expiry:        csr.expiry(),
+		Placement:     csr.placement(),
+	}
+	for _, field := range []struct {
+		plain     string
+		encrypted *[]byte
+	}{
+		{csr.Address, &i.Address},
+		{csr.PassportNumber, &i.PassportNumber},
+		{csr.LicenceNumber, &i.LicenceNumber},
+	} {
+		value, err := sh.encryptOptional(field.plain)
+		if err != nil {
+			return nil, err
+		}
+		*field.encrypted = value
+	}
+	return i, nil
+}
+
+// created audits the creation of a secret and responds with it.
+func (sh *SecretHandler) created(ctx *gin.Context, s Securable, fields []CustomField) {
+	id := s.Metadata().Id
+	indexSecrets(sh.Index, ctx.Param("id"), id)
+
+	audit.Log(sh.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretWrite,
+		TargetType: audit.TargetSecret,
+		TargetId:   id,
+		Detail:     string(s.Type()),
+	})
 	sr := SecretResponse{}
-	sr.load(c)
+	sr.load(s)
+	sr.loadFields(fields)
 
 	ctx.JSON(
 		http.StatusCreated,
 		sr,
 	)
 }
+
+// encryptOptional leaves empty values empty rather than storing an
+// encrypted empty string.
+func (sh *SecretHandler) encryptOptional(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+	encrypted, err := sh.Ep.Encrypt(value)
+	return []byte(encrypted), err
+}
+
+func (sh *SecretHandler) decryptOptional(value []byte) (string, error) {
+	if len(value) == 0 {
+		return "", nil
+	}
+	return sh.Ep.Decrypt(string(value))
+}
@@ -1,20 +1,687 @@
 package vaults
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
 
 type SecretRepository interface {
-	CreateCredential(credential *Credential) error
+	CreateCredential(credential *Credential, fields []CustomField) error
 	FindCredentials(credentials *[]Credential, vaultId string) error
+	FindKeys(keys *[]Key, vaultId string) error
+	FindDocuments(documents *[]Document, vaultId string) error
+	CreateTotp(t *Totp, fields []CustomField) error
+	FindTotps(totps *[]Totp, vaultId string) error
+	FindTotpById(id string, vaultId string, t *Totp) error
+	CreateSshKey(k *SshKey, fields []CustomField) error
+	FindSshKeys(keys *[]SshKey, vaultId string) error
+	FindSshKeyById(id string, vaultId string, k *SshKey) error
+	CreateCertificateAuthority(ca *CertificateAuthority, fields []CustomField) error
+	FindCertificateAuthorities(cas *[]CertificateAuthority, vaultId string) error
+	FindCertificateAuthorityById(id string, vaultId string, ca *CertificateAuthority) error
+	CreateCard(c *Card, fields []CustomField) error
+	FindCards(cards *[]Card, vaultId string) error
+	FindCardById(id string, vaultId string, c *Card) error
+	CreateIdentity(i *Identity, fields []CustomField) error
+	FindIdentities(identities *[]Identity, vaultId string) error
+	FindIdentityById(id string, vaultId string, i *Identity) error
+	FindSecretType(id string, vaultId string) (SecretType, error)
+	MoveSecret(secretType SecretType, id string, vaultId string, folderId *string) error
+	TagSecret(secretType SecretType, id string, vaultId string, tags []string) error
+	FindSummaries(ids []string, summaries *[]SecretSummary) error
+	FindPage(vaultId string, filter SecretFilter, pq PageQuery, summaries *[]SecretSummary) error
+	FindByRefs(refs []SecretSummary, secrets *[]Securable) error
+	FindScheduled(userId string, secrets *[]ScheduledSecret) error
+	MarkReminded(secretType SecretType, ids []string, at time.Time) error
+	DeleteSecret(secretType SecretType, id string, vaultId string) error
+	FindTrash(userId string, deletedBefore time.Time, secrets *[]TrashedSecret) error
+	FindTrashedSecret(id string, userId string, s *TrashedSecret) error
+	RestoreSecret(secretType SecretType, id string) error
+	PurgeSecret(secretType SecretType, id string) error
+	MoveSecrets(refs []SecretSummary, vaultId string, folderId *string) error
+	CopySecrets(copies []SecretCopy, vaultId string, folderId *string, attachments []Attachment) error
+	ApplyBatch(vaultId string, writes []SecretWrite, atomic bool) ([]error, error)
+	ImportSecrets(vaultId string, folders []Folder, writes []SecretWrite) error
+	RestoreVaults(restores []VaultRestore) error
+}
+
+// SecretCopy pairs a secret with the id its copy is created under.
+type SecretCopy struct {
+	Type  SecretType
+	Id    string
+	NewId string
+}
+
+// SecretWrite is one operation of a batch. Creates and updates carry the
+// whole secret with its fields; an update replaces everything but the id
+// and creation time. Deletes move the secret to the trash.
+type SecretWrite struct {
+	Op     BatchOp
+	Type   SecretType
+	Id     string
+	Secret Securable
+	Fields []CustomField
+}
+
+// VaultRestore is a vault to create along with the folders and secrets
+// restored into it.
+type VaultRestore struct {
+	Vault   Vault
+	Folders []Folder
+	Writes  []SecretWrite
+}
+
+// errBatchRolledBack aborts the transaction of an atomic batch after one of
+// its writes failed.
+var errBatchRolledBack = errors.New("batch rolled back")
+
+// secretTables lists the table behind every secret type, for queries that
+// span all of them.
+var secretTables = []struct {
+	secretType SecretType
+	table      string
+}{
+	{TypeCredential, "credentials"},
+	{TypeKey, "keys"},
+	{TypeDocument, "documents"},
+	{TypeTotp, "totps"},
+	{TypeSshKey, "ssh_keys"},
+	{TypeCertificateAuthority, "certificate_authorities"},
+	{TypeCard, "cards"},
+	{TypeIdentity, "identities"},
+}
+
+func secretTable(secretType SecretType) (string, error) {
+	for _, st := range secretTables {
+		if st.secretType == secretType {
+			return st.table, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown secret type %s", secretType)
 }
 
 type SecretRepositoryImpl struct {
 	Db *gorm.DB
 }
 
-func (sr *SecretRepositoryImpl) CreateCredential(credential *Credential) error {
-	return sr.Db.Create(credential).Error
+func (sr *SecretRepositoryImpl) CreateCredential(credential *Credential, fields []CustomField) error {
+	return sr.createSecret(credential, fields)
+}
+
+// createSecret stores a new secret along with its custom fields, both or
+// neither.
+func (sr *SecretRepositoryImpl) createSecret(record any, fields []CustomField) error {
+	return sr.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		return tx.Create(&fields).Error
+	})
 }
 
 func (sr *SecretRepositoryImpl) FindCredentials(credentials *[]Credential, vaultId string) error {
 	return sr.Db.Where("vault_refer = ?", vaultId).Order("updated_at DESC, id DESC").Find(credentials).Error
 }
+
+func (sr *SecretRepositoryImpl) FindKeys(keys *[]Key, vaultId string) error {
+	return sr.Db.Where("vault_refer = ?", vaultId).Order("updated_at DESC, id DESC").Find(keys).Error
+}
+
+func (sr *SecretRepositoryImpl) FindDocuments(documents *[]Document, vaultId string) error {
+	return sr.Db.Where("vault_refer = ?", vaultId).Order("updated_at DESC, id DESC").Find(documents).Error
+}
+
+func (sr *SecretRepositoryImpl) CreateTotp(t *Totp, fields []CustomField) error {
+	return sr.createSecret(t, fields)
+}
+
+func (sr *SecretRepositoryImpl) FindTotps(totps *[]Totp, vaultId string) error {
+	return sr.Db.Where("vault_refer = ?", vaultId).Order("updated_at DESC, id DESC").Find(totps).Error
+}
+
+func (sr *SecretRepositoryImpl) FindTotpById(id string, vaultId string, t *Totp) error {
+	return sr.Db.Where("id = ? AND vault_refer = ?", id, vaultId).First(t).Error
+}
+
+func (sr *SecretRepositoryImpl) CreateSshKey(k *SshKey, fields []CustomField) error {
+	return sr.createSecret(k, fields)
+}
+
+func (sr *SecretRepositoryImpl) FindSshKeys(keys *[]SshKey, vaultId string) error {
+	return sr.Db.Where("vault_refer = ?", vaultId).Order("updated_at DESC, id DESC").Find(keys).Error
+}
+
+func (sr *SecretRepositoryImpl) FindSshKeyById(id string, vaultId string, k *SshKey) error {
+	return sr.Db.Where("id = ? AND vault_refer = ?", id, vaultId).First(k).Error
+}
+
+func (sr *SecretRepositoryImpl) CreateCertificateAuthority(ca *CertificateAuthority, fields []CustomField) error {
+	return sr.createSecret(ca, fields)
+}
+
+func (sr *SecretRepositoryImpl) FindCertificateAuthorities(cas *[]CertificateAuthority, vaultId string) error {
+	return sr.Db.Where("vault_refer = ?", vaultId).Order("updated_at DESC, id DESC").Find(cas).Error
+}
+
+func (sr *SecretRepositoryImpl) FindCertificateAuthorityById(id string, vaultId string, ca *CertificateAuthority) error {
+	return sr.Db.Where("id = ? AND vault_refer = ?", id, vaultId).First(ca).Error
+}
+
+func (sr *SecretRepositoryImpl) CreateCard(c *Card, fields []CustomField) error {
+	return sr.createSecret(c, fields)
+}
+
+func (sr *SecretRepositoryImpl) FindCards(cards *[]Card, vaultId string) error {
+	return sr.Db.Where("vault_refer = ?", vaultId).Order("updated_at DESC, id DESC").Find(cards).Error
+}
+
+func (sr *SecretRepositoryImpl) FindCardById(id string, vaultId string, c *Card) error {
+	return sr.Db.Where("id = ? AND vault_refer = ?", id, vaultId).First(c).Error
+}
+
+func (sr *SecretRepositoryImpl) CreateIdentity(i *Identity, fields []CustomField) error {
+	return sr.createSecret(i, fields)
+}
+
+func (sr *SecretRepositoryImpl) FindIdentities(identities *[]Identity, vaultId string) error {
+	return sr.Db.Where("vault_refer = ?", vaultId).Order("updated_at DESC, id DESC").Find(identities).Error
+}
+
+func (sr *SecretRepositoryImpl) FindIdentityById(id string, vaultId string, i *Identity) error {
+	return sr.Db.Where("id = ? AND vault_refer = ?", id, vaultId).First(i).Error
+}
+
+// FindSecretType looks a secret up across every secret table and reports
+// which type it is, or gorm.ErrRecordNotFound.
+func (sr *SecretRepositoryImpl) FindSecretType(id string, vaultId string) (SecretType, error) {
+	for _, st := range secretTables {
+		var count int64
+		if err := sr.Db.Table(st.table).Where("id = ? AND vault_refer = ? AND deleted_at IS NULL", id, vaultId).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return st.secretType, nil
+		}
+	}
+	return "", gorm.ErrRecordNotFound
+}
+
+// FindSummaries returns the plaintext metadata of the given secrets,
+// whatever their types, along with the names of their vaults.
+func (sr *SecretRepositoryImpl) FindSummaries(ids []string, summaries *[]SecretSummary) error {
+	*summaries = []SecretSummary{}
+	for _, st := range secretTables {
+		var found []SecretSummary
+		err := sr.Db.Table(st.table).
+			Select(fmt.Sprintf("%[1]s.id, %[1]s.name, '%[2]s' AS type, %[1]s.vault_refer AS vault_id, vaults.name AS vault_name, "+
+				"%[1]s.updated_at, %[1]s.folder_id, %[1]s.tags", st.table, st.secretType)).
+			Joins(fmt.Sprintf("JOIN vaults ON vaults.id = %s.vault_refer", st.table)).
+			Where(fmt.Sprintf("%[1]s.id IN ? AND %[1]s.deleted_at IS NULL AND vaults.deleted_at IS NULL", st.table), ids).
+			Scan(&found).Error
+		if err != nil {
+			return err
+		}
+		*summaries = append(*summaries, found...)
+	}
+	return nil
+}
+
+// FindPage lists one page of the secrets of a vault across every secret
+// table, with up to pq.Limit+1 rows as FetchPageByUserId does. The tables
+// are combined in a single query so that the database alone orders them.
+func (sr *SecretRepositoryImpl) FindPage(vaultId string, filter SecretFilter, pq PageQuery, summaries *[]SecretSummary) error {
+	*summaries = []SecretSummary{}
+	var selects []string
+	var tables []any
+	for _, st := range secretTables {
+		if filter.Type != "" && filter.Type != st.secretType {
+			continue
+		}
+		selects = append(selects, "?")
+		tables = append(tables, sr.Db.Table(st.table).
+			Select(fmt.Sprintf("id, name, '%s' AS type, vault_refer AS vault_id, created_at, updated_at, folder_id, tags", st.secretType)).
+			Where("vault_refer = ? AND deleted_at IS NULL", vaultId))
+	}
+	if len(selects) == 0 {
+		return nil
+	}
+
+	query := sr.Db.Table("(?) AS secrets", sr.Db.Raw(strings.Join(selects, " UNION ALL "), tables...))
+	if filter.FolderIds != nil {
+		query = query.Where("folder_id IN ?", filter.FolderIds)
+	}
+	if filter.Tag != "" {
+		query = query.Where(`(',' || LOWER(tags) || ',') LIKE ? ESCAPE '\'`, "%,"+escapeLike(strings.ToLower(filter.Tag))+",%")
+	}
+	return query.Scopes(pq.scope).Scan(summaries).Error
+}
+
+// FindByRefs loads the secrets listed by FindPage, in the same order.
+func (sr *SecretRepositoryImpl) FindByRefs(refs []SecretSummary, secrets *[]Securable) error {
+	idsByType := make(map[SecretType][]string)
+	for _, r := range refs {
+		idsByType[r.Type] = append(idsByType[r.Type], r.Id)
+	}
+
+	found := make(map[string]Securable, len(refs))
+	for secretType, ids := range idsByType {
+		var err error
+		switch secretType {
+		case TypeCredential:
+			err = findByIds[Credential](sr.Db, ids, found)
+		case TypeKey:
+			err = findByIds[Key](sr.Db, ids, found)
+		case TypeDocument:
+			err = findByIds[Document](sr.Db, ids, found)
+		case TypeTotp:
+			err = findByIds[Totp](sr.Db, ids, found)
+		case TypeSshKey:
+			err = findByIds[SshKey](sr.Db, ids, found)
+		case TypeCertificateAuthority:
+			err = findByIds[CertificateAuthority](sr.Db, ids, found)
+		case TypeCard:
+			err = findByIds[Card](sr.Db, ids, found)
+		case TypeIdentity:
+			err = findByIds[Identity](sr.Db, ids, found)
+		default:
+			err = fmt.Errorf("Unknown secret type %s", secretType)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	*secrets = make([]Securable, 0, len(refs))
+	for _, r := range refs {
+		if s, ok := found[r.Id]; ok {
+			*secrets = append(*secrets, s)
+		}
+	}
+	return nil
+}
+
+func findByIds[T Securable](db *gorm.DB, ids []string, found map[string]Securable) error {
+	var rows []T
+	if err := db.Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		found[row.Metadata().Id] = row
+	}
+	return nil
+}
+
+// FindScheduled returns every secret with an expiry or rotation period,
+// across all secret types. An empty userId searches every user's vaults.
+func (sr *SecretRepositoryImpl) FindScheduled(userId string, secrets *[]ScheduledSecret) error {
+	*secrets = []ScheduledSecret{}
+	for _, st := range secretTables {
+		var found []ScheduledSecret
+		// The type is written into the query rather than bound, as Postgres
+		// cannot infer the type of a parameter in the select list. It only
+		// ever comes from secretTables.
+		query := sr.Db.Table(st.table).
+			Select(fmt.Sprintf("%[1]s.id, %[1]s.name, '%[2]s' AS type, %[1]s.vault_refer AS vault_id, vaults.name AS vault_name, "+
+				"vaults.user_refer AS user_id, users.email AS user_email, %[1]s.updated_at, %[1]s.expires_at, "+
+				"%[1]s.rotate_every_days, %[1]s.last_reminded_at", st.table, st.secretType)).
+			Joins(fmt.Sprintf("JOIN vaults ON vaults.id = %s.vault_refer", st.table)).
+			Joins("JOIN users ON users.id = vaults.user_refer").
+			Where(fmt.Sprintf("(%[1]s.expires_at IS NOT NULL OR %[1]s.rotate_every_days > 0) AND %[1]s.deleted_at IS NULL AND vaults.deleted_at IS NULL", st.table))
+		if userId != "" {
+			query = query.Where("vaults.user_refer = ?", userId)
+		}
+		if err := query.Scan(&found).Error; err != nil {
+			return err
+		}
+		*secrets = append(*secrets, found...)
+	}
+	return nil
+}
+
+// MarkReminded records when a reminder was sent without touching
+// updated_at, which drives the rotation schedule.
+func (sr *SecretRepositoryImpl) MarkReminded(secretType SecretType, ids []string, at time.Time) error {
+	table, err := secretTable(secretType)
+	if err != nil {
+		return err
+	}
+	return sr.Db.Table(table).Where("id IN ?", ids).UpdateColumn("last_reminded_at", at).Error
+}
+
+// MoveSecret puts a secret of any type in a folder, or at the top level
+// when folderId is nil.
+func (sr *SecretRepositoryImpl) MoveSecret(secretType SecretType, id string, vaultId string, folderId *string) error {
+	table, err := secretTable(secretType)
+	if err != nil {
+		return err
+	}
+	return sr.Db.Table(table).Where("id = ? AND vault_refer = ?", id, vaultId).Update("folder_id", folderId).Error
+}
+
+func (sr *SecretRepositoryImpl) TagSecret(secretType SecretType, id string, vaultId string, tags []string) error {
+	table, err := secretTable(secretType)
+	if err != nil {
+		return err
+	}
+	return sr.Db.Table(table).Where("id = ? AND vault_refer = ?", id, vaultId).Update("tags", strings.Join(tags, ",")).Error
+}
+
+// DeleteSecret moves a secret of any type to the trash.
+func (sr *SecretRepositoryImpl) DeleteSecret(secretType SecretType, id string, vaultId string) error {
+	table, err := secretTable(secretType)
+	if err != nil {
+		return err
+	}
+	return sr.Db.Table(table).Where("id = ? AND vault_refer = ? AND deleted_at IS NULL", id, vaultId).UpdateColumn("deleted_at", time.Now()).Error
+}
+
+// FindTrash returns the secrets deleted before deletedBefore, most recently
+// deleted first, as FetchTrash does for vaults. Secrets of a trashed vault
+// are left out: they are restored or purged with the vault.
+func (sr *SecretRepositoryImpl) FindTrash(userId string, deletedBefore time.Time, secrets *[]TrashedSecret) error {
+	query := sr.trashQuery().Where("secrets.deleted_at < ?", deletedBefore)
+	if userId != "" {
+		query = query.Where("vaults.user_refer = ?", userId)
+	}
+	*secrets = []TrashedSecret{}
+	return query.Order("secrets.deleted_at DESC, secrets.id DESC").Scan(secrets).Error
+}
+
+func (sr *SecretRepositoryImpl) FindTrashedSecret(id string, userId string, s *TrashedSecret) error {
+	var found []TrashedSecret
+	if err := sr.trashQuery().Where("secrets.id = ? AND vaults.user_refer = ?", id, userId).Limit(1).Scan(&found).Error; err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	*s = found[0]
+	return nil
+}
+
+// trashQuery selects the trashed secrets of every type whose vault is not
+// itself in the trash.
+func (sr *SecretRepositoryImpl) trashQuery() *gorm.DB {
+	var selects []string
+	var tables []any
+	for _, st := range secretTables {
+		selects = append(selects, "?")
+		tables = append(tables, sr.Db.Table(st.table).
+			Select(fmt.Sprintf("id, name, '%s' AS type, vault_refer, created_at, updated_at, deleted_at, folder_id, tags", st.secretType)).
+			Where("deleted_at IS NOT NULL"))
+	}
+	return sr.Db.Table("(?) AS secrets", sr.Db.Raw(strings.Join(selects, " UNION ALL "), tables...)).
+		Select("secrets.id, secrets.name, secrets.type, secrets.vault_refer AS vault_id, vaults.name AS vault_name, " +
+			"secrets.created_at, secrets.updated_at, secrets.deleted_at, secrets.folder_id, secrets.tags").
+		Joins("JOIN vaults ON vaults.id = secrets.vault_refer").
+		Where("vaults.deleted_at IS NULL")
+}
+
+func (sr *SecretRepositoryImpl) RestoreSecret(secretType SecretType, id string) error {
+	table, err := secretTable(secretType)
+	if err != nil {
+		return err
+	}
+	return sr.Db.Table(table).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error
+}
+
+// PurgeSecret permanently removes a secret along with its fields,
+// attachment records, search entries and, for certificate authorities and
+// SSH keys, the certificates and roles that depend on it.
+func (sr *SecretRepositoryImpl) PurgeSecret(secretType SecretType, id string) error {
+	table, err := secretTable(secretType)
+	if err != nil {
+		return err
+	}
+	return sr.Db.Transaction(func(tx *gorm.DB) error {
+		for _, dependent := range []any{&CustomField{}, &Attachment{}, &SearchEntry{}} {
+			if err := tx.Where("secret_id = ?", id).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		switch secretType {
+		case TypeCertificateAuthority:
+			if err := tx.Where("ca_refer = ?", id).Delete(&IssuedCertificate{}).Error; err != nil {
+				return err
+			}
+		case TypeSshKey:
+			if err := tx.Where("ca_key_refer = ?", id).Delete(&SshCaRole{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = ?", table), id).Error
+	})
+}
+
+// MoveSecrets hands secrets to another vault together with their fields,
+// attachments and, for SSH keys, the CA roles that sign with them. The rows
+// are updated in place, so ids and timestamps, and with them rotation
+// schedules and issued certificates, carry over.
+func (sr *SecretRepositoryImpl) MoveSecrets(refs []SecretSummary, vaultId string, folderId *string) error {
+	ids := make([]string, len(refs))
+	for i, r := range refs {
+		ids[i] = r.Id
+	}
+	return sr.Db.Transaction(func(tx *gorm.DB) error {
+		for _, r := range refs {
+			table, err := secretTable(r.Type)
+			if err != nil {
+				return err
+			}
+			err = tx.Table(table).Where("id = ?", r.Id).
+				UpdateColumns(map[string]any{"vault_refer": vaultId, "folder_id": folderId}).Error
+			if err != nil {
+				return err
+			}
+		}
+		for _, dependent := range []any{&CustomField{}, &Attachment{}} {
+			if err := tx.Model(dependent).Where("secret_id IN ?", ids).UpdateColumn("vault_refer", vaultId).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&SshCaRole{}).Where("ca_key_refer IN ?", ids).UpdateColumn("vault_refer", vaultId).Error; err != nil {
+			return err
+		}
+		return tx.Where("secret_id IN ?", ids).Delete(&SearchEntry{}).Error
+	})
+}
+
+// CopySecrets duplicates secrets into a vault with their fields, keeping
+// their timestamps, and records attachments whose blobs the caller has
+// already copied. Certificates a CA issued and SSH CA roles stay with the
+// original.
+func (sr *SecretRepositoryImpl) CopySecrets(copies []SecretCopy, vaultId string, folderId *string, attachments []Attachment) error {
+	newIds := make(map[string]string, len(copies))
+	ids := make([]string, len(copies))
+	for i, c := range copies {
+		newIds[c.Id] = c.NewId
+		ids[i] = c.Id
+	}
+	return sr.Db.Transaction(func(tx *gorm.DB) error {
+		for _, c := range copies {
+			table, err := secretTable(c.Type)
+			if err != nil {
+				return err
+			}
+			row := map[string]any{}
+			if err := tx.Table(table).Where("id = ?", c.Id).Take(&row).Error; err != nil {
+				return err
+			}
+			row["id"] = c.NewId
+			row["vault_refer"] = vaultId
+			row["folder_id"] = folderId
+			row["last_reminded_at"] = nil
+			if err := tx.Table(table).Create(row).Error; err != nil {
+				return err
+			}
+		}
+
+		var fields []CustomField
+		if err := tx.Where("secret_id IN ?", ids).Find(&fields).Error; err != nil {
+			return err
+		}
+		for i := range fields {
+			fields[i].Id = uuid.NewString()
+			fields[i].SecretId = newIds[fields[i].SecretId]
+			fields[i].VaultRefer = vaultId
+		}
+		if len(fields) > 0 {
+			if err := tx.Create(&fields).Error; err != nil {
+				return err
+			}
+		}
+		if len(attachments) == 0 {
+			return nil
+		}
+		return tx.Create(&attachments).Error
+	})
+}
+
+// ApplyBatch makes the writes in one transaction and returns the error of
+// each, nil for those that were applied. In an atomic batch the first
+// failure rolls back every write; otherwise each write runs in its own
+// savepoint so that only the failed ones are undone. The second error is
+// for failures of the transaction itself.
+func (sr *SecretRepositoryImpl) ApplyBatch(vaultId string, writes []SecretWrite, atomic bool) ([]error, error) {
+	errs := make([]error, len(writes))
+	err := sr.Db.Transaction(func(tx *gorm.DB) error {
+		for i, w := range writes {
+			if atomic {
+				if errs[i] = applyWrite(tx, vaultId, w); errs[i] != nil {
+					return errBatchRolledBack
+				}
+				continue
+			}
+
+			savepoint := fmt.Sprintf("batch_write_%d", i)
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+			if errs[i] = applyWrite(tx, vaultId, w); errs[i] != nil {
+				if err := tx.RollbackTo(savepoint).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchRolledBack) {
+		err = nil
+	}
+	return errs, err
+}
+
+// ImportSecrets creates folders, parents before their subfolders, and then
+// secrets, all or none of them.
+func (sr *SecretRepositoryImpl) ImportSecrets(vaultId string, folders []Folder, writes []SecretWrite) error {
+	return sr.Db.Transaction(func(tx *gorm.DB) error {
+		return importSecrets(tx, vaultId, folders, writes)
+	})
+}
+
+// RestoreVaults creates vaults with their folders and secrets, all or none
+// of them.
+func (sr *SecretRepositoryImpl) RestoreVaults(restores []VaultRestore) error {
+	return sr.Db.Transaction(func(tx *gorm.DB) error {
+		for i := range restores {
+			r := &restores[i]
+			if err := tx.Create(&r.Vault).Error; err != nil {
+				return err
+			}
+			if err := importSecrets(tx, r.Vault.Id, r.Folders, r.Writes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func importSecrets(tx *gorm.DB, vaultId string, folders []Folder, writes []SecretWrite) error {
+	for i := range folders {
+		if err := tx.Create(&folders[i]).Error; err != nil {
+			return err
+		}
+	}
+	for _, w := range writes {
+		if err := applyWrite(tx, vaultId, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyWrite(tx *gorm.DB, vaultId string, w SecretWrite) error {
+	if w.Op == BatchDelete {
+		table, err := secretTable(w.Type)
+		if err != nil {
+			return err
+		}
+		result := tx.Table(table).Where("id = ? AND vault_refer = ? AND deleted_at IS NULL", w.Id, vaultId).UpdateColumn("deleted_at", time.Now())
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	}
+
+	record, err := secretRecord(w.Secret)
+	if err != nil {
+		return err
+	}
+	switch w.Op {
+	case BatchCreate:
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+	case BatchUpdate:
+		result := tx.Model(record).Where("vault_refer = ?", vaultId).
+			Select("*").Omit("id", "created_at", "deleted_at", "vault_refer", "Vault").Updates(record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Where("secret_id = ?", w.Id).Delete(&CustomField{}).Error; err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown batch operation %s", w.Op)
+	}
+
+	if len(w.Fields) == 0 {
+		return nil
+	}
+	return tx.Create(&w.Fields).Error
+}
+
+// secretRecord returns a pointer to the concrete secret behind s, which is
+// what gorm needs to find its table.
+func secretRecord(s Securable) (any, error) {
+	switch secret := s.(type) {
+	case Credential:
+		return &secret, nil
+	case Key:
+		return &secret, nil
+	case Document:
+		return &secret, nil
+	case Totp:
+		return &secret, nil
+	case SshKey:
+		return &secret, nil
+	case CertificateAuthority:
+		return &secret, nil
+	case Card:
+		return &secret, nil
+	case Identity:
+		return &secret, nil
+	}
+	return nil, fmt.Errorf("Unknown secret %T", s)
+}
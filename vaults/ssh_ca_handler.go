@@ -0,0 +1,213 @@
+package vaults
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SshCaHandler lets a vault act as an SSH certificate authority. Each role
+// pairs an SshKey of the vault with the policy it signs under.
+type SshCaHandler struct {
+	Ep         utils.EncryptionProvider
+	Repo       SshCaRepository
+	SecretRepo SecretRepository
+	VaultRepo  VaultRepository
+	Audit      audit.Recorder
+}
+
+func (ch *SshCaHandler) CreateRole(ctx *gin.Context) {
+	var csr CreateSshRoleRequest
+	if err := ctx.ShouldBindJSON(&csr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	vaultId, ok := requireVaultOwner(ctx, ch.VaultRepo)
+	if !ok {
+		return
+	}
+
+	policy := csr.policy()
+	if err := policy.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	var caKey SshKey
+	if err := ch.SecretRepo.FindSshKeyById(csr.CaKeyId, vaultId, &caKey); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+
+	r := SshCaRole{
+		Id:                     uuid.NewString(),
+		Name:                   csr.Name,
+		CertType:               string(policy.CertType),
+		AllowedPrincipals:      strings.Join(policy.AllowedPrincipals, ","),
+		DefaultPrincipals:      strings.Join(policy.DefaultPrincipals, ","),
+		MaxTtlSeconds:          int(policy.MaxTTL / time.Second),
+		DefaultTtlSeconds:      int(policy.DefaultTTL / time.Second),
+		AllowedExtensions:      strings.Join(policy.AllowedExtensions, ","),
+		DefaultExtensions:      strings.Join(policy.DefaultExtensions, ","),
+		AllowedCriticalOptions: strings.Join(policy.AllowedCriticalOptions, ","),
+		CaKeyRefer:             caKey.Id,
+		VaultRefer:             vaultId,
+	}
+	if err := ch.Repo.CreateRole(&r); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(ch.Audit, ctx, audit.Event{
+		Action:     audit.ActionSshRoleCreate,
+		TargetType: audit.TargetSshRole,
+		TargetId:   r.Id,
+		Detail:     fmt.Sprintf("%s role %q signed by %s", r.CertType, r.Name, r.CaKeyRefer),
+	})
+
+	var srr SshRoleResponse
+	srr.load(r)
+	ctx.JSON(http.StatusCreated, srr)
+}
+
+func (ch *SshCaHandler) FetchRoles(ctx *gin.Context) {
+	vaultId, ok := requireVaultOwner(ctx, ch.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var roles []SshCaRole
+	if err := ch.Repo.FindRoles(vaultId, &roles); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	resp := SshRoleListResponse{Roles: make([]SshRoleResponse, len(roles))}
+	for i, r := range roles {
+		resp.Roles[i].load(r)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (ch *SshCaHandler) DeleteRole(ctx *gin.Context) {
+	vaultId, ok := requireVaultOwner(ctx, ch.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var r SshCaRole
+	if err := ch.Repo.FindRoleById(ctx.Param("roleId"), vaultId, &r); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+	if err := ch.Repo.DeleteRole(&r); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(ch.Audit, ctx, audit.Event{
+		Action:     audit.ActionSshRoleDelete,
+		TargetType: audit.TargetSshRole,
+		TargetId:   r.Id,
+		Detail:     r.Name,
+	})
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// SignCertificate issues a certificate under a role. Requests refused by the
+// role's policy are audited as failures so denied attempts stay visible.
+func (ch *SshCaHandler) SignCertificate(ctx *gin.Context) {
+	var scr SignSshCertificateRequest
+	if err := ctx.ShouldBindJSON(&scr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return
+	}
+
+	vaultId, ok := requireVaultOwner(ctx, ch.VaultRepo)
+	if !ok {
+		return
+	}
+
+	var r SshCaRole
+	if err := ch.Repo.FindRoleById(ctx.Param("roleId"), vaultId, &r); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+	var caKey SshKey
+	if err := ch.SecretRepo.FindSshKeyById(r.CaKeyRefer, vaultId, &caKey); err != nil {
+		handleGormError(ctx, err)
+		return
+	}
+
+	privateKey, err := ch.Ep.Decrypt(string(caKey.PrivateKey))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	ca, err := sshkeys.Parse([]byte(privateKey), "")
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	keyId := scr.KeyId
+	if keyId == "" {
+		keyId = fmt.Sprintf("%s:%s", ctx.GetString("user_id"), r.Name)
+	}
+	cert, err := r.Policy().Sign(ca, sshkeys.CertificateRequest{
+		PublicKey:       scr.PublicKey,
+		KeyId:           keyId,
+		Principals:      scr.Principals,
+		TTL:             time.Duration(scr.TtlSeconds) * time.Second,
+		Extensions:      scr.Extensions,
+		CriticalOptions: scr.CriticalOptions,
+	}, time.Now())
+	if err != nil {
+		if errors.Is(err, sshkeys.ErrPolicyViolation) || errors.Is(err, sshkeys.ErrInvalidPublicKey) {
+			audit.Log(ch.Audit, ctx, audit.Event{
+				Action:     audit.ActionSshCertIssue,
+				TargetType: audit.TargetSshRole,
+				TargetId:   r.Id,
+				Detail:     err.Error(),
+				Outcome:    audit.OutcomeFailure,
+			})
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(ch.Audit, ctx, audit.Event{
+		Action:     audit.ActionSshCertIssue,
+		TargetType: audit.TargetSshRole,
+		TargetId:   r.Id,
+		Detail: fmt.Sprintf(
+			"serial=%d key_id=%q principals=%s valid_before=%s",
+			cert.Serial, cert.KeyId, strings.Join(cert.Principals, ","), cert.ValidBefore.UTC().Format(time.RFC3339),
+		),
+	})
+
+	ctx.JSON(http.StatusOK, SshCertificateResponse{
+		Certificate:   cert.Certificate,
+		Serial:        strconv.FormatUint(cert.Serial, 10),
+		KeyId:         cert.KeyId,
+		CertType:      r.CertType,
+		Principals:    cert.Principals,
+		ValidAfter:    cert.ValidAfter.Unix(),
+		ValidBefore:   cert.ValidBefore.Unix(),
+		CaFingerprint: caKey.Fingerprint,
+	})
+}
@@ -0,0 +1,167 @@
+package vaults_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	audit_mocks "github.com/adarsh-a-tw/passwordly/audit/mocks"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/sshkeys"
+	utils_mocks "github.com/adarsh-a-tw/passwordly/utils/mocks"
+	v "github.com/adarsh-a-tw/passwordly/vaults"
+	vm "github.com/adarsh-a-tw/passwordly/vaults/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSshCaHandler_CreateRole_ShouldCreateRoleForVaultKey(t *testing.T) {
+	csr := v.CreateSshRoleRequest{
+		Name:              "deploy",
+		CaKeyId:           "mock-ca",
+		CertType:          "USER",
+		AllowedPrincipals: []string{"deploy", "ops"},
+		DefaultExtensions: []string{"permit-pty"},
+		AllowedExtensions: []string{"permit-pty"},
+		MaxTtlSeconds:     1800,
+	}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/ssh/roles", mockVault.Id), "POST", csr)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+
+	msr := vm.SecretRepository{}
+	msr.On("FindSshKeyById", "mock-ca", mockVault.Id, mock.AnythingOfType("*vaults.SshKey")).Run(func(args mock.Arguments) {
+		args.Get(2).(*v.SshKey).Id = "mock-ca"
+	}).Return(nil)
+	mcr := vm.SshCaRepository{}
+	mcr.On("CreateRole", mock.MatchedBy(func(r *v.SshCaRole) bool {
+		return r.CaKeyRefer == "mock-ca" && r.AllowedPrincipals == "deploy,ops" && r.MaxTtlSeconds == 1800 && r.DefaultTtlSeconds == 1800
+	})).Return(nil)
+	mvr, _ := ownedVaultMocks()
+
+	h := v.SshCaHandler{
+		Repo:       &mcr,
+		SecretRepo: &msr,
+		VaultRepo:  mvr,
+	}
+
+	h.CreateRole(ctx)
+
+	var resp v.SshRoleResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, []string{"deploy", "ops"}, resp.AllowedPrincipals)
+	assert.Equal(t, []string{"permit-pty"}, resp.DefaultExtensions)
+	mcr.AssertNumberOfCalls(t, "CreateRole", 1)
+}
+
+func TestSshCaHandler_CreateRole_ShouldRejectInconsistentPolicy(t *testing.T) {
+	csr := v.CreateSshRoleRequest{
+		Name:              "deploy",
+		CaKeyId:           "mock-ca",
+		CertType:          "USER",
+		AllowedPrincipals: []string{"deploy"},
+		DefaultPrincipals: []string{"root"},
+	}
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/ssh/roles", mockVault.Id), "POST", csr)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+
+	mvr, _ := ownedVaultMocks()
+
+	h := v.SshCaHandler{VaultRepo: mvr}
+
+	h.CreateRole(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSshCaHandler_SignCertificate_ShouldIssueAndAuditCertificate(t *testing.T) {
+	h, ctx, rec, recorder, caPub := prepareSignRequest(t, v.SignSshCertificateRequest{Principals: []string{"ops"}})
+
+	h.SignCertificate(ctx)
+
+	var resp v.SshCertificateResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"ops"}, resp.Principals)
+	assert.Equal(t, mockUser1.Id+":deploy", resp.KeyId)
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(resp.Certificate))
+	assert.NoError(t, err)
+	cert := pub.(*ssh.Certificate)
+	assert.Equal(t, caPub.Marshal(), cert.SignatureKey.Marshal())
+	assert.Equal(t, resp.Serial, fmt.Sprint(cert.Serial))
+
+	recorder.AssertCalled(t, "Record", mock.MatchedBy(func(e *audit.Event) bool {
+		return e.Action == audit.ActionSshCertIssue && e.TargetId == "mock-role" && e.Outcome == audit.OutcomeSuccess
+	}))
+}
+
+func TestSshCaHandler_SignCertificate_ShouldAuditPolicyViolation(t *testing.T) {
+	h, ctx, rec, recorder, _ := prepareSignRequest(t, v.SignSshCertificateRequest{Principals: []string{"root"}})
+
+	h.SignCertificate(ctx)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	recorder.AssertCalled(t, "Record", mock.MatchedBy(func(e *audit.Event) bool {
+		return e.Action == audit.ActionSshCertIssue && e.Outcome == audit.OutcomeFailure
+	}))
+}
+
+// prepareSignRequest wires a handler whose "deploy" role signs user
+// certificates for deploy and ops with a freshly generated CA key.
+func prepareSignRequest(t *testing.T, scr v.SignSshCertificateRequest) (*v.SshCaHandler, *gin.Context, *httptest.ResponseRecorder, *audit_mocks.EventRepository, ssh.PublicKey) {
+	ca, _ := sshkeys.Generate(sshkeys.KeyTypeEd25519, 0)
+	stored, _ := sshkeys.Export(ca, sshkeys.FormatOpenSSH, "", "")
+	caPub, _ := ssh.NewPublicKey(ca.Public())
+	user, _ := sshkeys.Generate(sshkeys.KeyTypeEd25519, 0)
+	info, _ := sshkeys.Describe(user, "")
+	scr.PublicKey = info.PublicKey
+
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, fmt.Sprintf("/api/v1/vaults/%s/ssh/roles/mock-role/sign", mockVault.Id), "POST", scr)
+	ctx.Set("user_id", mockUser1.Id)
+	ctx.AddParam("id", mockVault.Id)
+	ctx.AddParam("roleId", "mock-role")
+
+	mcr := vm.SshCaRepository{}
+	mcr.On("FindRoleById", "mock-role", mockVault.Id, mock.AnythingOfType("*vaults.SshCaRole")).Run(func(args mock.Arguments) {
+		r := args.Get(2).(*v.SshCaRole)
+		r.Id = "mock-role"
+		r.Name = "deploy"
+		r.CertType = "USER"
+		r.AllowedPrincipals = "deploy,ops"
+		r.MaxTtlSeconds = 3600
+		r.DefaultTtlSeconds = 600
+		r.CaKeyRefer = "mock-ca"
+	}).Return(nil)
+	msr := vm.SecretRepository{}
+	msr.On("FindSshKeyById", "mock-ca", mockVault.Id, mock.AnythingOfType("*vaults.SshKey")).Run(func(args mock.Arguments) {
+		k := args.Get(2).(*v.SshKey)
+		k.Id = "mock-ca"
+		k.PrivateKey = []byte("ENCRYPTED_CA")
+	}).Return(nil)
+	mvr, _ := ownedVaultMocks()
+
+	ep := utils_mocks.NewEncryptionProvider(t)
+	ep.On("Decrypt", "ENCRYPTED_CA").Return(string(stored), nil)
+	recorder := &audit_mocks.EventRepository{}
+	recorder.On("Record", mock.AnythingOfType("*audit.Event")).Return(nil)
+
+	h := &v.SshCaHandler{
+		Ep:         ep,
+		Repo:       &mcr,
+		SecretRepo: &msr,
+		VaultRepo:  mvr,
+		Audit:      recorder,
+	}
+	return h, ctx, rec, recorder, caPub
+}
@@ -0,0 +1,30 @@
+package vaults
+
+import "gorm.io/gorm"
+
+type SshCaRepository interface {
+	CreateRole(r *SshCaRole) error
+	FindRoles(vaultId string, roles *[]SshCaRole) error
+	FindRoleById(id string, vaultId string, r *SshCaRole) error
+	DeleteRole(r *SshCaRole) error
+}
+
+type SshCaRepositoryImpl struct {
+	Db *gorm.DB
+}
+
+func (scr *SshCaRepositoryImpl) CreateRole(r *SshCaRole) error {
+	return scr.Db.Create(r).Error
+}
+
+func (scr *SshCaRepositoryImpl) FindRoles(vaultId string, roles *[]SshCaRole) error {
+	return scr.Db.Where("vault_refer = ?", vaultId).Order("name ASC").Find(roles).Error
+}
+
+func (scr *SshCaRepositoryImpl) FindRoleById(id string, vaultId string, r *SshCaRole) error {
+	return scr.Db.Where("id = ? AND vault_refer = ?", id, vaultId).First(r).Error
+}
+
+func (scr *SshCaRepositoryImpl) DeleteRole(r *SshCaRole) error {
+	return scr.Db.Delete(r).Error
+}
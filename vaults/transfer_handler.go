@@ -0,0 +1,208 @@
+package vaults
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/blobs"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errTargetVaultMissing = errors.New("Target vault does not exist")
+	errSameVault          = errors.New("Secrets are already in the target vault")
+)
+
+// TransferHandler moves and copies secrets between the caller's vaults.
+// Every vault is encrypted under the same server key, so encrypted values
+// and attachment data keys are carried over without re-encryption.
+type TransferHandler struct {
+	Repo        SecretRepository
+	VaultRepo   VaultRepository
+	Folders     FolderRepository
+	Attachments AttachmentRepository
+	Blobs       blobs.BlobStore
+	Audit       audit.Recorder
+	Index       SearchIndexer
+}
+
+// transfer is a validated TransferSecretsRequest.
+type transfer struct {
+	sourceId string
+	targetId string
+	folderId *string
+	refs     []SecretSummary
+}
+
+func (th *TransferHandler) MoveSecrets(ctx *gin.Context) {
+	t, ok := th.bind(ctx)
+	if !ok {
+		return
+	}
+	if t.targetId == t.sourceId {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errSameVault.Error()})
+		return
+	}
+
+	if err := th.Repo.MoveSecrets(t.refs, t.targetId, t.folderId); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	ids := make([]string, len(t.refs))
+	for i, r := range t.refs {
+		ids[i] = r.Id
+	}
+	indexSecrets(th.Index, t.targetId, ids...)
+
+	resp := TransferSecretsResponse{VaultId: t.targetId, Secrets: make([]TransferredSecretResponse, 0, len(t.refs))}
+	for _, r := range t.refs {
+		audit.Log(th.Audit, ctx, audit.Event{
+			Action:     audit.ActionSecretMove,
+			TargetType: audit.TargetSecret,
+			TargetId:   r.Id,
+			Detail:     fmt.Sprintf("from vault %s to vault %s", t.sourceId, t.targetId),
+		})
+		resp.Secrets = append(resp.Secrets, TransferredSecretResponse{SourceId: r.Id, Id: r.Id})
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// CopySecrets duplicates secrets, which may also be into their own vault.
+// Attachment blobs are copied first and removed again if the copy fails.
+func (th *TransferHandler) CopySecrets(ctx *gin.Context) {
+	t, ok := th.bind(ctx)
+	if !ok {
+		return
+	}
+
+	copies := make([]SecretCopy, len(t.refs))
+	for i, r := range t.refs {
+		copies[i] = SecretCopy{Type: r.Type, Id: r.Id, NewId: uuid.NewString()}
+	}
+
+	attachments, err := th.copyAttachments(ctx.Request.Context(), copies, t.targetId)
+	if err != nil {
+		log.Printf("Could not copy attachments to vault %s: %v", t.targetId, err)
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	if err := th.Repo.CopySecrets(copies, t.targetId, t.folderId, attachments); err != nil {
+		deleteBlobs(ctx.Request.Context(), th.Blobs, attachments)
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	ids := make([]string, len(copies))
+	for i, c := range copies {
+		ids[i] = c.NewId
+	}
+	indexSecrets(th.Index, t.targetId, ids...)
+
+	resp := TransferSecretsResponse{VaultId: t.targetId, Secrets: make([]TransferredSecretResponse, 0, len(copies))}
+	for _, c := range copies {
+		audit.Log(th.Audit, ctx, audit.Event{
+			Action:     audit.ActionSecretCopy,
+			TargetType: audit.TargetSecret,
+			TargetId:   c.NewId,
+			Detail:     fmt.Sprintf("copy of secret %s in vault %s", c.Id, t.sourceId),
+		})
+		resp.Secrets = append(resp.Secrets, TransferredSecretResponse{SourceId: c.Id, Id: c.NewId})
+	}
+
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// bind checks that the caller owns both vaults, that the folder is in the
+// target vault and that every secret is in the source vault.
+func (th *TransferHandler) bind(ctx *gin.Context) (transfer, bool) {
+	var tsr TransferSecretsRequest
+	if err := ctx.ShouldBindJSON(&tsr); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: "Invalid Request body"})
+		return transfer{}, false
+	}
+
+	sourceId, ok := requireVaultOwner(ctx, th.VaultRepo)
+	if !ok {
+		return transfer{}, false
+	}
+	valid, err := ValidateVaultOwner(th.VaultRepo, tsr.TargetVaultId, ctx.GetString("user_id"))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return transfer{}, false
+	}
+	if !valid {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errTargetVaultMissing.Error()})
+		return transfer{}, false
+	}
+
+	if tsr.FolderId != "" {
+		var f Folder
+		if err := th.Folders.FindFolderById(tsr.FolderId, tsr.TargetVaultId, &f); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: errFolderMissing.Error()})
+				return transfer{}, false
+			}
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return transfer{}, false
+		}
+	}
+
+	t := transfer{sourceId: sourceId, targetId: tsr.TargetVaultId, folderId: optional(tsr.FolderId)}
+	for _, id := range tsr.SecretIds {
+		secretType, err := th.Repo.FindSecretType(id, sourceId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusBadRequest, common.ErrorResponse{Message: fmt.Sprintf("Secret %s is not in this vault", id)})
+				return transfer{}, false
+			}
+			ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+			return transfer{}, false
+		}
+		t.refs = append(t.refs, SecretSummary{Id: id, Type: secretType})
+	}
+	return t, true
+}
+
+// copyAttachments copies the blobs of every attachment of the copied
+// secrets under new keys in the target vault, returning their records. The
+// encrypted bytes and wrapped data key are reused as they are.
+func (th *TransferHandler) copyAttachments(ctx context.Context, copies []SecretCopy, vaultId string) ([]Attachment, error) {
+	var copied []Attachment
+	for _, c := range copies {
+		var attachments []Attachment
+		if err := th.Attachments.FindAttachments(c.Id, &attachments); err != nil {
+			deleteBlobs(ctx, th.Blobs, copied)
+			return nil, err
+		}
+		for _, a := range attachments {
+			source := a.StorageKey
+			a.Id = uuid.NewString()
+			a.SecretId = c.NewId
+			a.VaultRefer = vaultId
+			a.StorageKey = vaultId + "/" + a.Id
+			if err := th.copyBlob(ctx, source, a); err != nil {
+				deleteBlobs(ctx, th.Blobs, copied)
+				return nil, err
+			}
+			copied = append(copied, a)
+		}
+	}
+	return copied, nil
+}
+
+func (th *TransferHandler) copyBlob(ctx context.Context, source string, a Attachment) error {
+	r, err := th.Blobs.Get(ctx, source)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return th.Blobs.Put(ctx, a.StorageKey, r, utils.EncryptedSize(a.Size))
+}
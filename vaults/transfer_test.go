@@ -0,0 +1,176 @@
+package vaults_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/blobs"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/utils"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTransferHandler_MoveSecrets_ShouldMoveSecretsWithTheirMetadata(t *testing.T) {
+	xh, db := prepareTransfer(t)
+
+	resp, code := transferSecrets(t, xh.MoveSecrets, "work", vaults.TransferSecretsRequest{
+		SecretIds:     []string{"github", "deploy_key"},
+		TargetVaultId: "personal",
+		FolderId:      "archive",
+	})
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "personal", resp.VaultId)
+	assert.Equal(t, []vaults.TransferredSecretResponse{{SourceId: "github", Id: "github"}, {SourceId: "deploy_key", Id: "deploy_key"}}, resp.Secrets)
+
+	var c vaults.Credential
+	assert.NoError(t, db.First(&c, "id = ?", "github").Error)
+	assert.Equal(t, "personal", c.VaultRefer)
+	assert.Equal(t, "archive", *c.FolderId)
+	assert.Equal(t, "ci", c.Tags)
+	assert.True(t, c.UpdatedAt.Equal(transferTime), "moving must not restart the rotation schedule")
+
+	for _, model := range []any{&vaults.CustomField{}, &vaults.Attachment{}, &vaults.SshCaRole{}} {
+		var vaultIds []string
+		assert.NoError(t, db.Model(model).Pluck("vault_refer", &vaultIds).Error)
+		assert.Equal(t, []string{"personal"}, vaultIds, "%T", model)
+	}
+	var left int64
+	assert.NoError(t, db.Model(&vaults.Totp{}).Where("vault_refer = ?", "work").Count(&left).Error)
+	assert.Equal(t, int64(1), left)
+}
+
+func TestTransferHandler_CopySecrets_ShouldCopySecretsFieldsAndAttachments(t *testing.T) {
+	xh, db := prepareTransfer(t)
+
+	resp, code := transferSecrets(t, xh.CopySecrets, "work", vaults.TransferSecretsRequest{
+		SecretIds:     []string{"github"},
+		TargetVaultId: "personal",
+	})
+
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Len(t, resp.Secrets, 1)
+	copyId := resp.Secrets[0].Id
+	assert.NotEqual(t, "github", copyId)
+
+	var original, copied vaults.Credential
+	assert.NoError(t, db.First(&original, "id = ?", "github").Error)
+	assert.NoError(t, db.First(&copied, "id = ?", copyId).Error)
+	assert.Equal(t, "work", original.VaultRefer)
+	assert.Equal(t, "personal", copied.VaultRefer)
+	assert.Equal(t, original.Password, copied.Password)
+	assert.Equal(t, original.Name, copied.Name)
+	assert.Nil(t, copied.FolderId)
+	assert.True(t, copied.CreatedAt.Equal(original.CreatedAt))
+
+	var fields []vaults.CustomField
+	assert.NoError(t, db.Where("secret_id = ?", copyId).Find(&fields).Error)
+	assert.Len(t, fields, 1)
+	assert.Equal(t, "personal", fields[0].VaultRefer)
+
+	var attachments []vaults.Attachment
+	assert.NoError(t, db.Where("secret_id = ?", copyId).Find(&attachments).Error)
+	assert.Len(t, attachments, 1)
+	assert.Equal(t, "personal/"+attachments[0].Id, attachments[0].StorageKey)
+	assert.Equal(t, readBlob(t, xh.Blobs, "work/github_file"), readBlob(t, xh.Blobs, attachments[0].StorageKey))
+
+	var roles int64
+	assert.NoError(t, db.Model(&vaults.SshCaRole{}).Count(&roles).Error)
+	assert.Equal(t, int64(1), roles)
+}
+
+func TestTransferHandler_ShouldRejectInvalidTransfersWithoutChangingAnything(t *testing.T) {
+	xh, db := prepareTransfer(t)
+
+	for name, tc := range map[string]struct {
+		handle func(*gin.Context)
+		tsr    vaults.TransferSecretsRequest
+	}{
+		"other user's vault": {xh.MoveSecrets, vaults.TransferSecretsRequest{SecretIds: []string{"github"}, TargetVaultId: "other"}},
+		"unknown secret":     {xh.MoveSecrets, vaults.TransferSecretsRequest{SecretIds: []string{"github", "missing"}, TargetVaultId: "personal"}},
+		"same vault":         {xh.MoveSecrets, vaults.TransferSecretsRequest{SecretIds: []string{"github"}, TargetVaultId: "work"}},
+		"foreign folder":     {xh.CopySecrets, vaults.TransferSecretsRequest{SecretIds: []string{"github"}, TargetVaultId: "personal", FolderId: "work_folder"}},
+		"duplicate ids":      {xh.CopySecrets, vaults.TransferSecretsRequest{SecretIds: []string{"github", "github"}, TargetVaultId: "personal"}},
+	} {
+		_, code := transferSecrets(t, tc.handle, "work", tc.tsr)
+		assert.Equal(t, http.StatusBadRequest, code, name)
+	}
+
+	var count int64
+	assert.NoError(t, db.Model(&vaults.Credential{}).Where("vault_refer = ?", "work").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	assert.NoError(t, db.Model(&vaults.Credential{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+var transferTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// prepareTransfer stores a "work" vault of user_1 with a credential that
+// has a field and an attachment, an SSH CA key with a role and a TOTP seed,
+// an empty "personal" vault with an "archive" folder, and a vault of user_2.
+func prepareTransfer(t *testing.T) (*vaults.TransferHandler, *gorm.DB) {
+	db := prepareDb(t)
+
+	dataKey, err := utils.NewDataKey()
+	assert.NoError(t, err)
+	var blob bytes.Buffer
+	assert.NoError(t, utils.EncryptStream(&blob, strings.NewReader("blob"), dataKey))
+	store := &blobs.LocalStore{Dir: t.TempDir()}
+	assert.NoError(t, store.Put(context.Background(), "work/github_file", &blob, utils.EncryptedSize(4)))
+
+	for _, record := range []any{
+		&vaults.Vault{Id: "work", Name: "Work", UserRefer: "user_1"},
+		&vaults.Vault{Id: "personal", Name: "Personal", UserRefer: "user_1"},
+		&vaults.Vault{Id: "other", Name: "Other", UserRefer: "user_2"},
+		&vaults.Folder{Id: "archive", Name: "Archive", VaultRefer: "personal"},
+		&vaults.Folder{Id: "work_folder", Name: "Projects", VaultRefer: "work"},
+		&vaults.Credential{Id: "github", Name: "GitHub", Username: "octocat", Password: []byte("sealed"), VaultRefer: "work",
+			CreatedAt: transferTime, UpdatedAt: transferTime, Placement: vaults.Placement{Tags: "ci"}},
+		&vaults.CustomField{Id: "github_url", SecretId: "github", Name: "Website", Type: vaults.FieldUrl, Value: []byte("https://github.com"), VaultRefer: "work"},
+		&vaults.Attachment{Id: "github_file", SecretId: "github", Name: "codes.txt", Size: 4, StorageKey: "work/github_file", DataKey: []byte("x"), VaultRefer: "work"},
+		&vaults.SshKey{Id: "deploy_key", Name: "Deploy key", KeyType: "ed25519", PrivateKey: []byte("x"), PublicKey: "x", Fingerprint: "x", VaultRefer: "work"},
+		&vaults.SshCaRole{Id: "role", Name: "deploy", CertType: "user", AllowedPrincipals: "deploy", MaxTtlSeconds: 60, DefaultTtlSeconds: 60,
+			CaKeyRefer: "deploy_key", VaultRefer: "work"},
+		&vaults.Totp{Id: "totp", Name: "GitHub 2FA", Secret: []byte("x"), Algorithm: "SHA1", Digits: 6, Period: 30, VaultRefer: "work"},
+	} {
+		assert.NoError(t, db.Create(record).Error)
+	}
+
+	return &vaults.TransferHandler{
+		Repo:        &vaults.SecretRepositoryImpl{Db: db},
+		VaultRepo:   &vaults.VaultRepositoryImpl{Db: db},
+		Folders:     &vaults.FolderRepositoryImpl{Db: db},
+		Attachments: &vaults.AttachmentRepositoryImpl{Db: db},
+		Blobs:       store,
+	}, db
+}
+
+func transferSecrets(t *testing.T, handle func(*gin.Context), vaultId string, tsr vaults.TransferSecretsRequest) (vaults.TransferSecretsResponse, int) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, "/api/v1/vaults/"+vaultId+"/secrets/transfer", "POST", tsr)
+	ctx.Set("user_id", "user_1")
+	ctx.AddParam("id", vaultId)
+	handle(ctx)
+
+	var resp vaults.TransferSecretsResponse
+	if rec.Code < http.StatusBadRequest {
+		common.DecodeJSONResponse(t, rec, &resp)
+	}
+	return resp, rec.Code
+}
+
+func readBlob(t *testing.T, store blobs.BlobStore, key string) []byte {
+	r, err := store.Get(context.Background(), key)
+	assert.NoError(t, err)
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return content
+}
@@ -0,0 +1,140 @@
+package vaults
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/blobs"
+	"github.com/adarsh-a-tw/passwordly/common"
+)
+
+// fallbackTrashRetentionDays is used when TRASH_RETENTION_DAYS is not set.
+const fallbackTrashRetentionDays = 30
+
+// TrashedSecret is a secret in the trash, with when it was deleted.
+type TrashedSecret struct {
+	SecretSummary
+	DeletedAt time.Time
+}
+
+// trashRetention is how long deleted vaults and secrets can be restored
+// before they are purged.
+func trashRetention() time.Duration {
+	days := common.Cfg.TrashRetentionDays
+	if days == 0 {
+		days = fallbackTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+type Purger interface {
+	PurgeVault(ctx context.Context, v *Vault) error
+	PurgeSecret(ctx context.Context, s *TrashedSecret) error
+}
+
+// PurgerImpl permanently deletes trashed vaults and secrets, and then the
+// blobs of their attachments.
+type PurgerImpl struct {
+	VaultRepo   VaultRepository
+	SecretRepo  SecretRepository
+	Attachments AttachmentRepository
+	Blobs       blobs.BlobStore
+}
+
+func (p *PurgerImpl) PurgeVault(ctx context.Context, v *Vault) error {
+	var attachments []Attachment
+	if err := p.Attachments.FindAttachmentsByVault(v.Id, &attachments); err != nil {
+		return err
+	}
+	if err := p.VaultRepo.Purge(v); err != nil {
+		return err
+	}
+	deleteBlobs(ctx, p.Blobs, attachments)
+	return nil
+}
+
+func (p *PurgerImpl) PurgeSecret(ctx context.Context, s *TrashedSecret) error {
+	var attachments []Attachment
+	if err := p.Attachments.FindAttachments(s.Id, &attachments); err != nil {
+		return err
+	}
+	if err := p.SecretRepo.PurgeSecret(s.Type, s.Id); err != nil {
+		return err
+	}
+	deleteBlobs(ctx, p.Blobs, attachments)
+	return nil
+}
+
+// deleteBlobs removes the files of attachments that no longer have a record.
+// Without the record the data key is gone too, so blobs left behind by a
+// failure are unreadable; they are only logged.
+func deleteBlobs(ctx context.Context, store blobs.BlobStore, attachments []Attachment) {
+	for _, a := range attachments {
+		if err := store.Delete(ctx, a.StorageKey); err != nil {
+			log.Printf("Could not delete attachment blob %s: %v", a.StorageKey, err)
+		}
+	}
+}
+
+// PurgeScheduler periodically purges whatever has been in the trash for
+// longer than Retention.
+type PurgeScheduler struct {
+	Purger     Purger
+	VaultRepo  VaultRepository
+	SecretRepo SecretRepository
+	Interval   time.Duration
+	Retention  time.Duration
+}
+
+func (ps *PurgeScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(ps.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := ps.RunOnce(ctx, time.Now()); err != nil {
+			log.Println("Trash purge failed:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges the vaults and secrets deleted more than Retention before
+// now. A failure to purge one item is logged and the rest still go.
+func (ps *PurgeScheduler) RunOnce(ctx context.Context, now time.Time) error {
+	deletedBefore := now.Add(-ps.Retention)
+
+	var vaults []Vault
+	if err := ps.VaultRepo.FetchTrash("", deletedBefore, &vaults); err != nil {
+		return err
+	}
+	purged := 0
+	for i := range vaults {
+		if err := ps.Purger.PurgeVault(ctx, &vaults[i]); err != nil {
+			log.Printf("Could not purge vault %s: %v", vaults[i].Id, err)
+			continue
+		}
+		purged++
+	}
+
+	var secrets []TrashedSecret
+	if err := ps.SecretRepo.FindTrash("", deletedBefore, &secrets); err != nil {
+		return err
+	}
+	for i := range secrets {
+		if err := ps.Purger.PurgeSecret(ctx, &secrets[i]); err != nil {
+			log.Printf("Could not purge secret %s: %v", secrets[i].Id, err)
+			continue
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d vaults and secrets from the trash", purged)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package vaults
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/audit"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/gin-gonic/gin"
+)
+
+// TrashHandler lists the caller's deleted vaults and secrets and restores
+// or purges them before the purge scheduler does.
+type TrashHandler struct {
+	VaultRepo  VaultRepository
+	SecretRepo SecretRepository
+	Purger     Purger
+	Audit      audit.Recorder
+	Index      SearchIndexer
+}
+
+func (th *TrashHandler) FetchTrash(ctx *gin.Context) {
+	userId := ctx.GetString("user_id")
+	now := time.Now()
+
+	var vaults []Vault
+	if err := th.VaultRepo.FetchTrash(userId, now, &vaults); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	var secrets []TrashedSecret
+	if err := th.SecretRepo.FindTrash(userId, now, &secrets); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	var resp TrashResponse
+	resp.load(vaults, secrets, trashRetention())
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// RestoreVault brings a vault back from the trash with all of its secrets.
+func (th *TrashHandler) RestoreVault(ctx *gin.Context) {
+	v, ok := th.findVault(ctx)
+	if !ok {
+		return
+	}
+
+	if err := th.VaultRepo.Restore(&v); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	reindex(th.Index, v.Id)
+
+	audit.Log(th.Audit, ctx, audit.Event{
+		Action:     audit.ActionVaultRestore,
+		TargetType: audit.TargetVault,
+		TargetId:   v.Id,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Vault restored successfully"})
+}
+
+// PurgeVault permanently deletes a vault in the trash.
+func (th *TrashHandler) PurgeVault(ctx *gin.Context) {
+	v, ok := th.findVault(ctx)
+	if !ok {
+		return
+	}
+
+	if err := th.Purger.PurgeVault(ctx.Request.Context(), &v); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(th.Audit, ctx, audit.Event{
+		Action:     audit.ActionVaultPurge,
+		TargetType: audit.TargetVault,
+		TargetId:   v.Id,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Vault deleted permanently"})
+}
+
+// RestoreSecret brings a secret back from the trash into its vault.
+func (th *TrashHandler) RestoreSecret(ctx *gin.Context) {
+	s, ok := th.findSecret(ctx)
+	if !ok {
+		return
+	}
+
+	if err := th.SecretRepo.RestoreSecret(s.Type, s.Id); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+	indexSecrets(th.Index, s.VaultId, s.Id)
+
+	audit.Log(th.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretRestore,
+		TargetType: audit.TargetSecret,
+		TargetId:   s.Id,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Secret restored successfully"})
+}
+
+// PurgeSecret permanently deletes a secret in the trash.
+func (th *TrashHandler) PurgeSecret(ctx *gin.Context) {
+	s, ok := th.findSecret(ctx)
+	if !ok {
+		return
+	}
+
+	if err := th.Purger.PurgeSecret(ctx.Request.Context(), &s); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.InternalServerError())
+		return
+	}
+
+	audit.Log(th.Audit, ctx, audit.Event{
+		Action:     audit.ActionSecretPurge,
+		TargetType: audit.TargetSecret,
+		TargetId:   s.Id,
+	})
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Secret deleted permanently"})
+}
+
+// findVault loads the :id vault from the caller's trash, responding with
+// 404 when it is not there.
+func (th *TrashHandler) findVault(ctx *gin.Context) (Vault, bool) {
+	var v Vault
+	if err := th.VaultRepo.FetchTrashedById(ctx.Param("id"), &v); err != nil {
+		handleGormError(ctx, err)
+		return Vault{}, false
+	}
+	if v.UserRefer != ctx.GetString("user_id") {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return Vault{}, false
+	}
+	return v, true
+}
+
+func (th *TrashHandler) findSecret(ctx *gin.Context) (TrashedSecret, bool) {
+	var s TrashedSecret
+	if err := th.SecretRepo.FindTrashedSecret(ctx.Param("secretId"), ctx.GetString("user_id"), &s); err != nil {
+		handleGormError(ctx, err)
+		return TrashedSecret{}, false
+	}
+	return s, true
+}
@@ -0,0 +1,202 @@
+package vaults_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adarsh-a-tw/passwordly/blobs"
+	"github.com/adarsh-a-tw/passwordly/common"
+	"github.com/adarsh-a-tw/passwordly/vaults"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTrash_DeletedSecretShouldBeHiddenUntilRestored(t *testing.T) {
+	tf := prepareTrash(t)
+
+	ctx, rec := trashRequest(t, "DELETE", "/api/v1/vaults/work/secrets/github", "user_1", gin.Params{{Key: "id", Value: "work"}, {Key: "secretId", Value: "github"}})
+	tf.sh.DeleteSecret(ctx)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var summaries []vaults.SecretSummary
+	assert.NoError(t, tf.secrets.FindPage("work", vaults.SecretFilter{}, vaults.PageQuery{Sort: vaults.SortName, Limit: 10}, &summaries))
+	assert.Len(t, summaries, 1)
+	_, err := tf.secrets.FindSecretType("github", "work")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	trash := fetchTrash(t, tf, "user_1")
+	assert.Empty(t, trash.Vaults)
+	assert.Len(t, trash.Secrets, 1)
+	assert.Equal(t, "github", trash.Secrets[0].Id)
+	assert.Equal(t, "Work", trash.Secrets[0].VaultName)
+	assert.Equal(t, int64(30*24*60*60), trash.Secrets[0].PurgeAt-trash.Secrets[0].DeletedAt)
+
+	ctx, rec = trashRequest(t, "POST", "/api/v1/trash/secrets/github/restore", "user_1", gin.Params{{Key: "secretId", Value: "github"}})
+	tf.th.RestoreSecret(ctx)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	secretType, err := tf.secrets.FindSecretType("github", "work")
+	assert.NoError(t, err)
+	assert.Equal(t, vaults.TypeCredential, secretType)
+	assert.Empty(t, fetchTrash(t, tf, "user_1").Secrets)
+}
+
+func TestTrash_DeletedVaultShouldBeHiddenWithItsSecretsUntilRestored(t *testing.T) {
+	tf := prepareTrash(t)
+	assert.NoError(t, tf.vaults.Delete(&vaults.Vault{Id: "work"}))
+
+	var remaining []vaults.Vault
+	assert.NoError(t, tf.vaults.FetchByUserId("user_1", &remaining))
+	assert.Len(t, remaining, 1)
+	var scheduled []vaults.ScheduledSecret
+	assert.NoError(t, tf.secrets.FindScheduled("user_1", &scheduled))
+	assert.Empty(t, scheduled)
+
+	trash := fetchTrash(t, tf, "user_1")
+	assert.Len(t, trash.Vaults, 1)
+	assert.Equal(t, "work", trash.Vaults[0].Id)
+	assert.Empty(t, trash.Secrets)
+
+	ctx, rec := trashRequest(t, "POST", "/api/v1/trash/vaults/work/restore", "user_1", gin.Params{{Key: "id", Value: "work"}})
+	tf.th.RestoreVault(ctx)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var v vaults.Vault
+	assert.NoError(t, tf.vaults.FetchById("work", &v))
+	var summaries []vaults.SecretSummary
+	assert.NoError(t, tf.secrets.FindPage("work", vaults.SecretFilter{}, vaults.PageQuery{Sort: vaults.SortName, Limit: 10}, &summaries))
+	assert.Len(t, summaries, 2)
+}
+
+func TestTrash_ShouldOnlyRestoreAndPurgeOwnTrash(t *testing.T) {
+	tf := prepareTrash(t)
+	assert.NoError(t, tf.vaults.Delete(&vaults.Vault{Id: "work"}))
+
+	ctx, rec := trashRequest(t, "POST", "/api/v1/trash/vaults/work/restore", "user_2", gin.Params{{Key: "id", Value: "work"}})
+	tf.th.RestoreVault(ctx)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	ctx, rec = trashRequest(t, "DELETE", "/api/v1/trash/vaults/work", "user_2", gin.Params{{Key: "id", Value: "work"}})
+	tf.th.PurgeVault(ctx)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	ctx, rec = trashRequest(t, "DELETE", "/api/v1/trash/vaults/personal", "user_1", gin.Params{{Key: "id", Value: "personal"}})
+	tf.th.PurgeVault(ctx)
+	assert.Equal(t, http.StatusNotFound, rec.Code, "only trashed vaults can be purged")
+
+	assert.Empty(t, fetchTrash(t, tf, "user_2").Vaults)
+}
+
+func TestTrash_PurgeVaultShouldRemoveEverythingInIt(t *testing.T) {
+	tf := prepareTrash(t)
+	assert.NoError(t, tf.db.Create(&vaults.Key{Id: "api_key", Name: "Stripe", Value: []byte("x"), VaultRefer: "work"}).Error)
+	assert.NoError(t, tf.db.Create(&vaults.Document{Id: "contract", Name: "Contract", Content: []byte("x"), VaultRefer: "work"}).Error)
+	assert.NoError(t, tf.secrets.DeleteSecret(vaults.TypeCredential, "github", "work"))
+	assert.NoError(t, tf.vaults.Delete(&vaults.Vault{Id: "work"}))
+
+	ctx, rec := trashRequest(t, "DELETE", "/api/v1/trash/vaults/work", "user_1", gin.Params{{Key: "id", Value: "work"}})
+	tf.th.PurgeVault(ctx)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	ids := []string{"work", "github", "totp", "api_key", "contract", "github_url", "github_file"}
+	for _, model := range []any{&vaults.Vault{}, &vaults.Credential{}, &vaults.Totp{}, &vaults.Key{}, &vaults.Document{}, &vaults.CustomField{}, &vaults.Attachment{}} {
+		var count int64
+		assert.NoError(t, tf.db.Unscoped().Model(model).Where("id IN ?", ids).Count(&count).Error)
+		assert.Zero(t, count, "%T", model)
+	}
+	_, err := os.Stat(filepath.Join(tf.blobDir, "work", "github_file"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPurgeScheduler_RunOnce_ShouldOnlyPurgeExpiredTrash(t *testing.T) {
+	tf := prepareTrash(t)
+	assert.NoError(t, tf.secrets.DeleteSecret(vaults.TypeCredential, "github", "work"))
+	assert.NoError(t, tf.secrets.DeleteSecret(vaults.TypeCredential, "mail", "personal"))
+	assert.NoError(t, tf.db.Table("credentials").Where("id = ?", "github").UpdateColumn("deleted_at", time.Now().AddDate(0, 0, -8)).Error)
+
+	ps := &vaults.PurgeScheduler{Purger: tf.th.Purger, VaultRepo: tf.vaults, SecretRepo: tf.secrets, Retention: 7 * 24 * time.Hour}
+	assert.NoError(t, ps.RunOnce(context.Background(), time.Now()))
+
+	trash := fetchTrash(t, tf, "user_1")
+	assert.Len(t, trash.Secrets, 1)
+	assert.Equal(t, "mail", trash.Secrets[0].Id)
+
+	var count int64
+	assert.NoError(t, tf.db.Unscoped().Model(&vaults.Credential{}).Where("id = ?", "github").Count(&count).Error)
+	assert.Zero(t, count)
+	assert.NoError(t, tf.db.Model(&vaults.CustomField{}).Where("secret_id = ?", "github").Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+type trashFixture struct {
+	db      *gorm.DB
+	vaults  *vaults.VaultRepositoryImpl
+	secrets *vaults.SecretRepositoryImpl
+	sh      *vaults.SecretHandler
+	th      *vaults.TrashHandler
+	blobDir string
+}
+
+// prepareTrash stores a "work" vault of user_1 holding a credential with a
+// field and an attachment plus a TOTP seed, and a "personal" vault with one
+// credential.
+func prepareTrash(t *testing.T) *trashFixture {
+	db := prepareDb(t)
+
+	blobDir := t.TempDir()
+	store := &blobs.LocalStore{Dir: blobDir}
+	assert.NoError(t, store.Put(context.Background(), "work/github_file", strings.NewReader("blob"), 4))
+
+	dueAt := time.Now().AddDate(0, 1, 0)
+	for _, record := range []any{
+		&vaults.Vault{Id: "work", Name: "Work", UserRefer: "user_1"},
+		&vaults.Vault{Id: "personal", Name: "Personal", UserRefer: "user_1"},
+		&vaults.Credential{Id: "github", Name: "GitHub", Username: "octocat", Password: []byte("x"), VaultRefer: "work",
+			Expiry: vaults.Expiry{ExpiresAt: &dueAt}},
+		&vaults.Totp{Id: "totp", Name: "GitHub 2FA", Secret: []byte("x"), Algorithm: "SHA1", Digits: 6, Period: 30, VaultRefer: "work"},
+		&vaults.CustomField{Id: "github_url", SecretId: "github", Name: "Website", Type: vaults.FieldUrl, Value: []byte("https://github.com"), VaultRefer: "work"},
+		&vaults.Attachment{Id: "github_file", SecretId: "github", Name: "codes.txt", Size: 4, StorageKey: "work/github_file", DataKey: []byte("x"), VaultRefer: "work"},
+		&vaults.Credential{Id: "mail", Name: "Mail", Username: "me", Password: []byte("x"), VaultRefer: "personal"},
+	} {
+		assert.NoError(t, db.Create(record).Error)
+	}
+
+	vaultRepo := &vaults.VaultRepositoryImpl{Db: db}
+	secretRepo := &vaults.SecretRepositoryImpl{Db: db}
+	return &trashFixture{
+		db:      db,
+		vaults:  vaultRepo,
+		secrets: secretRepo,
+		sh:      &vaults.SecretHandler{Repo: secretRepo, VaultRepo: vaultRepo},
+		th: &vaults.TrashHandler{
+			VaultRepo:  vaultRepo,
+			SecretRepo: secretRepo,
+			Purger:     vaults.NewPurger(db, store),
+		},
+		blobDir: blobDir,
+	}
+}
+
+func trashRequest(t *testing.T, method string, url string, userId string, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	ctx, rec := common.PrepareContextAndResponseRecorder(t, url, method, nil)
+	ctx.Set("user_id", userId)
+	ctx.Params = params
+	return ctx, rec
+}
+
+func fetchTrash(t *testing.T, tf *trashFixture, userId string) vaults.TrashResponse {
+	ctx, rec := trashRequest(t, "GET", "/api/v1/trash", userId, nil)
+	tf.th.FetchTrash(ctx)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp vaults.TrashResponse
+	common.DecodeJSONResponse(t, rec, &resp)
+	return resp
+}
@@ -14,6 +14,15 @@ func validateSecretType(fl validator.FieldLevel) bool {
 	return secretType.IsValid()
 }
 
+func validateFieldType(fl validator.FieldLevel) bool {
+	fieldType, ok := fl.Field().Interface().(FieldType)
+	if !ok {
+		return false
+	}
+
+	return fieldType.IsValid()
+}
+
 func RegisterValidations() {
 
 	validators := []struct {
@@ -24,6 +33,10 @@ func RegisterValidations() {
 			name:      "secret_type",
 			validator: validateSecretType,
 		},
+		{
+			name:      "field_type",
+			validator: validateFieldType,
+		},
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {